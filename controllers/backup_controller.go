@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"github.com/d4r1us-drk/clido/repository"
+)
+
+// BackupController manages database backups and restores.
+type BackupController struct {
+	repo *repository.Repository
+}
+
+// NewBackupController creates and returns a new instance of BackupController.
+func NewBackupController(repo *repository.Repository) *BackupController {
+	return &BackupController{repo: repo}
+}
+
+// CreateBackup writes a backup of the database to the given path, or to the backup directory
+// when path is empty, and returns the path of the backup.
+func (bc *BackupController) CreateBackup(path string) (string, error) {
+	return bc.repo.Backup(path)
+}
+
+// AutoBackup writes an automatic backup before a destructive operation, rotating older ones.
+func (bc *BackupController) AutoBackup(reason string) (string, error) {
+	return bc.repo.AutoBackup(reason)
+}
+
+// ListBackups returns the backups of the database, newest first.
+func (bc *BackupController) ListBackups() ([]*repository.BackupInfo, error) {
+	return bc.repo.ListBackups()
+}
+
+// BackupDir returns the directory holding the backups of the database.
+func (bc *BackupController) BackupDir() string {
+	return bc.repo.BackupDir()
+}
+
+// RestoreBackup replaces the database with a backup, given by path or by name in the backup directory.
+// It returns the path of the automatic backup of the replaced database.
+func (bc *BackupController) RestoreBackup(nameOrPath string) (string, error) {
+	return bc.repo.Restore(bc.repo.ResolveBackupPath(nameOrPath))
+}
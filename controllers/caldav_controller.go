@@ -0,0 +1,919 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/d4r1us-drk/clido/internal/caldav"
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"github.com/d4r1us-drk/clido/models"
+	"github.com/d4r1us-drk/clido/repository"
+)
+
+// Settings of the CalDAV sync.
+const (
+	CalDAVCalendarPrefix = "clido-"        // Prefix of the calendars created for projects
+	CalDAVStateProperty  = "X-CLIDO-STATE" // VTODO property holding the exact workflow state of a task
+)
+
+// ErrNoCalDAVServer is returned when syncing without a CalDAV server URL given or saved.
+var ErrNoCalDAVServer = errcode.New(errcode.Validation, "no CalDAV server configured, set its URL first")
+
+// CalDAVResult summarizes a CalDAV sync: the changes applied to the database, the changes sent to the
+// server and the conflicts met.
+type CalDAVResult struct {
+	URL       string          // The URL of the calendar home collection
+	Created   int             // Projects and tasks created from server changes
+	Updated   int             // Projects and tasks updated from server changes
+	Deleted   int             // Projects and tasks deleted from server changes
+	Uploaded  int             // Calendars and tasks created or updated on the server
+	Removed   int             // Calendars and tasks deleted from the server
+	Conflicts []*SyncConflict // Items changed on both sides, with the side that was kept
+	Backup    string          // The automatic backup written before applying server changes, if any
+}
+
+// CalDAVController syncs projects and tasks with a CalDAV server, mapping projects to calendar collections
+// and tasks to VTODO objects.
+type CalDAVController struct {
+	repo *repository.Repository
+}
+
+// NewCalDAVController creates and returns a new instance of CalDAVController.
+func NewCalDAVController(repo *repository.Repository) *CalDAVController {
+	return &CalDAVController{repo: repo}
+}
+
+// Sync runs a two-way sync with the CalDAV server whose calendar home collection is at homeURL, or with
+// the saved server when homeURL is empty. The URL and username are saved for later syncs; the password
+// is not. Changes are detected with the ETags of the server and hashes of the local items recorded at
+// the last sync. Items changed on both sides keep the most recently modified version, and items deleted
+// on one side and changed on the other are kept.
+func (cc *CalDAVController) Sync(homeURL, username, password string) (*CalDAVResult, error) {
+	settings, err := cc.repo.GetCalDAVSettings()
+	if err != nil {
+		return nil, err
+	}
+	if homeURL == "" {
+		if settings == nil {
+			return nil, ErrNoCalDAVServer
+		}
+		homeURL = settings.URL
+	}
+	if username == "" && settings != nil && settings.URL == homeURL {
+		username = settings.Username
+	}
+
+	client, err := caldav.NewClient(homeURL, username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	// The sync state recorded for another server is meaningless
+	if settings != nil && settings.URL != homeURL {
+		if err = cc.repo.DeleteCalDAVItems(); err != nil {
+			return nil, err
+		}
+	}
+	if err = cc.repo.SaveCalDAVSettings(&models.CalDAVSettings{URL: homeURL, Username: username}); err != nil {
+		return nil, err
+	}
+
+	s := &caldavSync{
+		repo:     cc.repo,
+		client:   client,
+		workflow: cc.repo.Workflow(),
+		result:   &CalDAVResult{URL: homeURL},
+	}
+	if err = s.load(); err != nil {
+		return nil, err
+	}
+	if err = s.syncProjects(); err != nil {
+		return nil, err
+	}
+	if err = s.syncTasks(); err != nil {
+		return nil, err
+	}
+	return s.result, nil
+}
+
+// caldavSync holds the state of a CalDAV sync in progress.
+type caldavSync struct {
+	repo     *repository.Repository
+	client   *caldav.Client
+	workflow *models.Workflow
+	result   *CalDAVResult
+
+	local            *syncState                    // The local projects and tasks, by UUID
+	ids              *syncIDs                      // The local IDs of the projects and tasks, by UUID
+	items            map[string]*models.CalDAVItem // The sync state of the last sync, by href
+	calendars        map[string]*caldav.Calendar   // The calendars of the server, by href
+	projectCalendars map[string]string             // The calendar hrefs of the projects, by UUID
+}
+
+// load reads the local items, their sync state and the calendars of the server.
+func (s *caldavSync) load() error {
+	var err error
+	if s.local, s.ids, err = NewSyncController(s.repo).localSyncState(); err != nil {
+		return err
+	}
+
+	items, err := s.repo.GetCalDAVItems()
+	if err != nil {
+		return err
+	}
+	s.items = make(map[string]*models.CalDAVItem)
+	for _, item := range items {
+		s.items[item.Href] = item
+	}
+
+	calendars, err := s.client.ListCalendars()
+	if err != nil {
+		return err
+	}
+	s.calendars = make(map[string]*caldav.Calendar)
+	for _, calendar := range calendars {
+		s.calendars[calendar.Href] = calendar
+	}
+	s.projectCalendars = make(map[string]string)
+	return nil
+}
+
+// syncProjects syncs the projects with the calendars of the server. Project names are synced with calendar
+// display names; subprojects get their own top-level calendars.
+func (s *caldavSync) syncProjects() error {
+	for _, href := range sortedKeys(s.items) {
+		item := s.items[href]
+		if item.ItemType != models.SyncItemProject {
+			continue
+		}
+		if err := s.syncMappedProject(item); err != nil {
+			return err
+		}
+	}
+
+	linked := make(map[string]bool)
+	for _, href := range s.projectCalendars {
+		linked[href] = true
+	}
+
+	// Local projects without a calendar are linked to an unmapped calendar with the same name, or get a new one
+	for _, key := range sortedKeys(s.local.projects) {
+		if s.projectCalendars[key] != "" {
+			continue
+		}
+		name := s.local.projects[key][models.RecordFieldName]
+
+		href := ""
+		for _, calendarHref := range sortedKeys(s.calendars) {
+			calendar := s.calendars[calendarHref]
+			if !linked[calendarHref] && s.items[calendarHref] == nil && calendar.SupportsToDo &&
+				calendar.DisplayName == name {
+				href = calendarHref
+				break
+			}
+		}
+		if href == "" {
+			var err error
+			if href, err = s.client.CreateCalendar(CalDAVCalendarPrefix+key, name); err != nil {
+				return err
+			}
+			s.result.Uploaded++
+		}
+
+		linked[href] = true
+		if err := s.saveProjectItem(&models.CalDAVItem{}, key, href); err != nil {
+			return err
+		}
+	}
+
+	// Calendars without a project get a new project
+	for _, href := range sortedKeys(s.calendars) {
+		calendar := s.calendars[href]
+		if linked[href] || !calendar.SupportsToDo {
+			continue
+		}
+		key, err := s.createLocalProject(calendar)
+		if err != nil {
+			return err
+		}
+		if err = s.saveProjectItem(&models.CalDAVItem{}, key, href); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncMappedProject syncs a project that was mapped to a calendar at the last sync.
+func (s *caldavSync) syncMappedProject(item *models.CalDAVItem) error {
+	key := item.UID
+	record, localExists := s.local.projects[key]
+	calendar := s.calendars[item.Href]
+	localChanged := localExists && hashSyncRecord(record) != item.Hash
+
+	switch {
+	case !localExists:
+		// Deleted locally: the calendar and its tasks are deleted from the server
+		if calendar != nil {
+			if err := s.client.Delete(item.Href, ""); err != nil {
+				return err
+			}
+			delete(s.calendars, item.Href)
+			s.result.Removed++
+		}
+		return s.deleteItem(item)
+
+	case calendar == nil:
+		if !localChanged && !s.projectTasksChanged(key) {
+			// Deleted on the server and unchanged locally
+			if err := s.deleteLocalProject(key); err != nil {
+				return err
+			}
+			return s.deleteItem(item)
+		}
+		// Changed locally: the project is kept and gets a new calendar, where its tasks are created again
+		s.forgetCalendarItems(item.Href)
+		s.result.Conflicts = append(s.result.Conflicts, &SyncConflict{
+			ItemType: models.SyncItemProject, Key: key, Name: record[models.RecordFieldName],
+			Local: "changed", Remote: "deleted", Kept: SyncSideLocal,
+		})
+		return s.deleteItem(item)
+	}
+
+	s.projectCalendars[key] = item.Href
+	name := record[models.RecordFieldName]
+	if calendar.DisplayName != "" && calendar.DisplayName != name {
+		if localChanged {
+			if err := s.client.RenameCalendar(item.Href, name); err != nil {
+				return err
+			}
+			s.result.Uploaded++
+		} else if err := s.renameLocalProject(key, calendar.DisplayName); err != nil {
+			return err
+		}
+	}
+	return s.saveProjectItem(item, key, item.Href)
+}
+
+// syncTasks syncs the tasks with the VTODO objects of the calendars mapped to projects.
+func (s *caldavSync) syncTasks() error {
+	objects := make(map[string]*caldav.Object)
+	objectProjects := make(map[string]string)
+	for projectKey, calendarHref := range s.projectCalendars {
+		calendarObjects, err := s.client.ListObjects(calendarHref)
+		if err != nil {
+			return err
+		}
+		for _, object := range calendarObjects {
+			objects[object.Href] = object
+			objectProjects[object.Href] = projectKey
+		}
+	}
+
+	var pulled []*caldavPull
+	handled := make(map[string]bool)
+	seen := make(map[string]bool)
+
+	for _, href := range sortedKeys(s.items) {
+		item := s.items[href]
+		if item.ItemType != models.SyncItemTask {
+			continue
+		}
+		handled[item.UID] = true
+		seen[href] = true
+		pull, err := s.syncMappedTask(item, objects[href], objectProjects[href])
+		if err != nil {
+			return err
+		}
+		if pull != nil {
+			pulled = append(pulled, pull)
+		}
+	}
+
+	// Objects created on the server
+	for _, href := range sortedKeys(objects) {
+		if seen[href] {
+			continue
+		}
+		object, todo, err := s.fetch(href)
+		if err != nil {
+			return err
+		}
+		if todo == nil {
+			continue
+		}
+
+		key := todo.UID
+		if key == "" || handled[key] {
+			key = path.Base(href)
+		}
+		handled[key] = true
+
+		if _, exists := s.local.tasks[key]; exists {
+			// Known locally but not recorded, e.g. after the sync state was reset
+			pull, resolveErr := s.resolveTaskConflict(&models.CalDAVItem{}, key, object, todo, objectProjects[href])
+			if resolveErr != nil {
+				return resolveErr
+			}
+			if pull != nil {
+				pulled = append(pulled, pull)
+			}
+			continue
+		}
+
+		pull, err := s.pullTask(&models.CalDAVItem{}, key, object, todo, objectProjects[href])
+		if err != nil {
+			return err
+		}
+		pulled = append(pulled, pull)
+	}
+
+	// Tasks created locally
+	for _, key := range sortedKeys(s.local.tasks) {
+		if handled[key] {
+			continue
+		}
+		if err := s.pushTask(&models.CalDAVItem{}, key, ""); err != nil {
+			return err
+		}
+	}
+
+	return s.finishPulls(pulled)
+}
+
+// syncMappedTask syncs a task that was mapped to a VTODO object at the last sync. It returns the pulled
+// task, if any, whose parent is resolved once every task is pulled.
+func (s *caldavSync) syncMappedTask(
+	item *models.CalDAVItem,
+	object *caldav.Object,
+	projectKey string,
+) (*caldavPull, error) {
+	key := item.UID
+	record, localExists := s.local.tasks[key]
+	localChanged := !localExists || hashSyncRecord(record) != item.Hash
+	remoteChanged := object == nil || object.ETag != item.ETag
+
+	switch {
+	case !localChanged && !remoteChanged:
+		return nil, nil
+
+	case !localExists && object == nil:
+		return nil, s.deleteItem(item)
+
+	case !localExists && !remoteChanged:
+		if err := s.client.Delete(item.Href, item.ETag); err != nil {
+			return nil, err
+		}
+		s.result.Removed++
+		return nil, s.deleteItem(item)
+
+	case object == nil && !localChanged:
+		if err := s.deleteLocalTask(key); err != nil {
+			return nil, err
+		}
+		return nil, s.deleteItem(item)
+
+	case !localExists:
+		// Deleted locally but changed on the server: the task is restored
+		fetched, todo, err := s.fetch(item.Href)
+		if err != nil || todo == nil {
+			return nil, err
+		}
+		s.result.Conflicts = append(s.result.Conflicts, &SyncConflict{
+			ItemType: models.SyncItemTask, Key: key, Name: todo.Summary,
+			Local: "deleted", Remote: "changed", Kept: SyncSideRemote,
+		})
+		return s.pullTask(item, key, fetched, todo, projectKey)
+
+	case object == nil:
+		// Deleted on the server but changed locally: the task is created again
+		s.result.Conflicts = append(s.result.Conflicts, &SyncConflict{
+			ItemType: models.SyncItemTask, Key: key, Name: record[models.RecordFieldName],
+			Local: "changed", Remote: "deleted", Kept: SyncSideLocal,
+		})
+		if err := s.deleteItem(item); err != nil {
+			return nil, err
+		}
+		return nil, s.pushTask(&models.CalDAVItem{}, key, "")
+
+	case !remoteChanged:
+		return nil, s.pushTask(item, key, item.ETag)
+
+	case !localChanged:
+		fetched, todo, err := s.fetch(item.Href)
+		if err != nil || todo == nil {
+			return nil, err
+		}
+		return s.pullTask(item, key, fetched, todo, projectKey)
+
+	default:
+		fetched, todo, err := s.fetch(item.Href)
+		if err != nil || todo == nil {
+			return nil, err
+		}
+		return s.resolveTaskConflict(item, key, fetched, todo, projectKey)
+	}
+}
+
+// resolveTaskConflict keeps the most recently modified version of a task changed on both sides.
+func (s *caldavSync) resolveTaskConflict(
+	item *models.CalDAVItem,
+	key string,
+	object *caldav.Object,
+	todo *caldav.Todo,
+	projectKey string,
+) (*caldavPull, error) {
+	task, err := s.repo.GetTaskByID(s.ids.tasks[key])
+	if err != nil {
+		return nil, err
+	}
+
+	conflict := &SyncConflict{
+		ItemType: models.SyncItemTask, Key: key, Name: task.Name,
+		Local: "changed", Remote: "changed", Kept: SyncSideLocal,
+	}
+	s.result.Conflicts = append(s.result.Conflicts, conflict)
+
+	if todo.LastModified == nil || !task.LastUpdatedDate.Before(*todo.LastModified) {
+		item.Href = object.Href
+		return nil, s.pushTask(item, key, object.ETag)
+	}
+	conflict.Kept = SyncSideRemote
+	return s.pullTask(item, key, object, todo, projectKey)
+}
+
+// caldavPull is a task pulled from the server, whose parent is resolved once every task is pulled.
+type caldavPull struct {
+	item      *models.CalDAVItem
+	task      *models.Task
+	parentUID string
+}
+
+// pullTask creates or updates the local task of a VTODO object, in the project of its calendar.
+func (s *caldavSync) pullTask(
+	item *models.CalDAVItem,
+	key string,
+	object *caldav.Object,
+	todo *caldav.Todo,
+	projectKey string,
+) (*caldavPull, error) {
+	if err := s.beforeLocalChange(); err != nil {
+		return nil, err
+	}
+
+	// Tasks created from the server keep the UID of their object as UUID, so they are pushed back to it
+	task := &models.Task{UUID: key}
+	if id, exists := s.ids.tasks[key]; exists {
+		existing, err := s.repo.GetTaskByID(id)
+		if err != nil {
+			return nil, err
+		}
+		task = existing
+	}
+	s.applyTodo(task, todo)
+	task.ProjectID = s.ids.projects[projectKey]
+
+	if task.ID == 0 {
+		if err := s.repo.CreateTask(task); err != nil {
+			return nil, err
+		}
+		s.ids.tasks[key] = task.ID
+		s.result.Created++
+	} else {
+		if err := s.repo.ReplaceTask(task); err != nil {
+			return nil, err
+		}
+		s.result.Updated++
+	}
+
+	item.ItemType, item.ItemID, item.UID = models.SyncItemTask, task.ID, key
+	item.Href, item.ETag = object.Href, object.ETag
+	return &caldavPull{item: item, task: task, parentUID: todo.RelatedTo}, nil
+}
+
+// finishPulls sets the parents of the pulled tasks, now that every task exists locally, and records
+// their sync state.
+func (s *caldavSync) finishPulls(pulled []*caldavPull) error {
+	taskKeys := make(map[int]string)
+	for key, id := range s.ids.tasks {
+		taskKeys[id] = key
+	}
+	projectKeys := make(map[int]string)
+	for key, id := range s.ids.projects {
+		projectKeys[id] = key
+	}
+
+	for _, pull := range pulled {
+		task := pull.task
+		task.ParentTaskID = nil
+		if parentID, found := s.ids.tasks[pull.parentUID]; found && parentID != task.ID {
+			parent, err := s.repo.GetTaskByID(parentID)
+			if err == nil && parent.ProjectID == task.ProjectID {
+				task.ParentTaskID = &parentID
+			}
+		}
+		if err := s.repo.ReplaceTask(task); err != nil {
+			return err
+		}
+
+		parentKey := ""
+		if task.ParentTaskID != nil {
+			parentKey = taskKeys[*task.ParentTaskID]
+		}
+		key := taskKeys[task.ID]
+		s.local.tasks[key] = models.TaskRecord(task, projectKeys[task.ProjectID], parentKey)
+	}
+
+	// Hashes are computed once all parents are set
+	for _, pull := range pulled {
+		pull.item.Hash = hashSyncRecord(s.local.tasks[pull.item.UID])
+		if err := s.repo.SaveCalDAVItem(pull.item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pushTask creates or updates the VTODO object of a local task in the calendar of its project. The etag
+// is the ETag of the object on the server, or empty if it must not exist yet. Objects changed on the
+// server in the meantime are left for the next sync.
+func (s *caldavSync) pushTask(item *models.CalDAVItem, key, etag string) error {
+	task, err := s.repo.GetTaskByID(s.ids.tasks[key])
+	if err != nil {
+		return err
+	}
+	record := s.local.tasks[key]
+	calendarHref := s.projectCalendars[record[models.RecordFieldProject]]
+	if calendarHref == "" {
+		return nil
+	}
+
+	// Properties clido does not know are kept
+	var extra map[string]string
+	if etag != "" {
+		if _, existing, fetchErr := s.fetch(item.Href); fetchErr == nil && existing != nil {
+			extra = existing.Extra
+		}
+	}
+	data := s.todoFromTask(task, key, record[models.RecordFieldParent], extra).Encode()
+
+	// Tasks moved to another project move to the calendar of that project
+	href := item.Href
+	if href == "" || path.Dir(href)+"/" != calendarHref {
+		href = calendarHref + key + ".ics"
+	}
+	if item.Href != "" && item.Href != href {
+		if err = s.client.Delete(item.Href, etag); err != nil {
+			return err
+		}
+		etag = ""
+	}
+
+	newETag, err := s.client.PutObject(href, data, etag)
+	if errors.Is(err, caldav.ErrPreconditionFailed) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if newETag == "" {
+		// Some servers only return the ETag when the object is fetched
+		object, getErr := s.client.GetObject(href)
+		if getErr != nil {
+			return getErr
+		}
+		newETag = object.ETag
+	}
+	s.result.Uploaded++
+
+	item.ItemType, item.ItemID, item.UID = models.SyncItemTask, task.ID, key
+	item.Href, item.ETag, item.Hash = href, newETag, hashSyncRecord(record)
+	return s.repo.SaveCalDAVItem(item)
+}
+
+// fetch gets a calendar object and parses its VTODO. The todo is nil for objects without one, e.g. events.
+func (s *caldavSync) fetch(href string) (*caldav.Object, *caldav.Todo, error) {
+	object, err := s.client.GetObject(href)
+	if err != nil {
+		return nil, nil, err
+	}
+	todo, err := caldav.ParseTodo(object.Data)
+	if errors.Is(err, caldav.ErrNoTodo) {
+		return object, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return object, todo, nil
+}
+
+// todoFromTask returns the VTODO representation of a task.
+func (s *caldavSync) todoFromTask(task *models.Task, key, parentKey string, extra map[string]string) *caldav.Todo {
+	todo := &caldav.Todo{
+		UID:          key,
+		Summary:      task.Name,
+		Description:  task.Description,
+		Status:       s.todoStatus(task.State),
+		Priority:     todoPriority(task.Priority),
+		Due:          task.DueDate,
+		Created:      &task.CreationDate,
+		LastModified: &task.LastUpdatedDate,
+		RelatedTo:    parentKey,
+		Extra:        extra,
+	}
+	if s.workflow.IsTerminal(task.State) {
+		todo.Completed = task.CompletionDate
+	}
+	todo.SetProperty(CalDAVStateProperty, task.State)
+	return todo
+}
+
+// applyTodo sets the fields of a task, except its project and parent, from a VTODO.
+func (s *caldavSync) applyTodo(task *models.Task, todo *caldav.Todo) {
+	task.Name = todo.Summary
+	if task.Name == "" {
+		task.Name = "(untitled)"
+	}
+	task.Description = todo.Description
+	task.Priority = taskPriority(todo.Priority)
+	task.DueDate = todo.Due
+	task.State = s.taskState(todo)
+	task.TaskCompleted = task.State == models.StateDone
+
+	task.CompletionDate = nil
+	if s.workflow.IsTerminal(task.State) {
+		completed := time.Now()
+		if todo.Completed != nil {
+			completed = *todo.Completed
+		}
+		task.CompletionDate = &completed
+	}
+	if todo.Created != nil {
+		task.CreationDate = *todo.Created
+	}
+	task.LastUpdatedDate = time.Now()
+	if todo.LastModified != nil {
+		task.LastUpdatedDate = *todo.LastModified
+	}
+}
+
+// todoStatus returns the VTODO status matching a workflow state.
+func (s *caldavSync) todoStatus(state string) string {
+	switch {
+	case state == models.StateDone:
+		return caldav.StatusCompleted
+	case s.workflow.IsTerminal(state):
+		return caldav.StatusCancelled
+	case state == s.workflow.InitialState:
+		return caldav.StatusNeedsAction
+	default:
+		return caldav.StatusInProcess
+	}
+}
+
+// taskState returns the workflow state matching a VTODO. The exact state saved by clido is used when it
+// still matches the status, which other clients may have changed.
+func (s *caldavSync) taskState(todo *caldav.Todo) string {
+	status := todo.Status
+	if status == "" {
+		status = caldav.StatusNeedsAction
+	}
+	if state := todo.Property(CalDAVStateProperty); s.workflow.HasState(state) && s.todoStatus(state) == status {
+		return state
+	}
+
+	switch {
+	case status == caldav.StatusCompleted:
+		return models.StateDone
+	case status == caldav.StatusCancelled && s.workflow.HasState(models.StateCancelled):
+		return models.StateCancelled
+	case status == caldav.StatusCancelled:
+		return models.StateDone
+	case status == caldav.StatusInProcess && s.workflow.HasState(models.StateInProgress):
+		return models.StateInProgress
+	default:
+		return s.workflow.InitialState
+	}
+}
+
+// todoPriority maps a task priority (1: High to 4: None) onto a VTODO priority (1 to 9, 0 for none).
+func todoPriority(priority int) int {
+	switch priority {
+	case 1:
+		return 1
+	case 2:
+		return 5
+	case 3:
+		return 9
+	default:
+		return 0
+	}
+}
+
+// taskPriority maps a VTODO priority onto a task priority, following the ranges of RFC 5545.
+func taskPriority(priority int) int {
+	switch {
+	case priority >= 1 && priority <= 4:
+		return 1
+	case priority == 5:
+		return 2
+	case priority >= 6 && priority <= 9:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// projectTasksChanged reports whether any task of a project was created or changed locally since the
+// last sync.
+func (s *caldavSync) projectTasksChanged(projectKey string) bool {
+	hashes := make(map[string]string)
+	for _, item := range s.items {
+		if item.ItemType == models.SyncItemTask {
+			hashes[item.UID] = item.Hash
+		}
+	}
+	for key, record := range s.local.tasks {
+		if record[models.RecordFieldProject] == projectKey && hashes[key] != hashSyncRecord(record) {
+			return true
+		}
+	}
+	return false
+}
+
+// createLocalProject creates a project for a calendar of the server and returns its UUID. Projects
+// are named after the calendar, made unique if needed.
+func (s *caldavSync) createLocalProject(calendar *caldav.Calendar) (string, error) {
+	if err := s.beforeLocalChange(); err != nil {
+		return "", err
+	}
+
+	name := calendar.DisplayName
+	if name == "" {
+		name = path.Base(calendar.Href)
+	}
+	name = s.uniqueProjectName(name)
+
+	project := &models.Project{Name: name}
+	if err := s.repo.CreateProject(project); err != nil {
+		return "", err
+	}
+
+	s.ids.projects[project.UUID] = project.ID
+	s.local.projects[project.UUID] = models.ProjectRecord(project, "")
+	s.result.Created++
+	return project.UUID, nil
+}
+
+// renameLocalProject gives a project the name of its calendar, renamed on the server.
+func (s *caldavSync) renameLocalProject(key, name string) error {
+	project, err := s.repo.GetProjectByID(s.ids.projects[key])
+	if err != nil {
+		return err
+	}
+	if existing, _ := s.repo.GetProjectByName(name); existing != nil {
+		// The name is taken by another project, so the calendar gets the name of the project instead
+		if renameErr := s.client.RenameCalendar(s.projectCalendars[key], project.Name); renameErr != nil {
+			return renameErr
+		}
+		s.result.Uploaded++
+		return nil
+	}
+
+	if err = s.beforeLocalChange(); err != nil {
+		return err
+	}
+	project.Name = name
+	project.LastModifiedDate = time.Now()
+	if err = s.repo.ReplaceProject(project); err != nil {
+		return err
+	}
+	s.local.projects[key] = models.ProjectRecord(project, s.local.projects[key][models.RecordFieldParent])
+	s.result.Updated++
+	return nil
+}
+
+// deleteLocalProject deletes a project whose calendar was deleted on the server, with its tasks.
+// Its subprojects, which have their own calendars, are moved to the top level.
+func (s *caldavSync) deleteLocalProject(key string) error {
+	if err := s.beforeLocalChange(); err != nil {
+		return err
+	}
+	id := s.ids.projects[key]
+
+	tasks, err := s.repo.GetTasksByProjectID(id)
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		if err = s.repo.DeleteTask(task.ID); err != nil {
+			return err
+		}
+		s.forgetLocalTask(task.ID)
+	}
+
+	subprojects, err := s.repo.GetSubprojects(id)
+	if err != nil {
+		return err
+	}
+	for _, subproject := range subprojects {
+		subproject.ParentProjectID = nil
+		if err = s.repo.ReplaceProject(subproject); err != nil {
+			return err
+		}
+	}
+
+	if err = s.repo.DeleteProject(id); err != nil {
+		return err
+	}
+	delete(s.local.projects, key)
+	delete(s.ids.projects, key)
+	s.result.Deleted++
+	return nil
+}
+
+// deleteLocalTask deletes a task whose object was deleted on the server, with its subtasks.
+func (s *caldavSync) deleteLocalTask(key string) error {
+	if err := s.beforeLocalChange(); err != nil {
+		return err
+	}
+	id := s.ids.tasks[key]
+
+	removed, err := NewTaskController(s.repo).RemoveTask(id)
+	if err != nil {
+		return err
+	}
+	for _, removedID := range removed {
+		s.forgetLocalTask(removedID)
+	}
+	s.result.Deleted += len(removed)
+	return nil
+}
+
+// forgetLocalTask removes a deleted task from the local state.
+func (s *caldavSync) forgetLocalTask(id int) {
+	for key, taskID := range s.ids.tasks {
+		if taskID == id {
+			delete(s.ids.tasks, key)
+			delete(s.local.tasks, key)
+		}
+	}
+}
+
+// uniqueProjectName returns the name, or the name with a number appended if a project already has it.
+func (s *caldavSync) uniqueProjectName(name string) string {
+	unique := name
+	for suffix := 2; ; suffix++ {
+		if existing, _ := s.repo.GetProjectByName(unique); existing == nil {
+			return unique
+		}
+		unique = name + " (" + strconv.Itoa(suffix) + ")"
+	}
+}
+
+// saveProjectItem records the sync state of a project mapped to a calendar.
+func (s *caldavSync) saveProjectItem(item *models.CalDAVItem, key, href string) error {
+	s.projectCalendars[key] = href
+	item.ItemType, item.ItemID, item.UID = models.SyncItemProject, s.ids.projects[key], key
+	item.Href, item.ETag, item.Hash = href, "", hashSyncRecord(s.local.projects[key])
+	return s.repo.SaveCalDAVItem(item)
+}
+
+// forgetCalendarItems removes the sync state of the objects of a calendar, so they are created again.
+func (s *caldavSync) forgetCalendarItems(calendarHref string) {
+	for href, item := range s.items {
+		if item.ItemType == models.SyncItemTask && path.Dir(href)+"/" == calendarHref {
+			delete(s.items, href)
+			_ = s.repo.DeleteCalDAVItem(item.ID)
+		}
+	}
+}
+
+// deleteItem removes the sync state of an item that no longer exists on either side.
+func (s *caldavSync) deleteItem(item *models.CalDAVItem) error {
+	delete(s.items, item.Href)
+	return s.repo.DeleteCalDAVItem(item.ID)
+}
+
+// beforeLocalChange writes an automatic backup before the first change applied to the database.
+func (s *caldavSync) beforeLocalChange() error {
+	if s.result.Backup != "" {
+		return nil
+	}
+	backup, err := s.repo.AutoBackup("caldav")
+	if err != nil {
+		return err
+	}
+	s.result.Backup = backup
+	return nil
+}
+
+// hashSyncRecord returns a hash of a sync record, used to detect local changes between syncs.
+func hashSyncRecord(record syncRecord) string {
+	data, _ := marshalSyncRecord(record)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
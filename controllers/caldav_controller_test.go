@@ -0,0 +1,267 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/internal/caldav"
+	"github.com/d4r1us-drk/clido/internal/caldav/caldavtest"
+	"github.com/d4r1us-drk/clido/models"
+	"github.com/d4r1us-drk/clido/repository"
+	"github.com/d4r1us-drk/clido/utils"
+)
+
+// caldavReplica is a database synced with a CalDAV server, holding a project with a task and a subtask.
+type caldavReplica struct {
+	repo     *repository.Repository
+	tasks    *controllers.TaskController
+	server   *caldavtest.Server
+	calendar string // The href of the calendar of the project
+	task     *models.Task
+	subtask  *models.Task
+}
+
+// newCalDAVReplica creates a project with a task and a subtask, and pushes them to a new CalDAV server.
+func newCalDAVReplica(t *testing.T) *caldavReplica {
+	t.Helper()
+
+	repo := newTestRepository(t)
+	r := &caldavReplica{repo: repo, tasks: controllers.NewTaskController(repo), server: caldavtest.NewServer()}
+	t.Cleanup(r.server.Close)
+
+	project, err := controllers.NewProjectController(repo).CreateProject("Work", "", "")
+	if err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+	if r.task, err = r.tasks.CreateTask("Report", "Quarterly", "Work", "", "", utils.PriorityHigh, nil); err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+	if r.subtask, err = r.tasks.CreateTask("Draft", "", "Work", r.task.UUID, "", utils.PriorityNone, nil); err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+
+	// The calendar and both objects are created
+	if result := r.sync(t); result.Uploaded != 3 || result.Created != 0 {
+		t.Fatalf("first sync uploaded %d and created %d item(s), want 3 and 0", result.Uploaded, result.Created)
+	}
+	r.calendar = caldavtest.HomePath + controllers.CalDAVCalendarPrefix + project.UUID + "/"
+	if name := r.server.Calendars()[r.calendar]; name != "Work" {
+		t.Fatalf("calendar %s is named %q, want %q", r.calendar, name, "Work")
+	}
+	return r
+}
+
+// sync syncs the database with the server, failing the test on errors.
+func (r *caldavReplica) sync(t *testing.T) *controllers.CalDAVResult {
+	t.Helper()
+
+	result, err := controllers.NewCalDAVController(r.repo).Sync(r.server.HomeURL(), "user", "secret")
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	return result
+}
+
+// href returns the href of the object of a task.
+func (r *caldavReplica) href(task *models.Task) string {
+	return r.calendar + task.UUID + ".ics"
+}
+
+// todo returns the VTODO of a task on the server, failing the test when it does not exist.
+func (r *caldavReplica) todo(t *testing.T, task *models.Task) *caldav.Todo {
+	t.Helper()
+
+	data, _, exists := r.server.Object(r.href(task))
+	if !exists {
+		t.Fatalf("object %s does not exist", r.href(task))
+	}
+	todo, err := caldav.ParseTodo(data)
+	if err != nil {
+		t.Fatalf("ParseTodo() error = %v", err)
+	}
+	return todo
+}
+
+func TestCalDAVPushesTasks(t *testing.T) {
+	r := newCalDAVReplica(t)
+
+	if objects := r.server.Objects(r.calendar); len(objects) != 2 {
+		t.Fatalf("calendar holds %v, want the objects of both tasks", objects)
+	}
+	todo := r.todo(t, r.task)
+	if todo.UID != r.task.UUID || todo.Summary != "Report" || todo.Description != "Quarterly" ||
+		todo.Priority != 1 || todo.Status != caldav.StatusNeedsAction {
+		t.Errorf("VTODO of the task = %+v, want UID %s, Report, Quarterly, priority 1, %s",
+			todo, r.task.UUID, caldav.StatusNeedsAction)
+	}
+	if subtodo := r.todo(t, r.subtask); subtodo.RelatedTo != r.task.UUID {
+		t.Errorf("VTODO of the subtask is related to %q, want %q", subtodo.RelatedTo, r.task.UUID)
+	}
+
+	// Nothing changed: no object is fetched or stored
+	gets, puts := r.server.Requests(http.MethodGet), r.server.Requests(http.MethodPut)
+	result := r.sync(t)
+	if result.Uploaded != 0 || result.Created != 0 || result.Updated != 0 || result.Deleted != 0 {
+		t.Errorf("sync without changes = %+v, want no changes", result)
+	}
+	if r.server.Requests(http.MethodGet) != gets || r.server.Requests(http.MethodPut) != puts {
+		t.Error("sync without changes fetched or stored objects")
+	}
+}
+
+func TestCalDAVPullsTasks(t *testing.T) {
+	r := newCalDAVReplica(t)
+
+	// Another client creates a task in the calendar of the project, and a calendar with a subtask
+	call := &caldav.Todo{UID: "call-uid", Summary: "Call back", Priority: 5, Status: caldav.StatusInProcess}
+	r.server.PutObject(r.calendar+"call.ics", call.Encode())
+	home := r.server.AddCalendar("home", "Home", "VTODO")
+	r.server.PutObject(home+"paint.ics", (&caldav.Todo{UID: "paint-uid", Summary: "Paint"}).Encode())
+	buy := &caldav.Todo{UID: "buy-uid", Summary: "Buy paint", RelatedTo: "paint-uid"}
+	r.server.PutObject(home+"buy.ics", buy.Encode())
+	r.server.AddCalendar("events", "Events", "VEVENT")
+
+	if result := r.sync(t); result.Created != 4 || result.Updated != 0 {
+		t.Fatalf("sync created %d and updated %d item(s), want 4 and 0", result.Created, result.Updated)
+	}
+
+	// Pulled tasks keep the UIDs of their objects as UUIDs
+	pulled, err := r.repo.GetTaskByUUID("call-uid")
+	if err != nil {
+		t.Fatalf("GetTaskByUUID() error = %v", err)
+	}
+	if pulled.Name != "Call back" || pulled.ProjectID != r.task.ProjectID || pulled.Priority != utils.PriorityMedium ||
+		pulled.State != models.StateInProgress {
+		t.Errorf("pulled task = %+v, want Call back in Work, with medium priority, in progress", pulled)
+	}
+
+	project, err := r.repo.GetProjectByName("Home")
+	if err != nil {
+		t.Fatalf("GetProjectByName() error = %v", err)
+	}
+	paint, paintErr := r.repo.GetTaskByUUID("paint-uid")
+	subtask, subtaskErr := r.repo.GetTaskByUUID("buy-uid")
+	if paintErr != nil || subtaskErr != nil {
+		t.Fatalf("GetTaskByUUID() errors = %v, %v", paintErr, subtaskErr)
+	}
+	if subtask.ProjectID != project.ID || subtask.ParentTaskID == nil || *subtask.ParentTaskID != paint.ID {
+		t.Errorf("pulled subtask is in project %d under %v, want project %d under %d",
+			subtask.ProjectID, subtask.ParentTaskID, project.ID, paint.ID)
+	}
+	if _, err = r.repo.GetProjectByName("Events"); err == nil {
+		t.Error("a project was created for a calendar without tasks")
+	}
+
+	if result := r.sync(t); result.Created != 0 || result.Updated != 0 || result.Uploaded != 0 {
+		t.Errorf("sync after the pull = %+v, want no changes", result)
+	}
+}
+
+func TestCalDAVDetectsChangedETags(t *testing.T) {
+	r := newCalDAVReplica(t)
+
+	// Another client renames the task, giving its object a new ETag
+	todo := r.todo(t, r.task)
+	todo.Summary = "Annual report"
+	r.server.PutObject(r.href(r.task), todo.Encode())
+
+	gets := r.server.Requests(http.MethodGet)
+	if result := r.sync(t); result.Updated != 1 || result.Uploaded != 0 {
+		t.Fatalf("sync updated %d and uploaded %d item(s), want 1 and 0", result.Updated, result.Uploaded)
+	}
+	if fetched := r.server.Requests(http.MethodGet) - gets; fetched != 1 {
+		t.Errorf("sync fetched %d object(s), want only the changed one", fetched)
+	}
+	pulled, err := r.repo.GetTaskByUUID(r.task.UUID)
+	if err != nil || pulled.Name != "Annual report" || pulled.Description != "Quarterly" {
+		t.Fatalf("pulled task = %+v, %v, want Annual report, Quarterly", pulled, err)
+	}
+
+	// The task is then renamed locally and the object is replaced with the ETag of the pull
+	_, etag, _ := r.server.Object(r.href(r.task))
+	name := "Final report"
+	if _, err = r.tasks.UpdateTask(pulled.ID, controllers.TaskChanges{Name: &name}); err != nil {
+		t.Fatalf("UpdateTask() error = %v", err)
+	}
+	if result := r.sync(t); result.Uploaded != 1 || result.Updated != 0 {
+		t.Fatalf("sync uploaded %d and updated %d item(s), want 1 and 0", result.Uploaded, result.Updated)
+	}
+	if _, newETag, _ := r.server.Object(r.href(r.task)); newETag == etag {
+		t.Error("the object kept its ETag")
+	}
+	if todo = r.todo(t, r.task); todo.Summary != name {
+		t.Errorf("pushed summary = %q, want %q", todo.Summary, name)
+	}
+}
+
+func TestCalDAVSyncsDeletions(t *testing.T) {
+	r := newCalDAVReplica(t)
+
+	// Deleted on the server: the task is deleted locally
+	r.server.Delete(r.href(r.subtask))
+	if result := r.sync(t); result.Deleted != 1 || result.Removed != 0 {
+		t.Fatalf("sync deleted %d and removed %d item(s), want 1 and 0", result.Deleted, result.Removed)
+	}
+	if _, err := r.repo.GetTaskByUUID(r.subtask.UUID); err == nil {
+		t.Error("the task deleted on the server still exists")
+	}
+
+	// Deleted locally: the object is deleted from the server
+	if _, err := r.tasks.RemoveTask(r.task.ID); err != nil {
+		t.Fatalf("RemoveTask() error = %v", err)
+	}
+	if result := r.sync(t); result.Removed != 1 || result.Deleted != 0 {
+		t.Fatalf("sync removed %d and deleted %d item(s), want 1 and 0", result.Removed, result.Deleted)
+	}
+	if objects := r.server.Objects(r.calendar); len(objects) != 0 {
+		t.Errorf("calendar holds %v, want no objects", objects)
+	}
+
+	// A calendar deleted on the server deletes its unchanged project
+	r.server.Delete(r.calendar)
+	if result := r.sync(t); result.Deleted != 1 {
+		t.Fatalf("sync deleted %d item(s), want the project", result.Deleted)
+	}
+	if _, err := r.repo.GetProjectByName("Work"); err == nil {
+		t.Error("the project of the deleted calendar still exists")
+	}
+}
+
+func TestCalDAVKeepsTasksDeletedAndChanged(t *testing.T) {
+	r := newCalDAVReplica(t)
+
+	// Changed locally and deleted on the server: the object is created again
+	name := "Annual report"
+	if _, err := r.tasks.UpdateTask(r.task.ID, controllers.TaskChanges{Name: &name}); err != nil {
+		t.Fatalf("UpdateTask() error = %v", err)
+	}
+	r.server.Delete(r.href(r.task))
+
+	result := r.sync(t)
+	if len(result.Conflicts) != 1 || result.Conflicts[0].Kept != controllers.SyncSideLocal ||
+		result.Conflicts[0].Remote != "deleted" {
+		t.Fatalf("sync conflicts = %v, want the local task kept over the deletion", result.Conflicts)
+	}
+	if todo := r.todo(t, r.task); todo.Summary != name {
+		t.Errorf("recreated summary = %q, want %q", todo.Summary, name)
+	}
+
+	// Deleted locally and changed on the server: the task is restored with its UUID
+	todo := r.todo(t, r.subtask)
+	todo.Summary = "First draft"
+	r.server.PutObject(r.href(r.subtask), todo.Encode())
+	if _, err := r.tasks.RemoveTask(r.subtask.ID); err != nil {
+		t.Fatalf("RemoveTask() error = %v", err)
+	}
+
+	result = r.sync(t)
+	if len(result.Conflicts) != 1 || result.Conflicts[0].Kept != controllers.SyncSideRemote ||
+		result.Conflicts[0].Local != "deleted" {
+		t.Fatalf("sync conflicts = %v, want the server task kept over the deletion", result.Conflicts)
+	}
+	restored, err := r.repo.GetTaskByUUID(r.subtask.UUID)
+	if err != nil || restored.Name != "First draft" {
+		t.Fatalf("restored task = %+v, %v, want First draft", restored, err)
+	}
+}
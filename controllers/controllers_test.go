@@ -0,0 +1,21 @@
+package controllers_test
+
+import (
+	"testing"
+
+	"github.com/d4r1us-drk/clido/repository"
+)
+
+// newTestRepository opens the default workspace of a data directory created for the test.
+func newTestRepository(t *testing.T) *repository.Repository {
+	t.Helper()
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("APPDATA", t.TempDir())
+	repo, err := repository.NewRepository("")
+	if err != nil {
+		t.Fatalf("NewRepository() error = %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"github.com/d4r1us-drk/clido/repository"
+)
+
+// ErrInvalidSteps is returned when a rollback is asked for less than one migration.
+var ErrInvalidSteps = errcode.New(errcode.Validation, "the number of migrations to roll back must be at least 1")
+
+// DatabaseController manages the schema migrations of the database.
+type DatabaseController struct {
+	repo *repository.Repository
+}
+
+// NewDatabaseController creates and returns a new instance of DatabaseController.
+func NewDatabaseController(repo *repository.Repository) *DatabaseController {
+	return &DatabaseController{repo: repo}
+}
+
+// Migrate applies the pending migrations up to the target version (all of them when target is empty)
+// and returns the versions applied.
+func (dc *DatabaseController) Migrate(target string) ([]string, error) {
+	return dc.repo.MigrateDatabase(target)
+}
+
+// Rollback reverts the migrations newer than the target version, or the latest steps migrations
+// when target is empty, and returns the versions reverted.
+func (dc *DatabaseController) Rollback(target string, steps int) ([]string, error) {
+	if target == "" && steps < 1 {
+		return nil, ErrInvalidSteps
+	}
+	return dc.repo.RollbackDatabase(target, steps)
+}
+
+// CheckSchema returns repository.ErrSchemaOutdated when the database has pending migrations after a
+// rollback, as it must be migrated before it is used.
+func (dc *DatabaseController) CheckSchema() error {
+	return dc.repo.CheckSchema()
+}
+
+// Status returns the state of the migrations of the database, and the hold pausing automatic
+// migrations after a rollback (nil if there is none).
+func (dc *DatabaseController) Status() ([]*repository.MigrationStatus, *repository.MigrationHold, error) {
+	return dc.repo.MigrationStatus()
+}
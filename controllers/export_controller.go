@@ -0,0 +1,461 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"github.com/d4r1us-drk/clido/models"
+	"github.com/d4r1us-drk/clido/repository"
+	"github.com/d4r1us-drk/clido/utils"
+)
+
+// Error constants for export and import operations.
+var (
+	ErrUnsupportedSchema = errcode.New(errcode.Validation, "unsupported export schema version")
+	ErrInvalidImport     = errcode.New(errcode.Validation, "invalid import data")
+	ErrImportConflict    = errcode.New(errcode.Conflict, "a project with the same name already exists")
+)
+
+// Conflict strategies used when an imported project has the same name as an existing one.
+const (
+	ImportConflictFail   = "fail"   // Abort the import
+	ImportConflictMerge  = "merge"  // Import the tasks and subprojects into the existing project
+	ImportConflictRename = "rename" // Import the project under a new, unique name
+)
+
+// ImportResult summarizes an import, mapping the IDs found in the imported data to the IDs
+// of the created (or merged) projects and tasks.
+type ImportResult struct {
+	ProjectIDs     map[int]int
+	TaskIDs        map[int]int
+	MergedProjects int
+}
+
+// ExportController manages exporting the database to JSON and importing it back.
+type ExportController struct {
+	repo *repository.Repository
+}
+
+// NewExportController creates and returns a new instance of ExportController.
+func NewExportController(repo *repository.Repository) *ExportController {
+	return &ExportController{repo: repo}
+}
+
+// Export returns every project and task of the database. When nested is true, projects contain
+// their subprojects and tasks, and tasks contain their subtasks; otherwise all items are listed flat.
+func (ec *ExportController) Export(nested bool) (*models.Export, error) {
+	projects, err := ec.repo.GetAllProjects()
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := ec.repo.GetAllTasks()
+	if err != nil {
+		return nil, err
+	}
+
+	export := &models.Export{
+		SchemaVersion: models.ExportSchemaVersion,
+		ExportedAt:    time.Now(),
+		Nested:        nested,
+	}
+
+	if !nested {
+		for _, project := range projects {
+			export.Projects = append(export.Projects, toExportProject(project))
+		}
+		for _, task := range tasks {
+			export.Tasks = append(export.Tasks, toExportTask(task))
+		}
+		return export, nil
+	}
+
+	export.Projects = nestProjects(projects, tasks)
+	return export, nil
+}
+
+// Import recreates the projects and tasks of an export in the database, in a single transaction.
+// New IDs and UUIDs are assigned to every item and parent relationships are remapped accordingly. The
+// conflict strategy decides what happens when a project with the same name already exists.
+func (ec *ExportController) Import(export *models.Export, conflict string) (*ImportResult, error) {
+	return ec.importExport(export, conflict, false)
+}
+
+// importExport imports an export like Import, keeping the UUIDs of the exported items when keepUUIDs is
+// set, e.g. when they are moved from another workspace.
+func (ec *ExportController) importExport(
+	export *models.Export,
+	conflict string,
+	keepUUIDs bool,
+) (*ImportResult, error) {
+	if export.SchemaVersion < 1 || export.SchemaVersion > models.ExportSchemaVersion {
+		return nil, fmt.Errorf("%w: %d (supported: 1 to %d)",
+			ErrUnsupportedSchema, export.SchemaVersion, models.ExportSchemaVersion)
+	}
+	if conflict != ImportConflictFail && conflict != ImportConflictMerge && conflict != ImportConflictRename {
+		return nil, fmt.Errorf("%w: unknown conflict strategy '%s'", ErrInvalidImport, conflict)
+	}
+
+	projects, tasks := export.Projects, export.Tasks
+	if export.Nested {
+		projects, tasks = flattenExport(export.Projects)
+	}
+
+	result := &ImportResult{ProjectIDs: make(map[int]int), TaskIDs: make(map[int]int)}
+	err := ec.repo.Transaction(func(txRepo *repository.Repository) error {
+		importer := &importer{
+			repo:      txRepo,
+			conflict:  conflict,
+			keepUUIDs: keepUUIDs,
+			result:    result,
+			projects:  make(map[int]*models.ExportProject, len(projects)),
+			tasks:     make(map[int]*models.ExportTask, len(tasks)),
+			visiting:  make(map[string]bool),
+		}
+		return importer.run(projects, tasks)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// importer inserts flattened export data, remapping IDs as items are created.
+type importer struct {
+	repo      *repository.Repository
+	conflict  string
+	keepUUIDs bool
+	result    *ImportResult
+	projects  map[int]*models.ExportProject
+	tasks     map[int]*models.ExportTask
+	visiting  map[string]bool
+}
+
+// run indexes the items by their exported IDs, then imports every project and task,
+// making sure parents are always created before their children.
+func (im *importer) run(projects []*models.ExportProject, tasks []*models.ExportTask) error {
+	for _, project := range projects {
+		if _, duplicate := im.projects[project.ID]; duplicate {
+			return fmt.Errorf("%w: duplicate project ID %d", ErrInvalidImport, project.ID)
+		}
+		im.projects[project.ID] = project
+	}
+	for _, task := range tasks {
+		if _, duplicate := im.tasks[task.ID]; duplicate {
+			return fmt.Errorf("%w: duplicate task ID %d", ErrInvalidImport, task.ID)
+		}
+		im.tasks[task.ID] = task
+	}
+
+	for _, project := range projects {
+		if _, err := im.importProject(project.ID); err != nil {
+			return err
+		}
+	}
+	for _, task := range tasks {
+		if _, err := im.importTask(task.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importProject creates the project with the given exported ID (after its parent) and returns its new ID.
+func (im *importer) importProject(exportID int) (int, error) {
+	if newID, done := im.result.ProjectIDs[exportID]; done {
+		return newID, nil
+	}
+
+	exported, exists := im.projects[exportID]
+	if !exists {
+		return 0, fmt.Errorf("%w: unknown project ID %d", ErrInvalidImport, exportID)
+	}
+	if exported.Name == "" {
+		return 0, fmt.Errorf("%w: project %d: %w", ErrInvalidImport, exportID, ErrNoProjectName)
+	}
+
+	key := "project:" + strconv.Itoa(exportID)
+	if im.visiting[key] {
+		return 0, fmt.Errorf("%w: project %d: %w", ErrInvalidImport, exportID, ErrProjectCycle)
+	}
+	im.visiting[key] = true
+
+	var parentID *int
+	if exported.ParentID != nil {
+		newParentID, err := im.importProject(*exported.ParentID)
+		if err != nil {
+			return 0, err
+		}
+		parentID = &newParentID
+	}
+
+	name, existing, err := im.resolveProjectName(exported.Name)
+	if err != nil {
+		return 0, err
+	}
+	if existing != nil {
+		im.result.ProjectIDs[exportID] = existing.ID
+		im.result.MergedProjects++
+		return existing.ID, nil
+	}
+
+	project := &models.Project{
+		UUID:             im.uuid(exported.UUID),
+		Name:             name,
+		Description:      exported.Description,
+		CreationDate:     exported.CreationDate,
+		LastModifiedDate: exported.LastModifiedDate,
+		ParentProjectID:  parentID,
+	}
+	if createErr := im.repo.CreateProject(project); createErr != nil {
+		return 0, createErr
+	}
+
+	im.result.ProjectIDs[exportID] = project.ID
+	return project.ID, nil
+}
+
+// resolveProjectName applies the conflict strategy to an imported project name. It returns the name
+// to use, or the existing project to merge into.
+func (im *importer) resolveProjectName(name string) (string, *models.Project, error) {
+	existing, lookupErr := im.repo.GetProjectByName(name)
+	if lookupErr != nil || existing == nil {
+		return name, nil, nil
+	}
+
+	switch im.conflict {
+	case ImportConflictMerge:
+		return name, existing, nil
+	case ImportConflictRename:
+		for suffix := 2; ; suffix++ {
+			candidate := name + " (" + strconv.Itoa(suffix) + ")"
+			if project, err := im.repo.GetProjectByName(candidate); err != nil || project == nil {
+				return candidate, nil, nil
+			}
+		}
+	default:
+		return "", nil, fmt.Errorf("%w: '%s'", ErrImportConflict, name)
+	}
+}
+
+// importTask creates the task with the given exported ID (after its project and parent task)
+// and returns its new ID.
+func (im *importer) importTask(exportID int) (int, error) {
+	if newID, done := im.result.TaskIDs[exportID]; done {
+		return newID, nil
+	}
+
+	exported, exists := im.tasks[exportID]
+	if !exists {
+		return 0, fmt.Errorf("%w: unknown task ID %d", ErrInvalidImport, exportID)
+	}
+	if exported.Name == "" {
+		return 0, fmt.Errorf("%w: task %d: %w", ErrInvalidImport, exportID, ErrNoTaskName)
+	}
+
+	key := "task:" + strconv.Itoa(exportID)
+	if im.visiting[key] {
+		return 0, fmt.Errorf("%w: task %d: %w", ErrInvalidImport, exportID, ErrTaskCycle)
+	}
+	im.visiting[key] = true
+
+	projectID, err := im.importProject(exported.ProjectID)
+	if err != nil {
+		return 0, err
+	}
+
+	var parentID *int
+	if exported.ParentID != nil {
+		newParentID, parentErr := im.importTask(*exported.ParentID)
+		if parentErr != nil {
+			return 0, parentErr
+		}
+		if im.tasks[*exported.ParentID].ProjectID != exported.ProjectID {
+			return 0, fmt.Errorf("%w: task %d: %w", ErrInvalidImport, exportID, ErrParentTaskProject)
+		}
+		parentID = &newParentID
+	}
+
+	workflow := im.repo.Workflow()
+	state := exported.State
+	switch {
+	case state == "" && exported.TaskCompleted:
+		state = models.StateDone
+	case state == "":
+		state = workflow.InitialState
+	case !workflow.HasState(state):
+		return 0, fmt.Errorf("%w: task %d: %w: '%s'", ErrInvalidImport, exportID, ErrUnknownState, state)
+	}
+
+	priority := exported.Priority
+	if priority < utils.PriorityHigh || priority > utils.PriorityNone {
+		priority = utils.PriorityNone
+	}
+
+	task := &models.Task{
+		UUID:            im.uuid(exported.UUID),
+		Name:            exported.Name,
+		Description:     exported.Description,
+		ProjectID:       projectID,
+		State:           state,
+		TaskCompleted:   state == models.StateDone,
+		DueDate:         exported.DueDate,
+		CompletionDate:  exported.CompletionDate,
+		CreationDate:    exported.CreationDate,
+		LastUpdatedDate: exported.LastUpdatedDate,
+		Priority:        priority,
+		Tags:            exported.Tags,
+		ParentTaskID:    parentID,
+	}
+	if createErr := im.repo.CreateTask(task); createErr != nil {
+		return 0, createErr
+	}
+
+	im.result.TaskIDs[exportID] = task.ID
+	return task.ID, nil
+}
+
+// uuid returns the UUID an imported item is created with, empty for a new one.
+func (im *importer) uuid(exported string) string {
+	if im.keepUUIDs {
+		return exported
+	}
+	return ""
+}
+
+// nestProjects arranges projects and tasks into trees: top-level projects containing their
+// subprojects and tasks, and top-level tasks containing their subtasks.
+func nestProjects(projects []*models.Project, tasks []*models.Task) []*models.ExportProject {
+	knownProjects := make(map[int]bool, len(projects))
+	for _, project := range projects {
+		knownProjects[project.ID] = true
+	}
+
+	subprojects := make(map[int][]*models.Project)
+	var rootProjects []*models.Project
+	for _, project := range projects {
+		if project.ParentProjectID != nil && knownProjects[*project.ParentProjectID] {
+			subprojects[*project.ParentProjectID] = append(subprojects[*project.ParentProjectID], project)
+		} else {
+			rootProjects = append(rootProjects, project)
+		}
+	}
+
+	taskProjects := make(map[int]int, len(tasks))
+	for _, task := range tasks {
+		taskProjects[task.ID] = task.ProjectID
+	}
+
+	projectTasks := make(map[int][]*models.Task)
+	subtasks := make(map[int][]*models.Task)
+	for _, task := range tasks {
+		parentProjectID, parentKnown := 0, false
+		if task.ParentTaskID != nil {
+			parentProjectID, parentKnown = taskProjects[*task.ParentTaskID]
+		}
+		if parentKnown && parentProjectID == task.ProjectID {
+			subtasks[*task.ParentTaskID] = append(subtasks[*task.ParentTaskID], task)
+		} else {
+			projectTasks[task.ProjectID] = append(projectTasks[task.ProjectID], task)
+		}
+	}
+
+	var nestTask func(task *models.Task) *models.ExportTask
+	nestTask = func(task *models.Task) *models.ExportTask {
+		exported := toExportTask(task)
+		exported.ProjectID, exported.ParentID = 0, nil
+		for _, subtask := range subtasks[task.ID] {
+			exported.Subtasks = append(exported.Subtasks, nestTask(subtask))
+		}
+		return exported
+	}
+
+	var nestProject func(project *models.Project) *models.ExportProject
+	nestProject = func(project *models.Project) *models.ExportProject {
+		exported := toExportProject(project)
+		exported.ParentID = nil
+		for _, subproject := range subprojects[project.ID] {
+			exported.Subprojects = append(exported.Subprojects, nestProject(subproject))
+		}
+		for _, task := range projectTasks[project.ID] {
+			exported.Tasks = append(exported.Tasks, nestTask(task))
+		}
+		return exported
+	}
+
+	nested := make([]*models.ExportProject, 0, len(rootProjects))
+	for _, project := range rootProjects {
+		nested = append(nested, nestProject(project))
+	}
+	return nested
+}
+
+// flattenExport turns nested export projects into flat lists of projects and tasks, deriving
+// parent relationships from the nesting.
+func flattenExport(nested []*models.ExportProject) ([]*models.ExportProject, []*models.ExportTask) {
+	var projects []*models.ExportProject
+	var tasks []*models.ExportTask
+
+	var flattenTask func(task *models.ExportTask, projectID int, parentID *int)
+	flattenTask = func(task *models.ExportTask, projectID int, parentID *int) {
+		flat := *task
+		flat.ProjectID, flat.ParentID, flat.Subtasks = projectID, parentID, nil
+		tasks = append(tasks, &flat)
+		for _, subtask := range task.Subtasks {
+			flattenTask(subtask, projectID, &task.ID)
+		}
+	}
+
+	var flattenProject func(project *models.ExportProject, parentID *int)
+	flattenProject = func(project *models.ExportProject, parentID *int) {
+		flat := *project
+		flat.ParentID, flat.Subprojects, flat.Tasks = parentID, nil, nil
+		projects = append(projects, &flat)
+		for _, subproject := range project.Subprojects {
+			flattenProject(subproject, &project.ID)
+		}
+		for _, task := range project.Tasks {
+			flattenTask(task, project.ID, nil)
+		}
+	}
+
+	for _, project := range nested {
+		flattenProject(project, nil)
+	}
+	return projects, tasks
+}
+
+// toExportProject converts a project to its export representation.
+func toExportProject(project *models.Project) *models.ExportProject {
+	return &models.ExportProject{
+		ID:               project.ID,
+		UUID:             project.UUID,
+		Name:             project.Name,
+		Description:      project.Description,
+		CreationDate:     project.CreationDate,
+		LastModifiedDate: project.LastModifiedDate,
+		ParentID:         project.ParentProjectID,
+	}
+}
+
+// toExportTask converts a task to its export representation.
+func toExportTask(task *models.Task) *models.ExportTask {
+	return &models.ExportTask{
+		ID:              task.ID,
+		UUID:            task.UUID,
+		Name:            task.Name,
+		Description:     task.Description,
+		ProjectID:       task.ProjectID,
+		ParentID:        task.ParentTaskID,
+		State:           task.State,
+		TaskCompleted:   task.TaskCompleted,
+		DueDate:         task.DueDate,
+		CompletionDate:  task.CompletionDate,
+		CreationDate:    task.CreationDate,
+		LastUpdatedDate: task.LastUpdatedDate,
+		Priority:        task.Priority,
+		Tags:            task.Tags,
+	}
+}
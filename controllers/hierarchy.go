@@ -0,0 +1,33 @@
+package controllers
+
+// listSubtree returns the descendants of the item with the given ID, recursively, in depth-first order.
+// children returns the direct children of an item and idOf the ID of an item. Items met twice, which a
+// cycle of parents would cause, are only returned once.
+func listSubtree[T any](id int, children func(parentID int) ([]T, error), idOf func(item T) int) ([]T, error) {
+	var subtree []T
+	visited := map[int]bool{id: true}
+
+	var collect func(parentID int) error
+	collect = func(parentID int) error {
+		items, err := children(parentID)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if visited[idOf(item)] {
+				continue
+			}
+			visited[idOf(item)] = true
+			subtree = append(subtree, item)
+			if collectErr := collect(idOf(item)); collectErr != nil {
+				return collectErr
+			}
+		}
+		return nil
+	}
+
+	if err := collect(id); err != nil {
+		return nil, err
+	}
+	return subtree, nil
+}
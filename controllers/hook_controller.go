@@ -0,0 +1,205 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"github.com/d4r1us-drk/clido/internal/hooks"
+	"github.com/d4r1us-drk/clido/models"
+	"github.com/d4r1us-drk/clido/repository"
+	"github.com/d4r1us-drk/clido/utils"
+)
+
+// ErrHookRejected is returned when a pre-hook rejects a change, or returns an item that cannot be used.
+var ErrHookRejected = errcode.New(errcode.Rejected, "change rejected by hook")
+
+// HookResult is the outcome of a hook run by TestHooks.
+type HookResult struct {
+	Hook   hooks.Hook `json:"hook"`
+	Output string     `json:"output"`          // The standard output of the hook
+	Error  string     `json:"error,omitempty"` // Why the hook failed, empty when it succeeded
+}
+
+// HookController manages the hooks run when projects and tasks are created, modified, completed or
+// removed. Hooks are executable files of the hooks directory, next to the database, which receive the
+// change as JSON on their standard input: the event, the item type and the item before and after the
+// change. Pre-hooks run before the change is saved and reject it by exiting with a non-zero status, or
+// modify it by printing the changed item. Post-hooks run once the change is saved.
+// Changes made by syncing, replicating or importing data do not run hooks.
+type HookController struct {
+	repo *repository.Repository
+}
+
+// NewHookController creates and returns a new instance of HookController.
+func NewHookController(repo *repository.Repository) *HookController {
+	return &HookController{repo: repo}
+}
+
+// HooksDir returns the directory holding the hooks.
+func (hc *HookController) HooksDir() string {
+	return hc.repo.HooksDir()
+}
+
+// ListHooks returns the hooks of the hooks directory, sorted by file name.
+func (hc *HookController) ListHooks() ([]hooks.Hook, error) {
+	return hooks.List(hc.repo.HooksDir())
+}
+
+// TestHooks runs the pre-hooks and then the post-hooks of an event with a sample project or task,
+// without saving anything, and returns their outcome.
+func (hc *HookController) TestHooks(event, itemType string) ([]*HookResult, error) {
+	if !hooks.IsEvent(event) {
+		return nil, fmt.Errorf("%w: '%s'", hooks.ErrUnknownEvent, event)
+	}
+
+	before, after := hc.sampleChange(event, itemType)
+	input, err := hooks.Encode(event, itemType, before, after)
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := hc.ListHooks()
+	if err != nil {
+		return nil, err
+	}
+	var results []*HookResult
+	for _, pre := range []bool{true, false} {
+		for _, hook := range all {
+			if hook.Event != event || hook.Pre != pre {
+				continue
+			}
+			output, runErr := hooks.Run(hook, input)
+			result := &HookResult{Hook: hook, Output: string(bytes.TrimSpace(output))}
+			if runErr != nil {
+				result.Error = runErr.Error()
+			}
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// sampleChange returns the item before and after a sample change of the given event.
+func (hc *HookController) sampleChange(event, itemType string) (any, any) {
+	now := time.Now()
+	var before, after any
+	if itemType == models.SyncItemProject {
+		project := models.Project{
+			ID: 1, Name: "Sample project", Description: "A project", CreationDate: now, LastModifiedDate: now,
+		}
+		edited := project
+		edited.Description = "An edited project"
+		before, after = &project, &edited
+	} else {
+		task := models.Task{
+			ID: 1, Name: "Sample task", Description: "A task", ProjectID: 1,
+			State: hc.repo.Workflow().InitialState, Priority: 1, CreationDate: now, LastUpdatedDate: now,
+		}
+		edited := task
+		if event == hooks.OnComplete {
+			edited.State = models.StateDone
+			edited.TaskCompleted = true
+			edited.CompletionDate = &now
+		} else {
+			edited.Description = "An edited task"
+		}
+		before, after = &task, &edited
+	}
+
+	switch event {
+	case hooks.OnCreate:
+		before = nil
+	case hooks.OnRemove:
+		after = nil
+	}
+	return before, after
+}
+
+// runPreHooks runs the pre-hooks of an event on a change of a project or task, one after the other. A
+// hook rejects the change by failing, or modifies it by printing the item as JSON: the name,
+// description, due date and priority of a task, and the name and description of a project, are then
+// taken from it. Each hook receives the change modified by the previous ones.
+func runPreHooks(repo *repository.Repository, event, itemType string, before, after any) error {
+	preHooks, err := hooks.Find(repo.HooksDir(), event, true)
+	if err != nil {
+		return err
+	}
+
+	for _, hook := range preHooks {
+		input, encodeErr := hooks.Encode(event, itemType, before, after)
+		if encodeErr != nil {
+			return encodeErr
+		}
+		output, runErr := hooks.Run(hook, input)
+		if runErr != nil {
+			return fmt.Errorf("%w: %w", ErrHookRejected, runErr)
+		}
+		if after == nil || len(bytes.TrimSpace(output)) == 0 {
+			continue
+		}
+		if applyErr := applyHookOutput(after, output); applyErr != nil {
+			return fmt.Errorf("%w: invalid output of '%s': %w", ErrHookRejected, hook.Name, applyErr)
+		}
+	}
+	return nil
+}
+
+// runPostHooks runs the post-hooks of an event on a saved change of a project or task, once the current
+// transaction is committed. The change is captured when it is called.
+func runPostHooks(repo *repository.Repository, event, itemType string, before, after any) error {
+	postHooks, err := hooks.Find(repo.HooksDir(), event, false)
+	if err != nil || len(postHooks) == 0 {
+		return err
+	}
+
+	input, err := hooks.Encode(event, itemType, before, after)
+	if err != nil {
+		return err
+	}
+	repo.AfterCommit(func(*repository.Repository) { hooks.Notify(postHooks, input) })
+	return nil
+}
+
+// applyHookOutput copies the fields a pre-hook may modify from the item it printed to the changed item.
+// Fields the hook left out are unchanged.
+func applyHookOutput(after any, output []byte) error {
+	switch item := after.(type) {
+	case *models.Task:
+		// Pointers are not shared with the item, which decoding through them would change
+		modified := *item
+		modified.ParentTaskID, modified.CompletionDate = nil, nil
+		if item.DueDate != nil {
+			dueDate := *item.DueDate
+			modified.DueDate = &dueDate
+		}
+		if err := json.Unmarshal(output, &modified); err != nil {
+			return err
+		}
+		if modified.Name == "" {
+			return ErrNoTaskName
+		}
+		priorityChanged := modified.Priority != item.Priority
+		if priorityChanged && (modified.Priority < utils.PriorityHigh || modified.Priority > utils.PriorityNone) {
+			return fmt.Errorf("invalid priority %d", modified.Priority)
+		}
+		item.Name = modified.Name
+		item.Description = modified.Description
+		item.DueDate = modified.DueDate
+		item.Priority = modified.Priority
+	case *models.Project:
+		modified := *item
+		modified.ParentProjectID = nil
+		if err := json.Unmarshal(output, &modified); err != nil {
+			return err
+		}
+		if modified.Name == "" {
+			return ErrNoProjectName
+		}
+		item.Name = modified.Name
+		item.Description = modified.Description
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"github.com/d4r1us-drk/clido/models"
+	"github.com/d4r1us-drk/clido/repository"
+	"github.com/d4r1us-drk/clido/utils"
+)
+
+// DisplayIDPrefix starts the display ID of an open task in the working set, e.g. "@3".
+const DisplayIDPrefix = "@"
+
+// Errors returned when resolving project and task identifiers.
+var (
+	ErrInvalidIdentifier = errcode.New(errcode.Validation,
+		"invalid ID, use a numeric ID, a display ID (e.g. @3) or a UUID prefix",
+	)
+	ErrAmbiguousUUIDPrefix = errcode.New(errcode.Validation, "UUID prefix matches several items, use more characters")
+	ErrUnknownDisplayID    = errcode.New(errcode.NotFound,
+		"display ID is not in the working set, run 'clido list tasks' to number open tasks",
+	)
+	ErrStaleDisplayID = errcode.New(errcode.NotFound,
+		"display ID is stale, its task was closed or removed, run 'clido list tasks' to number open tasks",
+	)
+)
+
+// findProject returns the project identified by a numeric ID, a name or a UUID prefix, tried in that
+// order, or the notFound error.
+func findProject(repo *repository.Repository, identifier string, notFound error) (*models.Project, error) {
+	if id, parseErr := utils.ParseIntOrError(identifier); parseErr == nil {
+		project, getProjectErr := repo.GetProjectByID(id)
+		if getProjectErr != nil || project == nil {
+			return nil, notFound
+		}
+		return project, nil
+	}
+
+	if project, lookupErr := repo.GetProjectByName(identifier); lookupErr == nil && project != nil {
+		return project, nil
+	}
+
+	return findProjectByUUIDPrefix(repo, identifier, notFound)
+}
+
+// findProjectByUUIDPrefix returns the only project whose UUID starts with the given prefix, or the
+// notFound error.
+func findProjectByUUIDPrefix(
+	repo *repository.Repository,
+	prefix string,
+	notFound error,
+) (*models.Project, error) {
+	if !utils.IsUUIDPrefix(prefix) {
+		return nil, notFound
+	}
+	projects, err := repo.GetProjectsByUUIDPrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(projects) {
+	case 0:
+		return nil, notFound
+	case 1:
+		return projects[0], nil
+	default:
+		return nil, fmt.Errorf("%w: '%s'", ErrAmbiguousUUIDPrefix, prefix)
+	}
+}
+
+// findTask returns the task identified by a numeric ID, a display ID or a UUID prefix, or the notFound
+// error.
+func findTask(repo *repository.Repository, identifier string, notFound error) (*models.Task, error) {
+	if strings.HasPrefix(identifier, DisplayIDPrefix) {
+		return findTaskByDisplayID(repo, identifier)
+	}
+	if id, parseErr := utils.ParseIntOrError(identifier); parseErr == nil {
+		task, getTaskErr := repo.GetTaskByID(id)
+		if getTaskErr != nil {
+			return nil, notFound
+		}
+		return task, nil
+	}
+
+	if !utils.IsUUIDPrefix(identifier) {
+		return nil, notFound
+	}
+	tasks, err := repo.GetTasksByUUIDPrefix(identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(tasks) {
+	case 0:
+		return nil, notFound
+	case 1:
+		return tasks[0], nil
+	default:
+		return nil, fmt.Errorf("%w: '%s'", ErrAmbiguousUUIDPrefix, identifier)
+	}
+}
+
+// findTaskByDisplayID returns the open task given a display ID by the last update of the working set.
+// Display IDs of tasks closed or removed since then are stale.
+func findTaskByDisplayID(repo *repository.Repository, identifier string) (*models.Task, error) {
+	displayID, parseErr := utils.ParseIntOrError(strings.TrimPrefix(identifier, DisplayIDPrefix))
+	if parseErr != nil || displayID < 1 {
+		return nil, fmt.Errorf("%w: '%s'", ErrInvalidIdentifier, identifier)
+	}
+
+	entry, err := repo.GetWorkingSetEntry(displayID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: '%s'", ErrUnknownDisplayID, identifier)
+	}
+	// The UUID is checked rather than the ID, which may have been reused by a new task
+	task, err := repo.GetTaskByUUID(entry.TaskUUID)
+	if err != nil || repo.Workflow().IsTerminal(task.State) {
+		return nil, fmt.Errorf("%w: '%s'", ErrStaleDisplayID, identifier)
+	}
+	return task, nil
+}
+
+// isIdentifier reports whether a value can identify a task: a numeric ID, a display ID or a UUID prefix.
+func isIdentifier(value string) bool {
+	_, parseErr := utils.ParseIntOrError(value)
+	return parseErr == nil || strings.HasPrefix(value, DisplayIDPrefix) || utils.IsUUIDPrefix(value)
+}
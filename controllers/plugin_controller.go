@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"github.com/d4r1us-drk/clido/internal/version"
+	"github.com/d4r1us-drk/clido/repository"
+)
+
+// PluginPrefix starts the file names of plugin executables: 'clido foo' runs 'clido-foo'.
+const PluginPrefix = "clido-"
+
+// Environment variables passed to plugins.
+const (
+	PluginEnvBinary    = "CLIDO_BIN"       // The path of the clido executable, to run clido commands
+	PluginEnvDBPath    = "CLIDO_DB_PATH"   // The path of the SQLite database of the workspace
+	PluginEnvDataDir   = "CLIDO_DATA_DIR"  // The directory of the database, holding its configuration
+	PluginEnvWorkspace = "CLIDO_WORKSPACE" // The name of the workspace in use
+	PluginEnvVersion   = "CLIDO_VERSION"   // The version of clido
+)
+
+// ErrPluginNotFound is returned when no plugin executable exists for a command.
+var ErrPluginNotFound = errcode.New(errcode.NotFound, "plugin not found")
+
+// Plugin is an executable on the PATH adding a command to clido.
+type Plugin struct {
+	Name string `json:"name"` // The command the plugin adds, its file name without the prefix
+	Path string `json:"path"` // The full path of the executable
+}
+
+// PluginController finds and describes plugins, executables named 'clido-<command>' on the PATH that
+// add commands to clido without changing it, like git does. A plugin runs with the environment of clido
+// extended with the database and workspace in use and the path of clido itself, so it can read the
+// database or run clido commands, e.g. with --json, to call the controllers.
+type PluginController struct {
+	repo *repository.Repository
+}
+
+// NewPluginController creates and returns a new instance of PluginController.
+func NewPluginController(repo *repository.Repository) *PluginController {
+	return &PluginController{repo: repo}
+}
+
+// FindPlugin returns the plugin adding the given command, the first one found on the PATH.
+func (pc *PluginController) FindPlugin(name string) (*Plugin, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("%w: '%s'", ErrPluginNotFound, name)
+	}
+	path, err := exec.LookPath(PluginPrefix + name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: '%s'", ErrPluginNotFound, name)
+	}
+	return &Plugin{Name: name, Path: path}, nil
+}
+
+// ListPlugins returns the plugins found on the PATH, sorted by name. When several directories hold
+// a plugin of the same name, the first one is listed, as it is the one run.
+func (pc *PluginController) ListPlugins() ([]*Plugin, error) {
+	seen := make(map[string]bool)
+	var plugins []*Plugin
+
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue // Missing or unreadable directories of the PATH are skipped, like the shell does
+		}
+		for _, entry := range entries {
+			name, found := strings.CutPrefix(entry.Name(), PluginPrefix)
+			if runtime.GOOS == "windows" {
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			}
+			if !found || name == "" || seen[name] {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if info, statErr := os.Stat(path); statErr != nil || !info.Mode().IsRegular() || !isExecutable(info) {
+				continue
+			}
+			seen[name] = true
+			plugins = append(plugins, &Plugin{Name: name, Path: path})
+		}
+	}
+
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+	return plugins, nil
+}
+
+// PluginEnv returns the environment plugins run with: the environment of clido, extended with the
+// variables describing the database and workspace in use.
+func (pc *PluginController) PluginEnv() []string {
+	binary, err := os.Executable()
+	if err != nil {
+		binary = os.Args[0]
+	}
+
+	return append(os.Environ(),
+		PluginEnvBinary+"="+binary,
+		PluginEnvDBPath+"="+pc.repo.DBPath(),
+		PluginEnvDataDir+"="+filepath.Dir(pc.repo.DBPath()),
+		PluginEnvWorkspace+"="+pc.repo.Workspace(),
+		PluginEnvVersion+"="+version.Get().Version,
+	)
+}
+
+// isExecutable reports whether a file can be run, which on Windows depends on its extension only.
+func isExecutable(info os.FileInfo) bool {
+	return runtime.GOOS == "windows" || info.Mode().Perm()&0o111 != 0
+}
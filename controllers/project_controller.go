@@ -2,7 +2,11 @@ package controllers
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"github.com/d4r1us-drk/clido/internal/hooks"
 	"github.com/d4r1us-drk/clido/models"
 	"github.com/d4r1us-drk/clido/repository"
 	"github.com/d4r1us-drk/clido/utils"
@@ -10,11 +14,15 @@ import (
 
 // Error constants for project operations.
 var (
-	ErrNoProjectName           = errors.New("project name is required")
-	ErrParentProjectNotFound   = errors.New("parent project not found")
-	ErrNoParentProjectProvided = errors.New("no parent project provided")
+	ErrNoProjectName           = errcode.New(errcode.Validation, "project name is required")
+	ErrParentProjectNotFound   = errcode.New(errcode.NotFound, "parent project not found")
+	ErrNoParentProjectProvided = errcode.New(errcode.Validation, "no parent project provided")
+	ErrProjectCycle            = errcode.New(errcode.Conflict, "a project cannot be its own ancestor")
 )
 
+// NoParentIdentifier is the parent identifier used to detach a project or task from its parent.
+const NoParentIdentifier = "none"
+
 // ProjectController manages the project-related business logic.
 type ProjectController struct {
 	repo *repository.Repository
@@ -25,19 +33,19 @@ func NewProjectController(repo *repository.Repository) *ProjectController {
 	return &ProjectController{repo: repo}
 }
 
-// CreateProject handles the creation of a new project.
+// CreateProject handles the creation of a new project, and returns the project as stored.
 func (pc *ProjectController) CreateProject(
 	name, description, parentProjectIdentifier string,
-) error {
+) (*models.Project, error) {
 	// Validate project name
 	if name == "" {
-		return ErrNoProjectName
+		return nil, ErrNoProjectName
 	}
 
 	// Retrieve the parent project ID (if any)
 	parentProjectID, err := pc.getParentProjectID(parentProjectIdentifier)
 	if err != nil && !errors.Is(err, ErrNoParentProjectProvided) {
-		return err
+		return nil, err
 	}
 
 	// Create a new project
@@ -47,20 +55,31 @@ func (pc *ProjectController) CreateProject(
 		ParentProjectID: parentProjectID,
 	}
 
-	// Store the project in the repository
-	return pc.repo.CreateProject(&project)
+	// Run the pre-hooks, which may reject or modify the project, and store the project in the repository
+	if hookErr := runPreHooks(pc.repo, hooks.OnCreate, models.SyncItemProject, nil, &project); hookErr != nil {
+		return nil, hookErr
+	}
+	if createErr := pc.repo.CreateProject(&project); createErr != nil {
+		return nil, createErr
+	}
+
+	if hookErr := notifyChange(pc.repo, hooks.OnCreate, models.SyncItemProject, nil, &project); hookErr != nil {
+		return nil, hookErr
+	}
+	return &project, nil
 }
 
-// EditProject handles updating an existing project by its ID.
+// EditProject handles updating an existing project by its ID, and returns the project as stored.
 func (pc *ProjectController) EditProject(
 	id int,
 	name, description, parentProjectIdentifier string,
-) error {
+) (*models.Project, error) {
 	// Retrieve the existing project
 	project, getProjectErr := pc.repo.GetProjectByID(id)
 	if getProjectErr != nil {
-		return getProjectErr
+		return nil, ErrNoProjectFound
 	}
+	before := *project
 
 	// Apply updates
 	if name != "" {
@@ -70,15 +89,92 @@ func (pc *ProjectController) EditProject(
 		project.Description = description
 	}
 
-	// Retrieve and apply the parent project ID (if any)
-	parentProjectID, err := pc.getParentProjectID(parentProjectIdentifier)
-	if err != nil && !errors.Is(err, ErrNoParentProjectProvided) {
-		return err
+	// Detach the project, or retrieve and validate the new parent project (if any)
+	if parentProjectIdentifier != "" {
+		if err := pc.setParentProject(project, parentProjectIdentifier); err != nil {
+			return nil, err
+		}
+	}
+
+	// Run the pre-hooks, which may reject or modify the change, and update the project in the repository
+	if hookErr := runPreHooks(pc.repo, hooks.OnModify, models.SyncItemProject, &before, project); hookErr != nil {
+		return nil, hookErr
+	}
+	if updateErr := pc.repo.UpdateProject(project); updateErr != nil {
+		return nil, updateErr
 	}
-	project.ParentProjectID = parentProjectID
 
-	// Update the project in the repository
-	return pc.repo.UpdateProject(project)
+	if hookErr := notifyChange(pc.repo, hooks.OnModify, models.SyncItemProject, &before, project); hookErr != nil {
+		return nil, hookErr
+	}
+	return project, nil
+}
+
+// MoveProject re-parents a project under the project identified by parentProjectIdentifier
+// (name or ID), or moves it to the top level when NoParentIdentifier is given.
+// Subprojects and tasks follow the moved project. It returns the moved project followed by
+// all of its subprojects.
+func (pc *ProjectController) MoveProject(
+	id int,
+	parentProjectIdentifier string,
+) ([]*models.Project, error) {
+	if parentProjectIdentifier == "" {
+		return nil, ErrNoParentProjectProvided
+	}
+
+	project, getProjectErr := pc.repo.GetProjectByID(id)
+	if getProjectErr != nil {
+		return nil, ErrNoProjectFound
+	}
+	before := *project
+
+	if err := pc.setParentProject(project, parentProjectIdentifier); err != nil {
+		return nil, err
+	}
+
+	if hookErr := runPreHooks(pc.repo, hooks.OnModify, models.SyncItemProject, &before, project); hookErr != nil {
+		return nil, hookErr
+	}
+	if updateErr := pc.repo.UpdateProject(project); updateErr != nil {
+		return nil, updateErr
+	}
+	if hookErr := notifyChange(pc.repo, hooks.OnModify, models.SyncItemProject, &before, project); hookErr != nil {
+		return nil, hookErr
+	}
+
+	subprojects, subtreeErr := pc.ListProjectSubtree(project.ID)
+	if subtreeErr != nil {
+		return nil, subtreeErr
+	}
+
+	return append([]*models.Project{project}, subprojects...), nil
+}
+
+// ListProjectSubtree returns all subprojects of a project, recursively, in depth-first order.
+func (pc *ProjectController) ListProjectSubtree(id int) ([]*models.Project, error) {
+	return listSubtree(id, pc.repo.GetSubprojects, func(project *models.Project) int { return project.ID })
+}
+
+// ListProjectAncestors returns the parent chain of a project, starting with its top-level ancestor.
+func (pc *ProjectController) ListProjectAncestors(id int) ([]*models.Project, error) {
+	project, getProjectErr := pc.repo.GetProjectByID(id)
+	if getProjectErr != nil {
+		return nil, ErrNoProjectFound
+	}
+
+	var ancestors []*models.Project
+	visited := map[int]bool{project.ID: true}
+	for project.ParentProjectID != nil && !visited[*project.ParentProjectID] {
+		parent, getParentErr := pc.repo.GetProjectByID(*project.ParentProjectID)
+		if getParentErr != nil {
+			return nil, ErrParentProjectNotFound
+		}
+		visited[parent.ID] = true
+		ancestors = append([]*models.Project{parent}, ancestors...)
+		project = parent
+	}
+
+	return ancestors, nil
 }
 
 // ListProjects returns all projects stored in the repository.
@@ -91,6 +187,29 @@ func (pc *ProjectController) GetProjectByID(id int) (*models.Project, error) {
 	return pc.repo.GetProjectByID(id)
 }
 
+// ResolveProjectID returns the ID of the project identified by a numeric ID, returned as is, or by a prefix
+// of its UUID.
+func (pc *ProjectController) ResolveProjectID(identifier string) (int, error) {
+	if id, parseErr := utils.ParseIntOrError(identifier); parseErr == nil {
+		return id, nil
+	}
+	if !utils.IsUUIDPrefix(identifier) {
+		return 0, fmt.Errorf("%w: '%s'", ErrInvalidIdentifier, identifier)
+	}
+
+	notFound := fmt.Errorf("%w: no project UUID starts with '%s'", ErrNoProjectFound, identifier)
+	project, err := findProjectByUUIDPrefix(pc.repo, identifier, notFound)
+	if err != nil {
+		return 0, err
+	}
+	return project.ID, nil
+}
+
+// GetProject returns the project identified by a numeric ID, a name or a prefix of its UUID.
+func (pc *ProjectController) GetProject(identifier string) (*models.Project, error) {
+	return findProject(pc.repo, identifier, fmt.Errorf("%w: '%s'", ErrNoProjectFound, identifier))
+}
+
 // GetProjectByName returns a project by its name.
 func (pc *ProjectController) GetProjectByName(name string) (*models.Project, error) {
 	return pc.repo.GetProjectByName(name)
@@ -101,49 +220,148 @@ func (pc *ProjectController) ListSubprojects(parentID int) ([]*models.Project, e
 	return pc.repo.GetSubprojects(parentID)
 }
 
-// RemoveProject handles the recursive removal of a project and all its subprojects.
-func (pc *ProjectController) RemoveProject(id int) error {
+// FindProjects returns the projects matching a selection query made of "key:value" terms, all of
+// which must match. Supported keys are parent (name, ID or 'none') and name (case-insensitive substring).
+func (pc *ProjectController) FindProjects(query string) ([]*models.Project, error) {
+	return findMatching(query, pc.projectQueryFilter, pc.repo.GetAllProjects)
+}
+
+// projectQueryFilter builds the filter function for a single query term.
+func (pc *ProjectController) projectQueryFilter(term queryTerm) (func(project *models.Project) bool, error) {
+	switch term.key {
+	case "parent":
+		if term.value == NoParentIdentifier {
+			return func(project *models.Project) bool { return project.ParentProjectID == nil }, nil
+		}
+		parentID, err := pc.getParentProjectID(term.value)
+		if err != nil {
+			return nil, err
+		}
+		return func(project *models.Project) bool {
+			return project.ParentProjectID != nil && *project.ParentProjectID == *parentID
+		}, nil
+	case "name":
+		needle := strings.ToLower(term.value)
+		return func(project *models.Project) bool {
+			return strings.Contains(strings.ToLower(project.Name), needle)
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown key '%s'", ErrInvalidQuery, term.key)
+	}
+}
+
+// InTransaction runs fn with a ProjectController bound to a single database transaction,
+// so that all changes made through it are committed or rolled back together.
+func (pc *ProjectController) InTransaction(fn func(txController *ProjectController) error) error {
+	return pc.repo.Transaction(func(txRepo *repository.Repository) error {
+		txController := *pc
+		txController.repo = txRepo
+		return fn(&txController)
+	})
+}
+
+// RemoveProject handles the recursive removal of a project and all its subprojects, and returns the IDs of
+// the removed projects, starting with the project itself.
+func (pc *ProjectController) RemoveProject(id int) ([]int, error) {
+	return pc.removeProject(id, make(map[int]bool))
+}
+
+// removeProject removes a project and its subprojects, skipping those already being removed, which a cycle
+// of parents would cause.
+func (pc *ProjectController) removeProject(id int, removing map[int]bool) ([]int, error) {
+	removing[id] = true
+
+	project, getProjectErr := pc.repo.GetProjectByID(id)
+	if getProjectErr != nil {
+		return nil, ErrNoProjectFound
+	}
+	if hookErr := runPreHooks(pc.repo, hooks.OnRemove, models.SyncItemProject, project, nil); hookErr != nil {
+		return nil, hookErr
+	}
+
 	// Retrieve all subprojects of the project
 	subprojects, getSubprojectsErr := pc.repo.GetSubprojects(id)
 	if getSubprojectsErr != nil {
-		return getSubprojectsErr
+		return nil, getSubprojectsErr
 	}
 
 	// Recursively remove subprojects
+	removed := []int{id}
 	for _, subproject := range subprojects {
-		if removeErr := pc.RemoveProject(subproject.ID); removeErr != nil {
-			return removeErr
+		if removing[subproject.ID] {
+			continue
+		}
+		removedSubprojects, removeErr := pc.removeProject(subproject.ID, removing)
+		if removeErr != nil {
+			return nil, removeErr
 		}
+		removed = append(removed, removedSubprojects...)
 	}
 
 	// Remove the parent project
-	return pc.repo.DeleteProject(id)
+	if deleteErr := pc.repo.DeleteProject(id); deleteErr != nil {
+		return nil, deleteErr
+	}
+
+	if hookErr := notifyChange(pc.repo, hooks.OnRemove, models.SyncItemProject, project, nil); hookErr != nil {
+		return nil, hookErr
+	}
+	return removed, nil
 }
 
-// getParentProjectID checks and retrieves the parent project ID based on the identifier (name or ID).
+// getParentProjectID checks and retrieves the parent project ID based on the identifier (ID, name or
+// UUID prefix).
 func (pc *ProjectController) getParentProjectID(parentProjectIdentifier string) (*int, error) {
 	if parentProjectIdentifier == "" {
 		// No parent project identifier provided, so no parent project ID is needed
 		return nil, ErrNoParentProjectProvided
 	}
 
-	// Try to parse the parent project identifier as an integer ID
-	parentID, err := utils.ParseIntOrError(parentProjectIdentifier)
-	if err == nil {
-		// Successfully parsed as ID, now check if the project exists by ID
-		project, getProjectErr := pc.repo.GetProjectByID(parentID)
-		if getProjectErr != nil || project == nil {
-			return nil, ErrParentProjectNotFound
-		}
-		return &parentID, nil
+	project, err := findProject(pc.repo, parentProjectIdentifier, ErrParentProjectNotFound)
+	if err != nil {
+		return nil, err
 	}
+	return &project.ID, nil
+}
 
-	// If parsing failed, treat it as a project name and search by name
-	project, lookupErr := pc.repo.GetProjectByName(parentProjectIdentifier)
-	if lookupErr != nil || project == nil {
-		return nil, ErrParentProjectNotFound
+// setParentProject detaches the project when NoParentIdentifier is given, otherwise it resolves
+// the new parent project and ensures the change does not create a cycle.
+func (pc *ProjectController) setParentProject(project *models.Project, parentProjectIdentifier string) error {
+	if parentProjectIdentifier == NoParentIdentifier {
+		project.ParentProjectID = nil
+		return nil
 	}
 
-	// Return the found project ID
-	return &project.ID, nil
+	parentProjectID, err := pc.getParentProjectID(parentProjectIdentifier)
+	if err != nil {
+		return err
+	}
+	if cycleErr := pc.checkProjectAncestry(project.ID, *parentProjectID); cycleErr != nil {
+		return cycleErr
+	}
+	project.ParentProjectID = parentProjectID
+	return nil
+}
+
+// checkProjectAncestry walks up the ancestor chain starting at parentID and returns ErrProjectCycle
+// if projectID is found in it, which would make the project its own ancestor.
+func (pc *ProjectController) checkProjectAncestry(projectID, parentID int) error {
+	visited := make(map[int]bool)
+	currentID := &parentID
+
+	for currentID != nil {
+		// Reaching the project itself, or an already corrupted chain, means a cycle
+		if *currentID == projectID || visited[*currentID] {
+			return ErrProjectCycle
+		}
+		visited[*currentID] = true
+
+		ancestor, err := pc.repo.GetProjectByID(*currentID)
+		if err != nil {
+			return ErrParentProjectNotFound
+		}
+		currentID = ancestor.ParentProjectID
+	}
+
+	return nil
 }
@@ -0,0 +1,57 @@
+package controllers_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/d4r1us-drk/clido/controllers"
+)
+
+func TestMoveProjectRejectsCycles(t *testing.T) {
+	repo := newTestRepository(t)
+	projectController := controllers.NewProjectController(repo)
+
+	// Work > Clients > Acme
+	parentID := ""
+	var ids []int
+	for _, name := range []string{"Work", "Clients", "Acme"} {
+		project, err := projectController.CreateProject(name, "", parentID)
+		if err != nil {
+			t.Fatalf("CreateProject(%s) error = %v", name, err)
+		}
+		ids = append(ids, project.ID)
+		parentID = name
+	}
+
+	tests := []struct {
+		name   string
+		id     int
+		parent string
+	}{
+		{"under itself", ids[0], "Work"},
+		{"under its subproject", ids[0], "Clients"},
+		{"under a nested subproject", ids[1], "Acme"},
+	}
+	for _, tt := range tests {
+		if _, err := projectController.MoveProject(tt.id, tt.parent); !errors.Is(err, controllers.ErrProjectCycle) {
+			t.Errorf("MoveProject() %s error = %v, want %v", tt.name, err, controllers.ErrProjectCycle)
+		}
+		if _, err := projectController.EditProject(tt.id, "", "", tt.parent); !errors.Is(err, controllers.ErrProjectCycle) {
+			t.Errorf("EditProject() %s error = %v, want %v", tt.name, err, controllers.ErrProjectCycle)
+		}
+	}
+
+	// The hierarchy is unchanged
+	for i, id := range ids {
+		project, err := projectController.GetProjectByID(id)
+		if err != nil {
+			t.Fatalf("GetProjectByID(%d) error = %v", id, err)
+		}
+		switch {
+		case i == 0 && project.ParentProjectID != nil:
+			t.Errorf("project %s has parent %d, want none", project.Name, *project.ParentProjectID)
+		case i > 0 && (project.ParentProjectID == nil || *project.ParentProjectID != ids[i-1]):
+			t.Errorf("project %s has parent %v, want %d", project.Name, project.ParentProjectID, ids[i-1])
+		}
+	}
+}
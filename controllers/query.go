@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/d4r1us-drk/clido/internal/errcode"
+)
+
+// ErrInvalidQuery is returned when a selection query cannot be parsed or uses an unknown key.
+var ErrInvalidQuery = errcode.New(errcode.Validation, "invalid query")
+
+// queryTerm is a single "key:value" term of a selection query.
+type queryTerm struct {
+	key   string
+	value string
+}
+
+// parseQuery splits a selection query such as `project:"My Project" priority:1` into its terms.
+// Terms are separated by whitespace, and values containing spaces can be wrapped in double quotes.
+func parseQuery(query string) ([]queryTerm, error) {
+	var terms []queryTerm
+	var current strings.Builder
+	inQuotes := false
+
+	flush := func() error {
+		token := current.String()
+		current.Reset()
+		if token == "" {
+			return nil
+		}
+
+		key, value, found := strings.Cut(token, ":")
+		if !found || key == "" || value == "" {
+			return fmt.Errorf("%w: expected 'key:value', got '%s'", ErrInvalidQuery, token)
+		}
+		terms = append(terms, queryTerm{key: strings.ToLower(key), value: value})
+		return nil
+	}
+
+	for _, r := range query {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case (r == ' ' || r == '\t') && !inQuotes:
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+
+	if inQuotes {
+		return nil, fmt.Errorf("%w: unterminated quote", ErrInvalidQuery)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("%w: empty query", ErrInvalidQuery)
+	}
+
+	return terms, nil
+}
+
+// findMatching returns the items matching a selection query. filterFor builds the filter of each term of
+// the query, which all must match, and items returns the items to select from.
+func findMatching[T any](
+	query string,
+	filterFor func(term queryTerm) (func(item T) bool, error),
+	items func() ([]T, error),
+) ([]T, error) {
+	terms, parseErr := parseQuery(query)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	var filters []func(item T) bool
+	for _, term := range terms {
+		filter, filterErr := filterFor(term)
+		if filterErr != nil {
+			return nil, filterErr
+		}
+		filters = append(filters, filter)
+	}
+
+	all, getAllErr := items()
+	if getAllErr != nil {
+		return nil, getAllErr
+	}
+
+	var matches []T
+	for _, item := range all {
+		matched := true
+		for _, filter := range filters {
+			if !filter(item) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			matches = append(matches, item)
+		}
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"github.com/d4r1us-drk/clido/utils"
+)
+
+// Error constants for quick-add texts.
+var (
+	ErrInvalidQuickAdd   = errcode.New(errcode.Validation, "invalid quick-add text")
+	ErrQuickAddPriority  = errcode.New(errcode.Validation, "invalid priority, use !1 (High) to !4 (None)")
+	ErrQuickAddDuplicate = errcode.New(errcode.Validation, "token given more than once")
+)
+
+// QuickAdd is a task described by a quick-add text such as "Pay invoice +Finance !1 due:fri #billing @home".
+//
+// Tokens of the text, separated by spaces, are read as follows:
+//   - +Project: the project of the task, by name, ID or UUID prefix.
+//   - !1 to !4: the priority of the task, from High to None.
+//   - due:date: the due date of the task, in any format accepted by utils.ParseDueDate.
+//   - #tag: a tag of the task.
+//   - @context: a context of the task, stored as a tag starting with '@'.
+//   - Any other token is a word of the name of the task.
+//
+// Double quotes group words into one token, e.g. +"Big Project", and a token starting with a backslash or
+// a double quote is a word of the name, e.g. \#1 or "due:soon". Outside double quotes, a backslash also
+// escapes the character after it.
+type QuickAdd struct {
+	Name     string
+	Project  string
+	Priority int
+	Due      string
+	Tags     []string // Tags without their '#' and contexts with their '@'
+}
+
+// quickAddToken is a token of a quick-add text. Marked tokens start with an unquoted, unescaped character
+// and may be read as a project, priority, due date, tag or context.
+type quickAddToken struct {
+	text   string
+	marked bool
+}
+
+// ParseQuickAdd parses a quick-add text into the task it describes. It checks the tokens, but neither
+// looks up the project nor parses the due date.
+func ParseQuickAdd(text string) (*QuickAdd, error) {
+	tokens, err := tokenizeQuickAdd(text)
+	if err != nil {
+		return nil, err
+	}
+
+	quickAdd := &QuickAdd{}
+	var words []string
+	for _, token := range tokens {
+		if !token.marked || len(token.text) < 2 {
+			words = append(words, token.text)
+			continue
+		}
+
+		value := token.text[1:]
+		switch token.text[0] {
+		case '+':
+			if quickAdd.Project != "" {
+				return nil, fmt.Errorf("%w: project '%s'", ErrQuickAddDuplicate, token.text)
+			}
+			quickAdd.Project = value
+		case '!':
+			if quickAdd.Priority != 0 {
+				return nil, fmt.Errorf("%w: priority '%s'", ErrQuickAddDuplicate, token.text)
+			}
+			priority, parseErr := utils.ParseIntOrError(value)
+			if parseErr != nil || priority < utils.PriorityHigh || priority > utils.PriorityNone {
+				return nil, fmt.Errorf("%w: '%s' (write \\%s for a literal word)",
+					ErrQuickAddPriority, token.text, token.text)
+			}
+			quickAdd.Priority = priority
+		case '#':
+			quickAdd.Tags = append(quickAdd.Tags, value)
+		case '@':
+			quickAdd.Tags = append(quickAdd.Tags, token.text)
+		default:
+			if !strings.HasPrefix(strings.ToLower(token.text), "due:") || len(token.text) == len("due:") {
+				words = append(words, token.text)
+				continue
+			}
+			if quickAdd.Due != "" {
+				return nil, fmt.Errorf("%w: due date '%s'", ErrQuickAddDuplicate, token.text)
+			}
+			quickAdd.Due = token.text[len("due:"):]
+		}
+	}
+
+	quickAdd.Name = strings.Join(words, " ")
+	if quickAdd.Name == "" {
+		return nil, ErrNoTaskName
+	}
+	if _, tagsErr := joinTags(quickAdd.Tags); tagsErr != nil {
+		return nil, tagsErr
+	}
+	return quickAdd, nil
+}
+
+// tokenizeQuickAdd splits a quick-add text into tokens at unquoted spaces, removing double quotes and
+// escaping backslashes.
+func tokenizeQuickAdd(text string) ([]quickAddToken, error) {
+	var tokens []quickAddToken
+	var current strings.Builder
+	inToken, marked, escaped, quoted := false, false, false, false
+
+	for _, r := range text {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && !quoted:
+			if !inToken {
+				inToken, marked = true, false
+			}
+			escaped = true
+		case quoted:
+			if r == '"' {
+				quoted = false
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"':
+			if !inToken {
+				inToken, marked = true, false
+			}
+			quoted = true
+		case unicode.IsSpace(r):
+			if inToken {
+				tokens = append(tokens, quickAddToken{text: current.String(), marked: marked})
+				current.Reset()
+				inToken = false
+			}
+		default:
+			if !inToken {
+				inToken, marked = true, true
+			}
+			current.WriteRune(r)
+		}
+	}
+
+	if quoted {
+		return nil, fmt.Errorf("%w: unterminated quote", ErrInvalidQuickAdd)
+	}
+	if escaped {
+		current.WriteRune('\\')
+	}
+	if inToken {
+		tokens = append(tokens, quickAddToken{text: current.String(), marked: marked})
+	}
+	return tokens, nil
+}
@@ -0,0 +1,73 @@
+package controllers_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/d4r1us-drk/clido/controllers"
+)
+
+func TestParseQuickAdd(t *testing.T) {
+	tests := []struct {
+		text string
+		want controllers.QuickAdd
+	}{
+		{"Pay invoice", controllers.QuickAdd{Name: "Pay invoice"}},
+		{"Pay invoice +Finance", controllers.QuickAdd{Name: "Pay invoice", Project: "Finance"}},
+		{"+3 Pay invoice", controllers.QuickAdd{Name: "Pay invoice", Project: "3"}},
+		{`Plan +"Big Project"`, controllers.QuickAdd{Name: "Plan", Project: "Big Project"}},
+		{"Pay invoice !1", controllers.QuickAdd{Name: "Pay invoice", Priority: 1}},
+		{"Pay !4 invoice", controllers.QuickAdd{Name: "Pay invoice", Priority: 4}},
+		{
+			"Pay invoice #billing @home #urgent",
+			controllers.QuickAdd{Name: "Pay invoice", Tags: []string{"billing", "@home", "urgent"}},
+		},
+		{"Pay invoice due:today", controllers.QuickAdd{Name: "Pay invoice", Due: "today"}},
+		{"Pay invoice due:tomorrow", controllers.QuickAdd{Name: "Pay invoice", Due: "tomorrow"}},
+		{"Pay invoice DUE:fri", controllers.QuickAdd{Name: "Pay invoice", Due: "fri"}},
+		{"Pay invoice due:+3d", controllers.QuickAdd{Name: "Pay invoice", Due: "+3d"}},
+		{"Pay invoice due:+2w", controllers.QuickAdd{Name: "Pay invoice", Due: "+2w"}},
+		{`Pay invoice due:"2024-05-01 10:00"`, controllers.QuickAdd{Name: "Pay invoice", Due: "2024-05-01 10:00"}},
+
+		// Quoted and escaped tokens, lone markers and unknown tokens are words of the name
+		{`Fix \#12 "+1" "due:soon"`, controllers.QuickAdd{Name: "Fix #12 +1 due:soon"}},
+		{`Say "hello world"`, controllers.QuickAdd{Name: "Say hello world"}},
+		{`Back\ slash\`, controllers.QuickAdd{Name: `Back slash\`}},
+		{"Pay + invoice ! # @ due:", controllers.QuickAdd{Name: "Pay + invoice ! # @ due:"}},
+		{"Pay ~invoice key:value", controllers.QuickAdd{Name: "Pay ~invoice key:value"}},
+		{"  Pay\tinvoice  ", controllers.QuickAdd{Name: "Pay invoice"}},
+	}
+	for _, tt := range tests {
+		got, err := controllers.ParseQuickAdd(tt.text)
+		if err != nil {
+			t.Errorf("ParseQuickAdd(%q) error = %v", tt.text, err)
+			continue
+		}
+		if !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("ParseQuickAdd(%q) = %+v, want %+v", tt.text, *got, tt.want)
+		}
+	}
+}
+
+func TestParseQuickAddErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		want error
+	}{
+		{"Pay invoice !5", controllers.ErrQuickAddPriority},
+		{"Pay invoice !high", controllers.ErrQuickAddPriority},
+		{"Pay +Finance +Home", controllers.ErrQuickAddDuplicate},
+		{"Pay !1 !2", controllers.ErrQuickAddDuplicate},
+		{"Pay due:fri due:mon", controllers.ErrQuickAddDuplicate},
+		{`Pay "invoice`, controllers.ErrInvalidQuickAdd},
+		{"+Finance #billing", controllers.ErrNoTaskName},
+		{"", controllers.ErrNoTaskName},
+		{"Pay #bill,ing", controllers.ErrInvalidTag},
+	}
+	for _, tt := range tests {
+		if _, err := controllers.ParseQuickAdd(tt.text); !errors.Is(err, tt.want) {
+			t.Errorf("ParseQuickAdd(%q) error = %v, want %v", tt.text, err, tt.want)
+		}
+	}
+}
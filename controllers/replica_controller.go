@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"github.com/d4r1us-drk/clido/internal/hlc"
+	"github.com/d4r1us-drk/clido/models"
+	"github.com/d4r1us-drk/clido/repository"
+)
+
+// ErrInvalidReplicaBundle is returned when importing a replication bundle that cannot be read.
+var ErrInvalidReplicaBundle = errcode.New(errcode.Validation, "invalid replication bundle")
+
+// ReplicaResult summarizes the import of a replication bundle.
+type ReplicaResult struct {
+	Node     string // The replica that exported the bundle
+	Imported int    // Operations of the bundle that were not in the change log yet
+	Created  int    // Projects and tasks created
+	Updated  int    // Projects and tasks updated
+	Deleted  int    // Projects and tasks deleted
+	Backup   string // The automatic backup written before applying the changes, if any
+}
+
+// ReplicaController replicates the database through its change log, a list of per-field operations
+// ordered by hybrid logical clock timestamps. Each field of an item takes the value of its latest
+// operation, so databases that imported each other's operations hold the same projects and tasks,
+// whatever the order of the imports.
+type ReplicaController struct {
+	repo *repository.Repository
+}
+
+// NewReplicaController creates and returns a new instance of ReplicaController.
+func NewReplicaController(repo *repository.Repository) *ReplicaController {
+	return &ReplicaController{repo: repo}
+}
+
+// Export returns the change log of the database as a replication bundle. Changes not recorded in the
+// change log yet, such as items created before it existed, are recorded first.
+func (rc *ReplicaController) Export() (*models.ReplicaBundle, error) {
+	if err := rc.repo.Transaction(func(txRepo *repository.Repository) error {
+		return txRepo.LogAllChanges()
+	}); err != nil {
+		return nil, err
+	}
+
+	node, err := rc.repo.ReplicaNode()
+	if err != nil {
+		return nil, err
+	}
+	operations, err := rc.repo.GetChangeLog()
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ReplicaBundle{
+		FormatVersion: models.ReplicaBundleFormatVersion,
+		Node:          node,
+		ExportedAt:    time.Now().UTC(),
+		Operations:    operations,
+	}, nil
+}
+
+// Import adds the operations of a replication bundle to the change log and updates the projects and tasks
+// to the state it describes.
+func (rc *ReplicaController) Import(bundle *models.ReplicaBundle) (*ReplicaResult, error) {
+	if bundle.FormatVersion != models.ReplicaBundleFormatVersion {
+		return nil, fmt.Errorf("%w: unsupported format version %d (supported: %d)",
+			ErrInvalidReplicaBundle, bundle.FormatVersion, models.ReplicaBundleFormatVersion)
+	}
+	for _, operation := range bundle.Operations {
+		if _, err := hlc.Parse(operation.HLC); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidReplicaBundle, err)
+		}
+		if operation.ItemType != models.SyncItemProject && operation.ItemType != models.SyncItemTask {
+			return nil, fmt.Errorf("%w: unknown item type '%s'", ErrInvalidReplicaBundle, operation.ItemType)
+		}
+		if operation.ItemKey == "" || operation.Field == "" {
+			return nil, fmt.Errorf("%w: operation %s has no item key or field", ErrInvalidReplicaBundle, operation.HLC)
+		}
+	}
+
+	result := &ReplicaResult{Node: bundle.Node}
+	err := rc.repo.Transaction(func(txRepo *repository.Repository) error {
+		// Local changes must be in the change log before they are merged with the imported ones
+		if logErr := txRepo.LogAllChanges(); logErr != nil {
+			return logErr
+		}
+		imported, importErr := txRepo.ImportChangeOperations(bundle.Operations)
+		result.Imported = imported
+		return importErr
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	operations, err := rc.repo.GetChangeLog()
+	if err != nil {
+		return nil, err
+	}
+	merged := replicatedState(operations)
+
+	// The state is applied without being logged, as it is already described by the change log
+	syncController := NewSyncController(rc.repo.WithoutChangeLog())
+	local, ids, err := syncController.localSyncState()
+	if err != nil {
+		return nil, err
+	}
+	syncResult := &SyncResult{}
+	if err = syncController.applySyncState(local, merged, ids, syncResult, "replicate"); err != nil {
+		return nil, err
+	}
+
+	result.Created = syncResult.Created
+	result.Updated = syncResult.Updated
+	result.Deleted = syncResult.Deleted
+	result.Backup = syncResult.Backup
+	return result, nil
+}
+
+// replicatedState returns the projects and tasks described by a change log, oldest operation first: each
+// field holds the value of its latest operation, and deleted or incomplete items are left out. References
+// to missing items are then repaired the same way on every replica.
+func replicatedState(operations []*models.ChangeOperation) *syncState {
+	registers := newSyncState()
+	for _, operation := range operations {
+		items := registers.tasks
+		if operation.ItemType == models.SyncItemProject {
+			items = registers.projects
+		}
+		if items[operation.ItemKey] == nil {
+			items[operation.ItemKey] = make(syncRecord)
+		}
+		items[operation.ItemKey][operation.Field] = operation.Value
+	}
+
+	state := newSyncState()
+	live := func(record syncRecord) bool {
+		deleted, _ := strconv.ParseBool(record[models.ChangeFieldDeleted])
+		return !deleted && record[models.RecordFieldName] != ""
+	}
+	for key, record := range registers.projects {
+		if live(record) {
+			delete(record, models.ChangeFieldDeleted)
+			delete(record, syncFieldLegacyUUID)
+			state.projects[key] = record
+		}
+	}
+	for key, record := range registers.tasks {
+		if live(record) {
+			delete(record, models.ChangeFieldDeleted)
+			delete(record, syncFieldLegacyUUID)
+			state.tasks[key] = record
+		}
+	}
+
+	repairSyncReferences(state, newSyncState(), newSyncState())
+	return state
+}
@@ -0,0 +1,159 @@
+package controllers_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/models"
+	"github.com/d4r1us-drk/clido/repository"
+	"github.com/d4r1us-drk/clido/utils"
+)
+
+// exportReplica returns the replication bundle of a database, failing the test on errors.
+func exportReplica(t *testing.T, repo *repository.Repository) *models.ReplicaBundle {
+	t.Helper()
+
+	bundle, err := controllers.NewReplicaController(repo).Export()
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	return bundle
+}
+
+// importReplica imports replication bundles into a database in order, failing the test on errors.
+func importReplica(t *testing.T, repo *repository.Repository, bundles ...*models.ReplicaBundle) {
+	t.Helper()
+
+	for _, bundle := range bundles {
+		if _, err := controllers.NewReplicaController(repo).Import(bundle); err != nil {
+			t.Fatalf("Import() of the bundle of %s error = %v", bundle.Node, err)
+		}
+	}
+}
+
+// replicaSnapshot describes the projects and tasks of a database by UUID, with the fields replicated and
+// the UUIDs of the items they refer to, so databases can be compared whatever their local IDs.
+func replicaSnapshot(t *testing.T, repo *repository.Repository) map[string]string {
+	t.Helper()
+
+	projects, projectsErr := repo.GetAllProjects()
+	tasks, tasksErr := repo.GetAllTasks()
+	if projectsErr != nil || tasksErr != nil {
+		t.Fatalf("reading the database errors = %v, %v", projectsErr, tasksErr)
+	}
+	projectUUIDs := make(map[int]string)
+	for _, project := range projects {
+		projectUUIDs[project.ID] = project.UUID
+	}
+	taskUUIDs := make(map[int]string)
+	for _, task := range tasks {
+		taskUUIDs[task.ID] = task.UUID
+	}
+	uuid := func(uuids map[int]string, id *int) string {
+		if id == nil {
+			return ""
+		}
+		return uuids[*id]
+	}
+
+	snapshot := make(map[string]string)
+	for _, project := range projects {
+		snapshot[project.UUID] = fmt.Sprintf("project %q %q parent=%s",
+			project.Name, project.Description, uuid(projectUUIDs, project.ParentProjectID))
+	}
+	for _, task := range tasks {
+		snapshot[task.UUID] = fmt.Sprintf("task %q %q priority=%d state=%s tags=%q project=%s parent=%s",
+			task.Name, task.Description, task.Priority, task.State, task.Tags,
+			projectUUIDs[task.ProjectID], uuid(taskUUIDs, task.ParentTaskID))
+	}
+	return snapshot
+}
+
+func TestReplicasConvergeWhateverTheImportOrder(t *testing.T) {
+	origin := newTestRepository(t)
+	taskController := controllers.NewTaskController(origin)
+	tasks := newTestTasks(t, origin, "Report", "Review", "Publish")
+	if _, err := taskController.CreateTask("Figures", "", "Work", tasks[0].UUID, "", utils.PriorityNone, nil); err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+
+	// Two replicas start from the origin and change the same items, one after the other
+	replicaA := newTestWorkspace(t, origin, "replica-a")
+	replicaB := newTestWorkspace(t, origin, "replica-b")
+	initial := exportReplica(t, origin)
+	importReplica(t, replicaA, initial)
+	importReplica(t, replicaB, initial)
+
+	edit := func(repo *repository.Repository, uuid string, changes controllers.TaskChanges) {
+		t.Helper()
+		task, err := repo.GetTaskByUUID(uuid)
+		if err != nil {
+			t.Fatalf("GetTaskByUUID() error = %v", err)
+		}
+		if _, err = controllers.NewTaskController(repo).UpdateTask(task.ID, changes); err != nil {
+			t.Fatalf("UpdateTask() error = %v", err)
+		}
+	}
+	remove := func(repo *repository.Repository, uuid string) {
+		t.Helper()
+		task, err := repo.GetTaskByUUID(uuid)
+		if err != nil {
+			t.Fatalf("GetTaskByUUID() error = %v", err)
+		}
+		if _, err = controllers.NewTaskController(repo).RemoveTask(task.ID); err != nil {
+			t.Fatalf("RemoveTask() error = %v", err)
+		}
+	}
+	text := func(value string) *string { return &value }
+	priority := func(value int) *int { return &value }
+
+	// The same field of Report is changed on both replicas, and other fields on one of them each. Review
+	// is removed on one replica and edited on the other, and Publish is edited on one and removed on the
+	// other later.
+	edit(replicaA, tasks[0].UUID, controllers.TaskChanges{Name: text("Report A"), Description: text("Draft")})
+	remove(replicaA, tasks[1].UUID)
+	edit(replicaA, tasks[2].UUID, controllers.TaskChanges{Name: text("Publish A")})
+	edit(replicaB, tasks[0].UUID, controllers.TaskChanges{Name: text("Report B"), Priority: priority(1)})
+	edit(replicaB, tasks[1].UUID, controllers.TaskChanges{Name: text("Review B")})
+	remove(replicaB, tasks[2].UUID)
+
+	bundleA := exportReplica(t, replicaA)
+	bundleB := exportReplica(t, replicaB)
+
+	// Fresh databases import the bundles in both orders, and each replica imports the other's
+	forward := newTestWorkspace(t, origin, "forward")
+	backward := newTestWorkspace(t, origin, "backward")
+	importReplica(t, forward, bundleA, bundleB)
+	importReplica(t, backward, bundleB, bundleA)
+	importReplica(t, replicaA, bundleB)
+	importReplica(t, replicaB, bundleA)
+
+	want := replicaSnapshot(t, forward)
+	for name, repo := range map[string]*repository.Repository{
+		"backward": backward, "replica A": replicaA, "replica B": replicaB,
+	} {
+		if got := replicaSnapshot(t, repo); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s holds %v, want %v", name, got, want)
+		}
+	}
+
+	// Each field keeps its latest value, and deleted items stay deleted
+	report, err := forward.GetTaskByUUID(tasks[0].UUID)
+	if err != nil {
+		t.Fatalf("GetTaskByUUID() error = %v", err)
+	}
+	if report.Name != "Report B" || report.Description != "Draft" || report.Priority != 1 {
+		t.Errorf("merged task = %q %q priority %d, want %q %q priority 1",
+			report.Name, report.Description, report.Priority, "Report B", "Draft")
+	}
+	for _, deleted := range tasks[1:] {
+		if _, getErr := forward.GetTaskByUUID(deleted.UUID); getErr == nil {
+			t.Errorf("task %s, deleted on a replica and edited on the other, exists", deleted.Name)
+		}
+	}
+	if len(want) != 3 {
+		t.Errorf("replicas hold %d items, want the project, Report and Figures", len(want))
+	}
+}
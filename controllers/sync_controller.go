@@ -0,0 +1,490 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"github.com/d4r1us-drk/clido/internal/git"
+	"github.com/d4r1us-drk/clido/models"
+	"github.com/d4r1us-drk/clido/repository"
+)
+
+// Settings of the git repository used for syncing.
+const (
+	SyncBranch     = "main"
+	SyncRemoteName = "origin"
+	SyncProjectDir = "projects"
+	SyncTaskDir    = "tasks"
+)
+
+// ErrInvalidSyncFile is returned when a file of the sync repository cannot be read as a project or task.
+var ErrInvalidSyncFile = errcode.New(errcode.Validation, "invalid sync file")
+
+// SyncResult summarizes a sync: the changes applied to the database, the conflicts met while merging
+// and what happened in the git repository.
+type SyncResult struct {
+	Dir       string          // The directory of the sync repository
+	Remote    string          // The URL of the remote, empty if none is configured
+	Created   int             // Projects and tasks created from remote changes
+	Updated   int             // Projects and tasks updated from remote changes
+	Deleted   int             // Projects and tasks deleted from remote changes
+	Conflicts []*SyncConflict // Changes made on both sides, with the value that was kept
+	Backup    string          // The automatic backup written before applying remote changes, if any
+	Committed bool            // Whether local changes were committed
+	Pushed    bool            // Whether the result was pushed to the remote
+}
+
+// SyncController syncs the database with a git repository holding one file per project and task.
+type SyncController struct {
+	repo *repository.Repository
+}
+
+// NewSyncController creates and returns a new instance of SyncController.
+func NewSyncController(repo *repository.Repository) *SyncController {
+	return &SyncController{repo: repo}
+}
+
+// syncIDs maps the UUIDs of the projects and tasks of the database to their IDs.
+type syncIDs struct {
+	projects map[string]int
+	tasks    map[string]int
+	unified  map[string]string // The UUIDs of local projects merged into a remote one, mapped to its UUID
+}
+
+// Sync serializes the database into the sync repository and merges it with the remote. The remote URL
+// is saved when given, and used for later syncs otherwise. Remote changes are fetched and merged with a
+// field-level three-way merge against the last synced state, the merged state is applied to the database
+// and committed, and the commit is pushed unless push is false. Without a remote, local changes are only
+// committed.
+func (sc *SyncController) Sync(remoteURL string, push bool) (*SyncResult, error) {
+	repo, err := git.Open(sc.repo.SyncDir(), SyncBranch)
+	if err != nil {
+		return nil, err
+	}
+	result := &SyncResult{Dir: repo.Dir}
+
+	if result.Remote, err = configureSyncRepo(repo, remoteURL); err != nil {
+		return nil, err
+	}
+
+	remoteRef := ""
+	if result.Remote != "" {
+		if _, fetchErr := repo.Run("fetch", "--quiet", SyncRemoteName); fetchErr != nil {
+			return nil, fetchErr
+		}
+		if ref := "refs/remotes/" + SyncRemoteName + "/" + SyncBranch; repo.HasRef(ref) {
+			remoteRef = ref
+		}
+	}
+
+	// The base is the last state both sides agreed on
+	baseRef := ""
+	hasHead := repo.HasRef("HEAD")
+	switch {
+	case hasHead && remoteRef != "":
+		// Histories without a common commit are merged as if everything was added on both sides
+		baseRef, _ = repo.Run("merge-base", "HEAD", remoteRef)
+	case hasHead:
+		baseRef = "HEAD"
+	}
+
+	base, err := readSyncState(repo, baseRef)
+	if err != nil {
+		return nil, err
+	}
+	remote := base
+	if remoteRef != "" {
+		if remote, err = readSyncState(repo, remoteRef); err != nil {
+			return nil, err
+		}
+	}
+	local, ids, err := sc.localSyncState()
+	if err != nil {
+		return nil, err
+	}
+
+	ids.unified = unifySyncProjects(base, local, remote)
+	for localKey, remoteKey := range ids.unified {
+		ids.projects[remoteKey] = ids.projects[localKey]
+		delete(ids.projects, localKey)
+	}
+
+	merged, conflicts := mergeSyncStates(base, local, remote)
+	result.Conflicts = conflicts
+
+	if err = sc.applySyncState(local, merged, ids, result, "sync"); err != nil {
+		return nil, err
+	}
+
+	if err = writeSyncState(repo.Dir, merged); err != nil {
+		return nil, err
+	}
+	if result.Committed, err = commitSyncState(repo, remoteRef); err != nil {
+		return nil, err
+	}
+
+	if push && result.Remote != "" {
+		head, _ := repo.Run("rev-parse", "HEAD")
+		remoteHead, _ := repo.Run("rev-parse", "--verify", "--quiet", remoteRef)
+		if remoteRef == "" || head != remoteHead {
+			if _, pushErr := repo.Run("push", "--quiet", SyncRemoteName, "HEAD:refs/heads/"+SyncBranch); pushErr != nil {
+				return nil, fmt.Errorf("%w (the remote may have changed during the sync, sync again)", pushErr)
+			}
+			result.Pushed = true
+		}
+	}
+
+	return result, nil
+}
+
+// configureSyncRepo sets the remote of the sync repository when a URL is given, makes sure commits
+// can be created, and returns the URL of the remote, if any.
+func configureSyncRepo(repo *git.Repo, remoteURL string) (string, error) {
+	// Commits need an identity, which may not be configured on every machine
+	if name, _ := repo.Run("config", "user.name"); name == "" {
+		if _, err := repo.Run("config", "user.name", "clido"); err != nil {
+			return "", err
+		}
+	}
+	if email, _ := repo.Run("config", "user.email"); email == "" {
+		if _, err := repo.Run("config", "user.email", "clido@localhost"); err != nil {
+			return "", err
+		}
+	}
+
+	current, _ := repo.Run("remote", "get-url", SyncRemoteName)
+	switch {
+	case remoteURL == "" || remoteURL == current:
+		return current, nil
+	case current == "":
+		_, err := repo.Run("remote", "add", SyncRemoteName, remoteURL)
+		return remoteURL, err
+	default:
+		_, err := repo.Run("remote", "set-url", SyncRemoteName, remoteURL)
+		return remoteURL, err
+	}
+}
+
+// localSyncState returns the sync state of the database, its projects and tasks keyed by UUID.
+func (sc *SyncController) localSyncState() (*syncState, *syncIDs, error) {
+	projects, err := sc.repo.GetAllProjects()
+	if err != nil {
+		return nil, nil, err
+	}
+	tasks, err := sc.repo.GetAllTasks()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	projectKeys := make(map[int]string)
+	for _, project := range projects {
+		projectKeys[project.ID] = project.UUID
+	}
+	taskKeys := make(map[int]string)
+	for _, task := range tasks {
+		taskKeys[task.ID] = task.UUID
+	}
+
+	state := newSyncState()
+	ids := &syncIDs{projects: make(map[string]int), tasks: make(map[string]int)}
+	for _, project := range projects {
+		parentKey := ""
+		if project.ParentProjectID != nil {
+			parentKey = projectKeys[*project.ParentProjectID]
+		}
+		state.projects[project.UUID] = models.ProjectRecord(project, parentKey)
+		ids.projects[project.UUID] = project.ID
+	}
+	for _, task := range tasks {
+		parentKey := ""
+		if task.ParentTaskID != nil {
+			parentKey = taskKeys[*task.ParentTaskID]
+		}
+		state.tasks[task.UUID] = models.TaskRecord(task, projectKeys[task.ProjectID], parentKey)
+		ids.tasks[task.UUID] = task.ID
+	}
+	return state, ids, nil
+}
+
+// applySyncState updates the database from its local sync state to the merged one, in a single
+// transaction, after an automatic backup labeled with the given reason. The changes are counted in the result.
+func (sc *SyncController) applySyncState(
+	local, merged *syncState,
+	ids *syncIDs,
+	result *SyncResult,
+	reason string,
+) error {
+	changed := func(localItems, mergedItems map[string]syncRecord) bool {
+		return !maps.EqualFunc(localItems, mergedItems, func(a, b syncRecord) bool { return maps.Equal(a, b) })
+	}
+	if !changed(local.projects, merged.projects) && !changed(local.tasks, merged.tasks) {
+		// Only the UUIDs of unified projects may need to be saved
+		return sc.repo.Transaction(func(txRepo *repository.Repository) error {
+			return saveUnifiedProjects(txRepo, ids)
+		})
+	}
+
+	backup, err := sc.repo.AutoBackup(reason)
+	if err != nil {
+		return err
+	}
+	result.Backup = backup
+
+	return sc.repo.Transaction(func(txRepo *repository.Repository) error {
+		if saveErr := saveUnifiedProjects(txRepo, ids); saveErr != nil {
+			return saveErr
+		}
+
+		// New items are created first, so every reference can be resolved when the fields are set
+		for _, key := range sortedKeys(merged.projects) {
+			if _, exists := ids.projects[key]; exists {
+				continue
+			}
+			// Temporary unique names avoid clashes with projects renamed later in the transaction
+			project := &models.Project{UUID: key, Name: "sync:" + key}
+			if createErr := txRepo.CreateProject(project); createErr != nil {
+				return createErr
+			}
+			ids.projects[key] = project.ID
+			result.Created++
+		}
+		for _, key := range sortedKeys(merged.tasks) {
+			if _, exists := ids.tasks[key]; exists {
+				continue
+			}
+			task := &models.Task{
+				UUID: key, Name: key, ProjectID: ids.projects[merged.tasks[key][models.RecordFieldProject]],
+			}
+			if createErr := txRepo.CreateTask(task); createErr != nil {
+				return createErr
+			}
+			ids.tasks[key] = task.ID
+			result.Created++
+		}
+
+		if deleteErr := deleteSyncItems(txRepo, local, merged, ids, result); deleteErr != nil {
+			return deleteErr
+		}
+		if updateErr := updateSyncProjects(txRepo, local, merged, ids, result); updateErr != nil {
+			return updateErr
+		}
+		return updateSyncTasks(txRepo, local, merged, ids, result)
+	})
+}
+
+// deleteSyncItems deletes the tasks and projects that are missing from the merged state.
+func deleteSyncItems(
+	txRepo *repository.Repository,
+	local, merged *syncState,
+	ids *syncIDs,
+	result *SyncResult,
+) error {
+	for _, key := range sortedKeys(local.tasks) {
+		if merged.tasks[key] != nil {
+			continue
+		}
+		if err := txRepo.DeleteTask(ids.tasks[key]); err != nil {
+			return err
+		}
+		delete(ids.tasks, key)
+		result.Deleted++
+	}
+	for _, key := range sortedKeys(local.projects) {
+		if merged.projects[key] != nil {
+			continue
+		}
+		if err := txRepo.DeleteProject(ids.projects[key]); err != nil {
+			return err
+		}
+		delete(ids.projects, key)
+		result.Deleted++
+	}
+	return nil
+}
+
+// updateSyncProjects sets the fields of the projects that differ in the merged state.
+func updateSyncProjects(
+	txRepo *repository.Repository,
+	local, merged *syncState,
+	ids *syncIDs,
+	result *SyncResult,
+) error {
+	var renamed []*models.Project
+	for _, key := range sortedKeys(merged.projects) {
+		record, localRecord := merged.projects[key], local.projects[key]
+		if maps.Equal(record, localRecord) {
+			continue
+		}
+
+		project := &models.Project{ID: ids.projects[key]}
+		models.ApplyProjectRecord(project, record)
+		if parentKey := record[models.RecordFieldParent]; parentKey != "" {
+			parentID := ids.projects[parentKey]
+			project.ParentProjectID = &parentID
+		}
+
+		// Renamed projects get their final name once every other project is renamed
+		if localRecord == nil || localRecord[models.RecordFieldName] != record[models.RecordFieldName] {
+			renamed = append(renamed, project)
+			name := project.Name
+			project.Name = "sync:" + key
+			if err := txRepo.ReplaceProject(project); err != nil {
+				return err
+			}
+			project.Name = name
+		} else if err := txRepo.ReplaceProject(project); err != nil {
+			return err
+		}
+
+		if localRecord != nil {
+			result.Updated++
+		}
+	}
+
+	for _, project := range renamed {
+		if err := txRepo.ReplaceProject(project); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateSyncTasks sets the fields of the tasks that differ in the merged state.
+func updateSyncTasks(
+	txRepo *repository.Repository,
+	local, merged *syncState,
+	ids *syncIDs,
+	result *SyncResult,
+) error {
+	for _, key := range sortedKeys(merged.tasks) {
+		record, localRecord := merged.tasks[key], local.tasks[key]
+		if maps.Equal(record, localRecord) {
+			continue
+		}
+
+		task := &models.Task{ID: ids.tasks[key], ProjectID: ids.projects[record[models.RecordFieldProject]]}
+		models.ApplyTaskRecord(task, record)
+		if parentKey := record[models.RecordFieldParent]; parentKey != "" {
+			parentID := ids.tasks[parentKey]
+			task.ParentTaskID = &parentID
+		}
+		if err := txRepo.ReplaceTask(task); err != nil {
+			return err
+		}
+
+		if localRecord != nil {
+			result.Updated++
+		}
+	}
+	return nil
+}
+
+// saveUnifiedProjects gives the local projects merged into a project added remotely the UUID of that project.
+func saveUnifiedProjects(txRepo *repository.Repository, ids *syncIDs) error {
+	for _, remoteKey := range ids.unified {
+		if err := txRepo.ReplaceProjectUUID(ids.projects[remoteKey], remoteKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSyncState reads the sync state of a commit of the sync repository, or returns an empty state
+// when ref is empty.
+func readSyncState(repo *git.Repo, ref string) (*syncState, error) {
+	state := newSyncState()
+	if ref == "" {
+		return state, nil
+	}
+
+	files, err := repo.ReadTree(ref, SyncProjectDir, SyncTaskDir)
+	if err != nil {
+		return nil, err
+	}
+	for filePath, content := range files {
+		dir, name := path.Split(filePath)
+		key, isJSON := strings.CutSuffix(name, ".json")
+		if !isJSON || key == "" {
+			continue
+		}
+
+		var record syncRecord
+		if unmarshalErr := json.Unmarshal(content, &record); unmarshalErr != nil {
+			return nil, fmt.Errorf("%w '%s': %s", ErrInvalidSyncFile, filePath, unmarshalErr.Error())
+		}
+		delete(record, syncFieldLegacyUUID)
+		switch strings.TrimSuffix(dir, "/") {
+		case SyncProjectDir:
+			state.projects[key] = record
+		case SyncTaskDir:
+			state.tasks[key] = record
+		}
+	}
+	return state, nil
+}
+
+// writeSyncState replaces the project and task files of the sync working tree with the given state.
+func writeSyncState(dir string, state *syncState) error {
+	write := func(subdir string, items map[string]syncRecord) error {
+		itemDir := filepath.Join(dir, subdir)
+		if err := os.RemoveAll(itemDir); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(itemDir, 0o755); err != nil {
+			return err
+		}
+		for key, record := range items {
+			data, err := marshalSyncRecord(record)
+			if err != nil {
+				return err
+			}
+			if err = os.WriteFile(filepath.Join(itemDir, key+".json"), data, 0o644); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := write(SyncProjectDir, state.projects); err != nil {
+		return err
+	}
+	return write(SyncTaskDir, state.tasks)
+}
+
+// commitSyncState commits the working tree of the sync repository on top of the remote branch, if any,
+// and reports whether a commit was needed.
+func commitSyncState(repo *git.Repo, remoteRef string) (bool, error) {
+	// The merged state replaces both histories, keeping the history linear
+	if remoteRef != "" {
+		if _, err := repo.Run("update-ref", "HEAD", remoteRef); err != nil {
+			return false, err
+		}
+	}
+	if _, err := repo.Run("add", "--all"); err != nil {
+		return false, err
+	}
+
+	status, err := repo.Run("status", "--porcelain")
+	if err != nil || status == "" {
+		return false, err
+	}
+	if _, err = repo.Run("commit", "--quiet", "--message", "Sync from "+syncHostname()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// syncHostname returns the name of the machine, used in sync commit messages.
+func syncHostname() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		return "clido"
+	}
+	return hostname
+}
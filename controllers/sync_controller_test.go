@@ -0,0 +1,163 @@
+package controllers_test
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/models"
+	"github.com/d4r1us-drk/clido/repository"
+	"github.com/d4r1us-drk/clido/utils"
+)
+
+// syncReplica is a database synced with a git remote shared with other replicas.
+type syncReplica struct {
+	repo   *repository.Repository
+	tasks  *controllers.TaskController
+	remote string
+}
+
+// newSyncReplicas creates a bare git repository and two databases syncing with it, in two workspaces,
+// and creates a task in the first one that both databases share once synced. It returns the UUID of the task.
+func newSyncReplicas(t *testing.T) (*syncReplica, *syncReplica, string) {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	if output, err := exec.Command("git", "init", "--quiet", "--bare", remote).CombinedOutput(); err != nil {
+		t.Fatalf("git init error = %v: %s", err, output)
+	}
+
+	repo := newTestRepository(t)
+	desktop := &syncReplica{repo: repo, tasks: controllers.NewTaskController(repo), remote: remote}
+	laptopRepo := newTestWorkspace(t, repo, "laptop")
+	laptop := &syncReplica{repo: laptopRepo, tasks: controllers.NewTaskController(laptopRepo), remote: remote}
+
+	if _, err := controllers.NewProjectController(repo).CreateProject("Work", "", ""); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+	task, err := desktop.tasks.CreateTask("Report", "", "Work", "", "", utils.PriorityNone, nil)
+	if err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+
+	desktop.sync(t)
+	if result := laptop.sync(t); result.Created != 2 {
+		t.Fatalf("first sync of the second replica created %d item(s), want 2", result.Created)
+	}
+	return desktop, laptop, task.UUID
+}
+
+// sync syncs the replica with the remote, failing the test on errors.
+func (r *syncReplica) sync(t *testing.T) *controllers.SyncResult {
+	t.Helper()
+
+	result, err := controllers.NewSyncController(r.repo).Sync(r.remote, true)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	return result
+}
+
+// task returns the task of the replica with the given UUID, failing the test when it does not exist.
+func (r *syncReplica) task(t *testing.T, uuid string) *models.Task {
+	t.Helper()
+
+	task, err := r.repo.GetTaskByUUID(uuid)
+	if err != nil {
+		t.Fatalf("GetTaskByUUID(%s) error = %v", uuid, err)
+	}
+	return task
+}
+
+// edit applies changes to the task of the replica with the given UUID.
+func (r *syncReplica) edit(t *testing.T, uuid string, changes controllers.TaskChanges) {
+	t.Helper()
+
+	if _, err := r.tasks.UpdateTask(r.task(t, uuid).ID, changes); err != nil {
+		t.Fatalf("UpdateTask() error = %v", err)
+	}
+}
+
+func TestSyncMergesFields(t *testing.T) {
+	desktop, laptop, uuid := newSyncReplicas(t)
+
+	name, priority := "Annual report", utils.PriorityHigh
+	desktop.edit(t, uuid, controllers.TaskChanges{Name: &name})
+	laptop.edit(t, uuid, controllers.TaskChanges{Priority: &priority})
+
+	desktop.sync(t)
+	if result := laptop.sync(t); len(result.Conflicts) != 0 || result.Updated != 1 {
+		t.Errorf("Sync() = %d update(s) and conflicts %v, want 1 update and no conflicts",
+			result.Updated, result.Conflicts)
+	}
+	desktop.sync(t)
+
+	for _, replica := range []*syncReplica{desktop, laptop} {
+		if task := replica.task(t, uuid); task.Name != name || task.Priority != priority {
+			t.Errorf("task of %s = '%s' with priority %d, want both changes", replica.repo.Workspace(),
+				task.Name, task.Priority)
+		}
+	}
+}
+
+func TestSyncReportsConflictingEdits(t *testing.T) {
+	desktop, laptop, uuid := newSyncReplicas(t)
+
+	desktopName, laptopName := "Desktop report", "Laptop report"
+	desktop.edit(t, uuid, controllers.TaskChanges{Name: &desktopName})
+	laptop.edit(t, uuid, controllers.TaskChanges{Name: &laptopName})
+
+	desktop.sync(t)
+	result := laptop.sync(t)
+	if len(result.Conflicts) != 1 {
+		t.Fatalf("Sync() conflicts = %v, want 1", result.Conflicts)
+	}
+
+	// The most recent edit wins
+	conflict := result.Conflicts[0]
+	if conflict.Key != uuid || conflict.Field != models.RecordFieldName || conflict.Local != laptopName ||
+		conflict.Remote != desktopName || conflict.Kept != controllers.SyncSideLocal {
+		t.Errorf("Sync() conflict = %+v, want the laptop's name kept", conflict)
+	}
+
+	desktop.sync(t)
+	if task := desktop.task(t, uuid); task.Name != laptopName {
+		t.Errorf("task of the first replica is named '%s', want '%s'", task.Name, laptopName)
+	}
+}
+
+func TestSyncKeepsTasksDeletedAndEdited(t *testing.T) {
+	desktop, laptop, uuid := newSyncReplicas(t)
+
+	if _, err := desktop.tasks.RemoveTask(desktop.task(t, uuid).ID); err != nil {
+		t.Fatalf("RemoveTask() error = %v", err)
+	}
+	priority := utils.PriorityLow
+	laptop.edit(t, uuid, controllers.TaskChanges{Priority: &priority})
+
+	if result := desktop.sync(t); result.Deleted != 0 {
+		t.Errorf("Sync() deleted %d item(s) without remote changes, want 0", result.Deleted)
+	}
+	result := laptop.sync(t)
+	if len(result.Conflicts) != 1 || result.Deleted != 0 {
+		t.Fatalf("Sync() = %d deletion(s) and conflicts %v, want the task kept with 1 conflict",
+			result.Deleted, result.Conflicts)
+	}
+	conflict := result.Conflicts[0]
+	if conflict.Key != uuid || conflict.Local != "changed" || conflict.Remote != "deleted" ||
+		conflict.Kept != controllers.SyncSideLocal {
+		t.Errorf("Sync() conflict = %+v, want the edited task kept", conflict)
+	}
+
+	// The deleting replica gets the task back, with its UUID
+	if result = desktop.sync(t); result.Created != 1 {
+		t.Errorf("Sync() created %d item(s), want the task restored", result.Created)
+	}
+	if task := desktop.task(t, uuid); task.Priority != priority {
+		t.Errorf("restored task has priority %d, want %d", task.Priority, priority)
+	}
+}
@@ -0,0 +1,372 @@
+package controllers
+
+import (
+	"encoding/json"
+	"maps"
+	"sort"
+	"strconv"
+
+	"github.com/d4r1us-drk/clido/models"
+)
+
+// Sides of a sync merge, used to report which value was kept in a conflict.
+const (
+	SyncSideLocal  = "local"
+	SyncSideRemote = "remote"
+)
+
+// SyncConflict describes a change made on both sides of a sync that could not be merged automatically.
+// Field conflicts are resolved by keeping the value of the most recently updated side; deletions
+// conflicting with changes are resolved by keeping the item.
+type SyncConflict struct {
+	ItemType string `json:"item_type"`
+	Key      string `json:"key"`
+	Name     string `json:"name"`
+	Field    string `json:"field"`
+	Local    string `json:"local"`
+	Remote   string `json:"remote"`
+	Kept     string `json:"kept"`
+}
+
+// syncFieldLegacyUUID is the field holding the UUID of items in records written when they were keyed by
+// a separate sync key, ignored when they are read.
+const syncFieldLegacyUUID = "uuid"
+
+// syncRecord is the text representation of a project or task in the sync repository: its fields as
+// strings, referencing other items by UUID, with empty strings for missing values.
+type syncRecord map[string]string
+
+// syncState holds the records of all projects and tasks, indexed by UUID.
+type syncState struct {
+	projects map[string]syncRecord
+	tasks    map[string]syncRecord
+}
+
+// newSyncState returns an empty sync state.
+func newSyncState() *syncState {
+	return &syncState{projects: make(map[string]syncRecord), tasks: make(map[string]syncRecord)}
+}
+
+// marshalSyncRecord serializes a record as indented JSON with sorted keys, so files are deterministic
+// and diff-friendly.
+func marshalSyncRecord(record syncRecord) ([]byte, error) {
+	data, err := json.MarshalIndent(map[string]string(record), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// unifySyncProjects gives the UUID of the remote project to local projects added with the same name as a
+// project added remotely, since project names are unique, so both are merged into one. It returns the
+// replaced local UUIDs, mapped to the remote ones.
+func unifySyncProjects(base, local, remote *syncState) map[string]string {
+	added := func(state, other *syncState) map[string]string {
+		keys := make(map[string]string)
+		for key, record := range state.projects {
+			if base.projects[key] == nil && other.projects[key] == nil {
+				keys[record[models.RecordFieldName]] = key
+			}
+		}
+		return keys
+	}
+	remoteKeys := added(remote, local)
+
+	replaced := make(map[string]string)
+	for name, localKey := range added(local, remote) {
+		if remoteKey, found := remoteKeys[name]; found {
+			replaced[localKey] = remoteKey
+		}
+	}
+	if len(replaced) == 0 {
+		return replaced
+	}
+
+	for localKey, remoteKey := range replaced {
+		local.projects[remoteKey] = local.projects[localKey]
+		delete(local.projects, localKey)
+	}
+	rekey := func(items map[string]syncRecord, field string) {
+		for key, record := range items {
+			if remoteKey, found := replaced[record[field]]; found {
+				record = maps.Clone(record)
+				record[field] = remoteKey
+				items[key] = record
+			}
+		}
+	}
+	rekey(local.projects, models.RecordFieldParent)
+	rekey(local.tasks, models.RecordFieldProject)
+	return replaced
+}
+
+// mergeSyncStates merges the local and remote states with a three-way merge against their common base.
+func mergeSyncStates(base, local, remote *syncState) (*syncState, []*SyncConflict) {
+	merged := newSyncState()
+	var conflicts []*SyncConflict
+
+	var itemConflicts []*SyncConflict
+	merged.projects, itemConflicts = mergeSyncItems(
+		models.SyncItemProject, base.projects, local.projects, remote.projects, models.RecordFieldLastModifiedDate,
+	)
+	conflicts = append(conflicts, itemConflicts...)
+
+	merged.tasks, itemConflicts = mergeSyncItems(
+		models.SyncItemTask, base.tasks, local.tasks, remote.tasks, models.RecordFieldLastUpdatedDate,
+	)
+	conflicts = append(conflicts, itemConflicts...)
+
+	conflicts = append(conflicts, repairSyncReferences(merged, local, remote)...)
+	return merged, conflicts
+}
+
+// mergeSyncItems merges the records of one type of item. Items added on one side are kept, items deleted
+// on one side and unchanged on the other are deleted, and items changed on both sides are merged field
+// by field.
+func mergeSyncItems(
+	itemType string,
+	base, local, remote map[string]syncRecord,
+	dateField string,
+) (map[string]syncRecord, []*SyncConflict) {
+	merged := make(map[string]syncRecord)
+	var conflicts []*SyncConflict
+
+	for _, key := range sortedKeys(base, local, remote) {
+		baseRecord, localRecord, remoteRecord := base[key], local[key], remote[key]
+
+		switch {
+		case localRecord == nil && remoteRecord == nil:
+			// Deleted on both sides
+		case baseRecord == nil && remoteRecord == nil:
+			merged[key] = localRecord
+		case baseRecord == nil && localRecord == nil:
+			merged[key] = remoteRecord
+		case localRecord == nil:
+			// Deleted locally: only delete it if it was not changed remotely
+			if !maps.Equal(baseRecord, remoteRecord) {
+				merged[key] = remoteRecord
+				conflicts = append(conflicts, &SyncConflict{
+					ItemType: itemType, Key: key, Name: remoteRecord[models.RecordFieldName],
+					Local: "deleted", Remote: "changed", Kept: SyncSideRemote,
+				})
+			}
+		case remoteRecord == nil:
+			// Deleted remotely: only delete it if it was not changed locally
+			if !maps.Equal(baseRecord, localRecord) {
+				merged[key] = localRecord
+				conflicts = append(conflicts, &SyncConflict{
+					ItemType: itemType, Key: key, Name: localRecord[models.RecordFieldName],
+					Local: "changed", Remote: "deleted", Kept: SyncSideLocal,
+				})
+			}
+		default:
+			record, fieldConflicts := mergeSyncFields(itemType, key, baseRecord, localRecord, remoteRecord, dateField)
+			merged[key] = record
+			conflicts = append(conflicts, fieldConflicts...)
+		}
+	}
+
+	return merged, conflicts
+}
+
+// mergeSyncFields merges a record changed on both sides field by field. A field changed on one side only
+// takes the changed value; a field changed differently on both sides takes the value of the side updated
+// most recently according to dateField, and the date fields themselves take the earliest creation date and
+// the latest update date.
+func mergeSyncFields(
+	itemType, key string,
+	base, local, remote syncRecord,
+	dateField string,
+) (syncRecord, []*SyncConflict) {
+	localNewer := !syncTimeBefore(local[dateField], remote[dateField])
+
+	merged := make(syncRecord)
+	var conflicts []*SyncConflict
+	for _, field := range sortedKeys(base, local, remote) {
+		baseValue, localValue, remoteValue := base[field], local[field], remote[field]
+
+		switch {
+		case localValue == remoteValue, remoteValue == baseValue:
+			merged[field] = localValue
+		case localValue == baseValue:
+			merged[field] = remoteValue
+		case field == models.RecordFieldCreationDate:
+			// Items created on both sides keep the earliest creation date
+			merged[field] = localValue
+			if syncTimeBefore(remoteValue, localValue) {
+				merged[field] = remoteValue
+			}
+		case field == dateField:
+			merged[field] = localValue
+			if !localNewer {
+				merged[field] = remoteValue
+			}
+		default:
+			conflict := &SyncConflict{
+				ItemType: itemType, Key: key, Name: local[models.RecordFieldName],
+				Field: field, Local: localValue, Remote: remoteValue, Kept: SyncSideLocal,
+			}
+			merged[field] = localValue
+			if !localNewer {
+				merged[field] = remoteValue
+				conflict.Kept = SyncSideRemote
+			}
+			conflicts = append(conflicts, conflict)
+		}
+	}
+
+	return merged, conflicts
+}
+
+// repairSyncReferences fixes merged items referencing items deleted on one side: deleted projects and
+// parent tasks still in use are restored, and parent references that would create cycles or cross
+// projects are removed. Project names made ambiguous by the merge are made unique.
+func repairSyncReferences(merged, local, remote *syncState) []*SyncConflict {
+	var conflicts []*SyncConflict
+
+	restore := func(itemType, key string, mergedItems, localItems, remoteItems map[string]syncRecord) bool {
+		record, side := localItems[key], SyncSideLocal
+		if record == nil {
+			record, side = remoteItems[key], SyncSideRemote
+		}
+		if record == nil {
+			return false
+		}
+		mergedItems[key] = record
+		conflicts = append(conflicts, &SyncConflict{
+			ItemType: itemType, Key: key, Name: record[models.RecordFieldName],
+			Local: "deleted or in use", Remote: "deleted or in use", Kept: side,
+		})
+		return true
+	}
+
+	// Restore referenced items until every reference resolves
+	for changed := true; changed; {
+		changed = false
+		for _, key := range sortedKeys(merged.tasks) {
+			task := merged.tasks[key]
+			projectKey := task[models.RecordFieldProject]
+			if merged.projects[projectKey] == nil &&
+				restore(models.SyncItemProject, projectKey, merged.projects, local.projects, remote.projects) {
+				changed = true
+			}
+			parentKey := task[models.RecordFieldParent]
+			if parentKey != "" && merged.tasks[parentKey] == nil &&
+				restore(models.SyncItemTask, parentKey, merged.tasks, local.tasks, remote.tasks) {
+				changed = true
+			}
+		}
+		for _, key := range sortedKeys(merged.projects) {
+			parentKey := merged.projects[key][models.RecordFieldParent]
+			if parentKey != "" && merged.projects[parentKey] == nil &&
+				restore(models.SyncItemProject, parentKey, merged.projects, local.projects, remote.projects) {
+				changed = true
+			}
+		}
+	}
+
+	// Drop tasks whose project could not be restored
+	for key, task := range merged.tasks {
+		if merged.projects[task[models.RecordFieldProject]] == nil {
+			delete(merged.tasks, key)
+		}
+	}
+
+	conflicts = append(conflicts, detachSyncCycles(models.SyncItemProject, merged.projects, nil)...)
+	conflicts = append(conflicts, detachSyncCycles(models.SyncItemTask, merged.tasks, func(task, parent syncRecord) bool {
+		return task[models.RecordFieldProject] == parent[models.RecordFieldProject]
+	})...)
+
+	// Project names must stay unique
+	keysByName := make(map[string]string)
+	for _, key := range sortedKeys(merged.projects) {
+		project := merged.projects[key]
+		name := project[models.RecordFieldName]
+		if _, taken := keysByName[name]; !taken {
+			keysByName[name] = key
+			continue
+		}
+
+		renamed := name
+		for suffix := 2; keysByName[renamed] != ""; suffix++ {
+			renamed = name + " (" + strconv.Itoa(suffix) + ")"
+		}
+		renamedProject := maps.Clone(project)
+		renamedProject[models.RecordFieldName] = renamed
+		merged.projects[key] = renamedProject
+		keysByName[renamed] = key
+		conflicts = append(conflicts, &SyncConflict{
+			ItemType: models.SyncItemProject, Key: key, Name: renamed,
+			Field: models.RecordFieldName, Local: name, Remote: name, Kept: "renamed",
+		})
+	}
+
+	return conflicts
+}
+
+// detachSyncCycles removes parent references that are dangling, that would create a cycle, or that are
+// rejected by the valid function (if any).
+func detachSyncCycles(
+	itemType string,
+	items map[string]syncRecord,
+	valid func(item, parent syncRecord) bool,
+) []*SyncConflict {
+	var conflicts []*SyncConflict
+	detach := func(key string) {
+		item := maps.Clone(items[key])
+		parentKey := item[models.RecordFieldParent]
+		conflicts = append(conflicts, &SyncConflict{
+			ItemType: itemType, Key: key, Name: item[models.RecordFieldName],
+			Field: models.RecordFieldParent, Local: parentKey, Remote: parentKey, Kept: "none",
+		})
+		item[models.RecordFieldParent] = ""
+		items[key] = item
+	}
+
+	for _, key := range sortedKeys(items) {
+		parentKey := items[key][models.RecordFieldParent]
+		if parentKey == "" {
+			continue
+		}
+		parent := items[parentKey]
+		if parent == nil || (valid != nil && !valid(items[key], parent)) {
+			detach(key)
+			continue
+		}
+
+		visited := map[string]bool{key: true}
+		for ancestor := parentKey; ancestor != ""; ancestor = items[ancestor][models.RecordFieldParent] {
+			if visited[ancestor] {
+				detach(key)
+				break
+			}
+			visited[ancestor] = true
+		}
+	}
+	return conflicts
+}
+
+// syncTimeBefore reports whether the first time of a sync record is before the second one.
+func syncTimeBefore(a, b string) bool {
+	aTime, bTime := models.ParseRecordTime(a), models.ParseRecordTime(b)
+	if aTime == nil || bTime == nil {
+		return aTime == nil && bTime != nil
+	}
+	return aTime.Before(*bTime)
+}
+
+// sortedKeys returns the keys of all the given maps, sorted and without duplicates.
+func sortedKeys[V any](sets ...map[string]V) []string {
+	seen := make(map[string]bool)
+	for _, set := range sets {
+		for key := range set {
+			seen[key] = true
+		}
+	}
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -2,8 +2,16 @@ package controllers
 
 import (
 	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"github.com/d4r1us-drk/clido/internal/hooks"
 	"github.com/d4r1us-drk/clido/models"
 	"github.com/d4r1us-drk/clido/repository"
 	"github.com/d4r1us-drk/clido/utils"
@@ -11,15 +19,43 @@ import (
 
 // Error constants for task operations.
 var (
-	ErrNoTaskName         = errors.New("task name is required")
-	ErrNoProject          = errors.New("project name or numeric ID is required")
-	ErrNoProjectFound     = errors.New("project not found")
-	ErrInvalidParentTask  = errors.New("parent task must be identified by a numeric ID")
-	ErrInvalidDueDate     = errors.New("invalid due date format")
-	ErrTaskNotFound       = errors.New("task not found")
-	ErrParentTaskNotFound = errors.New("parent task not found")
+	ErrNoTaskName        = errcode.New(errcode.Validation, "task name is required")
+	ErrNoProject         = errcode.New(errcode.Validation, "project name or numeric ID is required")
+	ErrNoProjectFound    = errcode.New(errcode.NotFound, "project not found")
+	ErrInvalidParentTask = errcode.New(errcode.Validation,
+		"parent task must be identified by a numeric ID or a UUID prefix",
+	)
+	ErrInvalidDueDate     = errcode.New(errcode.Validation, "invalid due date format")
+	ErrInvalidTag         = errcode.New(errcode.Validation, "invalid tag")
+	ErrTaskNotFound       = errcode.New(errcode.NotFound, "task not found")
+	ErrParentTaskNotFound = errcode.New(errcode.NotFound, "parent task not found")
+	ErrTaskCycle          = errcode.New(errcode.Conflict, "a task cannot be its own ancestor")
+	ErrParentTaskProject  = errcode.New(errcode.Conflict, "parent task must belong to the same project")
+	ErrUnknownState       = errcode.New(errcode.Validation, "unknown task state")
+	ErrInvalidTransition  = errcode.New(errcode.Conflict, "transition not allowed by the workflow")
+	ErrOpenSubtasks       = errcode.New(errcode.Conflict,
+		"task has open subtasks; complete them first or force completion",
+	)
+	ErrToggleClosedTask = errcode.New(errcode.Validation,
+		"task is closed but not done, use 'state' or 'reopen' to change its state",
+	)
+	ErrMoveNeedsDetach = errcode.New(errcode.Conflict,
+		"subtasks can only be moved to another project when detached from their parent task",
+	)
+	ErrInvalidPriority = errcode.New(errcode.Validation,
+		"invalid priority, use 1 for High, 2 for Medium, 3 for Low, or 4 for None",
+	)
 )
 
+// TaskStats summarizes the progress of a set of tasks.
+type TaskStats struct {
+	Total     int            `json:"total"`
+	Completed int            `json:"completed"`
+	Open      int            `json:"open"`
+	Overdue   int            `json:"overdue"`
+	ByState   map[string]int `json:"by_state"`
+}
+
 // TaskController manages the task-related business logic.
 type TaskController struct {
 	repo *repository.Repository
@@ -30,43 +66,35 @@ func NewTaskController(repo *repository.Repository) *TaskController {
 	return &TaskController{repo: repo}
 }
 
-// CreateTask handles the creation of a new task.
+// CreateTask handles the creation of a new task, and returns the task as stored.
 func (tc *TaskController) CreateTask(
 	name, description, projectIdentifier, parentTaskIdentifier, dueDateStr string,
 	priority int,
-) error {
+	tags []string,
+) (*models.Task, error) {
 	// Validate mandatory arguments
 	if name == "" {
-		return ErrNoTaskName
+		return nil, ErrNoTaskName
 	}
 	if projectIdentifier == "" {
-		return ErrNoProject
+		return nil, ErrNoProject
 	}
 
-	// Try to parse projectIdentifier as an integer (ID), otherwise get the project by name
-	projectID, projectErr := utils.ParseIntOrError(projectIdentifier)
+	// Retrieve the project by its ID or name
+	project, projectErr := tc.getProject(projectIdentifier)
 	if projectErr != nil {
-		project, lookupErr := tc.repo.GetProjectByName(projectIdentifier)
-		if lookupErr != nil || project == nil {
-			return ErrNoProjectFound
-		}
-		projectID = project.ID
-	} else {
-		// Check if the project exists using the ID
-		project, getProjectErr := tc.repo.GetProjectByID(projectID)
-		if getProjectErr != nil || project == nil {
-			return ErrNoProjectFound
-		}
+		return nil, projectErr
 	}
+	projectID := project.ID
 
 	// Get parent task ID (optional)
 	var parentTaskID *int
 	if parentTaskIdentifier != "" {
-		id, parentErr := utils.ParseIntOrError(parentTaskIdentifier)
+		parentTask, parentErr := tc.getParentTask(parentTaskIdentifier, projectID)
 		if parentErr != nil {
-			return ErrInvalidParentTask
+			return nil, parentErr
 		}
-		parentTaskID = &id
+		parentTaskID = &parentTask.ID
 	}
 
 	// Parse due date (optional)
@@ -74,74 +102,292 @@ func (tc *TaskController) CreateTask(
 	if dueDateStr != "" {
 		parsedDate, dueDateErr := utils.ParseDueDate(dueDateStr)
 		if dueDateErr != nil {
-			return ErrInvalidDueDate
+			return nil, fmt.Errorf("%w: '%s'", ErrInvalidDueDate, dueDateStr)
 		}
 		dueDate = parsedDate
 	}
 
+	taskTags, tagsErr := joinTags(tags)
+	if tagsErr != nil {
+		return nil, tagsErr
+	}
+
 	// Create a new task
 	task := &models.Task{
 		Name:         name,
 		Description:  description,
 		ProjectID:    projectID,
 		DueDate:      dueDate,
+		State:        tc.repo.Workflow().InitialState,
 		Priority:     priority,
+		Tags:         taskTags,
 		ParentTaskID: parentTaskID,
 	}
 
-	// Store the task in the repository
+	// Run the pre-hooks, which may reject or modify the task, and store the task in the repository
+	if hookErr := runPreHooks(tc.repo, hooks.OnCreate, models.SyncItemTask, nil, task); hookErr != nil {
+		return nil, hookErr
+	}
 	if createErr := tc.repo.CreateTask(task); createErr != nil {
-		return createErr
+		return nil, createErr
 	}
 
-	return nil
+	if hookErr := notifyChange(tc.repo, hooks.OnCreate, models.SyncItemTask, nil, task); hookErr != nil {
+		return nil, hookErr
+	}
+	return task, nil
 }
 
-// EditTask handles updating an existing task by its ID.
+// joinTags validates the tags of a task and joins them as stored, without duplicates. A tag is a word,
+// given with or without its leading '#'; a context is a tag starting with '@'.
+func joinTags(tags []string) (string, error) {
+	var joined []string
+	for _, tag := range tags {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+		name := strings.TrimPrefix(tag, "@")
+		if name == "" || strings.ContainsAny(name, ",#@") || strings.ContainsFunc(name, unicode.IsSpace) {
+			return "", fmt.Errorf("%w: '%s'", ErrInvalidTag, tag)
+		}
+		if !slices.Contains(joined, tag) {
+			joined = append(joined, tag)
+		}
+	}
+	return strings.Join(joined, ","), nil
+}
+
+// EditTask handles updating an existing task by its ID, and returns the task as stored. Empty values and a
+// zero priority leave the fields unchanged.
 func (tc *TaskController) EditTask(
 	id int,
 	name, description, dueDateStr string,
 	priority int,
 	parentTaskIdentifier string,
-) error {
+) (*models.Task, error) {
+	var changes TaskChanges
+	if name != "" {
+		changes.Name = &name
+	}
+	if description != "" {
+		changes.Description = &description
+	}
+	if dueDateStr != "" {
+		changes.DueDate = &dueDateStr
+	}
+	if priority != 0 {
+		changes.Priority = &priority
+	}
+	if parentTaskIdentifier != "" {
+		changes.Parent = &parentTaskIdentifier
+	}
+	return tc.UpdateTask(id, changes)
+}
+
+// TaskChanges holds the changes applied to a task by UpdateTask. Nil fields are left unchanged, while
+// empty values clear the optional fields.
+type TaskChanges struct {
+	Name        *string
+	Description *string
+	DueDate     *string   // In a format accepted by utils.ParseDueDate, or empty to remove the due date
+	Priority    *int      // From 1 (High) to 4 (None)
+	Tags        *[]string // Tags and contexts, replacing the current ones
+	Parent      *string   // The parent task identifier, or NoParentIdentifier to detach the task
+}
+
+// UpdateTask applies changes to an existing task by its ID, and returns the task as stored.
+func (tc *TaskController) UpdateTask(id int, changes TaskChanges) (*models.Task, error) {
 	task, getTaskErr := tc.repo.GetTaskByID(id)
 	if getTaskErr != nil {
-		return ErrTaskNotFound
+		return nil, ErrTaskNotFound
 	}
+	before := *task
 
 	// Apply updates
-	if name != "" {
-		task.Name = name
+	if changes.Name != nil {
+		if *changes.Name == "" {
+			return nil, ErrNoTaskName
+		}
+		task.Name = *changes.Name
 	}
-	if description != "" {
-		task.Description = description
+	if changes.Description != nil {
+		task.Description = *changes.Description
 	}
-	if dueDateStr != "" {
-		dueDate, dueDateErr := utils.ParseDueDate(dueDateStr)
-		if dueDateErr != nil {
-			return ErrInvalidDueDate
+	if changes.DueDate != nil {
+		task.DueDate = nil
+		if *changes.DueDate != "" {
+			dueDate, dueDateErr := utils.ParseDueDate(*changes.DueDate)
+			if dueDateErr != nil {
+				return nil, fmt.Errorf("%w: '%s'", ErrInvalidDueDate, *changes.DueDate)
+			}
+			task.DueDate = dueDate
 		}
-		task.DueDate = dueDate
 	}
-	if priority != 0 {
-		task.Priority = priority
+	if changes.Priority != nil {
+		if *changes.Priority < utils.PriorityHigh || *changes.Priority > utils.PriorityNone {
+			return nil, fmt.Errorf("%w: %d", ErrInvalidPriority, *changes.Priority)
+		}
+		task.Priority = *changes.Priority
 	}
-	if parentTaskIdentifier != "" {
-		parentTaskID, parentErr := utils.ParseIntOrError(parentTaskIdentifier)
-		if parentErr != nil {
-			return ErrInvalidParentTask
+	if changes.Tags != nil {
+		tags, tagsErr := joinTags(*changes.Tags)
+		if tagsErr != nil {
+			return nil, tagsErr
+		}
+		task.Tags = tags
+	}
+	if changes.Parent != nil {
+		if parentErr := tc.setParentTask(task, *changes.Parent); parentErr != nil {
+			return nil, parentErr
 		}
-		task.ParentTaskID = &parentTaskID
 	}
 
-	// Update the task in the repository
+	// Run the pre-hooks, which may reject or modify the change, and update the task in the repository
+	if hookErr := runPreHooks(tc.repo, hooks.OnModify, models.SyncItemTask, &before, task); hookErr != nil {
+		return nil, hookErr
+	}
 	if updateErr := tc.repo.UpdateTask(task); updateErr != nil {
-		return updateErr
+		return nil, updateErr
+	}
+
+	if hookErr := notifyChange(tc.repo, hooks.OnModify, models.SyncItemTask, &before, task); hookErr != nil {
+		return nil, hookErr
 	}
+	return task, nil
+}
 
+// setParentTask sets the parent of a task from its identifier, or detaches the task with
+// NoParentIdentifier.
+func (tc *TaskController) setParentTask(task *models.Task, parentTaskIdentifier string) error {
+	if parentTaskIdentifier == NoParentIdentifier {
+		task.ParentTaskID = nil
+		return nil
+	}
+
+	parentTask, parentErr := tc.getParentTask(parentTaskIdentifier, task.ProjectID)
+	if parentErr != nil {
+		return parentErr
+	}
+	if cycleErr := tc.checkTaskAncestry(task.ID, parentTask.ID); cycleErr != nil {
+		return cycleErr
+	}
+	task.ParentTaskID = &parentTask.ID
 	return nil
 }
 
+// MoveTask moves a task and all of its subtasks to the project identified by projectIdentifier
+// (name or ID). A subtask can only change project when detach is set, which also detaches it
+// from its parent task. All changes are applied in a single transaction.
+// It returns the moved tasks, starting with the task itself, and the target project.
+func (tc *TaskController) MoveTask(
+	id int,
+	projectIdentifier string,
+	detach bool,
+) ([]*models.Task, *models.Project, error) {
+	if projectIdentifier == "" {
+		return nil, nil, ErrNoProject
+	}
+
+	task, getTaskErr := tc.repo.GetTaskByID(id)
+	if getTaskErr != nil {
+		return nil, nil, ErrTaskNotFound
+	}
+
+	project, projectErr := tc.getProject(projectIdentifier)
+	if projectErr != nil {
+		return nil, nil, projectErr
+	}
+
+	if task.ParentTaskID != nil && task.ProjectID != project.ID && !detach {
+		return nil, nil, ErrMoveNeedsDetach
+	}
+	previousParentID := task.ParentTaskID
+	if detach {
+		task.ParentTaskID = nil
+	}
+
+	subtasks, subtreeErr := tc.ListTaskSubtree(task.ID)
+	if subtreeErr != nil {
+		return nil, nil, subtreeErr
+	}
+	moved := append([]*models.Task{task}, subtasks...)
+
+	txErr := tc.repo.Transaction(func(txRepo *repository.Repository) error {
+		for _, movedTask := range moved {
+			before := *movedTask
+			if movedTask == task {
+				before.ParentTaskID = previousParentID
+			}
+			movedTask.ProjectID = project.ID
+			hookErr := runPreHooks(txRepo, hooks.OnModify, models.SyncItemTask, &before, movedTask)
+			if hookErr != nil {
+				return hookErr
+			}
+			if updateErr := txRepo.UpdateTask(movedTask); updateErr != nil {
+				return updateErr
+			}
+			hookErr = notifyChange(txRepo, hooks.OnModify, models.SyncItemTask, &before, movedTask)
+			if hookErr != nil {
+				return hookErr
+			}
+		}
+		return nil
+	})
+	if txErr != nil {
+		return nil, nil, txErr
+	}
+
+	return moved, project, nil
+}
+
+// ListTaskSubtree returns all subtasks of a task, recursively, in depth-first order.
+func (tc *TaskController) ListTaskSubtree(id int) ([]*models.Task, error) {
+	return listSubtree(id, tc.repo.GetSubtasks, func(task *models.Task) int { return task.ID })
+}
+
+// ListTaskAncestors returns the parent chain of a task, starting with its top-level ancestor.
+func (tc *TaskController) ListTaskAncestors(id int) ([]*models.Task, error) {
+	task, getTaskErr := tc.repo.GetTaskByID(id)
+	if getTaskErr != nil {
+		return nil, ErrTaskNotFound
+	}
+
+	var ancestors []*models.Task
+	visited := map[int]bool{task.ID: true}
+	for task.ParentTaskID != nil && !visited[*task.ParentTaskID] {
+		parent, getParentErr := tc.repo.GetTaskByID(*task.ParentTaskID)
+		if getParentErr != nil {
+			return nil, ErrParentTaskNotFound
+		}
+		visited[parent.ID] = true
+		ancestors = append([]*models.Task{parent}, ancestors...)
+		task = parent
+	}
+
+	return ancestors, nil
+}
+
+// GetTaskStats computes progress statistics over the given tasks. A task counts as completed when
+// it is done, and as open while it is not in a terminal state.
+func (tc *TaskController) GetTaskStats(tasks []*models.Task) TaskStats {
+	workflow := tc.repo.Workflow()
+	stats := TaskStats{ByState: make(map[string]int)}
+
+	for _, task := range tasks {
+		stats.Total++
+		stats.ByState[task.State]++
+		if task.State == models.StateDone {
+			stats.Completed++
+		}
+		if !workflow.IsTerminal(task.State) {
+			stats.Open++
+			if utils.IsPastDue(task.DueDate) {
+				stats.Overdue++
+			}
+		}
+	}
+
+	return stats
+}
+
 // ListTasks returns all tasks stored in the repository.
 func (tc *TaskController) ListTasks() ([]*models.Task, error) {
 	tasks, getAllErr := tc.repo.GetAllTasks()
@@ -151,6 +397,73 @@ func (tc *TaskController) ListTasks() ([]*models.Task, error) {
 	return tasks, nil
 }
 
+// FindTasks returns the tasks matching a selection query made of "key:value" terms, all of
+// which must match. Supported keys are project (name or ID), priority (1-4), completed (true/false),
+// state (workflow state), parent (task ID or 'none') and name (case-insensitive substring).
+func (tc *TaskController) FindTasks(query string) ([]*models.Task, error) {
+	return findMatching(query, tc.taskQueryFilter, tc.repo.GetAllTasks)
+}
+
+// taskQueryFilter builds the filter function for a single query term.
+func (tc *TaskController) taskQueryFilter(term queryTerm) (func(task *models.Task) bool, error) {
+	switch term.key {
+	case "project":
+		project, err := tc.getProject(term.value)
+		if err != nil {
+			return nil, err
+		}
+		return func(task *models.Task) bool { return task.ProjectID == project.ID }, nil
+	case "priority":
+		priority, err := strconv.Atoi(term.value)
+		if err != nil || priority < utils.PriorityHigh || priority > utils.PriorityNone {
+			return nil, fmt.Errorf("%w: priority must be between 1 and 4", ErrInvalidQuery)
+		}
+		return func(task *models.Task) bool { return task.Priority == priority }, nil
+	case "completed":
+		completed, err := strconv.ParseBool(term.value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: completed must be true or false", ErrInvalidQuery)
+		}
+		return func(task *models.Task) bool { return task.TaskCompleted == completed }, nil
+	case "state":
+		if !tc.repo.Workflow().HasState(term.value) {
+			return nil, fmt.Errorf("%w: '%s'", ErrUnknownState, term.value)
+		}
+		return func(task *models.Task) bool { return task.State == term.value }, nil
+	case "parent":
+		if term.value == NoParentIdentifier {
+			return func(task *models.Task) bool { return task.ParentTaskID == nil }, nil
+		}
+		parentID, err := tc.ResolveTaskID(term.value)
+		if errors.Is(err, ErrInvalidIdentifier) {
+			return nil, ErrInvalidParentTask
+		}
+		if err != nil {
+			return nil, err
+		}
+		return func(task *models.Task) bool {
+			return task.ParentTaskID != nil && *task.ParentTaskID == parentID
+		}, nil
+	case "name":
+		needle := strings.ToLower(term.value)
+		return func(task *models.Task) bool {
+			return strings.Contains(strings.ToLower(task.Name), needle)
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown key '%s'", ErrInvalidQuery, term.key)
+	}
+}
+
+// InTransaction runs fn with a TaskController bound to a single database transaction,
+// so that all changes made through it are committed or rolled back together.
+func (tc *TaskController) InTransaction(fn func(txController *TaskController) error) error {
+	return tc.repo.Transaction(func(txRepo *repository.Repository) error {
+		txController := *tc
+		txController.repo = txRepo
+		return fn(&txController)
+	})
+}
+
 // ListTasksByProjectFilter returns tasks filtered by project.
 func (tc *TaskController) ListTasksByProjectFilter(
 	projectFilter string,
@@ -161,21 +474,10 @@ func (tc *TaskController) ListTasksByProjectFilter(
 		return tasks, nil, getAllErr
 	}
 
-	// Try to parse the projectFilter as a numeric ID first
-	projectID, projectErr := utils.ParseIntOrError(projectFilter)
+	// The filter is a numeric ID, a project name or a UUID prefix
+	project, projectErr := tc.getProject(projectFilter)
 	if projectErr != nil {
-		// If parsing fails, assume it's a project name and get the project by name
-		project, lookupErr := tc.repo.GetProjectByName(projectFilter)
-		if lookupErr != nil || project == nil {
-			return nil, nil, ErrNoProjectFound
-		}
-		projectID = project.ID
-	}
-
-	// Retrieve the project by ID
-	project, getProjectErr := tc.repo.GetProjectByID(projectID)
-	if getProjectErr != nil || project == nil {
-		return nil, nil, ErrNoProjectFound
+		return nil, nil, projectErr
 	}
 
 	// Get tasks by project ID
@@ -187,71 +489,472 @@ func (tc *TaskController) ListTasksByProjectFilter(
 	return tasks, project, nil
 }
 
-// ToggleTaskCompletion toggles the completion status of a task.
-// If recursive is true, it also toggles the completion status of all subtasks.
-func (tc *TaskController) ToggleTaskCompletion(id int, recursive bool) (string, error) {
+// ToggleTaskCompletion toggles the completion status of a task, moving an open task to the "done" state,
+// or a done task back to the initial workflow state. Tasks closed in another terminal state, such as
+// cancelled tasks, are rejected with ErrToggleClosedTask.
+// If recursive is true, all subtasks are set to the new state of the task,
+// so that the whole subtree ends up in the same state.
+// The transitions must be allowed by the workflow unless force is true.
+// It returns the tasks whose state actually changed and the new completion status of the task.
+func (tc *TaskController) ToggleTaskCompletion(id int, recursive, force bool) ([]*models.Task, string, error) {
 	// Retrieve the task by its ID
 	task, getTaskErr := tc.repo.GetTaskByID(id)
 	if getTaskErr != nil {
-		return "", ErrTaskNotFound
+		return nil, "", ErrTaskNotFound
+	}
+
+	// Determine the new completion status
+	state := models.StateDone
+	completion := "completed"
+	switch {
+	case task.State == models.StateDone:
+		state = tc.repo.Workflow().InitialState
+		completion = "not completed"
+	case tc.repo.Workflow().IsTerminal(task.State):
+		return nil, "", fmt.Errorf("%w: '%s'", ErrToggleClosedTask, task.State)
 	}
 
-	// Toggle the completion status
-	completion := "not completed"
-	if task.TaskCompleted {
-		task.TaskCompleted = false
-		task.CompletionDate = nil
-	} else {
-		task.TaskCompleted = true
-		now := time.Now()
-		task.CompletionDate = &now
-		completion = "completed"
+	// If recursive flag is set, apply the same status to all subtasks
+	targets := []*models.Task{task}
+	if recursive {
+		subtasks, getSubtasksErr := tc.ListTaskSubtree(id)
+		if getSubtasksErr != nil {
+			return nil, "", getSubtasksErr
+		}
+		targets = append(targets, subtasks...)
+	}
+	if transitionErr := tc.checkTransitions(id, targets, state, force); transitionErr != nil {
+		return nil, "", transitionErr
 	}
 
-	// Update the task in the repository
-	updateErr := tc.repo.UpdateTask(task)
-	if updateErr != nil {
-		return "", updateErr
+	changed, setErr := tc.setState(targets, state)
+	if setErr != nil {
+		return nil, "", setErr
 	}
 
-	// If recursive flag is set, toggle the completion status of all subtasks
+	return changed, completion, nil
+}
+
+// CompleteTask moves a task to the "done" state. Completing an already completed task changes nothing.
+// If recursive is true, all subtasks are completed too; otherwise a task with open subtasks
+// can only be completed when force is true. The transitions must be allowed by the workflow
+// unless force is true.
+// It returns the tasks whose state actually changed.
+func (tc *TaskController) CompleteTask(id int, recursive, force bool) ([]*models.Task, error) {
+	task, getTaskErr := tc.repo.GetTaskByID(id)
+	if getTaskErr != nil {
+		return nil, ErrTaskNotFound
+	}
+	// A task already done, e.g. forced with open subtasks, stays as it is
+	if task.State == models.StateDone && !recursive {
+		return nil, nil
+	}
+
+	subtasks, getSubtasksErr := tc.ListTaskSubtree(id)
+	if getSubtasksErr != nil {
+		return nil, getSubtasksErr
+	}
+
+	if !recursive && !force && tc.hasOpenTasks(subtasks) {
+		return nil, ErrOpenSubtasks
+	}
+
+	targets := []*models.Task{task}
 	if recursive {
-		subtasks, getSubtasksErr := tc.ListSubtasks(id)
+		targets = append(targets, subtasks...)
+	}
+	if transitionErr := tc.checkTransitions(id, targets, models.StateDone, force); transitionErr != nil {
+		return nil, transitionErr
+	}
+	return tc.setState(targets, models.StateDone)
+}
+
+// ReopenTask moves a task back to the initial workflow state. Reopening an open task changes nothing.
+// If recursive is true, all subtasks are reopened too.
+// The transitions must be allowed by the workflow unless force is true.
+// It returns the tasks whose state actually changed.
+func (tc *TaskController) ReopenTask(id int, recursive, force bool) ([]*models.Task, error) {
+	task, getTaskErr := tc.repo.GetTaskByID(id)
+	if getTaskErr != nil {
+		return nil, ErrTaskNotFound
+	}
+
+	targets := []*models.Task{task}
+	if recursive {
+		subtasks, getSubtasksErr := tc.ListTaskSubtree(id)
 		if getSubtasksErr != nil {
-			return "", getSubtasksErr
+			return nil, getSubtasksErr
+		}
+		targets = append(targets, subtasks...)
+	}
+
+	workflow := tc.repo.Workflow()
+	var reopen []*models.Task
+	for _, target := range targets {
+		if workflow.IsTerminal(target.State) {
+			reopen = append(reopen, target)
+		}
+	}
+	if transitionErr := tc.checkTransitions(id, reopen, workflow.InitialState, force); transitionErr != nil {
+		return nil, transitionErr
+	}
+	return tc.setState(reopen, workflow.InitialState)
+}
+
+// SetTaskState moves a task to the given workflow state, following the transitions allowed by
+// the workflow unless force is true. Moving a task with open subtasks to a terminal state also
+// requires force. Setting the state the task is already in changes nothing.
+// It returns the task and whether its state changed.
+func (tc *TaskController) SetTaskState(id int, state string, force bool) (*models.Task, bool, error) {
+	workflow := tc.repo.Workflow()
+	if !workflow.HasState(state) {
+		return nil, false, fmt.Errorf("%w: '%s'", ErrUnknownState, state)
+	}
+
+	task, getTaskErr := tc.repo.GetTaskByID(id)
+	if getTaskErr != nil {
+		return nil, false, ErrTaskNotFound
+	}
+	if task.State == state {
+		return task, false, nil
+	}
+
+	if !force {
+		if !workflow.CanTransition(task.State, state) {
+			return nil, false, fmt.Errorf(
+				"%w: cannot move from '%s' to '%s'", ErrInvalidTransition, task.State, state,
+			)
 		}
 
-		for _, subtask := range subtasks {
-			if _, toggleErr := tc.ToggleTaskCompletion(subtask.ID, true); toggleErr != nil {
-				return "", toggleErr
+		if workflow.IsTerminal(state) {
+			subtasks, getSubtasksErr := tc.ListTaskSubtree(id)
+			if getSubtasksErr != nil {
+				return nil, false, getSubtasksErr
+			}
+			if tc.hasOpenTasks(subtasks) {
+				return nil, false, ErrOpenSubtasks
 			}
 		}
 	}
 
-	return completion, nil
+	if _, setErr := tc.setState([]*models.Task{task}, state); setErr != nil {
+		return nil, false, setErr
+	}
+	return task, true, nil
+}
+
+// ListTaskTransitions returns the state transitions of a task, oldest first.
+func (tc *TaskController) ListTaskTransitions(id int) ([]*models.TaskTransition, error) {
+	if _, getTaskErr := tc.repo.GetTaskByID(id); getTaskErr != nil {
+		return nil, ErrTaskNotFound
+	}
+	return tc.repo.GetTaskTransitions(id)
 }
 
-// RemoveTask handles the recursive removal of a task and all its subtasks.
-func (tc *TaskController) RemoveTask(id int) error {
+// Workflow returns the task workflow in use.
+func (tc *TaskController) Workflow() *models.Workflow {
+	return tc.repo.Workflow()
+}
+
+// CompleteParents walks up the parent chain of a task and completes every ancestor whose
+// subtasks are all closed, stopping at the first ancestor that still has open subtasks or
+// that the workflow does not allow to complete.
+// It returns the ancestors that were completed.
+func (tc *TaskController) CompleteParents(id int) ([]*models.Task, error) {
+	task, getTaskErr := tc.repo.GetTaskByID(id)
+	if getTaskErr != nil {
+		return nil, ErrTaskNotFound
+	}
+
+	var completed []*models.Task
+	visited := map[int]bool{task.ID: true}
+
+	for task.ParentTaskID != nil && !visited[*task.ParentTaskID] {
+		parent, getParentErr := tc.repo.GetTaskByID(*task.ParentTaskID)
+		if getParentErr != nil {
+			return nil, ErrParentTaskNotFound
+		}
+		visited[parent.ID] = true
+
+		subtasks, getSubtasksErr := tc.repo.GetSubtasks(parent.ID)
+		if getSubtasksErr != nil {
+			return nil, getSubtasksErr
+		}
+		if tc.hasOpenTasks(subtasks) {
+			return completed, nil
+		}
+		if parent.State != models.StateDone && !tc.repo.Workflow().CanTransition(parent.State, models.StateDone) {
+			return completed, nil
+		}
+
+		changed, setErr := tc.setState([]*models.Task{parent}, models.StateDone)
+		if setErr != nil {
+			return nil, setErr
+		}
+		completed = append(completed, changed...)
+		task = parent
+	}
+
+	return completed, nil
+}
+
+// checkTransitions checks that the workflow allows the given tasks to move to a state, unless force is
+// true. Tasks already in that state are ignored, and the others are the task with the given ID and its
+// subtasks.
+func (tc *TaskController) checkTransitions(id int, tasks []*models.Task, state string, force bool) error {
+	if force {
+		return nil
+	}
+
+	workflow := tc.repo.Workflow()
+	for _, task := range tasks {
+		switch {
+		case task.State == state || workflow.CanTransition(task.State, state):
+			continue
+		case task.ID == id:
+			return fmt.Errorf("%w: cannot move from '%s' to '%s'", ErrInvalidTransition, task.State, state)
+		default:
+			return fmt.Errorf("%w: cannot move subtask with ID '%d' from '%s' to '%s'",
+				ErrInvalidTransition, task.ID, task.State, state)
+		}
+	}
+	return nil
+}
+
+// hasOpenTasks reports whether any of the given tasks is not in a terminal state.
+func (tc *TaskController) hasOpenTasks(tasks []*models.Task) bool {
+	workflow := tc.repo.Workflow()
+	for _, task := range tasks {
+		if !workflow.IsTerminal(task.State) {
+			return true
+		}
+	}
+	return false
+}
+
+// setState moves the given tasks to a workflow state, skipping the ones already in that state,
+// records the transitions and returns the tasks that changed. The completion date is set when a
+// task enters a terminal state and cleared when it leaves one.
+func (tc *TaskController) setState(tasks []*models.Task, state string) ([]*models.Task, error) {
+	workflow := tc.repo.Workflow()
+	var changed []*models.Task
+
+	for _, task := range tasks {
+		if task.State == state {
+			continue
+		}
+
+		transition := &models.TaskTransition{TaskID: task.ID, FromState: task.State, ToState: state}
+		before := *task
+		switch {
+		case !workflow.IsTerminal(state):
+			task.CompletionDate = nil
+		case !workflow.IsTerminal(task.State):
+			now := time.Now()
+			task.CompletionDate = &now
+		}
+		task.State = state
+		task.TaskCompleted = state == models.StateDone
+
+		// Completing a task runs the on-complete hooks, any other change of state the on-modify ones
+		event := hooks.OnModify
+		if task.TaskCompleted {
+			event = hooks.OnComplete
+		}
+		if hookErr := runPreHooks(tc.repo, event, models.SyncItemTask, &before, task); hookErr != nil {
+			return nil, hookErr
+		}
+		if updateErr := tc.repo.UpdateTask(task); updateErr != nil {
+			return nil, updateErr
+		}
+		if transitionErr := tc.repo.CreateTaskTransition(transition); transitionErr != nil {
+			return nil, transitionErr
+		}
+		if hookErr := notifyChange(tc.repo, event, models.SyncItemTask, &before, task); hookErr != nil {
+			return nil, hookErr
+		}
+		changed = append(changed, task)
+	}
+
+	return changed, nil
+}
+
+// RemoveTask handles the recursive removal of a task and all its subtasks, and returns the IDs of the
+// removed tasks, starting with the task itself.
+func (tc *TaskController) RemoveTask(id int) ([]int, error) {
+	return tc.removeTask(id, make(map[int]bool))
+}
+
+// removeTask removes a task and its subtasks, skipping those already being removed, which a cycle of
+// parents would cause.
+func (tc *TaskController) removeTask(id int, removing map[int]bool) ([]int, error) {
+	removing[id] = true
+
+	task, getTaskErr := tc.repo.GetTaskByID(id)
+	if getTaskErr != nil {
+		return nil, ErrTaskNotFound
+	}
+	if hookErr := runPreHooks(tc.repo, hooks.OnRemove, models.SyncItemTask, task, nil); hookErr != nil {
+		return nil, hookErr
+	}
+
 	// Get all subtasks for the given task
 	subtasks, getSubtasksErr := tc.repo.GetSubtasks(id)
 	if getSubtasksErr != nil {
-		return getSubtasksErr
+		return nil, getSubtasksErr
 	}
 
 	// Recursively remove subtasks
+	removed := []int{id}
 	for _, subtask := range subtasks {
-		if removeErr := tc.RemoveTask(subtask.ID); removeErr != nil {
-			return removeErr
+		if removing[subtask.ID] {
+			continue
+		}
+		removedSubtasks, removeErr := tc.removeTask(subtask.ID, removing)
+		if removeErr != nil {
+			return nil, removeErr
 		}
+		removed = append(removed, removedSubtasks...)
 	}
 
 	// Remove the parent task
 	if deleteErr := tc.repo.DeleteTask(id); deleteErr != nil {
-		return deleteErr
+		return nil, deleteErr
 	}
 
-	return nil
+	if hookErr := notifyChange(tc.repo, hooks.OnRemove, models.SyncItemTask, task, nil); hookErr != nil {
+		return nil, hookErr
+	}
+	return removed, nil
+}
+
+// UpdateWorkingSet gives display IDs to the open tasks of the workspace missing from the working set, in
+// the order of their persistent IDs, reusing the smallest display IDs freed by tasks closed or removed
+// since the last update. The display IDs of the tasks still open do not change. It only writes the working
+// set when it changed, and returns the display IDs of the open tasks by task ID.
+func (tc *TaskController) UpdateWorkingSet() (map[int]int, error) {
+	tasks, err := tc.repo.GetAllTasks()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+
+	displayIDs, err := tc.DisplayIDs(tasks)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := tc.repo.GetWorkingSet()
+	if err != nil {
+		return nil, err
+	}
+
+	// Entries of closed and removed tasks are dropped, freeing their display IDs
+	taken := make(map[int]bool, len(displayIDs))
+	for _, displayID := range displayIDs {
+		taken[displayID] = true
+	}
+	changed := len(taken) != len(entries)
+
+	var kept []*models.WorkingSetEntry
+	for _, task := range tasks {
+		if displayID, numbered := displayIDs[task.ID]; numbered {
+			kept = append(kept, &models.WorkingSetEntry{DisplayID: displayID, TaskID: task.ID, TaskUUID: task.UUID})
+		}
+	}
+
+	nextDisplayID := 1
+	for _, task := range tasks {
+		if _, numbered := displayIDs[task.ID]; numbered || tc.repo.Workflow().IsTerminal(task.State) {
+			continue
+		}
+		for taken[nextDisplayID] {
+			nextDisplayID++
+		}
+		taken[nextDisplayID] = true
+		kept = append(kept, &models.WorkingSetEntry{DisplayID: nextDisplayID, TaskID: task.ID, TaskUUID: task.UUID})
+		displayIDs[task.ID] = nextDisplayID
+		changed = true
+	}
+
+	if changed {
+		if err = tc.repo.ReplaceWorkingSet(kept); err != nil {
+			return nil, err
+		}
+	}
+	return displayIDs, nil
+}
+
+// RenumberWorkingSet gives display IDs to the open tasks of the workspace, numbered from 1 in the order
+// of their persistent IDs, replacing the previous working set. It returns the display IDs by task ID.
+func (tc *TaskController) RenumberWorkingSet() (map[int]int, error) {
+	tasks, err := tc.repo.GetAllTasks()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+
+	displayIDs := make(map[int]int)
+	var entries []*models.WorkingSetEntry
+	for _, task := range tasks {
+		if tc.repo.Workflow().IsTerminal(task.State) {
+			continue
+		}
+		entry := &models.WorkingSetEntry{DisplayID: len(entries) + 1, TaskID: task.ID, TaskUUID: task.UUID}
+		entries = append(entries, entry)
+		displayIDs[task.ID] = entry.DisplayID
+	}
+
+	if err = tc.repo.ReplaceWorkingSet(entries); err != nil {
+		return nil, err
+	}
+	return displayIDs, nil
+}
+
+// DisplayIDs returns the display IDs given to open tasks by the last update of the working set, by task
+// ID. Tasks that were closed, removed or created since then have none.
+func (tc *TaskController) DisplayIDs(tasks []*models.Task) (map[int]int, error) {
+	entries, err := tc.repo.GetWorkingSet()
+	if err != nil {
+		return nil, err
+	}
+
+	// The UUID is checked rather than the ID, which may have been reused by a new task
+	byUUID := make(map[string]int, len(entries))
+	for _, entry := range entries {
+		byUUID[entry.TaskUUID] = entry.DisplayID
+	}
+	displayIDs := make(map[int]int)
+	for _, task := range tasks {
+		if displayID, found := byUUID[task.UUID]; found && !tc.repo.Workflow().IsTerminal(task.State) {
+			displayIDs[task.ID] = displayID
+		}
+	}
+	return displayIDs, nil
+}
+
+// ResolveTaskID returns the ID of the task identified by a numeric ID, returned as is, by a display ID
+// of the working set (e.g. "@3") or by a prefix of its UUID.
+func (tc *TaskController) ResolveTaskID(identifier string) (int, error) {
+	if id, parseErr := utils.ParseIntOrError(identifier); parseErr == nil {
+		return id, nil
+	}
+	if strings.HasPrefix(identifier, DisplayIDPrefix) {
+		task, err := findTaskByDisplayID(tc.repo, identifier)
+		if err != nil {
+			return 0, err
+		}
+		return task.ID, nil
+	}
+	if !utils.IsUUIDPrefix(identifier) {
+		return 0, fmt.Errorf("%w: '%s'", ErrInvalidIdentifier, identifier)
+	}
+
+	notFound := fmt.Errorf("%w: no task UUID starts with '%s'", ErrTaskNotFound, identifier)
+	task, err := findTask(tc.repo, identifier, notFound)
+	if err != nil {
+		return 0, err
+	}
+	return task.ID, nil
 }
 
 // GetTaskByID returns the task details for a given task ID.
@@ -279,3 +982,50 @@ func (tc *TaskController) ListSubtasks(taskID int) ([]*models.Task, error) {
 	}
 	return subtasks, nil
 }
+
+// getProject retrieves a project by its numeric ID, its name or a prefix of its UUID.
+func (tc *TaskController) getProject(projectIdentifier string) (*models.Project, error) {
+	return findProject(tc.repo, projectIdentifier, ErrNoProjectFound)
+}
+
+// getParentTask retrieves the parent task identified by its numeric ID or a prefix of its UUID and
+// ensures it belongs to the given project.
+func (tc *TaskController) getParentTask(parentTaskIdentifier string, projectID int) (*models.Task, error) {
+	if !isIdentifier(parentTaskIdentifier) {
+		return nil, ErrInvalidParentTask
+	}
+
+	parentTask, getTaskErr := findTask(tc.repo, parentTaskIdentifier, ErrParentTaskNotFound)
+	if getTaskErr != nil {
+		return nil, getTaskErr
+	}
+
+	if parentTask.ProjectID != projectID {
+		return nil, ErrParentTaskProject
+	}
+
+	return parentTask, nil
+}
+
+// checkTaskAncestry walks up the ancestor chain starting at parentID and returns ErrTaskCycle
+// if taskID is found in it, which would make the task its own ancestor.
+func (tc *TaskController) checkTaskAncestry(taskID, parentID int) error {
+	visited := make(map[int]bool)
+	currentID := &parentID
+
+	for currentID != nil {
+		// Reaching the task itself, or an already corrupted chain, means a cycle
+		if *currentID == taskID || visited[*currentID] {
+			return ErrTaskCycle
+		}
+		visited[*currentID] = true
+
+		ancestor, err := tc.repo.GetTaskByID(*currentID)
+		if err != nil {
+			return ErrParentTaskNotFound
+		}
+		currentID = ancestor.ParentTaskID
+	}
+
+	return nil
+}
@@ -0,0 +1,188 @@
+package controllers_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/models"
+	"github.com/d4r1us-drk/clido/repository"
+	"github.com/d4r1us-drk/clido/utils"
+)
+
+// newTestTasks creates a project named Work holding top-level tasks with the given names, in order.
+func newTestTasks(t *testing.T, repo *repository.Repository, names ...string) []*models.Task {
+	t.Helper()
+
+	if _, err := controllers.NewProjectController(repo).CreateProject("Work", "", ""); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+	tasks := make([]*models.Task, 0, len(names))
+	for _, name := range names {
+		task, err := controllers.NewTaskController(repo).CreateTask(name, "", "Work", "", "", utils.PriorityNone, nil)
+		if err != nil {
+			t.Fatalf("CreateTask(%s) error = %v", name, err)
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+func TestUpdateWorkingSetReusesFreedDisplayIDs(t *testing.T) {
+	repo := newTestRepository(t)
+	taskController := controllers.NewTaskController(repo)
+	tasks := newTestTasks(t, repo, "Plan", "Write", "Review")
+
+	if _, err := taskController.UpdateWorkingSet(); err != nil {
+		t.Fatalf("UpdateWorkingSet() error = %v", err)
+	}
+
+	// Closing and removing tasks makes their display IDs stale until the next update
+	if _, err := taskController.CompleteTask(tasks[1].ID, false, false); err != nil {
+		t.Fatalf("CompleteTask() error = %v", err)
+	}
+	if _, err := taskController.RemoveTask(tasks[2].ID); err != nil {
+		t.Fatalf("RemoveTask() error = %v", err)
+	}
+	for _, identifier := range []string{"@2", "@3"} {
+		if _, err := taskController.ResolveTaskID(identifier); !errors.Is(err, controllers.ErrStaleDisplayID) {
+			t.Errorf("ResolveTaskID(%s) error = %v, want %v", identifier, err, controllers.ErrStaleDisplayID)
+		}
+	}
+
+	created, err := taskController.CreateTask("Publish", "", "Work", "", "", utils.PriorityNone, nil)
+	if err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+	displayIDs, err := taskController.UpdateWorkingSet()
+	if err != nil {
+		t.Fatalf("UpdateWorkingSet() error = %v", err)
+	}
+	want := map[int]int{tasks[0].ID: 1, created.ID: 2}
+	if len(displayIDs) != len(want) {
+		t.Fatalf("UpdateWorkingSet() = %v, want %v", displayIDs, want)
+	}
+	for id, displayID := range want {
+		if displayIDs[id] != displayID {
+			t.Errorf("UpdateWorkingSet() = %v, want %v", displayIDs, want)
+		}
+	}
+
+	if id, resolveErr := taskController.ResolveTaskID("@2"); resolveErr != nil || id != created.ID {
+		t.Errorf("ResolveTaskID(@2) = %d, %v, want %d", id, resolveErr, created.ID)
+	}
+	if _, resolveErr := taskController.ResolveTaskID("@3"); !errors.Is(resolveErr, controllers.ErrUnknownDisplayID) {
+		t.Errorf("ResolveTaskID(@3) error = %v, want %v", resolveErr, controllers.ErrUnknownDisplayID)
+	}
+}
+
+func TestUpdateTaskRejectsCycles(t *testing.T) {
+	repo := newTestRepository(t)
+	taskController := controllers.NewTaskController(repo)
+	tasks := newTestTasks(t, repo, "Report")
+
+	// Report > Draft > Outline
+	subtask, err := taskController.CreateTask("Draft", "", "Work", tasks[0].UUID, "", utils.PriorityNone, nil)
+	if err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+	nested, err := taskController.CreateTask("Outline", "", "Work", subtask.UUID, "", utils.PriorityNone, nil)
+	if err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+	tasks = append(tasks, subtask, nested)
+
+	tests := []struct {
+		name   string
+		id     int
+		parent string
+	}{
+		{"under itself", tasks[0].ID, tasks[0].UUID},
+		{"under its subtask", tasks[0].ID, subtask.UUID},
+		{"under a nested subtask", tasks[0].ID, nested.UUID},
+		{"under its own subtask", subtask.ID, nested.UUID},
+	}
+	for _, tt := range tests {
+		parent := tt.parent
+		_, updateErr := taskController.UpdateTask(tt.id, controllers.TaskChanges{Parent: &parent})
+		if !errors.Is(updateErr, controllers.ErrTaskCycle) {
+			t.Errorf("UpdateTask() %s error = %v, want %v", tt.name, updateErr, controllers.ErrTaskCycle)
+		}
+	}
+
+	// The hierarchy is unchanged
+	for i, task := range tasks {
+		stored, getErr := taskController.GetTaskByID(task.ID)
+		if getErr != nil {
+			t.Fatalf("GetTaskByID(%d) error = %v", task.ID, getErr)
+		}
+		switch {
+		case i == 0 && stored.ParentTaskID != nil:
+			t.Errorf("task %s has parent %d, want none", stored.Name, *stored.ParentTaskID)
+		case i > 0 && (stored.ParentTaskID == nil || *stored.ParentTaskID != tasks[i-1].ID):
+			t.Errorf("task %s has parent %v, want %d", stored.Name, stored.ParentTaskID, tasks[i-1].ID)
+		}
+	}
+}
+
+func TestCompleteTaskAlreadyDone(t *testing.T) {
+	repo := newTestRepository(t)
+	taskController := controllers.NewTaskController(repo)
+	tasks := newTestTasks(t, repo, "Report")
+	subtask, err := taskController.CreateTask("Draft", "", "Work", tasks[0].UUID, "", utils.PriorityNone, nil)
+	if err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+
+	if _, err = taskController.CompleteTask(tasks[0].ID, false, false); !errors.Is(err, controllers.ErrOpenSubtasks) {
+		t.Fatalf("CompleteTask() with an open subtask error = %v, want %v", err, controllers.ErrOpenSubtasks)
+	}
+	if _, err = taskController.CompleteTask(tasks[0].ID, false, true); err != nil {
+		t.Fatalf("CompleteTask() with force error = %v", err)
+	}
+
+	// Completing the task again changes nothing, although its subtask is still open
+	changed, err := taskController.CompleteTask(tasks[0].ID, false, false)
+	if err != nil || len(changed) != 0 {
+		t.Fatalf("CompleteTask() of a done task = %d changed, %v, want none", len(changed), err)
+	}
+	stored, err := taskController.GetTaskByID(subtask.ID)
+	if err != nil || stored.State == models.StateDone {
+		t.Errorf("subtask = %+v, %v, want it still open", stored, err)
+	}
+
+	// Completing it recursively still completes the subtask
+	if changed, err = taskController.CompleteTask(tasks[0].ID, true, false); err != nil || len(changed) != 1 {
+		t.Errorf("CompleteTask() recursively = %d changed, %v, want the subtask", len(changed), err)
+	}
+}
+
+func TestToggleTaskCompletion(t *testing.T) {
+	repo := newTestRepository(t)
+	taskController := controllers.NewTaskController(repo)
+	tasks := newTestTasks(t, repo, "Report", "Meeting")
+
+	// Open tasks are completed and done tasks reopened
+	for _, want := range []string{models.StateDone, repo.Workflow().InitialState} {
+		changed, _, err := taskController.ToggleTaskCompletion(tasks[0].ID, false, false)
+		if err != nil || len(changed) != 1 || changed[0].State != want {
+			t.Fatalf("ToggleTaskCompletion() = %v, %v, want the task %s", changed, err, want)
+		}
+	}
+
+	// Cancelled tasks are closed without being done, so they are left to the state and reopen commands
+	if _, _, err := taskController.SetTaskState(tasks[1].ID, models.StateCancelled, false); err != nil {
+		t.Fatalf("SetTaskState() error = %v", err)
+	}
+	for _, force := range []bool{false, true} {
+		_, _, err := taskController.ToggleTaskCompletion(tasks[1].ID, false, force)
+		if !errors.Is(err, controllers.ErrToggleClosedTask) {
+			t.Errorf("ToggleTaskCompletion() of a cancelled task (force %t) error = %v, want %v",
+				force, err, controllers.ErrToggleClosedTask)
+		}
+	}
+	stored, err := taskController.GetTaskByID(tasks[1].ID)
+	if err != nil || stored.State != models.StateCancelled {
+		t.Errorf("cancelled task = %+v, %v, want it still cancelled", stored, err)
+	}
+}
@@ -0,0 +1,273 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"github.com/d4r1us-drk/clido/internal/hooks"
+	"github.com/d4r1us-drk/clido/internal/webhook"
+	"github.com/d4r1us-drk/clido/models"
+	"github.com/d4r1us-drk/clido/repository"
+	"github.com/google/uuid"
+)
+
+// MaxDeliveryAttempts is the number of attempts after which a delivery is given up.
+const MaxDeliveryAttempts = 8
+
+// Error constants for webhook operations.
+var (
+	ErrInvalidWebhookURL = errcode.New(errcode.Validation, "webhook URL must be an absolute http or https URL")
+	ErrWebhookNotFound   = errcode.New(errcode.NotFound, "webhook not found")
+)
+
+// WebhookPayload is the JSON document posted to webhooks. Before is null for a creation and After is null
+// for a removal.
+type WebhookPayload struct {
+	Event     string    `json:"event"`
+	ItemType  string    `json:"item_type"`
+	Workspace string    `json:"workspace"`
+	Timestamp time.Time `json:"timestamp"`
+	Before    any       `json:"before"`
+	After     any       `json:"after"`
+}
+
+// DeliveryResult summarizes the delivery of pending webhook deliveries.
+type DeliveryResult struct {
+	Attempted int // Deliveries attempted
+	Delivered int // Deliveries accepted by their endpoint
+	Retrying  int // Failed deliveries that will be attempted again
+	Failed    int // Failed deliveries given up after too many attempts
+}
+
+// WebhookController manages the webhooks notified of the changes of projects and tasks, for the events
+// of the hooks. Each change is queued in the database with the change itself, and posted once the change
+// is saved. Failed deliveries are attempted again by later changes or by DeliverPending, waiting longer
+// after each attempt, until they are given up.
+// Changes made by syncing, replicating or importing data are not posted.
+type WebhookController struct {
+	repo *repository.Repository
+}
+
+// NewWebhookController creates and returns a new instance of WebhookController.
+func NewWebhookController(repo *repository.Repository) *WebhookController {
+	return &WebhookController{repo: repo}
+}
+
+// AddWebhook adds a webhook posting the given events (all when none is given) of the whole workspace, or of
+// the project identified by projectIdentifier and its tasks. A random secret is generated when none is given.
+func (wc *WebhookController) AddWebhook(
+	endpoint, secret, projectIdentifier string,
+	events []string,
+) (*models.Webhook, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("%w: '%s'", ErrInvalidWebhookURL, endpoint)
+	}
+	for _, event := range events {
+		if !hooks.IsEvent(event) {
+			return nil, fmt.Errorf("%w: '%s'", hooks.ErrUnknownEvent, event)
+		}
+	}
+
+	if secret == "" {
+		if secret, err = webhook.NewSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	webhook := &models.Webhook{URL: endpoint, Secret: secret, Events: strings.Join(events, ",")}
+	if projectIdentifier != "" {
+		project, projectErr := findProject(wc.repo, projectIdentifier, ErrNoProjectFound)
+		if projectErr != nil {
+			return nil, projectErr
+		}
+		webhook.ProjectID = &project.ID
+	}
+
+	if err = wc.repo.CreateWebhook(webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// ListWebhooks returns the webhooks, in the order they were added.
+func (wc *WebhookController) ListWebhooks() ([]*models.Webhook, error) {
+	return wc.repo.GetWebhooks()
+}
+
+// RemoveWebhook removes a webhook and its deliveries.
+func (wc *WebhookController) RemoveWebhook(id int) error {
+	if _, err := wc.repo.GetWebhookByID(id); err != nil {
+		return fmt.Errorf("%w: %d", ErrWebhookNotFound, id)
+	}
+	return wc.repo.DeleteWebhook(id)
+}
+
+// ListDeliveries returns the latest deliveries, most recent first, of a webhook or of all webhooks when
+// webhookID is 0. At most limit deliveries are returned, or all of them when limit is 0.
+func (wc *WebhookController) ListDeliveries(webhookID, limit int) ([]*models.WebhookDelivery, error) {
+	if webhookID != 0 {
+		if _, err := wc.repo.GetWebhookByID(webhookID); err != nil {
+			return nil, fmt.Errorf("%w: %d", ErrWebhookNotFound, webhookID)
+		}
+	}
+	return wc.repo.GetWebhookDeliveries(webhookID, limit)
+}
+
+// DeliverPending attempts the pending deliveries that are due, or all of them when all is true.
+func (wc *WebhookController) DeliverPending(all bool) (*DeliveryResult, error) {
+	return deliverWebhooks(wc.repo, all)
+}
+
+// notifyChange runs the post-hooks of a saved change of a project or task and queues its deliveries to the
+// webhooks, which are attempted once the current transaction is committed.
+func notifyChange(repo *repository.Repository, event, itemType string, before, after any) error {
+	if err := runPostHooks(repo, event, itemType, before, after); err != nil {
+		return err
+	}
+	queued, err := queueWebhooks(repo, event, itemType, before, after)
+	if err != nil || queued == 0 {
+		return err
+	}
+
+	repo.AfterCommit(func(committedRepo *repository.Repository) {
+		result, deliverErr := deliverWebhooks(committedRepo, false)
+		if deliverErr != nil {
+			fmt.Fprintln(webhook.Output, "Warning: error delivering webhooks: "+deliverErr.Error())
+			return
+		}
+		if failed := result.Retrying + result.Failed; failed > 0 {
+			fmt.Fprintf(webhook.Output, "Warning: %d webhook delivery(ies) failed, see 'clido webhooks log'.\n", failed)
+		}
+	})
+	return nil
+}
+
+// queueWebhooks queues a delivery of a change to each webhook watching its event and item, and returns the
+// number of deliveries queued.
+func queueWebhooks(repo *repository.Repository, event, itemType string, before, after any) (int, error) {
+	webhooks, err := repo.GetWebhooks()
+	if err != nil || len(webhooks) == 0 {
+		return 0, err
+	}
+
+	itemID, projectID := changedItem(before, after)
+	body, err := json.Marshal(WebhookPayload{
+		Event:     event,
+		ItemType:  itemType,
+		Workspace: repo.Workspace(),
+		Timestamp: time.Now().UTC(),
+		Before:    before,
+		After:     after,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	queued := 0
+	for _, hook := range webhooks {
+		if hook.ProjectID != nil && *hook.ProjectID != projectID {
+			continue
+		}
+		if hook.Events != "" && !slices.Contains(strings.Split(hook.Events, ","), event) {
+			continue
+		}
+
+		delivery := &models.WebhookDelivery{
+			UUID:        uuid.NewString(),
+			WebhookID:   hook.ID,
+			Event:       event,
+			ItemType:    itemType,
+			ItemID:      itemID,
+			Payload:     string(body),
+			Status:      models.DeliveryPending,
+			NextAttempt: time.Now(),
+		}
+		if err = repo.CreateWebhookDelivery(delivery); err != nil {
+			return queued, err
+		}
+		queued++
+	}
+	return queued, nil
+}
+
+// deliverWebhooks attempts the pending deliveries that are due, or all of them when all is true, one after
+// the other. Each failed attempt delays the next one, until the delivery is given up.
+func deliverWebhooks(repo *repository.Repository, all bool) (*DeliveryResult, error) {
+	due := time.Now()
+	if all {
+		due = time.Time{}
+	}
+	deliveries, err := repo.GetPendingWebhookDeliveries(due)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DeliveryResult{}
+	webhooks := make(map[int]*models.Webhook)
+	for _, delivery := range deliveries {
+		hook, found := webhooks[delivery.WebhookID]
+		if !found {
+			if hook, err = repo.GetWebhookByID(delivery.WebhookID); err != nil {
+				hook = nil // The webhook was removed since
+			}
+			webhooks[delivery.WebhookID] = hook
+		}
+
+		result.Attempted++
+		delivery.Attempts++
+		var postErr error
+		if hook == nil {
+			delivery.Attempts = MaxDeliveryAttempts
+			postErr = ErrWebhookNotFound
+		} else {
+			delivery.ResponseCode, postErr = webhook.Post(
+				hook.URL, hook.Secret, delivery.Event, delivery.UUID, []byte(delivery.Payload),
+			)
+		}
+
+		now := time.Now()
+		switch {
+		case postErr == nil:
+			delivery.Status = models.DeliveryDelivered
+			delivery.LastError = ""
+			delivery.DeliveryDate = &now
+			result.Delivered++
+		case delivery.Attempts >= MaxDeliveryAttempts:
+			delivery.Status = models.DeliveryFailed
+			delivery.LastError = postErr.Error()
+			result.Failed++
+		default:
+			delivery.LastError = postErr.Error()
+			delivery.NextAttempt = now.Add(webhook.Backoff(delivery.Attempts))
+			result.Retrying++
+		}
+
+		if err = repo.UpdateWebhookDelivery(delivery); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// changedItem returns the ID of the project or task of a change, and the ID of the project it belongs to.
+func changedItem(before, after any) (int, int) {
+	item := after
+	if item == nil {
+		item = before
+	}
+
+	switch changed := item.(type) {
+	case *models.Task:
+		return changed.ID, changed.ProjectID
+	case *models.Project:
+		return changed.ID, changed.ID
+	default:
+		return 0, 0
+	}
+}
@@ -0,0 +1,174 @@
+package controllers_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/internal/hooks"
+	"github.com/d4r1us-drk/clido/internal/webhook"
+	"github.com/d4r1us-drk/clido/models"
+)
+
+// webhookReceiver is an endpoint answering with a given status and recording the deliveries it receives.
+type webhookReceiver struct {
+	*httptest.Server
+	mu         sync.Mutex
+	status     int
+	deliveries []string // The delivery IDs received, in order
+	signatures []bool   // Whether each delivery had a valid signature
+}
+
+func newWebhookReceiver(t *testing.T, secret string, status int) *webhookReceiver {
+	t.Helper()
+
+	receiver := &webhookReceiver{status: status}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+		receiver.deliveries = append(receiver.deliveries, r.Header.Get(webhook.HeaderDelivery))
+		receiver.signatures = append(receiver.signatures,
+			r.Header.Get(webhook.HeaderSignature) == webhook.Sign(secret, body))
+		w.WriteHeader(receiver.status)
+	}))
+	t.Cleanup(receiver.Close)
+
+	output := webhook.Output
+	webhook.Output = io.Discard
+	t.Cleanup(func() { webhook.Output = output })
+	return receiver
+}
+
+func (r *webhookReceiver) setStatus(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+// onlyDelivery returns the single delivery queued, failing the test when there is not exactly one.
+func onlyDelivery(t *testing.T, webhookController *controllers.WebhookController) *models.WebhookDelivery {
+	t.Helper()
+
+	deliveries, err := webhookController.ListDeliveries(0, 0)
+	if err != nil || len(deliveries) != 1 {
+		t.Fatalf("ListDeliveries() = %d deliveries, %v, want 1", len(deliveries), err)
+	}
+	return deliveries[0]
+}
+
+func TestWebhookDeliveryBackoff(t *testing.T) {
+	repo := newTestRepository(t)
+	webhookController := controllers.NewWebhookController(repo)
+	receiver := newWebhookReceiver(t, "secret", http.StatusInternalServerError)
+
+	if _, err := webhookController.AddWebhook(receiver.URL, "secret", "", nil); err != nil {
+		t.Fatalf("AddWebhook() error = %v", err)
+	}
+
+	// The change is queued and attempted once saved
+	before := time.Now()
+	if _, err := controllers.NewProjectController(repo).CreateProject("Inbox", "", ""); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+
+	for attempts := 1; attempts <= controllers.MaxDeliveryAttempts; attempts++ {
+		if attempts > 1 {
+			// Retries are not due before their next attempt
+			if result, err := webhookController.DeliverPending(false); err != nil || result.Attempted != 0 {
+				t.Fatalf("DeliverPending(false) = %+v, %v, want nothing attempted before it is due", result, err)
+			}
+
+			before = time.Now()
+			result, err := webhookController.DeliverPending(true)
+			if err != nil || result.Attempted != 1 {
+				t.Fatalf("DeliverPending(true) = %+v, %v, want 1 attempt", result, err)
+			}
+		}
+		after := time.Now()
+
+		delivery := onlyDelivery(t, webhookController)
+		if delivery.Attempts != attempts || delivery.ResponseCode != http.StatusInternalServerError {
+			t.Fatalf("attempt %d: delivery has %d attempts and status %d", attempts, delivery.Attempts,
+				delivery.ResponseCode)
+		}
+		if attempts == controllers.MaxDeliveryAttempts {
+			if delivery.Status != models.DeliveryFailed {
+				t.Errorf("attempt %d: delivery is %s, want %s", attempts, delivery.Status, models.DeliveryFailed)
+			}
+			break
+		}
+
+		backoff := webhook.Backoff(attempts)
+		if delivery.Status != models.DeliveryPending ||
+			delivery.NextAttempt.Before(before.Add(backoff)) || delivery.NextAttempt.After(after.Add(backoff)) {
+			t.Errorf("attempt %d: delivery is %s until %v, want pending for %v", attempts, delivery.Status,
+				delivery.NextAttempt, backoff)
+		}
+	}
+
+	// A failed delivery is given up
+	if result, err := webhookController.DeliverPending(true); err != nil || result.Attempted != 0 {
+		t.Errorf("DeliverPending(true) = %+v, %v, want nothing attempted after giving up", result, err)
+	}
+
+	delivery := onlyDelivery(t, webhookController)
+	if len(receiver.deliveries) != controllers.MaxDeliveryAttempts {
+		t.Fatalf("endpoint received %d attempts, want %d", len(receiver.deliveries), controllers.MaxDeliveryAttempts)
+	}
+	for i, id := range receiver.deliveries {
+		if id != delivery.UUID || !receiver.signatures[i] {
+			t.Errorf("attempt %d: delivery ID %q, valid signature %v, want %q signed", i+1, id,
+				receiver.signatures[i], delivery.UUID)
+		}
+	}
+}
+
+func TestWebhookDeliveryRetrySucceeds(t *testing.T) {
+	repo := newTestRepository(t)
+	webhookController := controllers.NewWebhookController(repo)
+	receiver := newWebhookReceiver(t, "secret", http.StatusBadGateway)
+
+	if _, err := webhookController.AddWebhook(receiver.URL, "secret", "", []string{hooks.OnCreate}); err != nil {
+		t.Fatalf("AddWebhook() error = %v", err)
+	}
+	if _, err := controllers.NewProjectController(repo).CreateProject("Inbox", "", ""); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+
+	receiver.setStatus(http.StatusOK)
+	result, err := webhookController.DeliverPending(true)
+	if err != nil || result.Delivered != 1 {
+		t.Fatalf("DeliverPending(true) = %+v, %v, want 1 delivered", result, err)
+	}
+
+	delivery := onlyDelivery(t, webhookController)
+	if delivery.Status != models.DeliveryDelivered || delivery.Attempts != 2 || delivery.LastError != "" ||
+		delivery.DeliveryDate == nil {
+		t.Errorf("delivery = %+v, want delivered at the second attempt", delivery)
+	}
+	if len(receiver.deliveries) != 2 || receiver.deliveries[0] != receiver.deliveries[1] {
+		t.Errorf("endpoint received delivery IDs %v, want the same ID twice", receiver.deliveries)
+	}
+}
+
+func TestAddWebhookGeneratesSecrets(t *testing.T) {
+	webhookController := controllers.NewWebhookController(newTestRepository(t))
+
+	first, err := webhookController.AddWebhook("https://example.com/hook", "", "", nil)
+	if err != nil {
+		t.Fatalf("AddWebhook() error = %v", err)
+	}
+	second, _ := webhookController.AddWebhook("https://example.com/hook", "", "", nil)
+	if len(first.Secret) != 64 || first.Secret == second.Secret {
+		t.Errorf("AddWebhook() secrets = %q and %q, want distinct 256-bit secrets", first.Secret, second.Secret)
+	}
+
+	if _, err = webhookController.AddWebhook("ftp://example.com", "", "", nil); err == nil {
+		t.Error("AddWebhook() accepted a non-HTTP URL")
+	}
+}
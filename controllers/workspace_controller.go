@@ -0,0 +1,279 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"github.com/d4r1us-drk/clido/models"
+	"github.com/d4r1us-drk/clido/repository"
+	"github.com/d4r1us-drk/clido/utils"
+)
+
+// Error constants for workspace operations.
+var (
+	ErrSameWorkspace     = errcode.New(errcode.Validation, "the target workspace is the workspace in use")
+	ErrWorkspaceNotEmpty = errcode.New(errcode.Conflict, "workspace is not empty")
+)
+
+// WorkspaceController manages workspaces, each with its own database and configuration,
+// and moves items between them.
+type WorkspaceController struct {
+	repo *repository.Repository
+}
+
+// NewWorkspaceController creates and returns a new instance of WorkspaceController.
+func NewWorkspaceController(repo *repository.Repository) *WorkspaceController {
+	return &WorkspaceController{repo: repo}
+}
+
+// ActiveWorkspace returns the name of the workspace in use.
+func (wc *WorkspaceController) ActiveWorkspace() string {
+	return wc.repo.Workspace()
+}
+
+// ListWorkspaces returns the names of all workspaces, starting with the default workspace.
+func (wc *WorkspaceController) ListWorkspaces() ([]string, error) {
+	return repository.ListWorkspaces()
+}
+
+// CreateWorkspace creates a new workspace with an empty database.
+func (wc *WorkspaceController) CreateWorkspace(name string) error {
+	if err := repository.ValidateWorkspaceName(name); err != nil {
+		return err
+	}
+	return repository.CreateWorkspace(name)
+}
+
+// UseWorkspace makes a workspace the current one, used when no workspace is given explicitly.
+func (wc *WorkspaceController) UseWorkspace(name string) error {
+	return repository.SetCurrentWorkspace(name)
+}
+
+// RemoveWorkspace deletes a workspace. Workspaces holding projects or tasks are only removed when
+// force is set, and neither the default workspace nor the workspace in use can be removed.
+func (wc *WorkspaceController) RemoveWorkspace(name string, force bool) error {
+	if name == repository.DefaultWorkspace {
+		return repository.ErrDefaultWorkspace
+	}
+	if name == wc.repo.Workspace() {
+		return repository.ErrCurrentWorkspace
+	}
+
+	if !force {
+		repo, err := repository.NewRepository(name)
+		if err != nil {
+			return err
+		}
+		projects, projectsErr := repo.GetAllProjects()
+		tasks, tasksErr := repo.GetAllTasks()
+		repo.Close()
+		if projectsErr != nil || tasksErr != nil {
+			return errors.Join(projectsErr, tasksErr)
+		}
+		if len(projects) > 0 || len(tasks) > 0 {
+			return fmt.Errorf("%w: '%s' holds %d project(s) and %d task(s)",
+				ErrWorkspaceNotEmpty, name, len(projects), len(tasks))
+		}
+	}
+
+	return repository.RemoveWorkspace(name)
+}
+
+// MoveTaskToWorkspace moves a task, together with all its subtasks, to a project of another workspace.
+// The target project is identified by name or ID in the target workspace, and defaults to a project with
+// the same name as the task's project; it is created when no project has that name. The moved tasks keep
+// their UUIDs but get new IDs, and are removed from the workspace in use like removed tasks, running their
+// hooks and webhooks.
+func (wc *WorkspaceController) MoveTaskToWorkspace(
+	id int,
+	workspace, projectIdentifier string,
+) (*ImportResult, error) {
+	task, getTaskErr := wc.repo.GetTaskByID(id)
+	if getTaskErr != nil {
+		return nil, ErrTaskNotFound
+	}
+	project, getProjectErr := wc.repo.GetProjectByID(task.ProjectID)
+	if getProjectErr != nil {
+		return nil, ErrNoProjectFound
+	}
+
+	subtasks, err := NewTaskController(wc.repo).ListTaskSubtree(id)
+	if err != nil {
+		return nil, err
+	}
+	moved := append([]*models.Task{task}, subtasks...)
+
+	// The task is detached from its parent task, as the parent stays behind, and so is the project, which
+	// is only copied
+	targetProject := toExportProject(project)
+	targetProject.UUID = ""
+	targetProject.ParentID = nil
+	export := &models.Export{SchemaVersion: models.ExportSchemaVersion, ExportedAt: time.Now()}
+	export.Projects = []*models.ExportProject{targetProject}
+	for _, movedTask := range moved {
+		exported := toExportTask(movedTask)
+		if movedTask.ID == id {
+			exported.ParentID = nil
+		}
+		export.Tasks = append(export.Tasks, exported)
+	}
+
+	// Subtasks are removed with the task
+	removeMoved := func(txRepo *repository.Repository) error {
+		_, removeErr := NewTaskController(txRepo).RemoveTask(id)
+		return removeErr
+	}
+
+	return wc.transfer(workspace, export, func(targetRepo *repository.Repository) error {
+		if projectIdentifier == "" {
+			return nil
+		}
+		// Resolve the target project in the target workspace, by ID, name or UUID prefix; other names
+		// are given to a new project
+		existing, projectErr := findProject(targetRepo, projectIdentifier, ErrNoProjectFound)
+		_, parseErr := utils.ParseIntOrError(projectIdentifier)
+		switch {
+		case projectErr == nil:
+			targetProject.Name = existing.Name
+		case parseErr == nil || errors.Is(projectErr, ErrAmbiguousUUIDPrefix):
+			return projectErr
+		default:
+			targetProject.Name = projectIdentifier
+		}
+		return nil
+	}, ImportConflictMerge, removeMoved)
+}
+
+// MoveProjectToWorkspace moves a project, together with all its subprojects and their tasks, to the top
+// level of another workspace. It fails if a project with the same name exists in the target workspace.
+// The moved items keep their UUIDs but get new IDs, and are removed from the workspace in use like removed
+// projects and tasks, running their hooks and webhooks.
+func (wc *WorkspaceController) MoveProjectToWorkspace(id int, workspace string) (*ImportResult, error) {
+	project, getProjectErr := wc.repo.GetProjectByID(id)
+	if getProjectErr != nil {
+		return nil, ErrNoProjectFound
+	}
+
+	subprojects, err := NewProjectController(wc.repo).ListProjectSubtree(id)
+	if err != nil {
+		return nil, err
+	}
+	projects := append([]*models.Project{project}, subprojects...)
+
+	export := &models.Export{SchemaVersion: models.ExportSchemaVersion, ExportedAt: time.Now()}
+	var tasks []*models.Task
+	for _, movedProject := range projects {
+		exported := toExportProject(movedProject)
+		if movedProject.ID == id {
+			exported.ParentID = nil
+		}
+		export.Projects = append(export.Projects, exported)
+
+		projectTasks, tasksErr := wc.repo.GetTasksByProjectID(movedProject.ID)
+		if tasksErr != nil {
+			return nil, tasksErr
+		}
+		for _, task := range projectTasks {
+			export.Tasks = append(export.Tasks, toExportTask(task))
+		}
+		tasks = append(tasks, projectTasks...)
+	}
+
+	// Subtasks are removed with their parent task, and subprojects with the project
+	removeMoved := func(txRepo *repository.Repository) error {
+		taskController := NewTaskController(txRepo)
+		removed := make(map[int]bool)
+		for _, task := range tasks {
+			if removed[task.ID] {
+				continue
+			}
+			removedIDs, removeErr := taskController.RemoveTask(task.ID)
+			if removeErr != nil {
+				return removeErr
+			}
+			for _, removedID := range removedIDs {
+				removed[removedID] = true
+			}
+		}
+		_, removeErr := NewProjectController(txRepo).RemoveProject(id)
+		return removeErr
+	}
+
+	return wc.transfer(workspace, export, nil, ImportConflictFail, removeMoved)
+}
+
+// transfer removes the exported items from the workspace in use with removeSource and imports the export
+// into another workspace, keeping the UUIDs of the items, in a transaction of the workspace in use: items
+// whose removal is rejected, e.g. by a hook, are not moved. If that transaction cannot be committed, the
+// imported tasks (and projects, unless they were merged into existing ones) are removed from the target
+// workspace again. The prepare function, if any, can adjust the export using the target workspace before
+// it is imported.
+func (wc *WorkspaceController) transfer(
+	workspace string,
+	export *models.Export,
+	prepare func(targetRepo *repository.Repository) error,
+	conflict string,
+	removeSource func(txRepo *repository.Repository) error,
+) (*ImportResult, error) {
+	if workspace == wc.repo.Workspace() {
+		return nil, ErrSameWorkspace
+	}
+	if !repository.WorkspaceExists(workspace) {
+		return nil, fmt.Errorf("%w: '%s'", repository.ErrWorkspaceNotFound, workspace)
+	}
+
+	targetRepo, err := repository.NewRepository(workspace)
+	if err != nil {
+		return nil, err
+	}
+	defer targetRepo.Close()
+
+	if prepare != nil {
+		if prepareErr := prepare(targetRepo); prepareErr != nil {
+			return nil, prepareErr
+		}
+	}
+
+	var result *ImportResult
+	err = wc.repo.Transaction(func(txRepo *repository.Repository) error {
+		if removeErr := removeSource(txRepo); removeErr != nil {
+			return removeErr
+		}
+		var importErr error
+		result, importErr = NewExportController(targetRepo).importExport(export, conflict, true)
+		return importErr
+	})
+	if err == nil || result == nil {
+		return result, err
+	}
+
+	// Undo the import so the items are not duplicated across workspaces
+	undoErr := targetRepo.Transaction(func(txRepo *repository.Repository) error {
+		taskController := NewTaskController(txRepo)
+		for _, taskID := range result.TaskIDs {
+			// Subtasks may already be removed with their parent
+			if _, getErr := txRepo.GetTaskByID(taskID); getErr != nil {
+				continue
+			}
+			if _, removeErr := taskController.RemoveTask(taskID); removeErr != nil {
+				return removeErr
+			}
+		}
+		if conflict == ImportConflictMerge {
+			return nil
+		}
+		projectController := NewProjectController(txRepo)
+		for _, projectID := range result.ProjectIDs {
+			if _, getErr := txRepo.GetProjectByID(projectID); getErr != nil {
+				continue
+			}
+			if _, removeErr := projectController.RemoveProject(projectID); removeErr != nil {
+				return removeErr
+			}
+		}
+		return nil
+	})
+	return nil, errors.Join(err, undoErr)
+}
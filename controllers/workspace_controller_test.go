@@ -0,0 +1,122 @@
+package controllers_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/models"
+	"github.com/d4r1us-drk/clido/repository"
+	"github.com/d4r1us-drk/clido/utils"
+)
+
+// newTestWorkspace creates a workspace next to the workspace of repo and opens it.
+func newTestWorkspace(t *testing.T, repo *repository.Repository, name string) *repository.Repository {
+	t.Helper()
+
+	if err := controllers.NewWorkspaceController(repo).CreateWorkspace(name); err != nil {
+		t.Fatalf("CreateWorkspace() error = %v", err)
+	}
+	workspaceRepo, err := repository.NewRepository(name)
+	if err != nil {
+		t.Fatalf("NewRepository() error = %v", err)
+	}
+	t.Cleanup(func() { workspaceRepo.Close() })
+	return workspaceRepo
+}
+
+func TestMoveTaskToWorkspaceKeepsUUIDs(t *testing.T) {
+	repo := newTestRepository(t)
+	taskController := controllers.NewTaskController(repo)
+	if _, err := controllers.NewProjectController(repo).CreateProject("Work", "", ""); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+	task, err := taskController.CreateTask("Report", "", "Work", "", "", utils.PriorityNone, nil)
+	if err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+	subtask, err := taskController.CreateTask("Figures", "", "Work", "1", "", utils.PriorityNone, nil)
+	if err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+	target := newTestWorkspace(t, repo, "other")
+
+	result, err := controllers.NewWorkspaceController(repo).MoveTaskToWorkspace(task.ID, "other", "")
+	if err != nil {
+		t.Fatalf("MoveTaskToWorkspace() error = %v", err)
+	}
+
+	for _, moved := range []*models.Task{task, subtask} {
+		copied, getErr := target.GetTaskByUUID(moved.UUID)
+		if getErr != nil || copied.ID != result.TaskIDs[moved.ID] || copied.Name != moved.Name {
+			t.Errorf("task '%s' in the target workspace = %+v, %v, want its UUID kept", moved.Name, copied, getErr)
+		}
+	}
+
+	// The tasks are removed like removed tasks, recording their deletion
+	if tasks, _ := repo.GetAllTasks(); len(tasks) != 0 {
+		t.Errorf("the workspace in use still holds %d task(s)", len(tasks))
+	}
+	operations, err := repo.GetChangeLog()
+	if err != nil {
+		t.Fatalf("GetChangeLog() error = %v", err)
+	}
+	deleted := make(map[string]bool)
+	for _, operation := range operations {
+		if operation.Field == models.ChangeFieldDeleted && operation.Value == "true" {
+			deleted[operation.ItemKey] = true
+		}
+	}
+	if !deleted[task.UUID] || !deleted[subtask.UUID] {
+		t.Errorf("change log deletions = %v, want both moved tasks", deleted)
+	}
+
+	// The project stays behind, so its copy has another UUID
+	project, _ := repo.GetProjectByName("Work")
+	copied, _ := target.GetProjectByName("Work")
+	if project == nil || copied == nil || copied.UUID == project.UUID {
+		t.Errorf("projects = %+v and %+v, want a copy with its own UUID", project, copied)
+	}
+}
+
+func TestMoveProjectToWorkspaceRunsRemoveHooks(t *testing.T) {
+	repo := newTestRepository(t)
+	project, err := controllers.NewProjectController(repo).CreateProject("Work", "", "")
+	if err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+	target := newTestWorkspace(t, repo, "other")
+
+	// A pre-hook rejecting removals also rejects moves
+	if err = os.MkdirAll(repo.HooksDir(), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	hook := filepath.Join(repo.HooksDir(), "pre-on-remove")
+	if err = os.WriteFile(hook, []byte("#!/bin/sh\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	workspaceController := controllers.NewWorkspaceController(repo)
+	_, err = workspaceController.MoveProjectToWorkspace(project.ID, "other")
+	if !errors.Is(err, controllers.ErrHookRejected) {
+		t.Fatalf("MoveProjectToWorkspace() error = %v, want %v", err, controllers.ErrHookRejected)
+	}
+	if projects, _ := target.GetAllProjects(); len(projects) != 0 {
+		t.Errorf("the target workspace holds %d project(s) after a rejected move", len(projects))
+	}
+
+	if err = os.Remove(hook); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = workspaceController.MoveProjectToWorkspace(project.ID, "other"); err != nil {
+		t.Fatalf("MoveProjectToWorkspace() error = %v", err)
+	}
+	if moved, getErr := target.GetProjectByName("Work"); getErr != nil || moved == nil || moved.UUID != project.UUID {
+		t.Errorf("project in the target workspace = %+v, %v, want its UUID kept", moved, getErr)
+	}
+	if _, getErr := repo.GetProjectByID(project.ID); getErr == nil {
+		t.Error("the project is still in the workspace in use")
+	}
+}
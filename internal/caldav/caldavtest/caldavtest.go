@@ -0,0 +1,449 @@
+// Package caldavtest provides an in-memory CalDAV server for tests, holding the calendars of a single
+// calendar home collection and their objects, with ETags changing on every write.
+package caldavtest
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// HomePath is the path of the calendar home collection of the server.
+const HomePath = "/calendars/user/"
+
+// Server is a CalDAV server answering the PROPFIND, REPORT, MKCALENDAR, PROPPATCH, GET, PUT and DELETE
+// requests of the calendar home and its calendars. Its methods change the calendars directly, like
+// another client of the server would.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	calendars map[string]*calendar // By href
+	objects   map[string]*object   // By href
+	requests  map[string]int       // The number of requests received, by method
+	etags     int                  // The last ETag given
+}
+
+// calendar is a calendar collection of the server.
+type calendar struct {
+	displayName string
+	components  []string // The components accepted, all of them if empty
+}
+
+// object is a calendar object resource of the server.
+type object struct {
+	data string
+	etag string
+}
+
+// NewServer starts and returns a new server with an empty calendar home. The caller should call Close
+// when finished, to shut it down.
+func NewServer() *Server {
+	s := &Server{
+		calendars: make(map[string]*calendar),
+		objects:   make(map[string]*object),
+		requests:  make(map[string]int),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// HomeURL returns the URL of the calendar home collection.
+func (s *Server) HomeURL() string {
+	return s.URL + HomePath
+}
+
+// AddCalendar creates a calendar accepting the given components, or all of them if none are given, and
+// returns its href.
+func (s *Server) AddCalendar(segment, displayName string, components ...string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	href := HomePath + segment + "/"
+	s.calendars[href] = &calendar{displayName: displayName, components: components}
+	return href
+}
+
+// Calendars returns the display names of the calendars, by href.
+func (s *Server) Calendars() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make(map[string]string)
+	for href, calendar := range s.calendars {
+		names[href] = calendar.displayName
+	}
+	return names
+}
+
+// Objects returns the hrefs of the objects of a calendar, sorted.
+func (s *Server) Objects(calendarHref string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var hrefs []string
+	for href := range s.objects {
+		if path.Dir(href)+"/" == calendarHref {
+			hrefs = append(hrefs, href)
+		}
+	}
+	sort.Strings(hrefs)
+	return hrefs
+}
+
+// Object returns the data and ETag of an object, and whether it exists.
+func (s *Server) Object(href string) (string, string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, exists := s.objects[href]
+	if !exists {
+		return "", "", false
+	}
+	return stored.data, stored.etag, true
+}
+
+// PutObject creates or replaces an object of an existing calendar and returns its new ETag.
+func (s *Server) PutObject(href, data string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.store(href, data)
+}
+
+// Delete removes an object, or a calendar with its objects.
+func (s *Server) Delete(href string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(href)
+}
+
+// Requests returns the number of requests received with a method.
+func (s *Server) Requests(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests[method]
+}
+
+// serveHTTP answers a request of a client.
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[r.Method]++
+
+	switch r.Method {
+	case "PROPFIND":
+		s.propfind(w, r)
+	case "REPORT":
+		s.report(w, r, body)
+	case "MKCALENDAR":
+		s.mkcalendar(w, r, body)
+	case "PROPPATCH":
+		s.proppatch(w, r, body)
+	case http.MethodGet:
+		s.get(w, r)
+	case http.MethodPut:
+		s.put(w, r, body)
+	case http.MethodDelete:
+		s.delete(w, r)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// propfind lists the properties of a collection and, at depth 1, of its members, whatever properties
+// were asked for.
+func (s *Server) propfind(w http.ResponseWriter, r *http.Request) {
+	href := r.URL.Path
+	depth := r.Header.Get("Depth")
+	var responses []string
+
+	switch {
+	case href == HomePath:
+		responses = append(responses, davResponse(href, "<d:resourcetype><d:collection/></d:resourcetype>"))
+		if depth != "0" {
+			for _, calendarHref := range sortedKeys(s.calendars) {
+				responses = append(responses, s.calendarResponse(calendarHref))
+			}
+		}
+
+	case s.calendars[href] != nil:
+		responses = append(responses, s.calendarResponse(href))
+		if depth != "0" {
+			for _, objectHref := range s.calendarObjects(href) {
+				responses = append(responses, s.objectResponse(objectHref, false))
+			}
+		}
+
+	case s.objects[href] != nil:
+		responses = append(responses, s.objectResponse(href, false))
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	writeMultistatus(w, responses)
+}
+
+// report answers calendar-query reports with every object of a calendar, and calendar-multiget reports
+// with the objects whose hrefs are given, with their ETags and data.
+func (s *Server) report(w http.ResponseWriter, r *http.Request, body []byte) {
+	calendarHref := r.URL.Path
+	if s.calendars[calendarHref] == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var request struct {
+		XMLName xml.Name
+		Hrefs   []string `xml:"DAV: href"`
+	}
+	if err := xml.Unmarshal(body, &request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var responses []string
+	switch request.XMLName.Local {
+	case "calendar-query":
+		for _, href := range s.calendarObjects(calendarHref) {
+			responses = append(responses, s.objectResponse(href, true))
+		}
+	case "calendar-multiget":
+		for _, href := range request.Hrefs {
+			if s.objects[href] == nil {
+				responses = append(responses, "<d:response><d:href>"+escape(href)+
+					"</d:href><d:status>HTTP/1.1 404 Not Found</d:status></d:response>")
+				continue
+			}
+			responses = append(responses, s.objectResponse(href, true))
+		}
+	default:
+		http.Error(w, "unsupported report", http.StatusBadRequest)
+		return
+	}
+
+	writeMultistatus(w, responses)
+}
+
+// mkcalendar creates a calendar directly under the calendar home.
+func (s *Server) mkcalendar(w http.ResponseWriter, r *http.Request, body []byte) {
+	href := r.URL.Path
+	if !strings.HasSuffix(href, "/") {
+		href += "/"
+	}
+	switch {
+	case s.calendars[href] != nil:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	case path.Dir(strings.TrimSuffix(href, "/"))+"/" != HomePath:
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	var request struct {
+		DisplayName string `xml:"set>prop>displayname"`
+		Components  []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"set>prop>supported-calendar-component-set>comp"`
+	}
+	if err := xml.Unmarshal(body, &request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	created := &calendar{displayName: request.DisplayName}
+	for _, component := range request.Components {
+		created.components = append(created.components, component.Name)
+	}
+	s.calendars[href] = created
+	w.WriteHeader(http.StatusCreated)
+}
+
+// proppatch sets the display name of a calendar.
+func (s *Server) proppatch(w http.ResponseWriter, r *http.Request, body []byte) {
+	href := r.URL.Path
+	updated := s.calendars[href]
+	if updated == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var request struct {
+		DisplayName string `xml:"set>prop>displayname"`
+	}
+	if err := xml.Unmarshal(body, &request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	updated.displayName = request.DisplayName
+
+	writeMultistatus(w, []string{davResponse(href, "<d:displayname/>")})
+}
+
+// get returns the data of an object, with its ETag.
+func (s *Server) get(w http.ResponseWriter, r *http.Request) {
+	stored := s.objects[r.URL.Path]
+	if stored == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("ETag", stored.etag)
+	_, _ = io.WriteString(w, stored.data)
+}
+
+// put creates or replaces an object of a calendar, honouring the If-Match and If-None-Match preconditions.
+func (s *Server) put(w http.ResponseWriter, r *http.Request, body []byte) {
+	href := r.URL.Path
+	if s.calendars[path.Dir(href)+"/"] == nil {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	existing := s.objects[href]
+	if !preconditionsMet(r, existing) {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+
+	w.Header().Set("ETag", s.store(href, string(body)))
+	if existing != nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// delete removes an object, or a calendar with its objects, honouring the If-Match precondition.
+func (s *Server) delete(w http.ResponseWriter, r *http.Request) {
+	href := r.URL.Path
+	existing := s.objects[href]
+	if existing == nil && s.calendars[href] == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if existing != nil && !preconditionsMet(r, existing) {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+
+	s.remove(href)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// store saves the data of an object under a new ETag, which it returns.
+func (s *Server) store(href, data string) string {
+	s.etags++
+	etag := `"` + strconv.Itoa(s.etags) + `"`
+	s.objects[href] = &object{data: data, etag: etag}
+	return etag
+}
+
+// remove deletes an object, or a calendar with its objects.
+func (s *Server) remove(href string) {
+	if s.calendars[href] != nil {
+		for _, objectHref := range s.calendarObjects(href) {
+			delete(s.objects, objectHref)
+		}
+		delete(s.calendars, href)
+		return
+	}
+	delete(s.objects, href)
+}
+
+// calendarObjects returns the hrefs of the objects of a calendar, sorted.
+func (s *Server) calendarObjects(calendarHref string) []string {
+	var hrefs []string
+	for _, href := range sortedKeys(s.objects) {
+		if path.Dir(href)+"/" == calendarHref {
+			hrefs = append(hrefs, href)
+		}
+	}
+	return hrefs
+}
+
+// calendarResponse returns the multi-status response describing a calendar.
+func (s *Server) calendarResponse(href string) string {
+	described := s.calendars[href]
+	props := "<d:resourcetype><d:collection/><c:calendar/></d:resourcetype>" +
+		"<d:displayname>" + escape(described.displayName) + "</d:displayname>"
+	if len(described.components) > 0 {
+		props += "<c:supported-calendar-component-set>"
+		for _, component := range described.components {
+			props += `<c:comp name="` + escape(component) + `"/>`
+		}
+		props += "</c:supported-calendar-component-set>"
+	}
+	return davResponse(href, props)
+}
+
+// objectResponse returns the multi-status response describing an object, with its data if withData is set.
+func (s *Server) objectResponse(href string, withData bool) string {
+	stored := s.objects[href]
+	props := "<d:resourcetype/><d:getetag>" + escape(stored.etag) + "</d:getetag>"
+	if withData {
+		props += "<c:calendar-data>" + escape(stored.data) + "</c:calendar-data>"
+	}
+	return davResponse(href, props)
+}
+
+// preconditionsMet reports whether the If-Match and If-None-Match headers of a request hold for a
+// resource, which is nil if it does not exist.
+func preconditionsMet(r *http.Request, existing *object) bool {
+	if match := r.Header.Get("If-Match"); match != "" {
+		return existing != nil && (match == "*" || match == existing.etag)
+	}
+	if noneMatch := r.Header.Get("If-None-Match"); noneMatch != "" {
+		return existing == nil || (noneMatch != "*" && noneMatch != existing.etag)
+	}
+	return true
+}
+
+// davResponse returns a multi-status response holding properties found for a resource.
+func davResponse(href, props string) string {
+	return "<d:response><d:href>" + escape(href) + "</d:href><d:propstat><d:prop>" + props +
+		"</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>"
+}
+
+// writeMultistatus writes a multi-status response body.
+func writeMultistatus(w http.ResponseWriter, responses []string) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	_, _ = io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?>`+
+		`<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">`+
+		strings.Join(responses, "")+"</d:multistatus>")
+}
+
+// escape escapes text for use in an XML element or attribute.
+func escape(text string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(text))
+	return buf.String()
+}
+
+// sortedKeys returns the keys of a map, sorted.
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,360 @@
+package caldav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// Errors returned by the CalDAV client.
+var (
+	ErrNotFound           = errors.New("resource not found")
+	ErrPreconditionFailed = errors.New("resource changed on the server")
+	ErrUnexpectedStatus   = errors.New("unexpected server response")
+)
+
+// Calendar is a calendar collection of the server.
+type Calendar struct {
+	Href         string // The absolute path of the collection, ending with a slash
+	DisplayName  string
+	SupportsToDo bool // Whether the calendar accepts VTODO components
+}
+
+// Object is a calendar object resource of a collection.
+type Object struct {
+	Href string // The absolute path of the resource
+	ETag string
+	Data string // The iCalendar data, only set when the object was fetched
+}
+
+// Client talks to a CalDAV server using the WebDAV and CalDAV methods needed to sync tasks.
+type Client struct {
+	home     *url.URL // The calendar home collection, holding the user's calendars
+	username string
+	password string
+	http     *http.Client
+}
+
+// NewClient returns a client for the calendars under the given calendar home collection URL,
+// authenticating with HTTP basic authentication when a username is given.
+func NewClient(homeURL, username, password string) (*Client, error) {
+	home, err := url.Parse(homeURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CalDAV URL: %w", err)
+	}
+	if home.Scheme != "http" && home.Scheme != "https" {
+		return nil, fmt.Errorf("invalid CalDAV URL '%s': the scheme must be http or https", homeURL)
+	}
+	if !strings.HasSuffix(home.Path, "/") {
+		home.Path += "/"
+	}
+
+	return &Client{
+		home:     home,
+		username: username,
+		password: password,
+		http:     &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// HomePath returns the path of the calendar home collection.
+func (c *Client) HomePath() string {
+	return c.home.Path
+}
+
+// ListCalendars returns the calendar collections directly under the calendar home.
+func (c *Client) ListCalendars() ([]*Calendar, error) {
+	body := `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:resourcetype/><d:displayname/><c:supported-calendar-component-set/></d:prop>
+</d:propfind>`
+
+	responses, err := c.propfind(c.home.Path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var calendars []*Calendar
+	for _, response := range responses {
+		prop := response.prop()
+		if prop == nil || prop.ResourceType.Calendar == nil {
+			continue
+		}
+		calendar := &Calendar{Href: response.Href, DisplayName: prop.DisplayName, SupportsToDo: true}
+		if components := prop.ComponentSet.Components; len(components) > 0 {
+			calendar.SupportsToDo = false
+			for _, component := range components {
+				if strings.EqualFold(component.Name, "VTODO") {
+					calendar.SupportsToDo = true
+				}
+			}
+		}
+		calendars = append(calendars, calendar)
+	}
+	return calendars, nil
+}
+
+// CreateCalendar creates a calendar collection for tasks under the calendar home, named after the given
+// segment, and returns its path.
+func (c *Client) CreateCalendar(segment, displayName string) (string, error) {
+	href := path.Join(c.home.Path, segment) + "/"
+	body := `<?xml version="1.0" encoding="utf-8"?>
+<c:mkcalendar xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:set><d:prop>
+    <d:displayname>` + xmlEscape(displayName) + `</d:displayname>
+    <c:supported-calendar-component-set><c:comp name="VTODO"/></c:supported-calendar-component-set>
+  </d:prop></d:set>
+</c:mkcalendar>`
+
+	response, err := c.do("MKCALENDAR", href, body, nil)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusCreated {
+		return "", statusError("MKCALENDAR", href, response)
+	}
+	return href, nil
+}
+
+// RenameCalendar sets the display name of a calendar collection.
+func (c *Client) RenameCalendar(href, displayName string) error {
+	body := `<?xml version="1.0" encoding="utf-8"?>
+<d:propertyupdate xmlns:d="DAV:">
+  <d:set><d:prop><d:displayname>` + xmlEscape(displayName) + `</d:displayname></d:prop></d:set>
+</d:propertyupdate>`
+
+	response, err := c.do("PROPPATCH", href, body, nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusMultiStatus && response.StatusCode != http.StatusOK {
+		return statusError("PROPPATCH", href, response)
+	}
+	return nil
+}
+
+// ListObjects returns the calendar objects of a collection with their ETags, without their data.
+func (c *Client) ListObjects(calendarHref string) ([]*Object, error) {
+	body := `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:"><d:prop><d:resourcetype/><d:getetag/></d:prop></d:propfind>`
+
+	responses, err := c.propfind(calendarHref, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []*Object
+	for _, response := range responses {
+		prop := response.prop()
+		if prop == nil || prop.ResourceType.Collection != nil || strings.HasSuffix(response.Href, "/") {
+			continue
+		}
+		objects = append(objects, &Object{Href: response.Href, ETag: prop.ETag})
+	}
+	return objects, nil
+}
+
+// GetObject fetches a calendar object with its data and ETag.
+func (c *Client) GetObject(href string) (*Object, error) {
+	response, err := c.do(http.MethodGet, href, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusGone:
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, href)
+	default:
+		return nil, statusError("GET", href, response)
+	}
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &Object{Href: href, ETag: response.Header.Get("ETag"), Data: string(data)}, nil
+}
+
+// PutObject stores a calendar object and returns its new ETag, which is empty when the server does
+// not return one. With an ETag, the object is only replaced if it did not change on the server; without
+// one, it is only created if it does not exist yet.
+func (c *Client) PutObject(href, data, etag string) (string, error) {
+	headers := map[string]string{"Content-Type": "text/calendar; charset=utf-8"}
+	if etag != "" {
+		headers["If-Match"] = etag
+	} else {
+		headers["If-None-Match"] = "*"
+	}
+
+	response, err := c.do(http.MethodPut, href, data, headers)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return response.Header.Get("ETag"), nil
+	case http.StatusPreconditionFailed:
+		return "", fmt.Errorf("%w: %s", ErrPreconditionFailed, href)
+	default:
+		return "", statusError("PUT", href, response)
+	}
+}
+
+// Delete removes a calendar object or collection. With an ETag, it is only removed if it did not change
+// on the server. Resources that are already gone are not reported as errors.
+func (c *Client) Delete(href, etag string) error {
+	var headers map[string]string
+	if etag != "" {
+		headers = map[string]string{"If-Match": etag}
+	}
+
+	response, err := c.do(http.MethodDelete, href, "", headers)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound, http.StatusGone:
+		return nil
+	case http.StatusPreconditionFailed:
+		return fmt.Errorf("%w: %s", ErrPreconditionFailed, href)
+	default:
+		return statusError("DELETE", href, response)
+	}
+}
+
+// multistatus is the body of a WebDAV multi-status response.
+type multistatus struct {
+	Responses []davResponse `xml:"DAV: response"`
+}
+
+// davResponse is the status of one resource in a multi-status response.
+type davResponse struct {
+	Href      string        `xml:"DAV: href"`
+	PropStats []davPropStat `xml:"DAV: propstat"`
+}
+
+// davPropStat holds properties sharing the same status.
+type davPropStat struct {
+	Status string  `xml:"DAV: status"`
+	Prop   davProp `xml:"DAV: prop"`
+}
+
+// davProp holds the properties requested by the client.
+type davProp struct {
+	ResourceType struct {
+		Collection *struct{} `xml:"DAV: collection"`
+		Calendar   *struct{} `xml:"urn:ietf:params:xml:ns:caldav calendar"`
+	} `xml:"DAV: resourcetype"`
+	DisplayName  string `xml:"DAV: displayname"`
+	ETag         string `xml:"DAV: getetag"`
+	ComponentSet struct {
+		Components []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"urn:ietf:params:xml:ns:caldav comp"`
+	} `xml:"urn:ietf:params:xml:ns:caldav supported-calendar-component-set"`
+}
+
+// prop returns the properties found for the resource, or nil if none were.
+func (r *davResponse) prop() *davProp {
+	for i, propStat := range r.PropStats {
+		if strings.Contains(propStat.Status, " 200") {
+			return &r.PropStats[i].Prop
+		}
+	}
+	return nil
+}
+
+// propfind runs a PROPFIND request of depth 1 and returns the responses, with hrefs as unescaped paths.
+func (c *Client) propfind(href, body string) ([]davResponse, error) {
+	response, err := c.do("PROPFIND", href, body, map[string]string{"Depth": "1"})
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusMultiStatus:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, href)
+	default:
+		return nil, statusError("PROPFIND", href, response)
+	}
+
+	var status multistatus
+	if decodeErr := xml.NewDecoder(response.Body).Decode(&status); decodeErr != nil {
+		return nil, fmt.Errorf("invalid PROPFIND response: %w", decodeErr)
+	}
+
+	for i := range status.Responses {
+		status.Responses[i].Href = c.resolvePath(status.Responses[i].Href)
+	}
+	return status.Responses, nil
+}
+
+// do sends a request for a path of the server.
+func (c *Client) do(method, href, body string, headers map[string]string) (*http.Response, error) {
+	target := *c.home
+	target.Path = href
+	target.RawPath = ""
+
+	request, err := http.NewRequest(method, target.String(), strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != "" && strings.HasPrefix(body, "<?xml") {
+		request.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	}
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	if c.username != "" {
+		request.SetBasicAuth(c.username, c.password)
+	}
+
+	response, err := c.http.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", method, href, err)
+	}
+	return response, nil
+}
+
+// resolvePath returns the unescaped path of an href, which may be a full URL.
+func (c *Client) resolvePath(href string) string {
+	parsed, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return parsed.Path
+}
+
+// statusError returns an error describing an unexpected response.
+func statusError(method, href string, response *http.Response) error {
+	message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+	detail := strings.TrimSpace(string(message))
+	if detail != "" && !strings.HasPrefix(detail, "<") {
+		return fmt.Errorf("%w: %s %s: %s: %s", ErrUnexpectedStatus, method, href, response.Status, detail)
+	}
+	return fmt.Errorf("%w: %s %s: %s", ErrUnexpectedStatus, method, href, response.Status)
+}
+
+// xmlEscape escapes text for use in an XML element.
+func xmlEscape(text string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(text))
+	return buf.String()
+}
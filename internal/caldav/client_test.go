@@ -0,0 +1,108 @@
+package caldav_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/d4r1us-drk/clido/internal/caldav"
+	"github.com/d4r1us-drk/clido/internal/caldav/caldavtest"
+)
+
+func newTestClient(t *testing.T) (*caldav.Client, *caldavtest.Server) {
+	t.Helper()
+
+	server := caldavtest.NewServer()
+	t.Cleanup(server.Close)
+	client, err := caldav.NewClient(server.HomeURL(), "user", "secret")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return client, server
+}
+
+func TestListCalendars(t *testing.T) {
+	client, server := newTestClient(t)
+	server.AddCalendar("events", "Events", "VEVENT")
+	server.AddCalendar("any", "Anything")
+
+	href, err := client.CreateCalendar("clido-1", "Work & Home")
+	if err != nil || href != caldavtest.HomePath+"clido-1/" {
+		t.Fatalf("CreateCalendar() = %q, %v, want %q", href, err, caldavtest.HomePath+"clido-1/")
+	}
+	if _, err = client.CreateCalendar("clido-1", "Again"); !errors.Is(err, caldav.ErrUnexpectedStatus) {
+		t.Errorf("CreateCalendar() of an existing calendar error = %v, want %v", err, caldav.ErrUnexpectedStatus)
+	}
+	if err = client.RenameCalendar(href, "Work"); err != nil {
+		t.Fatalf("RenameCalendar() error = %v", err)
+	}
+
+	calendars, err := client.ListCalendars()
+	if err != nil {
+		t.Fatalf("ListCalendars() error = %v", err)
+	}
+	want := map[string]caldav.Calendar{
+		caldavtest.HomePath + "any/":     {DisplayName: "Anything", SupportsToDo: true},
+		caldavtest.HomePath + "clido-1/": {DisplayName: "Work", SupportsToDo: true},
+		caldavtest.HomePath + "events/":  {DisplayName: "Events", SupportsToDo: false},
+	}
+	if len(calendars) != len(want) {
+		t.Fatalf("ListCalendars() = %d calendars, want %d", len(calendars), len(want))
+	}
+	for _, calendar := range calendars {
+		wanted, found := want[calendar.Href]
+		if !found || calendar.DisplayName != wanted.DisplayName || calendar.SupportsToDo != wanted.SupportsToDo {
+			t.Errorf("ListCalendars() has %+v, want %+v", *calendar, wanted)
+		}
+	}
+}
+
+func TestObjectETags(t *testing.T) {
+	client, server := newTestClient(t)
+	calendarHref := server.AddCalendar("tasks", "Tasks", "VTODO")
+	href := calendarHref + "task.ics"
+
+	// Without an ETag, objects are only created
+	etag, err := client.PutObject(href, "first", "")
+	if err != nil || etag == "" {
+		t.Fatalf("PutObject() = %q, %v, want an ETag", etag, err)
+	}
+	if _, err = client.PutObject(href, "again", ""); !errors.Is(err, caldav.ErrPreconditionFailed) {
+		t.Errorf("PutObject() of an existing object error = %v, want %v", err, caldav.ErrPreconditionFailed)
+	}
+
+	objects, err := client.ListObjects(calendarHref)
+	if err != nil || len(objects) != 1 || objects[0].Href != href || objects[0].ETag != etag {
+		t.Fatalf("ListObjects() = %v, %v, want %s with ETag %s", objects, err, href, etag)
+	}
+
+	// Another client changes the object, so the ETag known by the client is stale
+	changedETag := server.PutObject(href, "changed")
+	if _, err = client.PutObject(href, "second", etag); !errors.Is(err, caldav.ErrPreconditionFailed) {
+		t.Errorf("PutObject() with a stale ETag error = %v, want %v", err, caldav.ErrPreconditionFailed)
+	}
+	if err = client.Delete(href, etag); !errors.Is(err, caldav.ErrPreconditionFailed) {
+		t.Errorf("Delete() with a stale ETag error = %v, want %v", err, caldav.ErrPreconditionFailed)
+	}
+
+	object, err := client.GetObject(href)
+	if err != nil || object.Data != "changed" || object.ETag != changedETag {
+		t.Fatalf("GetObject() = %+v, %v, want the changed object with ETag %s", object, err, changedETag)
+	}
+	newETag, err := client.PutObject(href, "second", object.ETag)
+	if err != nil || newETag == "" || newETag == changedETag {
+		t.Fatalf("PutObject() with the current ETag = %q, %v, want a new ETag", newETag, err)
+	}
+
+	if err = client.Delete(href, newETag); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, _, exists := server.Object(href); exists {
+		t.Error("Delete() kept the object")
+	}
+	if _, err = client.GetObject(href); !errors.Is(err, caldav.ErrNotFound) {
+		t.Errorf("GetObject() of a deleted object error = %v, want %v", err, caldav.ErrNotFound)
+	}
+	if err = client.Delete(href, ""); err != nil {
+		t.Errorf("Delete() of a deleted object error = %v, want nil", err)
+	}
+}
@@ -0,0 +1,284 @@
+package caldav
+
+import (
+	"bufio"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VTODO statuses defined by RFC 5545.
+const (
+	StatusNeedsAction = "NEEDS-ACTION"
+	StatusInProcess   = "IN-PROCESS"
+	StatusCompleted   = "COMPLETED"
+	StatusCancelled   = "CANCELLED"
+)
+
+// ErrNoTodo is returned when a calendar object holds no VTODO component.
+var ErrNoTodo = errors.New("calendar object holds no VTODO")
+
+// Formats of iCalendar dates and UTC date-times.
+const (
+	icalDateTime = "20060102T150405Z"
+	icalDate     = "20060102"
+)
+
+// Todo is a VTODO component of an iCalendar object, limited to the properties clido maps onto tasks.
+// Extra holds the properties clido does not know, which are written back unchanged.
+type Todo struct {
+	UID          string
+	Summary      string
+	Description  string
+	Status       string
+	Priority     int // 0 (undefined) or 1 (highest) to 9 (lowest)
+	Due          *time.Time
+	Completed    *time.Time
+	Created      *time.Time
+	LastModified *time.Time
+	RelatedTo    string            // The UID of the parent VTODO
+	Extra        map[string]string // Other properties, e.g. X-CLIDO-STATE, as raw content lines without the name
+}
+
+// Encode returns the todo as an iCalendar object with CRLF line endings and folded lines.
+func (t *Todo) Encode() string {
+	var lines []string
+	add := func(name, value string) {
+		lines = append(lines, name+":"+value)
+	}
+	addTime := func(name string, value *time.Time) {
+		if value != nil {
+			add(name, value.UTC().Format(icalDateTime))
+		}
+	}
+
+	add("BEGIN", "VCALENDAR")
+	add("VERSION", "2.0")
+	add("PRODID", "-//clido//clido//EN")
+	add("BEGIN", "VTODO")
+	add("UID", escapeText(t.UID))
+	stamp := time.Now()
+	if t.LastModified != nil {
+		stamp = *t.LastModified
+	}
+	addTime("DTSTAMP", &stamp)
+	add("SUMMARY", escapeText(t.Summary))
+	if t.Description != "" {
+		add("DESCRIPTION", escapeText(t.Description))
+	}
+	if t.Status != "" {
+		add("STATUS", t.Status)
+	}
+	if t.Priority != 0 {
+		add("PRIORITY", strconv.Itoa(t.Priority))
+	}
+	addTime("DUE", t.Due)
+	addTime("COMPLETED", t.Completed)
+	addTime("CREATED", t.Created)
+	addTime("LAST-MODIFIED", t.LastModified)
+	if t.RelatedTo != "" {
+		add("RELATED-TO", escapeText(t.RelatedTo))
+	}
+	for _, name := range sortedNames(t.Extra) {
+		lines = append(lines, name+t.Extra[name])
+	}
+	add("END", "VTODO")
+	add("END", "VCALENDAR")
+
+	var builder strings.Builder
+	for _, line := range lines {
+		builder.WriteString(foldLine(line))
+		builder.WriteString("\r\n")
+	}
+	return builder.String()
+}
+
+// ParseTodo parses the first VTODO component of an iCalendar object.
+func ParseTodo(data string) (*Todo, error) {
+	todo := &Todo{Extra: make(map[string]string)}
+	inTodo, found := false, false
+	depth := 0
+
+	for _, line := range unfoldLines(data) {
+		name, params, value := splitContentLine(line)
+		switch {
+		case name == "BEGIN" && value == "VTODO" && !found:
+			inTodo, found = true, true
+			continue
+		case name == "END" && value == "VTODO" && inTodo && depth == 0:
+			inTodo = false
+			continue
+		case !inTodo:
+			continue
+		case name == "BEGIN":
+			// Nested components such as VALARM are skipped
+			depth++
+			continue
+		case name == "END":
+			depth--
+			continue
+		case depth > 0:
+			continue
+		}
+
+		switch name {
+		case "UID":
+			todo.UID = unescapeText(value)
+		case "SUMMARY":
+			todo.Summary = unescapeText(value)
+		case "DESCRIPTION":
+			todo.Description = unescapeText(value)
+		case "STATUS":
+			todo.Status = strings.ToUpper(value)
+		case "PRIORITY":
+			todo.Priority, _ = strconv.Atoi(value)
+		case "DUE":
+			todo.Due = parseICalTime(params, value)
+		case "COMPLETED":
+			todo.Completed = parseICalTime(params, value)
+		case "CREATED":
+			todo.Created = parseICalTime(params, value)
+		case "LAST-MODIFIED":
+			todo.LastModified = parseICalTime(params, value)
+		case "RELATED-TO":
+			// Only parent relations are mapped, which is the default relation type
+			if !strings.Contains(strings.ToUpper(params), "RELTYPE=") ||
+				strings.Contains(strings.ToUpper(params), "RELTYPE=PARENT") {
+				todo.RelatedTo = unescapeText(value)
+			}
+		case "DTSTAMP", "SEQUENCE":
+			// Regenerated when encoding
+		default:
+			todo.Extra[name] = line[len(name):]
+		}
+	}
+
+	if !found {
+		return nil, ErrNoTodo
+	}
+	return todo, nil
+}
+
+// Property returns the value of a property kept in Extra, or an empty string if the todo does not have it.
+func (t *Todo) Property(name string) string {
+	raw, found := t.Extra[strings.ToUpper(name)]
+	if !found {
+		return ""
+	}
+	_, _, value := splitContentLine(name + raw)
+	return unescapeText(value)
+}
+
+// SetProperty sets the value of a property kept in Extra.
+func (t *Todo) SetProperty(name, value string) {
+	if t.Extra == nil {
+		t.Extra = make(map[string]string)
+	}
+	t.Extra[strings.ToUpper(name)] = ":" + escapeText(value)
+}
+
+// unfoldLines splits an iCalendar object into content lines, joining folded lines.
+func unfoldLines(data string) []string {
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// foldLine folds a content line into lines of at most 75 bytes, without splitting UTF-8 sequences.
+func foldLine(line string) string {
+	const maxLength = 75
+	var builder strings.Builder
+	length := 0
+	for _, r := range line {
+		size := len(string(r))
+		if length+size > maxLength {
+			builder.WriteString("\r\n ")
+			length = 1
+		}
+		builder.WriteRune(r)
+		length += size
+	}
+	return builder.String()
+}
+
+// splitContentLine splits a content line into its upper-cased name, its parameters and its value.
+func splitContentLine(line string) (string, string, string) {
+	// The value starts at the first colon outside of quoted parameter values
+	quoted := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		} else if r == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return strings.ToUpper(line), "", ""
+	}
+
+	name, params, _ := strings.Cut(line[:colon], ";")
+	return strings.ToUpper(name), params, line[colon+1:]
+}
+
+// parseICalTime parses an iCalendar date or date-time. Floating and zoned date-times are read in the
+// local time zone, or in the zone given by TZID when it is known.
+func parseICalTime(params, value string) *time.Time {
+	location := time.Local
+	for _, param := range strings.Split(params, ";") {
+		if key, zone, found := strings.Cut(param, "="); found && strings.EqualFold(key, "TZID") {
+			if loaded, err := time.LoadLocation(strings.Trim(zone, `"`)); err == nil {
+				location = loaded
+			}
+		}
+	}
+
+	var parsed time.Time
+	var err error
+	switch {
+	case strings.HasSuffix(value, "Z"):
+		parsed, err = time.Parse(icalDateTime, value)
+	case len(value) == len(icalDate):
+		parsed, err = time.ParseInLocation(icalDate, value, location)
+	default:
+		parsed, err = time.ParseInLocation(strings.TrimSuffix(icalDateTime, "Z"), value, location)
+	}
+	if err != nil {
+		return nil
+	}
+	return &parsed
+}
+
+// escapeText escapes a TEXT value.
+func escapeText(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(value)
+}
+
+// unescapeText unescapes a TEXT value.
+func unescapeText(value string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(value)
+}
+
+// sortedNames returns the keys of a map of properties, sorted, so encoding is deterministic.
+func sortedNames(properties map[string]string) []string {
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,52 @@
+package errcode
+
+import "errors"
+
+// Code is the kind of a failure, as reported to scripts by the exit status and the JSON errors of clido.
+type Code string
+
+// Kinds of failures.
+const (
+	NotFound   Code = "not_found"  // A project, task or other item does not exist
+	Validation Code = "validation" // The input is invalid, e.g. a missing name or a malformed date
+	Conflict   Code = "conflict"   // The change conflicts with the current data, e.g. a cycle or open subtasks
+	Rejected   Code = "rejected"   // The change was vetoed by a pre-hook
+	Storage    Code = "storage"    // The database could not be read or written
+	Internal   Code = "internal"   // Any other failure
+)
+
+// Error is an error of a known kind. Sentinel errors are declared with New and compared with errors.Is, and
+// wrapping them keeps their code.
+type Error struct {
+	Code    Code
+	Message string
+	Err     error // The error given a kind by Wrap, if any
+}
+
+// New returns an error of the given kind with the given message.
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap gives a kind to an error of another package, keeping its message and its chain.
+func Wrap(code Code, err error) *Error {
+	return &Error{Code: code, Message: err.Error(), Err: err}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the error given a kind by Wrap, or nil.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Of returns the kind of an error: the code of the first Error it wraps, or Internal.
+func Of(err error) Code {
+	var coded *Error
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+	return Internal
+}
@@ -0,0 +1,147 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrGitNotFound is returned when the git executable cannot be found.
+var ErrGitNotFound = errors.New("git is not installed or not in PATH")
+
+// Repo is a git working tree, manipulated through the git command-line tool.
+type Repo struct {
+	Dir string // The directory of the working tree
+}
+
+// Open returns the git working tree in dir, initializing it with the given branch if needed.
+func Open(dir, branch string) (*Repo, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, ErrGitNotFound
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating git directory: %w", err)
+	}
+
+	repo := &Repo{Dir: dir}
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		return repo, nil
+	}
+
+	if _, err := repo.Run("init", "--quiet", "--initial-branch="+branch); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// Run runs a git command in the working tree and returns its trimmed standard output.
+func (r *Repo) Run(args ...string) (string, error) {
+	return r.RunWithInput(nil, args...)
+}
+
+// RunWithInput runs a git command in the working tree with the given standard input
+// and returns its trimmed standard output.
+func (r *Repo) RunWithInput(input io.Reader, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.Dir
+	cmd.Stdin = input
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], message)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// HasRef reports whether a reference (branch, remote branch or commit) exists.
+func (r *Repo) HasRef(ref string) bool {
+	_, err := r.Run("rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	return err == nil
+}
+
+// ReadTree returns the content of every file of a commit under the given directories, indexed by path.
+func (r *Repo) ReadTree(ref string, dirs ...string) (map[string][]byte, error) {
+	listing, err := r.Run(append([]string{"ls-tree", "-r", "--full-tree", ref, "--"}, dirs...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte)
+	if listing == "" {
+		return files, nil
+	}
+
+	// Each line is "<mode> blob <object>\t<path>"
+	var paths, objects []string
+	for _, line := range strings.Split(listing, "\n") {
+		meta, path, found := strings.Cut(line, "\t")
+		fields := strings.Fields(meta)
+		if !found || len(fields) != 3 || fields[1] != "blob" {
+			continue
+		}
+		paths = append(paths, path)
+		objects = append(objects, fields[2])
+	}
+
+	contents, err := r.readObjects(objects)
+	if err != nil {
+		return nil, err
+	}
+	for i, path := range paths {
+		files[path] = contents[i]
+	}
+	return files, nil
+}
+
+// readObjects reads the content of blobs in a single git process.
+func (r *Repo) readObjects(objects []string) ([][]byte, error) {
+	if len(objects) == 0 {
+		return nil, nil
+	}
+
+	cmd := exec.Command("git", "cat-file", "--batch")
+	cmd.Dir = r.Dir
+	cmd.Stdin = strings.NewReader(strings.Join(objects, "\n") + "\n")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+
+	// The output is, for each object, a "<object> <type> <size>" header, the content and a newline
+	reader := bufio.NewReader(bytes.NewReader(output))
+	contents := make([][]byte, 0, len(objects))
+	for range objects {
+		header, headerErr := reader.ReadString('\n')
+		if headerErr != nil {
+			return nil, fmt.Errorf("git cat-file: %w", headerErr)
+		}
+		fields := strings.Fields(header)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("git cat-file: unexpected output '%s'", strings.TrimSpace(header))
+		}
+		size, sizeErr := strconv.Atoi(fields[2])
+		if sizeErr != nil {
+			return nil, fmt.Errorf("git cat-file: %w", sizeErr)
+		}
+
+		content := make([]byte, size+1)
+		if _, readErr := io.ReadFull(reader, content); readErr != nil {
+			return nil, fmt.Errorf("git cat-file: %w", readErr)
+		}
+		contents = append(contents, content[:size])
+	}
+	return contents, nil
+}
@@ -0,0 +1,97 @@
+package hlc
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidTimestamp is returned when a timestamp cannot be parsed.
+var ErrInvalidTimestamp = errors.New("invalid hybrid logical clock timestamp")
+
+// Timestamp is a hybrid logical clock timestamp, ordering events across machines consistently with causality
+// while staying close to physical time. It holds a physical time in nanoseconds, a logical counter
+// ordering events with the same physical time, and the node that produced it, which breaks ties.
+type Timestamp struct {
+	Wall    int64
+	Logical uint32
+	Node    string
+}
+
+// String formats the timestamp so that timestamps sort lexicographically in clock order.
+func (t Timestamp) String() string {
+	return fmt.Sprintf("%016x-%08x-%s", t.Wall, t.Logical, t.Node)
+}
+
+// IsZero reports whether the timestamp is the zero timestamp.
+func (t Timestamp) IsZero() bool {
+	return t.Wall == 0 && t.Logical == 0 && t.Node == ""
+}
+
+// Before reports whether the timestamp is ordered before another one.
+func (t Timestamp) Before(other Timestamp) bool {
+	if t.Wall != other.Wall {
+		return t.Wall < other.Wall
+	}
+	if t.Logical != other.Logical {
+		return t.Logical < other.Logical
+	}
+	return t.Node < other.Node
+}
+
+// Parse parses a timestamp formatted by String.
+func Parse(value string) (Timestamp, error) {
+	parts := strings.SplitN(value, "-", 3)
+	if len(parts) != 3 || len(parts[0]) != 16 || len(parts[1]) != 8 || parts[2] == "" {
+		return Timestamp{}, fmt.Errorf("%w: '%s'", ErrInvalidTimestamp, value)
+	}
+	wall, wallErr := strconv.ParseInt(parts[0], 16, 64)
+	logical, logicalErr := strconv.ParseUint(parts[1], 16, 32)
+	if wallErr != nil || logicalErr != nil {
+		return Timestamp{}, fmt.Errorf("%w: '%s'", ErrInvalidTimestamp, value)
+	}
+	return Timestamp{Wall: wall, Logical: uint32(logical), Node: parts[2]}, nil
+}
+
+// Clock is the hybrid logical clock of a node.
+type Clock struct {
+	node string
+	last Timestamp
+	now  func() time.Time
+}
+
+// NewClock returns the clock of a node, resuming after the last timestamp it produced or observed.
+func NewClock(node string, last Timestamp) *Clock {
+	return &Clock{node: node, last: last, now: time.Now}
+}
+
+// Node returns the node of the clock.
+func (c *Clock) Node() string {
+	return c.node
+}
+
+// Last returns the last timestamp produced or observed by the clock.
+func (c *Clock) Last() Timestamp {
+	return c.last
+}
+
+// Now returns a new timestamp for a local event, after every timestamp produced or observed so far.
+func (c *Clock) Now() Timestamp {
+	wall := c.now().UnixNano()
+	if wall > c.last.Wall {
+		c.last = Timestamp{Wall: wall, Node: c.node}
+	} else {
+		c.last = Timestamp{Wall: c.last.Wall, Logical: c.last.Logical + 1, Node: c.node}
+	}
+	return c.last
+}
+
+// Observe advances the clock past a timestamp received from another node, so later local events are
+// ordered after it.
+func (c *Clock) Observe(remote Timestamp) {
+	if c.last.Before(remote) {
+		c.last = Timestamp{Wall: remote.Wall, Logical: remote.Logical, Node: c.node}
+	}
+}
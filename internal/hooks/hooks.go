@@ -0,0 +1,217 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/d4r1us-drk/clido/internal/errcode"
+)
+
+// Hook points, run when a project or task is created, modified, completed or removed.
+const (
+	OnCreate   = "on-create"
+	OnModify   = "on-modify"
+	OnComplete = "on-complete"
+	OnRemove   = "on-remove"
+)
+
+// Constants for running hooks.
+const (
+	PrePrefix      = "pre-"               // Prefix of the file names of pre-hooks, run before the change is saved
+	DefaultTimeout = 10 * time.Second     // Time a hook may run before it is killed
+	TimeoutEnv     = "CLIDO_HOOK_TIMEOUT" // Environment variable overriding the timeout, e.g. "30s"
+	waitDelay      = time.Second          // Time left to close the output of a killed hook
+)
+
+// Events lists the hook points, in the order they are documented.
+var Events = []string{OnCreate, OnModify, OnComplete, OnRemove}
+
+// Error constants for running hooks.
+var (
+	ErrUnknownEvent = errcode.New(errcode.Validation, "unknown hook event")
+	ErrHookFailed   = errors.New("hook failed")
+	ErrHookTimeout  = errors.New("hook timed out")
+)
+
+// Output receives the diagnostics of hooks and the output of post-hooks.
+var Output io.Writer = os.Stderr
+
+// Hook is an executable file of the hooks directory, run for a hook point.
+type Hook struct {
+	Name  string `json:"name"`  // The file name of the hook
+	Path  string `json:"path"`  // The full path of the hook
+	Event string `json:"event"` // The hook point the hook runs for
+	Pre   bool   `json:"pre"`   // Whether the hook runs before the change is saved
+}
+
+// Payload is the JSON document written to the standard input of a hook. Before is null for a creation
+// and After is null for a removal.
+type Payload struct {
+	Event    string `json:"event"`
+	ItemType string `json:"item_type"`
+	Before   any    `json:"before"`
+	After    any    `json:"after"`
+}
+
+// IsEvent reports whether name is a hook point.
+func IsEvent(name string) bool {
+	for _, event := range Events {
+		if event == name {
+			return true
+		}
+	}
+	return false
+}
+
+// List returns the hooks of a directory, sorted by file name. A hook is an executable file named after a
+// hook point, optionally prefixed with "pre-" and followed by a "." or "-" and any suffix, e.g.
+// "on-complete.notify.sh" or "pre-on-create-validate". A missing directory holds no hooks.
+func List(dir string) ([]Hook, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading hooks directory: %w", err)
+	}
+
+	var hooks []Hook
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") {
+			continue
+		}
+
+		hook := Hook{Name: name, Path: filepath.Join(dir, name), Pre: strings.HasPrefix(name, PrePrefix)}
+		hook.Event = eventOf(strings.TrimPrefix(name, PrePrefix))
+		if hook.Event == "" {
+			continue
+		}
+
+		// Symbolic links are followed, so hooks can be shared between workspaces
+		info, statErr := os.Stat(hook.Path)
+		if statErr != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if runtime.GOOS != "windows" && info.Mode().Perm()&0o111 == 0 {
+			continue
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, nil
+}
+
+// Find returns the pre-hooks or post-hooks of a directory that run for a hook point.
+func Find(dir, event string, pre bool) ([]Hook, error) {
+	hooks, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []Hook
+	for _, hook := range hooks {
+		if hook.Event == event && hook.Pre == pre {
+			found = append(found, hook)
+		}
+	}
+	return found, nil
+}
+
+// Encode returns the payload of a change as the JSON input of a hook.
+func Encode(event, itemType string, before, after any) ([]byte, error) {
+	return json.Marshal(Payload{Event: event, ItemType: itemType, Before: before, After: after})
+}
+
+// Run runs a hook with the given standard input and returns its standard output. The hook is killed
+// when it runs longer than the timeout. A hook that exits with a non-zero status fails with the first
+// line of its standard error, or of its standard output, as message. The standard error of a successful
+// hook is copied to Output.
+func Run(hook Hook, input []byte) ([]byte, error) {
+	timeout := Timeout()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, hook.Path)
+	cmd.Dir = filepath.Dir(hook.Path)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = waitDelay
+
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("%w after %s: '%s'", ErrHookTimeout, timeout, hook.Name)
+	}
+	if err != nil {
+		message := firstLine(stderr.String())
+		if message == "" {
+			message = firstLine(stdout.String())
+		}
+		if message == "" {
+			message = err.Error()
+		}
+		return nil, fmt.Errorf("%w: '%s': %s", ErrHookFailed, hook.Name, message)
+	}
+
+	if stderr.Len() > 0 {
+		_, _ = Output.Write(stderr.Bytes())
+	}
+	return stdout.Bytes(), nil
+}
+
+// Notify runs post-hooks with the given standard input, one after the other. Their output is copied to
+// Output, and failures are reported there as warnings, as the change they follow is already saved.
+func Notify(hooks []Hook, input []byte) {
+	for _, hook := range hooks {
+		output, err := Run(hook, input)
+		if err != nil {
+			fmt.Fprintln(Output, "Warning: "+err.Error())
+			continue
+		}
+		if len(output) > 0 {
+			_, _ = Output.Write(output)
+		}
+	}
+}
+
+// Timeout returns the time a hook may run, read from the TimeoutEnv environment variable when it holds
+// a valid positive duration.
+func Timeout() time.Duration {
+	if value := os.Getenv(TimeoutEnv); value != "" {
+		if timeout, err := time.ParseDuration(value); err == nil && timeout > 0 {
+			return timeout
+		}
+	}
+	return DefaultTimeout
+}
+
+// eventOf returns the hook point a file name, without its "pre-" prefix, runs for, or "" if none.
+func eventOf(name string) string {
+	for _, event := range Events {
+		if name == event || strings.HasPrefix(name, event+".") || strings.HasPrefix(name, event+"-") {
+			return event
+		}
+	}
+	return ""
+}
+
+// firstLine returns the first non-blank line of a text, trimmed.
+func firstLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
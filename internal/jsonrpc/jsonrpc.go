@@ -0,0 +1,223 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+)
+
+// Version is the JSON-RPC version spoken by the server.
+const Version = "2.0"
+
+// Error codes defined by the JSON-RPC 2.0 specification.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// maxMessageSize is the size of the largest message the server reads.
+const maxMessageSize = 16 * 1024 * 1024
+
+// Error is a JSON-RPC error object. Handlers return it to choose the code of their errors.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Handler handles the calls of a method, given their raw parameters, and returns their result.
+type Handler func(params json.RawMessage) (any, error)
+
+// request is a JSON-RPC request, or a notification when it has no ID.
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// response is a JSON-RPC response, holding either a result or an error.
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  any             `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// notification is a JSON-RPC notification sent by the server.
+type notification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+// Server serves JSON-RPC 2.0 requests read from a stream, one message per line, and writes one response
+// per line. Requests are handled one at a time, in the order they are read.
+type Server struct {
+	handlers  map[string]Handler
+	mapError  func(err error) *Error
+	out       io.Writer
+	writeLock sync.Mutex
+}
+
+// NewServer creates a server writing its responses and notifications to out. mapError converts the
+// errors returned by handlers that are not an *Error; errors it returns nil for are internal errors.
+func NewServer(out io.Writer, mapError func(err error) *Error) *Server {
+	return &Server{handlers: make(map[string]Handler), mapError: mapError, out: out}
+}
+
+// Handle registers the handler of a method.
+func (s *Server) Handle(method string, handler Handler) {
+	s.handlers[method] = handler
+}
+
+// Methods returns the names of the registered methods, sorted.
+func (s *Server) Methods() []string {
+	methods := make([]string, 0, len(s.handlers))
+	for method := range s.handlers {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// Notify sends a notification to the client.
+func (s *Server) Notify(method string, params any) error {
+	return s.write(notification{JSONRPC: Version, Method: method, Params: params})
+}
+
+// Serve handles the messages read from in until it ends. Each message is a request, a notification or
+// a batch of them, on a single line. Blank lines are ignored.
+func (s *Server) Serve(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxMessageSize)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if reply := s.handleMessage(line); reply != nil {
+			if err := s.write(reply); err != nil {
+				return err
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// handleMessage handles a message and returns its reply, or nil when nothing must be replied.
+func (s *Server) handleMessage(message []byte) any {
+	if message[0] != '[' {
+		var req request
+		if err := json.Unmarshal(message, &req); err != nil {
+			return s.errorResponse(nil, &Error{Code: CodeParseError, Message: "parse error: " + err.Error()})
+		}
+		// A nil *response in an interface is not nil: notifications must not be replied to
+		if reply := s.handleRequest(&req); reply != nil {
+			return reply
+		}
+		return nil
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(message, &batch); err != nil {
+		return s.errorResponse(nil, &Error{Code: CodeParseError, Message: "parse error: " + err.Error()})
+	}
+	if len(batch) == 0 {
+		return s.errorResponse(nil, &Error{Code: CodeInvalidRequest, Message: "empty batch"})
+	}
+
+	// Notifications of a batch are not replied to, and a batch of notifications has no reply at all
+	var replies []*response
+	for _, item := range batch {
+		var req request
+		var reply *response
+		if err := json.Unmarshal(item, &req); err != nil {
+			reply = s.errorResponse(nil, &Error{Code: CodeInvalidRequest, Message: "invalid request: " + err.Error()})
+		} else {
+			reply = s.handleRequest(&req)
+		}
+		if reply != nil {
+			replies = append(replies, reply)
+		}
+	}
+	if len(replies) == 0 {
+		return nil
+	}
+	return replies
+}
+
+// handleRequest calls the handler of a request and returns its response, or nil for a notification.
+func (s *Server) handleRequest(req *request) *response {
+	isNotification := len(req.ID) == 0
+	if req.JSONRPC != Version || req.Method == "" {
+		return s.errorResponse(req.ID, &Error{Code: CodeInvalidRequest, Message: "invalid request"})
+	}
+
+	handler, found := s.handlers[req.Method]
+	if !found {
+		if isNotification {
+			return nil
+		}
+		return s.errorResponse(req.ID, &Error{Code: CodeMethodNotFound, Message: "method not found: " + req.Method})
+	}
+
+	result, err := handler(req.Params)
+	if isNotification {
+		return nil
+	}
+	if err != nil {
+		return s.errorResponse(req.ID, s.toError(err))
+	}
+	if result == nil {
+		result = json.RawMessage("null")
+	}
+	return &response{JSONRPC: Version, Result: result, ID: req.ID}
+}
+
+// toError converts an error returned by a handler to a JSON-RPC error.
+func (s *Server) toError(err error) *Error {
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+	if s.mapError != nil {
+		if mapped := s.mapError(err); mapped != nil {
+			return mapped
+		}
+	}
+	return &Error{Code: CodeInternalError, Message: err.Error()}
+}
+
+// errorResponse returns an error response to the request with the given ID, null when unknown.
+func (s *Server) errorResponse(id json.RawMessage, rpcErr *Error) *response {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return &response{JSONRPC: Version, Error: rpcErr, ID: id}
+}
+
+// write writes a message on its own line.
+func (s *Server) write(message any) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	_, err = s.out.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,142 @@
+package jsonrpc_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/d4r1us-drk/clido/internal/jsonrpc"
+)
+
+// serve runs a server with an "echo" method, a "fail" method and a "count" method counting its calls on
+// the given input, and returns the lines it wrote.
+func serve(t *testing.T, input string) ([]string, int) {
+	t.Helper()
+
+	var out bytes.Buffer
+	calls := 0
+	server := jsonrpc.NewServer(&out, func(err error) *jsonrpc.Error {
+		return &jsonrpc.Error{Code: -32001, Message: err.Error()}
+	})
+	server.Handle("echo", func(params json.RawMessage) (any, error) {
+		calls++
+		return params, nil
+	})
+	server.Handle("fail", func(_ json.RawMessage) (any, error) {
+		calls++
+		return nil, errors.New("failed")
+	})
+	server.Handle("count", func(_ json.RawMessage) (any, error) {
+		calls++
+		return nil, nil
+	})
+
+	if err := server.Serve(strings.NewReader(input)); err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+	output := strings.TrimSpace(out.String())
+	if output == "" {
+		return nil, calls
+	}
+	return strings.Split(output, "\n"), calls
+}
+
+func TestServeNotificationsAreNotReplied(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		calls int
+	}{
+		{"notification", `{"jsonrpc":"2.0","method":"count","params":{"id":1}}`, 1},
+		{"failing notification", `{"jsonrpc":"2.0","method":"fail"}`, 1},
+		{"unknown method", `{"jsonrpc":"2.0","method":"unknown"}`, 0},
+		{"batch of notifications", `[{"jsonrpc":"2.0","method":"count"},{"jsonrpc":"2.0","method":"echo"}]`, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, calls := serve(t, tt.input)
+			if len(lines) != 0 {
+				t.Errorf("Serve() wrote %q, want nothing", lines)
+			}
+			if calls != tt.calls {
+				t.Errorf("handlers called %d times, want %d", calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestServeRequests(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			"result",
+			`{"jsonrpc":"2.0","id":1,"method":"echo","params":{"a":1}}`,
+			`{"jsonrpc":"2.0","result":{"a":1},"id":1}`,
+		},
+		{
+			"null result",
+			`{"jsonrpc":"2.0","id":"x","method":"count"}`,
+			`{"jsonrpc":"2.0","result":null,"id":"x"}`,
+		},
+		{
+			"mapped error",
+			`{"jsonrpc":"2.0","id":2,"method":"fail"}`,
+			`{"jsonrpc":"2.0","error":{"code":-32001,"message":"failed"},"id":2}`,
+		},
+		{
+			"unknown method",
+			`{"jsonrpc":"2.0","id":3,"method":"unknown"}`,
+			`{"jsonrpc":"2.0","error":{"code":-32601,"message":"method not found: unknown"},"id":3}`,
+		},
+		{
+			"invalid version",
+			`{"jsonrpc":"1.0","id":4,"method":"echo"}`,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request"},"id":4}`,
+		},
+		{
+			"batch replying to requests only",
+			`[{"jsonrpc":"2.0","id":5,"method":"count"},{"jsonrpc":"2.0","method":"count"}]`,
+			`[{"jsonrpc":"2.0","result":null,"id":5}]`,
+		},
+		{
+			"empty batch",
+			`[]`,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"empty batch"},"id":null}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, _ := serve(t, tt.input)
+			if len(lines) != 1 || lines[0] != tt.want {
+				t.Errorf("Serve() wrote %q, want %q", lines, tt.want)
+			}
+		})
+	}
+}
+
+func TestServeParseError(t *testing.T) {
+	lines, _ := serve(t, "{not json\n\n")
+	if len(lines) != 1 {
+		t.Fatalf("Serve() wrote %d lines, want 1", len(lines))
+	}
+
+	var reply struct {
+		Error struct {
+			Code int `json:"code"`
+		} `json:"error"`
+		ID json.RawMessage `json:"id"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &reply); err != nil {
+		t.Fatalf("invalid reply %q: %v", lines[0], err)
+	}
+	if reply.Error.Code != jsonrpc.CodeParseError || string(reply.ID) != "null" {
+		t.Errorf("Serve() wrote %q, want a parse error with a null ID", lines[0])
+	}
+}
@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/d4r1us-drk/clido/internal/version"
+)
+
+// Headers sent with each delivery.
+const (
+	HeaderEvent     = "X-Clido-Event"     // The event of the change, e.g. "on-complete"
+	HeaderDelivery  = "X-Clido-Delivery"  // The identifier of the delivery, the same for every attempt
+	HeaderSignature = "X-Clido-Signature" // "sha256=" followed by the hex HMAC-SHA256 of the body
+)
+
+// Constants for delivering webhooks.
+const (
+	SignaturePrefix = "sha256="
+	Timeout         = 5 * time.Second // Time an endpoint has to answer
+	maxErrorBody    = 200             // Number of bytes of an error response kept as error message
+	secretSize      = 32              // Number of random bytes of a generated secret, the size of a SHA-256 key
+)
+
+// Output receives the warnings about failed deliveries.
+var Output io.Writer = os.Stderr
+
+// NewSecret returns a new random secret to sign deliveries with, as hex.
+func NewSecret() (string, error) {
+	buf := make([]byte, secretSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Sign returns the signature of a body with the given secret, as sent in the signature header.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return SignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Post posts a JSON body to an endpoint, signed with the secret, and returns the HTTP status of the response.
+// Any status other than 2xx is an error, and so is the absence of a response, with status 0.
+func Post(url, secret, event, deliveryID string, body []byte) (int, error) {
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "clido/"+version.Get().Version)
+	request.Header.Set(HeaderEvent, event)
+	request.Header.Set(HeaderDelivery, deliveryID)
+	request.Header.Set(HeaderSignature, Sign(secret, body))
+
+	client := &http.Client{Timeout: Timeout}
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorBody))
+		return response.StatusCode, fmt.Errorf("HTTP %d: %s", response.StatusCode, bytes.TrimSpace(message))
+	}
+	_, _ = io.Copy(io.Discard, response.Body)
+	return response.StatusCode, nil
+}
+
+// Backoff returns the time to wait before attempting a delivery again after the given number of failed
+// attempts: 30 seconds, doubled after each attempt, up to an hour.
+func Backoff(attempts int) time.Duration {
+	const first, longest = 30 * time.Second, time.Hour
+	delay := first
+	for i := 1; i < attempts && delay < longest; i++ {
+		delay *= 2
+	}
+	return min(delay, longest)
+}
@@ -0,0 +1,115 @@
+package webhook_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d4r1us-drk/clido/internal/webhook"
+)
+
+func TestPostSignsDeliveries(t *testing.T) {
+	const secret, body = "s3cret", `{"event":"on-add"}`
+
+	var request *http.Request
+	var received []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request = r
+		received, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	status, err := webhook.Post(server.URL, secret, "on-add", "delivery-1", []byte(body))
+	if err != nil || status != http.StatusNoContent {
+		t.Fatalf("Post() = %d, %v, want %d", status, err, http.StatusNoContent)
+	}
+
+	// The receiver checks the signature with its own HMAC of the body
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(received)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{webhook.HeaderSignature, want},
+		{webhook.HeaderEvent, "on-add"},
+		{webhook.HeaderDelivery, "delivery-1"},
+		{"Content-Type", "application/json"},
+	}
+	for _, tt := range tests {
+		if got := request.Header.Get(tt.header); got != tt.want {
+			t.Errorf("header %s = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+	if string(received) != body || request.Method != http.MethodPost {
+		t.Errorf("received %s %q, want POST %q", request.Method, received, body)
+	}
+	if webhook.Sign("other", received) == want {
+		t.Error("Sign() gives the same signature with another secret")
+	}
+}
+
+func TestPostFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, strings.Repeat("overloaded ", 100), http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	status, err := webhook.Post(server.URL, "secret", "on-add", "delivery-1", []byte("{}"))
+	if status != http.StatusServiceUnavailable || err == nil {
+		t.Fatalf("Post() = %d, %v, want %d and an error", status, err, http.StatusServiceUnavailable)
+	}
+	if !strings.HasPrefix(err.Error(), "HTTP 503: overloaded") || len(err.Error()) > 220 {
+		t.Errorf("Post() error = %q, want the start of the response", err)
+	}
+
+	server.Close()
+	status, err = webhook.Post(server.URL, "secret", "on-add", "delivery-1", []byte("{}"))
+	if status != 0 || err == nil {
+		t.Errorf("Post() to a closed server = %d, %v, want 0 and an error", status, err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{20, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := webhook.Backoff(tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	first, err := webhook.NewSecret()
+	if err != nil {
+		t.Fatalf("NewSecret() error = %v", err)
+	}
+	second, _ := webhook.NewSecret()
+
+	if decoded, decodeErr := hex.DecodeString(first); decodeErr != nil || len(decoded) != 32 {
+		t.Errorf("NewSecret() = %q, want 32 random bytes as hex", first)
+	}
+	if first == second {
+		t.Error("NewSecret() returned the same secret twice")
+	}
+}
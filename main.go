@@ -10,27 +10,50 @@ import (
 )
 
 func run() int {
-	// Initialize the repository
-	repo, repoErr := repository.NewRepository()
+	// Initialize the repository of the workspace given with --workspace, or of the current workspace
+	repo, repoErr := repository.NewRepository(cmd.WorkspaceFromArgs(os.Args[1:]))
 	if repoErr != nil {
-		log.Printf("Error initializing repository: %v", repoErr)
-		return 1 // Exit code 1 indicates failure
+		if cmd.JSONFromArgs(os.Args[1:]) {
+			cmd.WriteJSONError(os.Stderr, repoErr)
+		} else {
+			log.Printf("Error initializing repository: %v", repoErr)
+		}
+		return cmd.ExitCode(repoErr) // The exit status tells the kind of failure
 	}
 	defer repo.Close()
 
 	// Initialize controllers
 	projectController := controllers.NewProjectController(repo)
 	taskController := controllers.NewTaskController(repo)
+	exportController := controllers.NewExportController(repo)
+	backupController := controllers.NewBackupController(repo)
+	databaseController := controllers.NewDatabaseController(repo)
+	workspaceController := controllers.NewWorkspaceController(repo)
+	syncController := controllers.NewSyncController(repo)
+	caldavController := controllers.NewCalDAVController(repo)
+	replicaController := controllers.NewReplicaController(repo)
+	hookController := controllers.NewHookController(repo)
+	pluginController := controllers.NewPluginController(repo)
+	webhookController := controllers.NewWebhookController(repo)
 
 	// Initialize the root command with controllers
-	rootCmd := cmd.NewRootCmd(projectController, taskController)
-
-	// Execute the root command
-	if err := rootCmd.Execute(); err != nil {
-		return 1 // Return 1 on error
-	}
-
-	return 0 // Exit code 0 indicates success
+	rootCmd := cmd.NewRootCmd(
+		projectController,
+		taskController,
+		exportController,
+		backupController,
+		databaseController,
+		workspaceController,
+		syncController,
+		caldavController,
+		replicaController,
+		hookController,
+		pluginController,
+		webhookController,
+	)
+
+	// Execute the root command, exiting with 0 on success or with the status of the kind of failure
+	return cmd.Run(rootCmd, os.Args[1:])
 }
 
 func main() {
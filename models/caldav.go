@@ -0,0 +1,44 @@
+package models
+
+// CalDAVSettings holds the CalDAV server used to sync tasks. Passwords are never stored.
+//
+// Fields:
+//   - ID: Always 1, so only one server is configured.
+//   - URL: The URL of the calendar home collection, holding the user's calendars.
+//   - Username: The username used to authenticate (optional).
+type CalDAVSettings struct {
+	ID       int    `gorm:"primaryKey" json:"id"`
+	URL      string `gorm:"not null"   json:"url"`
+	Username string `                  json:"username"`
+}
+
+// TableName returns the name of the table holding the CalDAV settings.
+func (CalDAVSettings) TableName() string {
+	return "caldav_settings"
+}
+
+// CalDAVItem records the state of a project or task at the last CalDAV sync, to detect the changes made
+// on either side since then. Projects are mapped to calendar collections and tasks to VTODO objects.
+//
+// Fields:
+//   - ID: The unique identifier for the record.
+//   - ItemType: The type of the item, "project" or "task".
+//   - ItemID: The local ID of the project or task.
+//   - UID: The UUID of the item, which tells a deleted item from a new one reusing its ID.
+//   - Href: The path of the calendar collection or object on the server.
+//   - ETag: The ETag of the object on the server at the last sync (empty for collections).
+//   - Hash: A hash of the local item at the last sync.
+type CalDAVItem struct {
+	ID       int    `gorm:"primaryKey"      json:"id"`
+	ItemType string `gorm:"not null"        json:"item_type"`
+	ItemID   int    `gorm:"not null"        json:"item_id"`
+	UID      string `gorm:"not null"        json:"uid"`
+	Href     string `gorm:"not null;unique" json:"href"`
+	ETag     string `gorm:"column:etag"     json:"etag"`
+	Hash     string `                       json:"hash"`
+}
+
+// TableName returns the name of the table holding the CalDAV sync state.
+func (CalDAVItem) TableName() string {
+	return "caldav_items"
+}
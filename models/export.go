@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// ExportSchemaVersion is the version of the JSON export format produced by this version of clido.
+// It must be increased whenever the format changes in a way older versions cannot read.
+const ExportSchemaVersion = 1
+
+// Export is the root document of a JSON export of the database.
+//
+// In a nested export, top-level projects are listed in Projects and contain their subprojects
+// and tasks, which in turn contain their subtasks; parent relationships are implied by nesting.
+// In a flat export, every project is listed in Projects and every task in Tasks, and parent
+// relationships are given by ParentID and ProjectID.
+//
+// Fields:
+//   - SchemaVersion: The version of the export format.
+//   - ExportedAt: The date and time when the export was produced.
+//   - Nested: Whether the export is nested or flat.
+//   - Projects: The exported projects.
+//   - Tasks: The exported tasks (flat exports only).
+type Export struct {
+	SchemaVersion int              `json:"schema_version"`
+	ExportedAt    time.Time        `json:"exported_at"`
+	Nested        bool             `json:"nested"`
+	Projects      []*ExportProject `json:"projects"`
+	Tasks         []*ExportTask    `json:"tasks,omitempty"`
+}
+
+// ExportProject is a project in a JSON export. IDs are the ones of the exported database and are
+// remapped on import, where projects also get new UUIDs.
+type ExportProject struct {
+	ID               int              `json:"id"`
+	UUID             string           `json:"uuid,omitempty"`
+	Name             string           `json:"name"`
+	Description      string           `json:"description"`
+	CreationDate     time.Time        `json:"creation_date"`
+	LastModifiedDate time.Time        `json:"last_modified_date"`
+	ParentID         *int             `json:"parent_id,omitempty"`
+	Subprojects      []*ExportProject `json:"subprojects,omitempty"`
+	Tasks            []*ExportTask    `json:"tasks,omitempty"`
+}
+
+// ExportTask is a task in a JSON export. IDs are the ones of the exported database and are
+// remapped on import, where tasks also get new UUIDs.
+type ExportTask struct {
+	ID              int           `json:"id"`
+	UUID            string        `json:"uuid,omitempty"`
+	Name            string        `json:"name"`
+	Description     string        `json:"description"`
+	ProjectID       int           `json:"project_id,omitempty"`
+	ParentID        *int          `json:"parent_id,omitempty"`
+	State           string        `json:"state"`
+	TaskCompleted   bool          `json:"task_completed"`
+	DueDate         *time.Time    `json:"due_date,omitempty"`
+	CompletionDate  *time.Time    `json:"completion_date,omitempty"`
+	CreationDate    time.Time     `json:"creation_date"`
+	LastUpdatedDate time.Time     `json:"last_updated_date"`
+	Priority        int           `json:"priority"`
+	Tags            string        `json:"tags,omitempty"`
+	Subtasks        []*ExportTask `json:"subtasks,omitempty"`
+}
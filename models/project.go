@@ -3,6 +3,7 @@ package models
 import (
 	"time"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -12,6 +13,8 @@ import (
 //
 // Fields:
 //   - ID: The unique identifier for the project.
+//   - UUID: The immutable, globally unique identifier of the project, shared by synced databases and
+//     identifying it in the change log, the sync repository and CalDAV (unique index).
 //   - Name: The name of the project, which must be unique and non-null.
 //   - Description: A description of the project (optional).
 //   - CreationDate: The date and time when the project was created (automatically set).
@@ -21,22 +24,31 @@ import (
 //   - SubProjects: A list of subprojects belonging to this project (not serialized to JSON).
 //   - Tasks: A list of tasks associated with this project (not serialized to JSON).
 type Project struct {
-	ID               int       `gorm:"primaryKey"                 json:"id"`
-	Name             string    `gorm:"unique;not null"            json:"name"`
-	Description      string    `                                  json:"description"`
-	CreationDate     time.Time `gorm:"not null"                   json:"creation_date"`
-	LastModifiedDate time.Time `gorm:"not null"                   json:"last_modified_date"`
-	ParentProjectID  *int      `                                  json:"parent_project_id,omitempty"`
-	ParentProject    *Project  `gorm:"foreignKey:ParentProjectID" json:"-"`
-	SubProjects      []Project `gorm:"foreignKey:ParentProjectID" json:"-"`
-	Tasks            []Task    `gorm:"foreignKey:ProjectID"       json:"-"`
+	ID               int       `gorm:"primaryKey"                      json:"id"`
+	UUID             string    `gorm:"not null;default:'';uniqueIndex" json:"uuid"`
+	Name             string    `gorm:"unique;not null"                 json:"name"`
+	Description      string    `                                       json:"description"`
+	CreationDate     time.Time `gorm:"not null"                        json:"creation_date"`
+	LastModifiedDate time.Time `gorm:"not null"                        json:"last_modified_date"`
+	ParentProjectID  *int      `                                       json:"parent_project_id,omitempty"`
+	ParentProject    *Project  `gorm:"foreignKey:ParentProjectID"      json:"-"`
+	SubProjects      []Project `gorm:"foreignKey:ParentProjectID"      json:"-"`
+	Tasks            []Task    `gorm:"foreignKey:ProjectID"            json:"-"`
 }
 
-// BeforeCreate is a GORM hook that sets the CreationDate and LastModifiedDate fields
-// to the current time before a new project is inserted into the database.
+// BeforeCreate is a GORM hook that gives a new project a UUID and sets the CreationDate and
+// LastModifiedDate fields to the current time, unless already set, before it is inserted into the database.
 func (p *Project) BeforeCreate(_ *gorm.DB) error {
-	p.CreationDate = time.Now()
-	p.LastModifiedDate = time.Now()
+	if p.UUID == "" {
+		p.UUID = uuid.NewString()
+	}
+	// Dates are kept when already set, e.g. when importing exported data
+	if p.CreationDate.IsZero() {
+		p.CreationDate = time.Now()
+	}
+	if p.LastModifiedDate.IsZero() {
+		p.LastModifiedDate = time.Now()
+	}
 	return nil
 }
 
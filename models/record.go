@@ -0,0 +1,108 @@
+package models
+
+import (
+	"strconv"
+	"time"
+)
+
+// Item types of records.
+const (
+	SyncItemProject = "project"
+	SyncItemTask    = "task"
+)
+
+// Fields of the records of projects and tasks, their text representation used to sync and replicate them.
+// Records are identified by the UUID of their item, and references to other projects and tasks are given
+// by their UUIDs, as local IDs differ between databases.
+const (
+	RecordFieldName             = "name"
+	RecordFieldDescription      = "description"
+	RecordFieldParent           = "parent"
+	RecordFieldProject          = "project"
+	RecordFieldState            = "state"
+	RecordFieldTaskCompleted    = "task_completed"
+	RecordFieldPriority         = "priority"
+	RecordFieldTags             = "tags"
+	RecordFieldDueDate          = "due_date"
+	RecordFieldCompletionDate   = "completion_date"
+	RecordFieldCreationDate     = "creation_date"
+	RecordFieldLastModifiedDate = "last_modified_date"
+	RecordFieldLastUpdatedDate  = "last_updated_date"
+)
+
+// ProjectRecord returns the record of a project: its fields as strings, with empty strings for
+// missing values.
+func ProjectRecord(project *Project, parentKey string) map[string]string {
+	return map[string]string{
+		RecordFieldName:             project.Name,
+		RecordFieldDescription:      project.Description,
+		RecordFieldParent:           parentKey,
+		RecordFieldCreationDate:     FormatRecordTime(&project.CreationDate),
+		RecordFieldLastModifiedDate: FormatRecordTime(&project.LastModifiedDate),
+	}
+}
+
+// TaskRecord returns the record of a task: its fields as strings, with empty strings for missing values.
+func TaskRecord(task *Task, projectKey, parentKey string) map[string]string {
+	return map[string]string{
+		RecordFieldName:            task.Name,
+		RecordFieldDescription:     task.Description,
+		RecordFieldProject:         projectKey,
+		RecordFieldParent:          parentKey,
+		RecordFieldState:           task.State,
+		RecordFieldTaskCompleted:   strconv.FormatBool(task.TaskCompleted),
+		RecordFieldPriority:        strconv.Itoa(task.Priority),
+		RecordFieldTags:            task.Tags,
+		RecordFieldDueDate:         FormatRecordTime(task.DueDate),
+		RecordFieldCompletionDate:  FormatRecordTime(task.CompletionDate),
+		RecordFieldCreationDate:    FormatRecordTime(&task.CreationDate),
+		RecordFieldLastUpdatedDate: FormatRecordTime(&task.LastUpdatedDate),
+	}
+}
+
+// ApplyProjectRecord sets the fields of a project, except its UUID and parent, from a record.
+func ApplyProjectRecord(project *Project, record map[string]string) {
+	project.Name = record[RecordFieldName]
+	project.Description = record[RecordFieldDescription]
+	project.CreationDate = parseRecordTimeOrNow(record[RecordFieldCreationDate])
+	project.LastModifiedDate = parseRecordTimeOrNow(record[RecordFieldLastModifiedDate])
+}
+
+// ApplyTaskRecord sets the fields of a task, except its UUID, project and parent, from a record.
+func ApplyTaskRecord(task *Task, record map[string]string) {
+	task.Name = record[RecordFieldName]
+	task.Description = record[RecordFieldDescription]
+	task.State = record[RecordFieldState]
+	task.TaskCompleted, _ = strconv.ParseBool(record[RecordFieldTaskCompleted])
+	task.Priority, _ = strconv.Atoi(record[RecordFieldPriority])
+	task.Tags = record[RecordFieldTags]
+	task.DueDate = ParseRecordTime(record[RecordFieldDueDate])
+	task.CompletionDate = ParseRecordTime(record[RecordFieldCompletionDate])
+	task.CreationDate = parseRecordTimeOrNow(record[RecordFieldCreationDate])
+	task.LastUpdatedDate = parseRecordTimeOrNow(record[RecordFieldLastUpdatedDate])
+}
+
+// FormatRecordTime formats a time in UTC for records, or returns an empty string for nil.
+func FormatRecordTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// ParseRecordTime parses a time of a record, returning nil for empty or invalid values.
+func ParseRecordTime(value string) *time.Time {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// parseRecordTimeOrNow parses a time of a record, returning the current time for empty or invalid values.
+func parseRecordTimeOrNow(value string) time.Time {
+	if t := ParseRecordTime(value); t != nil {
+		return *t
+	}
+	return time.Now().UTC()
+}
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// ReplicaBundleFormatVersion is the version of the format of replication bundles.
+const ReplicaBundleFormatVersion = 1
+
+// ChangeFieldDeleted is the pseudo-field of change operations recording that an item was deleted.
+const ChangeFieldDeleted = "deleted"
+
+// ChangeOperation is an entry of the change log: the value given to one field of a project or task.
+// The state of an item is the value of each field in its latest operation, so databases holding the same
+// operations converge to the same state, whatever the order in which they received them.
+//
+// Fields:
+//   - ID: The local identifier of the operation.
+//   - HLC: The hybrid logical clock timestamp of the operation, unique and ordering operations globally.
+//   - ItemType: The type of the item, "project" or "task".
+//   - ItemKey: The UUID of the item, shared by all databases.
+//   - Field: The name of the field of the item's record, or "deleted" when the item was deleted.
+//   - Value: The new value of the field.
+type ChangeOperation struct {
+	ID       int    `gorm:"primaryKey"               json:"-"`
+	HLC      string `gorm:"column:hlc;not null;unique" json:"hlc"`
+	ItemType string `gorm:"not null"                 json:"item_type"`
+	ItemKey  string `gorm:"not null;index"           json:"item_key"`
+	Field    string `gorm:"not null"                 json:"field"`
+	Value    string `                                json:"value"`
+}
+
+// TableName returns the name of the table holding the change log.
+func (ChangeOperation) TableName() string {
+	return "change_log"
+}
+
+// ReplicaState holds the identity and clock of the database as a replica.
+//
+// Fields:
+//   - ID: Always 1, as a database is a single replica.
+//   - Node: The random identifier of the replica, part of the timestamps of its operations.
+//   - Clock: The last timestamp produced or observed by the replica's hybrid logical clock.
+type ReplicaState struct {
+	ID    int    `gorm:"primaryKey" json:"id"`
+	Node  string `gorm:"not null"   json:"node"`
+	Clock string `                  json:"clock"`
+}
+
+// TableName returns the name of the table holding the replica state.
+func (ReplicaState) TableName() string {
+	return "replica"
+}
+
+// ReplicaBundle is the exported change log of a database, imported by other replicas to converge with it.
+//
+// Fields:
+//   - FormatVersion: The version of the bundle format.
+//   - Node: The replica that exported the bundle.
+//   - ExportedAt: The time of the export.
+//   - Operations: The operations of the change log, oldest first.
+type ReplicaBundle struct {
+	FormatVersion int                `json:"format_version"`
+	Node          string             `json:"node"`
+	ExportedAt    time.Time          `json:"exported_at"`
+	Operations    []*ChangeOperation `json:"operations"`
+}
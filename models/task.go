@@ -3,6 +3,7 @@ package models
 import (
 	"time"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -12,41 +13,56 @@ import (
 //
 // Fields:
 //   - ID: The unique identifier for the task.
+//   - UUID: The immutable, globally unique identifier of the task, shared by synced databases and
+//     identifying it in the change log, the sync repository and CalDAV (unique index).
 //   - Name: The name of the task, which is required.
 //   - Description: A description of the task (optional).
 //   - ProjectID: The ID of the project to which the task belongs (required).
 //   - Project: A reference to the project this task belongs to (not serialized to JSON).
-//   - TaskCompleted: A boolean indicating whether the task is completed (required).
+//   - State: The workflow state of the task, e.g. "todo", "in-progress" or "done" (required).
+//   - TaskCompleted: A boolean indicating whether the task is in the "done" state (required).
 //   - DueDate: The due date for the task (optional).
-//   - CompletionDate: The date when the task was completed (optional, set when TaskCompleted is true).
+//   - CompletionDate: The date when the task entered a terminal state (optional, e.g. "done" or "cancelled").
 //   - CreationDate: The date and time when the task was created (automatically set).
 //   - LastUpdatedDate: The date and time when the task was last updated (automatically set).
 //   - Priority: The priority level of the task, represented by an integer (1: High, 2: Medium, 3: Low, 4: None).
+//   - Tags: The comma-separated tags of the task, contexts starting with '@', e.g. "billing,@home" (optional).
 //   - ParentTaskID: The ID of the parent task, if this task is a subtask (optional).
 //   - ParentTask: A reference to the parent task (not serialized to JSON).
 //   - SubTasks: A list of subtasks belonging to this task (not serialized to JSON).
 type Task struct {
-	ID              int        `gorm:"primaryKey"              json:"id"`
-	Name            string     `gorm:"not null"                json:"name"`
-	Description     string     `                               json:"description"`
-	ProjectID       int        `gorm:"not null"                json:"project_id"`
-	Project         Project    `gorm:"foreignKey:ProjectID"    json:"-"`
-	TaskCompleted   bool       `gorm:"not null"                json:"task_completed"`
-	DueDate         *time.Time `                               json:"due_date,omitempty"`
-	CompletionDate  *time.Time `                               json:"completion_date,omitempty"`
-	CreationDate    time.Time  `gorm:"not null"                json:"creation_date"`
-	LastUpdatedDate time.Time  `gorm:"not null"                json:"last_updated_date"`
-	Priority        int        `gorm:"not null;default:4"      json:"priority"`
-	ParentTaskID    *int       `                               json:"parent_task_id,omitempty"`
-	ParentTask      *Task      `gorm:"foreignKey:ParentTaskID" json:"-"`
-	SubTasks        []Task     `gorm:"foreignKey:ParentTaskID" json:"-"`
+	ID              int        `gorm:"primaryKey"                      json:"id"`
+	UUID            string     `gorm:"not null;default:'';uniqueIndex" json:"uuid"`
+	Name            string     `gorm:"not null"                        json:"name"`
+	Description     string     `                                       json:"description"`
+	ProjectID       int        `gorm:"not null"                        json:"project_id"`
+	Project         Project    `gorm:"foreignKey:ProjectID"            json:"-"`
+	State           string     `gorm:"not null;default:todo"           json:"state"`
+	TaskCompleted   bool       `gorm:"not null"                        json:"task_completed"`
+	DueDate         *time.Time `                                       json:"due_date,omitempty"`
+	CompletionDate  *time.Time `                                       json:"completion_date,omitempty"`
+	CreationDate    time.Time  `gorm:"not null"                        json:"creation_date"`
+	LastUpdatedDate time.Time  `gorm:"not null"                        json:"last_updated_date"`
+	Priority        int        `gorm:"not null;default:4"              json:"priority"`
+	Tags            string     `gorm:"not null;default:''"             json:"tags"`
+	ParentTaskID    *int       `                                       json:"parent_task_id,omitempty"`
+	ParentTask      *Task      `gorm:"foreignKey:ParentTaskID"         json:"-"`
+	SubTasks        []Task     `gorm:"foreignKey:ParentTaskID"         json:"-"`
 }
 
-// BeforeCreate is a GORM hook that sets the CreationDate and LastUpdatedDate fields
-// to the current time before a new task is inserted into the database.
+// BeforeCreate is a GORM hook that gives a new task a UUID and sets the CreationDate and LastUpdatedDate
+// fields to the current time, unless already set, before it is inserted into the database.
 func (t *Task) BeforeCreate(_ *gorm.DB) error {
-	t.CreationDate = time.Now()
-	t.LastUpdatedDate = time.Now()
+	if t.UUID == "" {
+		t.UUID = uuid.NewString()
+	}
+	// Dates are kept when already set, e.g. when importing exported data
+	if t.CreationDate.IsZero() {
+		t.CreationDate = time.Now()
+	}
+	if t.LastUpdatedDate.IsZero() {
+		t.LastUpdatedDate = time.Now()
+	}
 	return nil
 }
 
@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+// Statuses of webhook deliveries.
+const (
+	DeliveryPending   = "pending"   // Not delivered yet, attempted again once NextAttempt is reached
+	DeliveryDelivered = "delivered" // Accepted by the endpoint with a 2xx status
+	DeliveryFailed    = "failed"    // Given up after too many attempts
+)
+
+// Webhook is an HTTP endpoint notified of the changes of projects and tasks, for the whole workspace or for
+// a single project.
+//
+// Fields:
+//   - ID: The unique identifier of the webhook.
+//   - URL: The URL the changes are posted to.
+//   - Secret: The key of the HMAC-SHA256 signature of the payloads (not serialized to JSON).
+//   - ProjectID: The project whose changes, and the changes of its tasks, are posted (optional, all if empty).
+//   - Events: The comma-separated events posted, e.g. "on-create,on-complete" (all if empty).
+//   - CreationDate: The date and time when the webhook was added (automatically set).
+type Webhook struct {
+	ID           int       `gorm:"primaryKey"          json:"id"`
+	URL          string    `gorm:"column:url;not null" json:"url"`
+	Secret       string    `gorm:"not null"            json:"-"`
+	ProjectID    *int      `                           json:"project_id,omitempty"`
+	Events       string    `                           json:"events"`
+	CreationDate time.Time `gorm:"not null"            json:"creation_date"`
+}
+
+// TableName returns the name of the table holding the webhooks.
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+// WebhookDelivery is a change posted, or to be posted, to a webhook. Deliveries are queued with the change
+// they describe, so failed deliveries are attempted again by later runs.
+//
+// Fields:
+//   - ID: The unique identifier of the delivery.
+//   - UUID: The identifier sent with the delivery, the same for every attempt.
+//   - WebhookID: The ID of the webhook the change is posted to.
+//   - Event: The event of the change, e.g. "on-modify".
+//   - ItemType: The type of the changed item, "project" or "task".
+//   - ItemID: The ID of the changed item.
+//   - Payload: The JSON document posted.
+//   - Status: The status of the delivery: "pending", "delivered" or "failed".
+//   - Attempts: The number of attempts made.
+//   - ResponseCode: The HTTP status of the last attempt (0 if no response was received).
+//   - LastError: Why the last attempt failed (empty if it succeeded).
+//   - NextAttempt: The date and time after which a pending delivery is attempted again.
+//   - CreationDate: The date and time when the change was queued (automatically set).
+//   - DeliveryDate: The date and time when the endpoint accepted the change (optional).
+type WebhookDelivery struct {
+	ID           int        `gorm:"primaryKey" json:"id"`
+	UUID         string     `gorm:"not null"   json:"uuid"`
+	WebhookID    int        `gorm:"not null"   json:"webhook_id"`
+	Event        string     `gorm:"not null"   json:"event"`
+	ItemType     string     `gorm:"not null"   json:"item_type"`
+	ItemID       int        `gorm:"not null"   json:"item_id"`
+	Payload      string     `gorm:"not null"   json:"payload"`
+	Status       string     `gorm:"not null"   json:"status"`
+	Attempts     int        `gorm:"not null"   json:"attempts"`
+	ResponseCode int        `                  json:"response_code"`
+	LastError    string     `                  json:"last_error,omitempty"`
+	NextAttempt  time.Time  `gorm:"not null"   json:"next_attempt"`
+	CreationDate time.Time  `gorm:"not null"   json:"creation_date"`
+	DeliveryDate *time.Time `                  json:"delivery_date,omitempty"`
+}
+
+// TableName returns the name of the table holding the webhook deliveries.
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
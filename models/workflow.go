@@ -0,0 +1,140 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"gorm.io/gorm"
+)
+
+// Default task workflow states.
+const (
+	StateTodo       = "todo"
+	StateInProgress = "in-progress"
+	StateWaiting    = "waiting"
+	StateReview     = "review"
+	StateDone       = "done"
+	StateCancelled  = "cancelled"
+)
+
+// ErrInvalidWorkflow is returned when a workflow definition is inconsistent.
+var ErrInvalidWorkflow = errcode.New(errcode.Validation, "invalid workflow")
+
+// Workflow describes the states a task can be in and the transitions allowed between them.
+//
+// Fields:
+//   - InitialState: The state given to new and reopened tasks.
+//   - States: All the states a task can be in. It must contain the "done" state.
+//   - Terminal: The states in which a task is closed; entering one sets the task's CompletionDate.
+//   - Transitions: For each state, the states a task can move to.
+//   - WIPLimits: The maximum number of tasks each state should hold (optional, used by the board).
+type Workflow struct {
+	InitialState string              `json:"initial_state"`
+	States       []string            `json:"states"`
+	Terminal     []string            `json:"terminal"`
+	Transitions  map[string][]string `json:"transitions"`
+	WIPLimits    map[string]int      `json:"wip_limits,omitempty"`
+}
+
+// DefaultWorkflow returns the workflow used when no custom workflow is configured:
+// todo, in-progress, waiting, review, done and cancelled.
+func DefaultWorkflow() *Workflow {
+	return &Workflow{
+		InitialState: StateTodo,
+		States: []string{
+			StateTodo, StateInProgress, StateWaiting, StateReview, StateDone, StateCancelled,
+		},
+		Terminal: []string{StateDone, StateCancelled},
+		Transitions: map[string][]string{
+			StateTodo:       {StateInProgress, StateWaiting, StateDone, StateCancelled},
+			StateInProgress: {StateTodo, StateWaiting, StateReview, StateDone, StateCancelled},
+			StateWaiting:    {StateTodo, StateInProgress, StateDone, StateCancelled},
+			StateReview:     {StateInProgress, StateDone, StateCancelled},
+			StateDone:       {StateTodo, StateInProgress},
+			StateCancelled:  {StateTodo},
+		},
+	}
+}
+
+// Validate checks that the workflow is consistent: every referenced state must be declared,
+// and the initial state and the "done" state must exist, with "done" being terminal.
+func (w *Workflow) Validate() error {
+	seen := make(map[string]bool, len(w.States))
+	for _, state := range w.States {
+		if state == "" || seen[state] {
+			return fmt.Errorf("%w: states must be unique and non-empty", ErrInvalidWorkflow)
+		}
+		seen[state] = true
+	}
+
+	if !seen[w.InitialState] {
+		return fmt.Errorf("%w: unknown initial state '%s'", ErrInvalidWorkflow, w.InitialState)
+	}
+	if !seen[StateDone] || !w.IsTerminal(StateDone) {
+		return fmt.Errorf("%w: the '%s' state must exist and be terminal", ErrInvalidWorkflow, StateDone)
+	}
+
+	for _, state := range w.Terminal {
+		if !seen[state] {
+			return fmt.Errorf("%w: unknown terminal state '%s'", ErrInvalidWorkflow, state)
+		}
+	}
+	for from, targets := range w.Transitions {
+		if !seen[from] {
+			return fmt.Errorf("%w: unknown state '%s' in transitions", ErrInvalidWorkflow, from)
+		}
+		for _, to := range targets {
+			if !seen[to] {
+				return fmt.Errorf("%w: unknown state '%s' in transitions", ErrInvalidWorkflow, to)
+			}
+		}
+	}
+
+	for state, limit := range w.WIPLimits {
+		if !seen[state] || limit < 1 {
+			return fmt.Errorf("%w: invalid WIP limit for state '%s'", ErrInvalidWorkflow, state)
+		}
+	}
+
+	return nil
+}
+
+// HasState reports whether the workflow declares the given state.
+func (w *Workflow) HasState(state string) bool {
+	return slices.Contains(w.States, state)
+}
+
+// IsTerminal reports whether the given state closes a task.
+func (w *Workflow) IsTerminal(state string) bool {
+	return slices.Contains(w.Terminal, state)
+}
+
+// CanTransition reports whether a task may move from one state to another.
+func (w *Workflow) CanTransition(from, to string) bool {
+	return slices.Contains(w.Transitions[from], to)
+}
+
+// TaskTransition records a change of state of a task.
+//
+// Fields:
+//   - ID: The unique identifier for the transition.
+//   - TaskID: The ID of the task that changed state.
+//   - FromState: The state the task was in before the transition.
+//   - ToState: The state the task moved to.
+//   - TransitionDate: The date and time of the transition (automatically set).
+type TaskTransition struct {
+	ID             int       `gorm:"primaryKey"     json:"id"`
+	TaskID         int       `gorm:"not null;index" json:"task_id"`
+	FromState      string    `gorm:"not null"       json:"from_state"`
+	ToState        string    `gorm:"not null"       json:"to_state"`
+	TransitionDate time.Time `gorm:"not null"       json:"transition_date"`
+}
+
+// BeforeCreate is a GORM hook that sets the TransitionDate field to the current time
+// before a new transition is inserted into the database.
+func (tt *TaskTransition) BeforeCreate(_ *gorm.DB) error {
+	tt.TransitionDate = time.Now()
+	return nil
+}
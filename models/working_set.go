@@ -0,0 +1,20 @@
+package models
+
+// WorkingSetEntry gives a short display ID to an open task, so it can be typed instead of its persistent ID.
+// Listing tasks numbers the open tasks missing from the working set with the smallest free display IDs,
+// freeing those of closed and removed tasks, and renumbering it compacts it.
+//
+// Fields:
+//   - DisplayID: The display ID of the task, numbered from 1.
+//   - TaskID: The persistent ID of the task.
+//   - TaskUUID: The UUID of the task, which tells a removed task from a new one reusing its ID.
+type WorkingSetEntry struct {
+	DisplayID int    `gorm:"primaryKey" json:"display_id"`
+	TaskID    int    `gorm:"not null"   json:"task_id"`
+	TaskUUID  string `gorm:"not null"   json:"task_uuid"`
+}
+
+// TableName returns the name of the table holding the working set.
+func (WorkingSetEntry) TableName() string {
+	return "working_set"
+}
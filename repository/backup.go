@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/d4r1us-drk/clido/internal/errcode"
+)
+
+// Constants for database backups.
+const (
+	BackupDirName       = "backups"             // Directory, next to the database, holding the backups
+	BackupFileExtension = ".db"                 // Extension of backup files
+	AutoBackupPrefix    = "auto-"               // Prefix of automatic backup file names
+	MaxAutoBackups      = 10                    // Number of automatic backups kept before the oldest are removed
+	backupTimeLayout    = "20060102-150405.000" // Timestamp layout used in backup file names
+	sqliteHeader        = "SQLite format 3\x00" // Magic header of SQLite database files
+)
+
+// Error constants for backup and restore operations.
+var (
+	ErrBackupExists       = errcode.New(errcode.Conflict, "backup file already exists")
+	ErrInvalidBackup      = errcode.New(errcode.Validation, "not a valid clido database")
+	ErrIncompatibleBackup = errcode.New(errcode.Conflict, "backup was created by a newer version of clido")
+)
+
+// BackupInfo describes a backup file.
+//
+// Fields:
+//   - Name: The file name of the backup.
+//   - Path: The full path of the backup.
+//   - Size: The size of the backup in bytes.
+//   - CreationDate: The date and time when the backup was written.
+//   - Automatic: Whether the backup was created automatically.
+//   - SchemaVersion: The version of the latest migration applied to the backup (empty if unreadable).
+type BackupInfo struct {
+	Name          string    `json:"name"`
+	Path          string    `json:"path"`
+	Size          int64     `json:"size"`
+	CreationDate  time.Time `json:"creation_date"`
+	Automatic     bool      `json:"automatic"`
+	SchemaVersion string    `json:"schema_version"`
+}
+
+// BackupDir returns the directory holding the backups of the database.
+func (r *Repository) BackupDir() string {
+	return filepath.Join(filepath.Dir(r.dbPath), BackupDirName)
+}
+
+// Backup writes a consistent copy of the database to the given path using VACUUM INTO, which is safe
+// while the database is in use. When path is empty, the backup is written to the backup directory.
+// It returns the path of the backup.
+func (r *Repository) Backup(path string) (string, error) {
+	if path == "" {
+		path = filepath.Join(r.BackupDir(), "clido-"+time.Now().Format(backupTimeLayout)+BackupFileExtension)
+	}
+	return path, r.vacuumInto(path)
+}
+
+// AutoBackup writes a backup of the database to the backup directory before a risky operation
+// (e.g. "migrate" or "remove"), then removes the oldest automatic backups beyond MaxAutoBackups.
+// It returns the path of the backup.
+func (r *Repository) AutoBackup(reason string) (string, error) {
+	name := AutoBackupPrefix + reason + "-" + time.Now().Format(backupTimeLayout) + BackupFileExtension
+	path := filepath.Join(r.BackupDir(), name)
+	if err := r.vacuumInto(path); err != nil {
+		return "", err
+	}
+
+	return path, r.rotateAutoBackups()
+}
+
+// ListBackups returns the backups found in the backup directory, newest first.
+func (r *Repository) ListBackups() ([]*BackupInfo, error) {
+	entries, err := os.ReadDir(r.BackupDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading backup directory: %w", err)
+	}
+
+	var backups []*BackupInfo
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != BackupFileExtension {
+			continue
+		}
+		info, infoErr := entry.Info()
+		if infoErr != nil {
+			return nil, infoErr
+		}
+
+		path := filepath.Join(r.BackupDir(), entry.Name())
+		version, _ := r.backupSchemaVersion(path)
+		backups = append(backups, &BackupInfo{
+			Name:          entry.Name(),
+			Path:          path,
+			Size:          info.Size(),
+			CreationDate:  info.ModTime(),
+			Automatic:     strings.HasPrefix(entry.Name(), AutoBackupPrefix),
+			SchemaVersion: version,
+		})
+	}
+
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].CreationDate.After(backups[j].CreationDate)
+	})
+	return backups, nil
+}
+
+// ResolveBackupPath returns the path of a backup given either a path or the name of a file
+// in the backup directory.
+func (r *Repository) ResolveBackupPath(nameOrPath string) string {
+	if _, err := os.Stat(nameOrPath); err == nil {
+		return nameOrPath
+	}
+	if candidate := filepath.Join(r.BackupDir(), nameOrPath); fileExists(candidate) {
+		return candidate
+	}
+	return nameOrPath
+}
+
+// Restore replaces the database with the backup at the given path. The backup must be a clido database
+// whose schema is not newer than this version of clido supports; older schemas are migrated after restoring.
+// An automatic backup of the current database is written first and its path is returned.
+func (r *Repository) Restore(path string) (string, error) {
+	version, err := r.backupSchemaVersion(path)
+	if err != nil {
+		return "", err
+	}
+	if compareVersions(version, r.migrator.LatestVersion()) > 0 {
+		return "", fmt.Errorf("%w: schema version %s (supported: up to %s)",
+			ErrIncompatibleBackup, version, r.migrator.LatestVersion())
+	}
+
+	safetyBackup, err := r.AutoBackup("restore")
+	if err != nil {
+		return "", fmt.Errorf("error backing up the current database: %w", err)
+	}
+
+	// Copy the backup next to the database first so the replacement itself is atomic
+	tmpPath := r.dbPath + ".restore"
+	if copyErr := copyFile(path, tmpPath); copyErr != nil {
+		return safetyBackup, copyErr
+	}
+
+	if closeErr := r.Close(); closeErr != nil {
+		os.Remove(tmpPath)
+		return safetyBackup, closeErr
+	}
+	renameErr := os.Rename(tmpPath, r.dbPath)
+
+	// Reopen the database, whether or not the replacement succeeded
+	db, openErr := openDatabase(r.dbPath)
+	if openErr != nil {
+		return safetyBackup, openErr
+	}
+	r.db = db
+	r.clock.clock = nil
+	if renameErr != nil {
+		os.Remove(tmpPath)
+		return safetyBackup, fmt.Errorf("error replacing the database: %w", renameErr)
+	}
+
+	return safetyBackup, r.migratePending()
+}
+
+// vacuumInto writes a compacted, consistent copy of the database to the given path.
+func (r *Repository) vacuumInto(path string) error {
+	if fileExists(path) {
+		return fmt.Errorf("%w: %s", ErrBackupExists, path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("error creating backup directory: %w", err)
+	}
+
+	if err := r.db.Exec("VACUUM INTO ?", path).Error; err != nil {
+		return fmt.Errorf("error writing backup: %w", err)
+	}
+	return nil
+}
+
+// rotateAutoBackups removes the oldest automatic backups, keeping at most MaxAutoBackups of them.
+func (r *Repository) rotateAutoBackups() error {
+	backups, err := r.ListBackups()
+	if err != nil {
+		return err
+	}
+
+	kept := 0
+	for _, backup := range backups {
+		if !backup.Automatic {
+			continue
+		}
+		kept++
+		if kept > MaxAutoBackups {
+			if removeErr := os.Remove(backup.Path); removeErr != nil {
+				return fmt.Errorf("error removing old backup: %w", removeErr)
+			}
+		}
+	}
+	return nil
+}
+
+// backupSchemaVersion checks that the file at the given path is a clido database and returns
+// the version of the latest migration applied to it.
+func (r *Repository) backupSchemaVersion(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("error opening backup: %w", err)
+	}
+	header := make([]byte, len(sqliteHeader))
+	_, readErr := io.ReadFull(file, header)
+	file.Close()
+	if readErr != nil || !bytes.Equal(header, []byte(sqliteHeader)) {
+		return "", ErrInvalidBackup
+	}
+
+	db, err := openDatabase(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if sqlDB, dbErr := db.DB(); dbErr == nil {
+			sqlDB.Close()
+		}
+	}()
+
+	var integrity string
+	if checkErr := db.Raw("PRAGMA quick_check").Scan(&integrity).Error; checkErr != nil || integrity != "ok" {
+		return "", fmt.Errorf("%w: integrity check failed", ErrInvalidBackup)
+	}
+
+	version := r.migrator.CurrentVersion(db)
+	if version == "" {
+		return "", fmt.Errorf("%w: no schema version found", ErrInvalidBackup)
+	}
+	return version, nil
+}
+
+// copyFile copies the file at src to dst, replacing dst if it exists.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("error opening backup: %w", err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("error copying backup: %w", err)
+	}
+	if _, copyErr := io.Copy(out, in); copyErr != nil {
+		out.Close()
+		os.Remove(dst)
+		return fmt.Errorf("error copying backup: %w", copyErr)
+	}
+	return out.Close()
+}
+
+// fileExists reports whether a file exists at the given path.
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
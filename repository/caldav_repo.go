@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/d4r1us-drk/clido/models"
+	"gorm.io/gorm"
+)
+
+// GetCalDAVSettings retrieves the CalDAV server settings, or nil if none are saved.
+func (r *Repository) GetCalDAVSettings() (*models.CalDAVSettings, error) {
+	var settings models.CalDAVSettings
+	err := r.db.First(&settings, 1).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// SaveCalDAVSettings saves the CalDAV server settings.
+func (r *Repository) SaveCalDAVSettings(settings *models.CalDAVSettings) error {
+	settings.ID = 1
+	return r.db.Save(settings).Error
+}
+
+// GetCalDAVItems retrieves the CalDAV sync state of all projects and tasks.
+func (r *Repository) GetCalDAVItems() ([]*models.CalDAVItem, error) {
+	var items []*models.CalDAVItem
+	err := r.db.Order("id").Find(&items).Error
+	return items, err
+}
+
+// SaveCalDAVItem creates or updates the CalDAV sync state of a project or task.
+func (r *Repository) SaveCalDAVItem(item *models.CalDAVItem) error {
+	return r.db.Save(item).Error
+}
+
+// DeleteCalDAVItem removes the CalDAV sync state of a project or task.
+func (r *Repository) DeleteCalDAVItem(id int) error {
+	return r.db.Delete(&models.CalDAVItem{}, id).Error
+}
+
+// DeleteCalDAVItems removes the whole CalDAV sync state, e.g. when switching to another server.
+func (r *Repository) DeleteCalDAVItems() error {
+	return r.db.Where("1 = 1").Delete(&models.CalDAVItem{}).Error
+}
@@ -0,0 +1,262 @@
+package repository
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strconv"
+
+	"github.com/d4r1us-drk/clido/internal/hlc"
+	"github.com/d4r1us-drk/clido/models"
+	"gorm.io/gorm"
+)
+
+// replicaClock holds the hybrid logical clock of the database, loaded on first use.
+type replicaClock struct {
+	clock *hlc.Clock
+}
+
+// WithoutChangeLog returns a repository whose changes are not recorded in the change log, used to apply
+// the state replicated from other databases.
+func (r *Repository) WithoutChangeLog() *Repository {
+	unlogged := *r
+	unlogged.unlogged = true
+	return &unlogged
+}
+
+// ReplicaNode returns the identifier of the database as a replica, creating it on first use.
+func (r *Repository) ReplicaNode() (string, error) {
+	clock, err := r.replicaClock()
+	if err != nil {
+		return "", err
+	}
+	return clock.Node(), nil
+}
+
+// GetChangeLog retrieves every operation of the change log, oldest first.
+func (r *Repository) GetChangeLog() ([]*models.ChangeOperation, error) {
+	var operations []*models.ChangeOperation
+	err := r.db.Order("hlc").Find(&operations).Error
+	return operations, err
+}
+
+// ImportChangeOperations adds the operations received from another replica that are not in the change log
+// yet, and advances the clock past them so later local changes are ordered after them. It returns the
+// number of operations added.
+func (r *Repository) ImportChangeOperations(operations []*models.ChangeOperation) (int, error) {
+	clock, err := r.replicaClock()
+	if err != nil {
+		return 0, err
+	}
+
+	var known []string
+	if err = r.db.Model(&models.ChangeOperation{}).Pluck("hlc", &known).Error; err != nil {
+		return 0, err
+	}
+	seen := make(map[string]bool, len(known))
+	for _, timestamp := range known {
+		seen[timestamp] = true
+	}
+
+	imported := 0
+	for _, operation := range operations {
+		timestamp, parseErr := hlc.Parse(operation.HLC)
+		if parseErr != nil {
+			return imported, parseErr
+		}
+		clock.Observe(timestamp)
+		if seen[operation.HLC] {
+			continue
+		}
+		seen[operation.HLC] = true
+
+		entry := *operation
+		entry.ID = 0
+		if err = r.db.Create(&entry).Error; err != nil {
+			return imported, err
+		}
+		imported++
+	}
+	return imported, r.saveReplicaClock()
+}
+
+// LogAllChanges records the fields of every project and task that differ from their state in the change log,
+// e.g. for items created before the change log existed.
+func (r *Repository) LogAllChanges() error {
+	projects, err := r.GetAllProjects()
+	if err != nil {
+		return err
+	}
+	for _, project := range projects {
+		if err = r.logProject(project); err != nil {
+			return err
+		}
+	}
+
+	tasks, err := r.GetAllTasks()
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		if err = r.logTask(task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// logProject records the fields of a project that differ from its state in the change log.
+func (r *Repository) logProject(project *models.Project) error {
+	if r.unlogged {
+		return nil
+	}
+	parentKey := ""
+	if project.ParentProjectID != nil {
+		key, err := r.itemKey(&models.Project{}, *project.ParentProjectID)
+		if err != nil {
+			return err
+		}
+		parentKey = key
+	}
+	return r.logChanges(models.SyncItemProject, project.UUID, models.ProjectRecord(project, parentKey))
+}
+
+// logTask records the fields of a task that differ from its state in the change log.
+func (r *Repository) logTask(task *models.Task) error {
+	if r.unlogged {
+		return nil
+	}
+	projectKey, err := r.itemKey(&models.Project{}, task.ProjectID)
+	if err != nil {
+		return err
+	}
+	parentKey := ""
+	if task.ParentTaskID != nil {
+		parentKey, err = r.itemKey(&models.Task{}, *task.ParentTaskID)
+		if err != nil {
+			return err
+		}
+	}
+	return r.logChanges(models.SyncItemTask, task.UUID, models.TaskRecord(task, projectKey, parentKey))
+}
+
+// logDeletion records the deletion of a project or task, given its model and ID.
+func (r *Repository) logDeletion(itemType string, model any, itemID int) error {
+	if r.unlogged {
+		return nil
+	}
+	key, err := r.itemKey(model, itemID)
+	if err != nil || key == "" {
+		return err
+	}
+	return r.logOperation(itemType, key, models.ChangeFieldDeleted, strconv.FormatBool(true))
+}
+
+// logChanges records an operation for each field of an item's record whose value differs from the latest
+// one in the change log. Fields are logged in a fixed order so their timestamps are reproducible.
+func (r *Repository) logChanges(itemType, key string, record map[string]string) error {
+	var operations []*models.ChangeOperation
+	if err := r.db.Where("item_key = ?", key).Order("hlc").Find(&operations).Error; err != nil {
+		return err
+	}
+	current := make(map[string]string)
+	for _, operation := range operations {
+		current[operation.Field] = operation.Value
+	}
+
+	// An item logged as deleted that still exists was restored
+	if current[models.ChangeFieldDeleted] == strconv.FormatBool(true) {
+		if err := r.logOperation(itemType, key, models.ChangeFieldDeleted, strconv.FormatBool(false)); err != nil {
+			return err
+		}
+	}
+
+	for _, field := range sortedFields(record) {
+		if value, found := current[field]; found && value == record[field] {
+			continue
+		}
+		if err := r.logOperation(itemType, key, field, record[field]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// logOperation appends an operation to the change log, with a new timestamp of the replica's clock.
+func (r *Repository) logOperation(itemType, key, field, value string) error {
+	clock, err := r.replicaClock()
+	if err != nil {
+		return err
+	}
+	operation := &models.ChangeOperation{
+		HLC:      clock.Now().String(),
+		ItemType: itemType,
+		ItemKey:  key,
+		Field:    field,
+		Value:    value,
+	}
+	if err = r.db.Create(operation).Error; err != nil {
+		return err
+	}
+	return r.saveReplicaClock()
+}
+
+// itemKey returns the key of a project or task in the change log, its UUID, given its model and ID, or an
+// empty key when it does not exist.
+func (r *Repository) itemKey(model any, itemID int) (string, error) {
+	var keys []string
+	if err := r.db.Model(model).Where("id = ?", itemID).Pluck("uuid", &keys).Error; err != nil {
+		return "", err
+	}
+	if len(keys) == 0 {
+		return "", nil
+	}
+	return keys[0], nil
+}
+
+// replicaClock returns the clock of the replica, loading it from the database on first use. The identity
+// of the replica is created with a random node the first time.
+func (r *Repository) replicaClock() (*hlc.Clock, error) {
+	if r.clock.clock != nil {
+		return r.clock.clock, nil
+	}
+
+	var state models.ReplicaState
+	err := r.db.First(&state, 1).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		buf := make([]byte, 8)
+		if _, err = rand.Read(buf); err != nil {
+			return nil, err
+		}
+		state = models.ReplicaState{ID: 1, Node: hex.EncodeToString(buf)}
+		err = r.db.Create(&state).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var last hlc.Timestamp
+	if state.Clock != "" {
+		if last, err = hlc.Parse(state.Clock); err != nil {
+			return nil, err
+		}
+	}
+	r.clock.clock = hlc.NewClock(state.Node, last)
+	return r.clock.clock, nil
+}
+
+// saveReplicaClock stores the last timestamp of the replica's clock.
+func (r *Repository) saveReplicaClock() error {
+	return r.db.Model(&models.ReplicaState{ID: 1}).Update("clock", r.clock.clock.Last().String()).Error
+}
+
+// sortedFields returns the fields of a record in alphabetical order.
+func sortedFields(record map[string]string) []string {
+	fields := make([]string, 0, len(record))
+	for field := range record {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
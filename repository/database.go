@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"fmt"
+)
+
+// MigrateDatabase applies the pending migrations up to the target version (all of them when target
+// is empty), after backing up the database, and returns the versions applied.
+func (r *Repository) MigrateDatabase(target string) ([]string, error) {
+	if len(r.migrator.Pending(r.db)) == 0 {
+		return r.migrator.MigrateTo(r.db, target)
+	}
+
+	if _, err := r.AutoBackup("migrate"); err != nil {
+		return nil, fmt.Errorf("failed to back up database before migrating: %w", err)
+	}
+	return r.migrator.MigrateTo(r.db, target)
+}
+
+// RollbackDatabase reverts the migrations newer than the target version, or the latest steps
+// migrations when target is empty, after backing up the database. It returns the versions reverted.
+func (r *Repository) RollbackDatabase(target string, steps int) ([]string, error) {
+	if _, err := r.AutoBackup("rollback"); err != nil {
+		return nil, fmt.Errorf("failed to back up database before rolling back: %w", err)
+	}
+	return r.migrator.Rollback(r.db, target, steps)
+}
+
+// MigrationStatus returns the state of the migrations of the database, and the hold pausing automatic
+// migrations after a rollback (nil if there is none).
+func (r *Repository) MigrationStatus() ([]*MigrationStatus, *MigrationHold, error) {
+	statuses, err := r.migrator.Status(r.db)
+	if err != nil {
+		return nil, nil, err
+	}
+	return statuses, r.migrator.Hold(r.db), nil
+}
+
+// CheckSchema returns ErrSchemaOutdated when migrations are pending, which only happens while automatic
+// migrations are paused after a rollback: the data is then kept as it is until the database is migrated
+// again, rather than used under a schema older than the one this version of clido expects.
+func (r *Repository) CheckSchema() error {
+	pending := r.migrator.Pending(r.db)
+	if len(pending) == 0 {
+		return nil
+	}
+	currentVersion := r.migrator.CurrentVersion(r.db)
+	if currentVersion == "" {
+		currentVersion = "none"
+	}
+	return fmt.Errorf("%w: schema version %s (expected: %s)", ErrSchemaOutdated, currentVersion,
+		r.migrator.LatestVersion())
+}
+
+// migratePending applies the pending migrations when the database is opened, backing up existing data
+// first, unless automatic migrations are paused after a rollback. Databases migrated by a newer version
+// of clido are rejected.
+func (r *Repository) migratePending() error {
+	currentVersion := r.migrator.CurrentVersion(r.db)
+	if currentVersion != "" && compareVersions(currentVersion, r.migrator.LatestVersion()) > 0 {
+		return fmt.Errorf("%w: schema version %s (supported: up to %s)",
+			ErrDatabaseTooNew, currentVersion, r.migrator.LatestVersion())
+	}
+	if len(r.migrator.Pending(r.db)) == 0 || r.migrator.Hold(r.db) != nil {
+		return nil
+	}
+
+	if currentVersion != "" {
+		if _, err := r.AutoBackup("migrate"); err != nil {
+			return fmt.Errorf("failed to back up database before migrating: %w", err)
+		}
+	}
+	if err := r.migrator.Migrate(r.db); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package repository
+
+import "gorm.io/gorm"
+
+// TestMigration is the definition of a migration built by tests.
+type TestMigration struct {
+	Version         string
+	Description     string
+	Up              func(*gorm.DB) error
+	Down            func(*gorm.DB) error
+	UpSQL           []string
+	DownSQL         []string
+	Source          string
+	FormerChecksums []string
+}
+
+func (m TestMigration) migration() migration {
+	return migration{
+		version:         m.Version,
+		description:     m.Description,
+		up:              m.Up,
+		down:            m.Down,
+		upSQL:           m.UpSQL,
+		downSQL:         m.DownSQL,
+		source:          m.Source,
+		formerChecksums: m.FormerChecksums,
+	}
+}
+
+// Checksum returns the checksum recorded when the migration is applied.
+func (m TestMigration) Checksum() string {
+	definition := m.migration()
+	return definition.checksum()
+}
+
+// NewTestMigrator creates a migrator from the given migrations.
+func NewTestMigrator(migrations ...TestMigration) (*Migrator, error) {
+	definitions := make([]migration, len(migrations))
+	for i, m := range migrations {
+		definitions[i] = m.migration()
+	}
+	return newMigrator(definitions)
+}
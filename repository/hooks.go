@@ -0,0 +1,12 @@
+package repository
+
+import "path/filepath"
+
+// HooksDirName is the name of the directory, next to the database, holding the hooks run when projects and
+// tasks change.
+const HooksDirName = "hooks"
+
+// HooksDir returns the directory holding the hooks of the database.
+func (r *Repository) HooksDir() string {
+	return filepath.Join(filepath.Dir(r.dbPath), HooksDirName)
+}
@@ -1,87 +1,721 @@
 package repository
 
 import (
-	"github.com/d4r1us-drk/clido/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// MigrationLockTimeout is the age after which a migration lock is considered stale, e.g. when
+// a previous run was interrupted, and can be taken over.
+const MigrationLockTimeout = 10 * time.Minute
+
+// Error constants for migrations.
+var (
+	ErrMigrationLocked   = errcode.New(errcode.Conflict, "another migration is in progress")
+	ErrChecksumMismatch  = errcode.New(errcode.Storage, "applied migration has been modified")
+	ErrUnknownMigration  = errcode.New(errcode.Validation, "unknown migration version")
+	ErrDatabaseTooNew    = errcode.New(errcode.Conflict, "database was migrated by a newer version of clido")
+	ErrIrreversible      = errcode.New(errcode.Conflict, "migration cannot be rolled back")
+	ErrInvalidMigrations = errcode.New(errcode.Internal, "invalid migration list")
+	ErrSchemaOutdated    = errcode.New(errcode.Storage,
+		"database schema is older than this version of clido expects, run 'clido db migrate' to update it",
+	)
+)
+
 // Migration represents a database migration entry.
 // Each migration is uniquely identified by a version string.
 type Migration struct {
-	ID      uint   `gorm:"primaryKey"`  // Primary key for the migration
-	Version string `gorm:"uniqueIndex"` // Unique version identifier for each migration
+	ID          uint      `gorm:"primaryKey"`  // Primary key for the migration
+	Version     string    `gorm:"uniqueIndex"` // Unique version identifier for each migration
+	Description string    // What the migration does
+	Checksum    string    // Checksum of the migration when it was applied, used to detect edits
+	AppliedAt   time.Time // Date and time when the migration was applied
 }
 
-// Migrator is responsible for applying database migrations.
-// It holds a list of migrations, each associated with a version and a function that applies the migration.
-type Migrator struct {
-	migrations []struct {
-		version string               // The version of the migration
-		migrate func(*gorm.DB) error // The function that applies the migration
+// MigrationLock is the single-row table preventing concurrent migrations.
+type MigrationLock struct {
+	ID       int       `gorm:"primaryKey"` // Always 1, so only one lock can exist
+	Owner    string    // Host and process holding the lock
+	LockedAt time.Time // Date and time when the lock was acquired
+}
+
+// MigrationHold records that automatic migrations are paused after a rollback (or a migration to
+// an older version than the latest), until all migrations are applied explicitly.
+type MigrationHold struct {
+	ID      int       `gorm:"primaryKey"` // Always 1, so only one hold can exist
+	Version string    // The version the database was held at
+	HeldAt  time.Time // Date and time when the hold was placed
+}
+
+// MigrationStatus describes the state of a migration in a database.
+//
+// Fields:
+//   - Version: The version of the migration.
+//   - Description: What the migration does.
+//   - Applied: Whether the migration has been applied to the database.
+//   - AppliedAt: The date and time when the migration was applied (nil if pending).
+//   - Modified: Whether the migration changed since it was applied (checksum mismatch).
+//   - Unknown: Whether the migration was applied by another version of clido and is unknown to this one.
+type MigrationStatus struct {
+	Version     string     `json:"version"`
+	Description string     `json:"description"`
+	Applied     bool       `json:"applied"`
+	AppliedAt   *time.Time `json:"applied_at,omitempty"`
+	Modified    bool       `json:"modified"`
+	Unknown     bool       `json:"unknown"`
+}
+
+// migration is a versioned schema change with the way to apply and revert it, either as Go functions
+// or as SQL statements. The checksum covers the version, the description, the SQL statements and the
+// source tag of the Go functions, so editing an applied migration is detected.
+type migration struct {
+	version         string               // The version of the migration, e.g. "1.2"
+	description     string               // What the migration does
+	up              func(*gorm.DB) error // The function that applies the migration (or nil to run upSQL)
+	down            func(*gorm.DB) error // The function that reverts the migration (or nil to run downSQL)
+	upSQL           []string             // The statements that apply the migration
+	downSQL         []string             // The statements that revert the migration
+	source          string               // The tag of the Go functions, e.g. "addUUIDColumn/v1", changed with them
+	formerChecksums []string             // The checksums of earlier, equivalent definitions of the migration
+}
+
+// checksum returns the checksum identifying the definition of the migration.
+func (m *migration) checksum() string {
+	source := m.version + "\n" + m.description + "\n" +
+		strings.Join(m.upSQL, ";\n") + "\n--\n" + strings.Join(m.downSQL, ";\n")
+	if m.source != "" {
+		source += "\n--\n" + m.source
 	}
+	sum := sha256.Sum256([]byte(source))
+	return hex.EncodeToString(sum[:])
+}
+
+// matches reports whether a checksum recorded when the migration was applied matches its definition,
+// the current one or an earlier one.
+func (m *migration) matches(checksum string) bool {
+	return checksum == m.checksum() || slices.Contains(m.formerChecksums, checksum)
+}
+
+// reversible reports whether the migration can be rolled back.
+func (m *migration) reversible() bool {
+	return m.down != nil || len(m.downSQL) > 0
 }
 
-// NewMigrator initializes a new Migrator with a list of migrations.
+// apply runs the migration.
+func (m *migration) apply(db *gorm.DB) error {
+	if m.up != nil {
+		return m.up(db)
+	}
+	return execStatements(db, m.upSQL)
+}
+
+// revert undoes the migration.
+func (m *migration) revert(db *gorm.DB) error {
+	if m.down != nil {
+		return m.down(db)
+	}
+	return execStatements(db, m.downSQL)
+}
+
+// execStatements runs SQL statements in order, stopping at the first error.
+func execStatements(db *gorm.DB, statements []string) error {
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Migrator is responsible for applying and reverting database migrations.
+// It holds the list of migrations, sorted by version.
+type Migrator struct {
+	migrations []migration
+}
+
+// NewMigrator initializes a new Migrator with the list of migrations of the application.
+//
+// Each migration has a version, compared numerically component by component (so "1.10" comes after "1.9"),
+// and either Go functions or SQL statements applying and reverting it. New migrations are appended to the
+// list, preferably as SQL statements so they are covered by the checksum:
+//
+//	{
+//		version:     "1.2",
+//		description: "Add a status to projects",
+//		upSQL:       []string{"ALTER TABLE projects ADD COLUMN status VARCHAR(50)"},
+//		downSQL:     []string{"ALTER TABLE projects DROP COLUMN status"},
+//	},
 //
-// Each migration is represented by a version string and a function that performs the migration.
-// In this case, the initial migration (version "1.0") creates the `Project` and `Task` tables.
+// Migrations written as Go functions need a source tag, which the checksum covers in place of their code:
+// it must change whenever the functions do, e.g. from "addUUIDColumn/v1" to "addUUIDColumn/v2". Migrations
+// never depend on the models, which change after them. When the definition of an applied migration is
+// rewritten without changing what it does, the previous checksum is kept in formerChecksums.
 func NewMigrator() *Migrator {
-	return &Migrator{
-		migrations: []struct {
-			version string
-			migrate func(*gorm.DB) error
-		}{
-			{
-				version: "1.0", // The first version of the database schema
-				migrate: func(db *gorm.DB) error {
-					// Automatically migrates the schema for the Project and Task models
-					return db.AutoMigrate(&models.Project{}, &models.Task{})
-				},
+	migrator, err := newMigrator([]migration{
+		{
+			version:     "1.0", // The first version of the database schema
+			description: "Create the projects and tasks tables",
+			upSQL: []string{
+				"CREATE TABLE `projects` (" +
+					"`id` integer PRIMARY KEY AUTOINCREMENT,`name` text NOT NULL,`description` text," +
+					"`creation_date` datetime NOT NULL,`last_modified_date` datetime NOT NULL," +
+					"`parent_project_id` integer," +
+					"CONSTRAINT `fk_projects_sub_projects` FOREIGN KEY (`parent_project_id`) " +
+					"REFERENCES `projects`(`id`)," +
+					"CONSTRAINT `uni_projects_name` UNIQUE (`name`))",
+				"CREATE TABLE `tasks` (" +
+					"`id` integer PRIMARY KEY AUTOINCREMENT,`name` text NOT NULL,`description` text," +
+					"`project_id` integer NOT NULL,`task_completed` numeric NOT NULL,`due_date` datetime," +
+					"`completion_date` datetime,`creation_date` datetime NOT NULL," +
+					"`last_updated_date` datetime NOT NULL,`priority` integer NOT NULL DEFAULT 4," +
+					"`parent_task_id` integer," +
+					"CONSTRAINT `fk_tasks_sub_tasks` FOREIGN KEY (`parent_task_id`) REFERENCES `tasks`(`id`)," +
+					"CONSTRAINT `fk_projects_tasks` FOREIGN KEY (`project_id`) REFERENCES `projects`(`id`))",
+			},
+			downSQL: []string{"DROP TABLE tasks", "DROP TABLE projects"},
+			// Applied from the models before being written as SQL
+			formerChecksums: []string{"548743e65c8f4edff83c16db012a8378c5b46f3826b91635d56532894ab1e712"},
+		},
+		{
+			version:     "1.1",
+			description: "Add task workflow states and transition history",
+			upSQL: []string{
+				"ALTER TABLE `tasks` ADD `state` text NOT NULL DEFAULT 'todo'",
+				"CREATE TABLE `task_transitions` (" +
+					"`id` integer PRIMARY KEY AUTOINCREMENT,`task_id` integer NOT NULL," +
+					"`from_state` text NOT NULL,`to_state` text NOT NULL,`transition_date` datetime NOT NULL)",
+				"CREATE INDEX `idx_task_transitions_task_id` ON `task_transitions`(`task_id`)",
+				// Map the existing completion booleans onto workflow states
+				"UPDATE tasks SET state = CASE WHEN task_completed THEN 'done' ELSE 'todo' END",
+			},
+			downSQL: []string{"DROP TABLE task_transitions", "ALTER TABLE tasks DROP COLUMN state"},
+			// Applied from the models before being written as SQL
+			formerChecksums: []string{"ce86db00aaa421ba0276d533055da58f9964e81890d592b92fd1fed94ea58853"},
+		},
+		{
+			version:     "1.2",
+			description: "Add the sync keys of projects and tasks",
+			upSQL: []string{
+				"CREATE TABLE sync_keys (" +
+					"id INTEGER PRIMARY KEY, item_type TEXT NOT NULL, item_id INTEGER NOT NULL, " +
+					"key TEXT NOT NULL UNIQUE, UNIQUE (item_type, item_id))",
+			},
+			downSQL: []string{"DROP TABLE sync_keys"},
+		},
+		{
+			version:     "1.3",
+			description: "Add the CalDAV settings and sync state",
+			upSQL: []string{
+				"CREATE TABLE caldav_settings (id INTEGER PRIMARY KEY, url TEXT NOT NULL, username TEXT)",
+				"CREATE TABLE caldav_items (" +
+					"id INTEGER PRIMARY KEY, item_type TEXT NOT NULL, item_id INTEGER NOT NULL, " +
+					"uid TEXT NOT NULL, href TEXT NOT NULL UNIQUE, etag TEXT, hash TEXT)",
+			},
+			downSQL: []string{"DROP TABLE caldav_items", "DROP TABLE caldav_settings"},
+		},
+		{
+			version:     "1.4",
+			description: "Add the change log and replica state",
+			upSQL: []string{
+				"CREATE TABLE change_log (" +
+					"id INTEGER PRIMARY KEY, hlc TEXT NOT NULL UNIQUE, item_type TEXT NOT NULL, " +
+					"item_key TEXT NOT NULL, field TEXT NOT NULL, value TEXT)",
+				"CREATE INDEX idx_change_log_item_key ON change_log (item_key)",
+				"CREATE TABLE replica (id INTEGER PRIMARY KEY, node TEXT NOT NULL, clock TEXT)",
+			},
+			downSQL: []string{"DROP TABLE replica", "DROP TABLE change_log"},
+		},
+		{
+			version:     "1.5",
+			description: "Add UUIDs to projects and tasks",
+			up: func(db *gorm.DB) error {
+				for _, table := range []string{"projects", "tasks"} {
+					if err := addUUIDColumn(db, table); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			down: func(db *gorm.DB) error {
+				for _, table := range []string{"projects", "tasks"} {
+					if err := db.Exec("DROP INDEX IF EXISTS idx_" + table + "_uuid").Error; err != nil {
+						return err
+					}
+					if err := db.Exec("ALTER TABLE " + table + " DROP COLUMN uuid").Error; err != nil {
+						return err
+					}
+				}
+				return nil
 			},
-			// Example of how to add a new migration:
-			// {
-			//   version: "1.1",
-			//   migrate: func(db *gorm.DB) error {
-			//     // SQL or schema changes for version 1.1
-			//     return db.Exec("ALTER TABLE projects ADD COLUMN status VARCHAR(50)").Error
-			//   },
-			// },
+			source: "addUUIDColumn/v1",
+			// Applied before Go migrations had a source tag
+			formerChecksums: []string{"5f5b815c43a8af65ab8d62f29cb5ed553b9a0aed7904ff481d7bcf8e8d45f9ab"},
 		},
+		{
+			version:     "1.6",
+			description: "Add the working set of task display IDs",
+			upSQL: []string{
+				"CREATE TABLE working_set (" +
+					"display_id INTEGER PRIMARY KEY, task_id INTEGER NOT NULL, task_uuid TEXT NOT NULL)",
+			},
+			downSQL: []string{"DROP TABLE working_set"},
+		},
+		{
+			version:     "1.7",
+			description: "Add webhooks and their delivery queue",
+			upSQL: []string{
+				"CREATE TABLE webhooks (" +
+					"id INTEGER PRIMARY KEY, url TEXT NOT NULL, secret TEXT NOT NULL, " +
+					"project_id INTEGER, events TEXT, " +
+					"creation_date DATETIME NOT NULL)",
+				"CREATE TABLE webhook_deliveries (" +
+					"id INTEGER PRIMARY KEY, uuid TEXT NOT NULL, " +
+					"webhook_id INTEGER NOT NULL, " +
+					"event TEXT NOT NULL, item_type TEXT NOT NULL, item_id INTEGER NOT NULL, payload TEXT NOT NULL, " +
+					"status TEXT NOT NULL, attempts INTEGER NOT NULL, response_code INTEGER, last_error TEXT, " +
+					"next_attempt DATETIME NOT NULL, creation_date DATETIME NOT NULL, delivery_date DATETIME)",
+				"CREATE INDEX idx_webhook_deliveries_status ON webhook_deliveries (status, next_attempt)",
+			},
+			downSQL: []string{"DROP TABLE webhook_deliveries", "DROP TABLE webhooks"},
+		},
+		{
+			version:     "1.8",
+			description: "Add tags to tasks",
+			up: func(db *gorm.DB) error {
+				// Databases created from the models before 1.0 was written as SQL may have the column
+				if db.Migrator().HasColumn("tasks", "tags") {
+					return nil
+				}
+				return db.Exec("ALTER TABLE tasks ADD COLUMN tags TEXT NOT NULL DEFAULT ''").Error
+			},
+			downSQL: []string{"ALTER TABLE tasks DROP COLUMN tags"},
+			source:  "addTagsColumn/v1",
+			// Applied before Go migrations had a source tag
+			formerChecksums: []string{"d04ff1621374edfcd1d605e4af91898fb0da68c8b2a50211f76c2da634adc5ed"},
+		},
+		{
+			version:     "1.9",
+			description: "Identify projects and tasks by their UUID instead of a sync key",
+			upSQL: []string{
+				// Sync keys are known to the change log, the sync repository and CalDAV servers, so they
+				// become the UUIDs of their items
+				"UPDATE projects SET uuid = (SELECT key FROM sync_keys " +
+					"WHERE item_type = 'project' AND item_id = projects.id) " +
+					"WHERE id IN (SELECT item_id FROM sync_keys WHERE item_type = 'project')",
+				// Display IDs follow their task, but not removed tasks whose ID was reused
+				"UPDATE working_set SET task_uuid = (SELECT key FROM sync_keys " +
+					"WHERE item_type = 'task' AND item_id = working_set.task_id) " +
+					"WHERE EXISTS (SELECT 1 FROM tasks JOIN sync_keys ON item_type = 'task' AND item_id = tasks.id " +
+					"WHERE tasks.id = working_set.task_id AND tasks.uuid = working_set.task_uuid)",
+				"UPDATE tasks SET uuid = (SELECT key FROM sync_keys " +
+					"WHERE item_type = 'task' AND item_id = tasks.id) " +
+					"WHERE id IN (SELECT item_id FROM sync_keys WHERE item_type = 'task')",
+				"DROP TABLE sync_keys",
+			},
+			downSQL: []string{
+				"CREATE TABLE sync_keys (" +
+					"id INTEGER PRIMARY KEY, item_type TEXT NOT NULL, item_id INTEGER NOT NULL, " +
+					"key TEXT NOT NULL UNIQUE, UNIQUE (item_type, item_id))",
+				"INSERT INTO sync_keys (item_type, item_id, key) SELECT 'project', id, uuid FROM projects",
+				"INSERT INTO sync_keys (item_type, item_id, key) SELECT 'task', id, uuid FROM tasks",
+			},
+		},
+	})
+	if err != nil {
+		panic(err)
 	}
+	return migrator
 }
 
-// Migrate applies any pending migrations to the database.
-//
-// It first ensures that the `Migration` table exists, then checks the latest applied migration.
-// Migrations that have a version greater than the last applied one are executed sequentially.
-// After each migration is applied, a record is inserted into the `Migration` table.
-func (m *Migrator) Migrate(db *gorm.DB) error {
-	// Ensure the Migration table exists
-	err := db.AutoMigrate(&Migration{})
-	if err != nil {
+// addUUIDColumn adds the uuid column to a table unless the first migration already created it from the
+// model, as it did before being written as SQL, gives a UUID to every row that has none, and makes UUIDs unique.
+func addUUIDColumn(db *gorm.DB, table string) error {
+	if !db.Migrator().HasColumn(table, "uuid") {
+		if err := db.Exec("ALTER TABLE " + table + " ADD COLUMN uuid TEXT NOT NULL DEFAULT ''").Error; err != nil {
+			return err
+		}
+	}
+
+	var ids []int
+	if err := db.Table(table).Where("uuid = '' OR uuid IS NULL").Pluck("id", &ids).Error; err != nil {
 		return err
 	}
+	for _, id := range ids {
+		if err := db.Table(table).Where("id = ?", id).Update("uuid", uuid.NewString()).Error; err != nil {
+			return err
+		}
+	}
 
-	// Retrieve the latest migration version from the database
-	var lastMigration Migration
-	db.Order("version desc").First(&lastMigration)
+	return db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_" + table + "_uuid ON " + table + " (uuid)").Error
+}
 
-	// Apply pending migrations
-	for _, migration := range m.migrations {
-		if migration.version > lastMigration.Version {
-			// Execute the migration function
-			err = migration.migrate(db)
+// newMigrator creates a Migrator from a list of migrations, checking that versions are valid and unique, and
+// that Go functions have a source tag.
+func newMigrator(migrations []migration) (*Migrator, error) {
+	sorted := make([]migration, len(migrations))
+	copy(sorted, migrations)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return compareVersions(sorted[i].version, sorted[j].version) < 0
+	})
+
+	for i, migration := range sorted {
+		if _, err := parseVersion(migration.version); err != nil {
+			return nil, err
+		}
+		if migration.up == nil && len(migration.upSQL) == 0 {
+			return nil, fmt.Errorf("%w: migration %s does nothing", ErrInvalidMigrations, migration.version)
+		}
+		if (migration.up != nil || migration.down != nil) && migration.source == "" {
+			return nil, fmt.Errorf("%w: migration %s has Go functions but no source tag",
+				ErrInvalidMigrations, migration.version)
+		}
+		if i > 0 && compareVersions(sorted[i-1].version, migration.version) == 0 {
+			return nil, fmt.Errorf("%w: duplicate version %s", ErrInvalidMigrations, migration.version)
+		}
+	}
+
+	return &Migrator{migrations: sorted}, nil
+}
+
+// Migrate applies all pending migrations to the database.
+func (m *Migrator) Migrate(db *gorm.DB) error {
+	_, err := m.MigrateTo(db, "")
+	return err
+}
+
+// MigrateTo applies the pending migrations up to and including the target version (all of them when
+// target is empty), each in its own transaction, and returns the versions applied.
+//
+// Migrations run while holding the migration lock. Nothing is applied if an applied migration was modified
+// since, or if the database was migrated by a newer version of clido.
+func (m *Migrator) MigrateTo(db *gorm.DB, target string) ([]string, error) {
+	if target != "" && m.find(target) == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMigration, target)
+	}
+
+	var applied []string
+	err := m.withLock(db, func(records map[string]*Migration) error {
+		for _, record := range records {
+			if m.find(record.Version) == nil &&
+				compareVersions(record.Version, m.LatestVersion()) > 0 {
+				return fmt.Errorf("%w: schema version %s (supported: up to %s)",
+					ErrDatabaseTooNew, record.Version, m.LatestVersion())
+			}
+		}
+
+		for _, migration := range m.migrations {
+			if target != "" && compareVersions(migration.version, target) > 0 {
+				break
+			}
+			if records[migration.version] != nil {
+				continue
+			}
+
+			// Apply the migration and record it atomically
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := migration.apply(tx); err != nil {
+					return err
+				}
+				return tx.Create(&Migration{
+					Version:     migration.version,
+					Description: migration.description,
+					Checksum:    migration.checksum(),
+					AppliedAt:   time.Now(),
+				}).Error
+			})
 			if err != nil {
-				return err
+				return fmt.Errorf("migration %s: %w", migration.version, err)
 			}
+			applied = append(applied, migration.version)
+		}
 
-			// Record the applied migration version
-			err = db.Create(&Migration{Version: migration.version}).Error
+		// Migrating to an older version keeps automatic migrations paused, migrating to the latest resumes them
+		if target != "" && compareVersions(target, m.LatestVersion()) < 0 {
+			return db.Save(&MigrationHold{ID: 1, Version: m.CurrentVersion(db), HeldAt: time.Now()}).Error
+		}
+		return db.Where("1 = 1").Delete(&MigrationHold{}).Error
+	})
+
+	return applied, err
+}
+
+// Rollback reverts applied migrations, newest first, each in its own transaction, and returns the
+// versions reverted. When target is set, every migration newer than the target version is reverted;
+// otherwise the latest steps migrations are.
+func (m *Migrator) Rollback(db *gorm.DB, target string, steps int) ([]string, error) {
+	if target != "" && m.find(target) == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMigration, target)
+	}
+
+	var reverted []string
+	err := m.withLock(db, func(records map[string]*Migration) error {
+		versions := make([]string, 0, len(records))
+		for version := range records {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool {
+			return compareVersions(versions[i], versions[j]) > 0
+		})
+
+		for _, version := range versions {
+			if target != "" && compareVersions(version, target) <= 0 {
+				break
+			}
+			if target == "" && len(reverted) >= steps {
+				break
+			}
+
+			migration := m.find(version)
+			if migration == nil {
+				return fmt.Errorf("%w: %s was applied by another version of clido", ErrUnknownMigration, version)
+			}
+			if !migration.reversible() {
+				return fmt.Errorf("%w: %s", ErrIrreversible, version)
+			}
+
+			// Revert the migration and remove its record atomically
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := migration.revert(tx); err != nil {
+					return err
+				}
+				return tx.Where("version = ?", version).Delete(&Migration{}).Error
+			})
 			if err != nil {
-				return err
+				return fmt.Errorf("rollback of migration %s: %w", version, err)
 			}
+			reverted = append(reverted, version)
 		}
+
+		// Keep the rolled back migrations from being applied again automatically
+		if len(reverted) == 0 {
+			return nil
+		}
+		return db.Save(&MigrationHold{ID: 1, Version: m.CurrentVersion(db), HeldAt: time.Now()}).Error
+	})
+
+	return reverted, err
+}
+
+// Status returns the state of every known migration, followed by the applied migrations unknown
+// to this version of clido.
+func (m *Migrator) Status(db *gorm.DB) ([]*MigrationStatus, error) {
+	records, err := m.appliedMigrations(db)
+	if err != nil {
+		return nil, err
 	}
 
+	statuses := make([]*MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := &MigrationStatus{Version: migration.version, Description: migration.description}
+		if record := records[migration.version]; record != nil {
+			status.Applied = true
+			status.AppliedAt = recordedTime(record.AppliedAt)
+			status.Modified = record.Checksum != "" && !migration.matches(record.Checksum)
+		}
+		statuses = append(statuses, status)
+	}
+
+	for _, record := range records {
+		if m.find(record.Version) == nil {
+			statuses = append(statuses, &MigrationStatus{
+				Version:     record.Version,
+				Description: record.Description,
+				Applied:     true,
+				AppliedAt:   recordedTime(record.AppliedAt),
+				Unknown:     true,
+			})
+		}
+	}
+
+	sort.SliceStable(statuses, func(i, j int) bool {
+		return compareVersions(statuses[i].Version, statuses[j].Version) < 0
+	})
+	return statuses, nil
+}
+
+// CurrentVersion returns the version of the latest migration applied to the database,
+// or an empty string if no migration has been applied yet.
+func (m *Migrator) CurrentVersion(db *gorm.DB) string {
+	records, err := m.appliedMigrations(db)
+	if err != nil {
+		return ""
+	}
+
+	current := ""
+	for version := range records {
+		if current == "" || compareVersions(version, current) > 0 {
+			current = version
+		}
+	}
+	return current
+}
+
+// Hold returns the hold pausing automatic migrations after a rollback, or nil if there is none.
+func (m *Migrator) Hold(db *gorm.DB) *MigrationHold {
+	if !db.Migrator().HasTable(&MigrationHold{}) {
+		return nil
+	}
+
+	var hold MigrationHold
+	if err := db.Limit(1).Find(&hold).Error; err != nil || hold.ID == 0 {
+		return nil
+	}
+	return &hold
+}
+
+// LatestVersion returns the version of the latest migration known to this version of clido.
+func (m *Migrator) LatestVersion() string {
+	return m.migrations[len(m.migrations)-1].version
+}
+
+// Pending returns the versions of the migrations that have not been applied to the database yet.
+func (m *Migrator) Pending(db *gorm.DB) []string {
+	records, err := m.appliedMigrations(db)
+	if err != nil {
+		return nil
+	}
+
+	var pending []string
+	for _, migration := range m.migrations {
+		if records[migration.version] == nil {
+			pending = append(pending, migration.version)
+		}
+	}
+	return pending
+}
+
+// withLock runs fn while holding the migration lock, after checking that no applied migration was
+// modified. It passes fn the applied migrations, indexed by version.
+func (m *Migrator) withLock(db *gorm.DB, fn func(records map[string]*Migration) error) error {
+	// Ensure the migration tables exist
+	if err := db.AutoMigrate(&Migration{}, &MigrationLock{}, &MigrationHold{}); err != nil {
+		return err
+	}
+
+	// Take over stale locks left by interrupted runs, then acquire the lock
+	db.Where("locked_at < ?", time.Now().Add(-MigrationLockTimeout)).Delete(&MigrationLock{})
+	hostname, _ := os.Hostname()
+	lock := &MigrationLock{ID: 1, Owner: hostname + ":" + strconv.Itoa(os.Getpid()), LockedAt: time.Now()}
+	if err := db.Create(lock).Error; err != nil {
+		var holder MigrationLock
+		db.First(&holder, 1)
+		return fmt.Errorf("%w (held by %s since %s)",
+			ErrMigrationLocked, holder.Owner, holder.LockedAt.Format(time.RFC3339))
+	}
+	defer db.Delete(&MigrationLock{}, 1)
+
+	records, err := m.appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	// Detect edited migrations, recording the checksums of migrations applied before they were tracked or
+	// before their definition was rewritten
+	for _, record := range records {
+		migration := m.find(record.Version)
+		if migration == nil || record.Checksum == migration.checksum() {
+			continue
+		}
+		if record.Checksum == "" || migration.matches(record.Checksum) {
+			record.Checksum = migration.checksum()
+			if record.Description == "" {
+				record.Description = migration.description
+			}
+			if saveErr := db.Save(record).Error; saveErr != nil {
+				return saveErr
+			}
+			continue
+		}
+		return fmt.Errorf("%w: %s", ErrChecksumMismatch, record.Version)
+	}
+
+	return fn(records)
+}
+
+// appliedMigrations returns the migrations recorded in the database, indexed by version.
+func (m *Migrator) appliedMigrations(db *gorm.DB) (map[string]*Migration, error) {
+	records := make(map[string]*Migration)
+	if !db.Migrator().HasTable(&Migration{}) {
+		return records, nil
+	}
+
+	var migrations []*Migration
+	if err := db.Find(&migrations).Error; err != nil {
+		return nil, err
+	}
+	for _, migration := range migrations {
+		records[migration.Version] = migration
+	}
+	return records, nil
+}
+
+// find returns the known migration with the given version, or nil if there is none.
+func (m *Migrator) find(version string) *migration {
+	for i := range m.migrations {
+		if compareVersions(m.migrations[i].version, version) == 0 {
+			return &m.migrations[i]
+		}
+	}
 	return nil
 }
+
+// recordedTime returns a pointer to the given time, or nil for migrations recorded before
+// application times were tracked.
+func recordedTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// parseVersion splits a dotted version such as "1.10" or "2.0.1" into its numeric components.
+func parseVersion(version string) ([]int, error) {
+	parts := strings.Split(version, ".")
+	numbers := make([]int, len(parts))
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return nil, fmt.Errorf("%w: invalid version '%s'", ErrInvalidMigrations, version)
+		}
+		numbers[i] = number
+	}
+	return numbers, nil
+}
+
+// compareVersions compares two dotted versions numerically, component by component, treating missing
+// components as zero. It returns -1, 0 or 1. Invalid versions are compared as strings.
+func compareVersions(a, b string) int {
+	aParts, aErr := parseVersion(a)
+	bParts, bErr := parseVersion(b)
+	if aErr != nil || bErr != nil {
+		return strings.Compare(a, b)
+	}
+
+	for i := range max(len(aParts), len(bParts)) {
+		var aPart, bPart int
+		if i < len(aParts) {
+			aPart = aParts[i]
+		}
+		if i < len(bParts) {
+			bPart = bParts[i]
+		}
+		if aPart != bPart {
+			if aPart < bPart {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
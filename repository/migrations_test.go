@@ -0,0 +1,437 @@
+package repository_test
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/d4r1us-drk/clido/repository"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB opens an empty database in a temporary directory.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	// Tests do not need durable writes, which make each migration slow
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_pragma=synchronous(off)&_pragma=journal_mode(memory)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, dbErr := db.DB(); dbErr == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// createTable returns a migration creating a table, reverted by dropping it.
+func createTable(version, table string) repository.TestMigration {
+	return repository.TestMigration{
+		Version:     version,
+		Description: "Create " + table,
+		UpSQL:       []string{"CREATE TABLE " + table + " (id INTEGER PRIMARY KEY)"},
+		DownSQL:     []string{"DROP TABLE " + table},
+	}
+}
+
+func newTestMigrator(t *testing.T, migrations ...repository.TestMigration) *repository.Migrator {
+	t.Helper()
+
+	migrator, err := repository.NewTestMigrator(migrations...)
+	if err != nil {
+		t.Fatalf("NewTestMigrator() error = %v", err)
+	}
+	return migrator
+}
+
+func TestNewMigratorRejectsInvalidLists(t *testing.T) {
+	goUp := func(*gorm.DB) error { return nil }
+
+	tests := []struct {
+		name       string
+		migrations []repository.TestMigration
+	}{
+		{"invalid version", []repository.TestMigration{createTable("1.x", "a")}},
+		{"negative version", []repository.TestMigration{createTable("1.-1", "a")}},
+		{"duplicate version", []repository.TestMigration{createTable("1.0", "a"), createTable("1.0", "b")}},
+		{"equal versions", []repository.TestMigration{createTable("1.0", "a"), createTable("1", "b")}},
+		{"no up", []repository.TestMigration{{Version: "1.0", DownSQL: []string{"DROP TABLE a"}}}},
+		{"Go up without source", []repository.TestMigration{{Version: "1.0", Up: goUp}}},
+		{"Go down without source", []repository.TestMigration{
+			{Version: "1.0", UpSQL: []string{"CREATE TABLE a (id INTEGER)"}, Down: goUp},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := repository.NewTestMigrator(tt.migrations...)
+			if !errors.Is(err, repository.ErrInvalidMigrations) {
+				t.Errorf("NewTestMigrator() error = %v, want %v", err, repository.ErrInvalidMigrations)
+			}
+		})
+	}
+}
+
+func TestMigrateAppliesInVersionOrder(t *testing.T) {
+	var order []string
+	record := func(version string) repository.TestMigration {
+		return repository.TestMigration{
+			Version: version,
+			Up: func(*gorm.DB) error {
+				order = append(order, version)
+				return nil
+			},
+			Source: "record/v1",
+		}
+	}
+
+	db := openTestDB(t)
+	migrator := newTestMigrator(t, record("1.10"), record("2.0"), record("1.9"), record("1.2.1"), record("1.2"))
+
+	if latest := migrator.LatestVersion(); latest != "2.0" {
+		t.Errorf("LatestVersion() = %s, want 2.0", latest)
+	}
+	applied, err := migrator.MigrateTo(db, "1.10")
+	if err != nil {
+		t.Fatalf("MigrateTo() error = %v", err)
+	}
+
+	want := []string{"1.2", "1.2.1", "1.9", "1.10"}
+	if !slices.Equal(applied, want) || !slices.Equal(order, want) {
+		t.Errorf("MigrateTo() applied %v in order %v, want %v", applied, order, want)
+	}
+	if pending := migrator.Pending(db); !slices.Equal(pending, []string{"2.0"}) {
+		t.Errorf("Pending() = %v, want [2.0]", pending)
+	}
+	if current := migrator.CurrentVersion(db); current != "1.10" {
+		t.Errorf("CurrentVersion() = %s, want 1.10", current)
+	}
+}
+
+func TestMigrateDetectsModifiedMigrations(t *testing.T) {
+	original := repository.TestMigration{
+		Version: "1.1",
+		Up:      func(*gorm.DB) error { return nil },
+		Source:  "noop/v1",
+	}
+	rewritten := createTable("1.1", "b")
+	rewritten.FormerChecksums = []string{original.Checksum()}
+
+	tests := []struct {
+		name     string
+		changed  repository.TestMigration
+		modified bool
+	}{
+		{"unchanged", original, false},
+		{"SQL changed", repository.TestMigration{
+			Version: "1.1", UpSQL: []string{"CREATE TABLE c (id INTEGER)"},
+		}, true},
+		{"description changed", repository.TestMigration{
+			Version: "1.1", Description: "Other", Up: original.Up, Source: "noop/v1",
+		}, true},
+		{"source tag changed", repository.TestMigration{Version: "1.1", Up: original.Up, Source: "noop/v2"}, true},
+		{"rewritten with its former checksum", rewritten, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+			if err := newTestMigrator(t, createTable("1.0", "a"), original).Migrate(db); err != nil {
+				t.Fatalf("Migrate() error = %v", err)
+			}
+
+			migrator := newTestMigrator(t, createTable("1.0", "a"), tt.changed, createTable("1.2", "d"))
+			statuses, err := migrator.Status(db)
+			if err != nil {
+				t.Fatalf("Status() error = %v", err)
+			}
+			if statuses[1].Modified != tt.modified {
+				t.Errorf("Status() modified = %v, want %v", statuses[1].Modified, tt.modified)
+			}
+
+			err = migrator.Migrate(db)
+			if tt.modified {
+				if !errors.Is(err, repository.ErrChecksumMismatch) {
+					t.Errorf("Migrate() error = %v, want %v", err, repository.ErrChecksumMismatch)
+				}
+				if pending := migrator.Pending(db); !slices.Equal(pending, []string{"1.2"}) {
+					t.Errorf("Pending() = %v, want nothing applied after a mismatch", pending)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Migrate() error = %v", err)
+			}
+
+			// A former checksum is replaced by the current one
+			var record repository.Migration
+			db.Where("version = ?", "1.1").First(&record)
+			if record.Checksum != tt.changed.Checksum() {
+				t.Errorf("recorded checksum = %s, want %s", record.Checksum, tt.changed.Checksum())
+			}
+		})
+	}
+}
+
+func TestMigrateLock(t *testing.T) {
+	tests := []struct {
+		name    string
+		age     time.Duration
+		wantErr error
+	}{
+		{"held lock", time.Minute, repository.ErrMigrationLocked},
+		{"stale lock", repository.MigrationLockTimeout + time.Minute, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+			if err := db.AutoMigrate(&repository.MigrationLock{}); err != nil {
+				t.Fatalf("AutoMigrate() error = %v", err)
+			}
+			lock := &repository.MigrationLock{ID: 1, Owner: "other:1", LockedAt: time.Now().Add(-tt.age)}
+			if err := db.Create(lock).Error; err != nil {
+				t.Fatalf("failed to create lock: %v", err)
+			}
+
+			migrator := newTestMigrator(t, createTable("1.0", "a"))
+			err := migrator.Migrate(db)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Migrate() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var locks int64
+			db.Model(&repository.MigrationLock{}).Count(&locks)
+			applied := len(migrator.Pending(db)) == 0
+			if tt.wantErr != nil && (locks != 1 || applied) {
+				t.Errorf("held lock: %d lock(s), applied = %v, want the lock kept and nothing applied", locks, applied)
+			}
+			if tt.wantErr == nil && (locks != 0 || !applied) {
+				t.Errorf("stale lock: %d lock(s), applied = %v, want the lock released and all applied", locks, applied)
+			}
+		})
+	}
+}
+
+func TestRollback(t *testing.T) {
+	irreversible := repository.TestMigration{Version: "1.0", UpSQL: []string{"CREATE TABLE a (id INTEGER)"}}
+
+	tests := []struct {
+		name       string
+		migrations []repository.TestMigration
+		target     string
+		steps      int
+		want       []string
+		wantErr    error
+	}{
+		{
+			"latest steps",
+			[]repository.TestMigration{createTable("1.0", "a"), createTable("1.1", "b"), createTable("1.2", "c")},
+			"", 2, []string{"1.2", "1.1"}, nil,
+		},
+		{
+			"to a target",
+			[]repository.TestMigration{createTable("1.0", "a"), createTable("1.9", "b"), createTable("1.10", "c")},
+			"1.0", 0, []string{"1.10", "1.9"}, nil,
+		},
+		{
+			"unknown target",
+			[]repository.TestMigration{createTable("1.0", "a")},
+			"0.9", 0, nil, repository.ErrUnknownMigration,
+		},
+		{
+			"irreversible",
+			[]repository.TestMigration{irreversible, createTable("1.1", "b")},
+			"", 2, nil, repository.ErrIrreversible,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+			migrator := newTestMigrator(t, tt.migrations...)
+			if err := migrator.Migrate(db); err != nil {
+				t.Fatalf("Migrate() error = %v", err)
+			}
+
+			reverted, err := migrator.Rollback(db, tt.target, tt.steps)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Rollback() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Rollback() error = %v", err)
+			}
+			if !slices.Equal(reverted, tt.want) {
+				t.Errorf("Rollback() reverted %v, want %v", reverted, tt.want)
+			}
+			wantPending := slices.Clone(tt.want)
+			slices.Reverse(wantPending)
+			if pending := migrator.Pending(db); !slices.Equal(pending, wantPending) {
+				t.Errorf("Pending() = %v, want %v", pending, wantPending)
+			}
+		})
+	}
+}
+
+func TestRollbackHoldsAutomaticMigrations(t *testing.T) {
+	db := openTestDB(t)
+	migrator := newTestMigrator(t, createTable("1.0", "a"), createTable("1.1", "b"))
+	if err := migrator.Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	if hold := migrator.Hold(db); hold != nil {
+		t.Fatalf("Hold() = %+v after migrating, want none", hold)
+	}
+
+	if _, err := migrator.Rollback(db, "", 1); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	hold := migrator.Hold(db)
+	if hold == nil || hold.Version != "1.0" {
+		t.Fatalf("Hold() = %+v after rolling back, want a hold at 1.0", hold)
+	}
+	if db.Migrator().HasTable("b") {
+		t.Error("table of the rolled back migration still exists")
+	}
+
+	// Migrating to an older version than the latest keeps the hold, migrating to the latest lifts it
+	if _, err := migrator.MigrateTo(db, "1.0"); err != nil {
+		t.Fatalf("MigrateTo() error = %v", err)
+	}
+	if migrator.Hold(db) == nil {
+		t.Error("Hold() = nil after migrating to an older version, want a hold")
+	}
+	if _, err := migrator.MigrateTo(db, ""); err != nil {
+		t.Fatalf("MigrateTo() error = %v", err)
+	}
+	if hold = migrator.Hold(db); hold != nil {
+		t.Errorf("Hold() = %+v after migrating to the latest version, want none", hold)
+	}
+}
+
+func TestMigrateRejectsNewerDatabases(t *testing.T) {
+	db := openTestDB(t)
+	if err := newTestMigrator(t, createTable("1.0", "a"), createTable("1.1", "b")).Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	err := newTestMigrator(t, createTable("1.0", "a")).Migrate(db)
+	if !errors.Is(err, repository.ErrDatabaseTooNew) {
+		t.Errorf("Migrate() error = %v, want %v", err, repository.ErrDatabaseTooNew)
+	}
+}
+
+func TestApplicationMigrationsRoundTrip(t *testing.T) {
+	db := openTestDB(t)
+	migrator := repository.NewMigrator()
+
+	if err := migrator.Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	for _, column := range []string{"state", "uuid", "tags"} {
+		if !db.Migrator().HasColumn("tasks", column) {
+			t.Errorf("tasks has no %s column after migrating", column)
+		}
+	}
+
+	reverted, err := migrator.Rollback(db, "", len(migrator.Pending(db))+100)
+	if err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	if migrator.CurrentVersion(db) != "" || db.Migrator().HasTable("tasks") {
+		t.Errorf("Rollback() reverted %v, want every migration reverted", reverted)
+	}
+
+	if _, err = migrator.MigrateTo(db, ""); err != nil {
+		t.Fatalf("MigrateTo() error after a full rollback = %v", err)
+	}
+}
+
+func TestSyncKeysBecomeUUIDs(t *testing.T) {
+	db := openTestDB(t)
+	migrator := repository.NewMigrator()
+	if _, err := migrator.MigrateTo(db, "1.8"); err != nil {
+		t.Fatalf("MigrateTo() error = %v", err)
+	}
+
+	for _, statement := range []string{
+		"INSERT INTO projects (id, uuid, name, creation_date, last_modified_date) " +
+			"VALUES (1, 'project-uuid', 'Work', '2024-01-01', '2024-01-01')",
+		"INSERT INTO tasks (id, uuid, name, project_id, task_completed, creation_date, last_updated_date) " +
+			"VALUES (1, 'synced-uuid', 'Synced', 1, 0, '2024-01-01', '2024-01-01'), " +
+			"(2, 'local-uuid', 'Local', 1, 0, '2024-01-01', '2024-01-01')",
+		"INSERT INTO sync_keys (item_type, item_id, key) VALUES ('project', 1, 'project-key'), ('task', 1, 'task-key')",
+		// The second entry belonged to a removed task whose ID was reused
+		"INSERT INTO working_set (display_id, task_id, task_uuid) VALUES (1, 1, 'synced-uuid'), (2, 2, 'removed-uuid')",
+	} {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatalf("Exec() error = %v", err)
+		}
+	}
+
+	if err := migrator.Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	var uuids []string
+	db.Raw("SELECT uuid FROM projects UNION ALL SELECT uuid FROM tasks ORDER BY 1").Scan(&uuids)
+	if want := []string{"local-uuid", "project-key", "task-key"}; !slices.Equal(uuids, want) {
+		t.Errorf("UUIDs after migrating = %v, want %v", uuids, want)
+	}
+	var working []string
+	db.Raw("SELECT task_uuid FROM working_set ORDER BY display_id").Scan(&working)
+	if want := []string{"task-key", "removed-uuid"}; !slices.Equal(working, want) {
+		t.Errorf("working set after migrating = %v, want %v", working, want)
+	}
+	if db.Migrator().HasTable("sync_keys") {
+		t.Error("sync_keys still exists after migrating")
+	}
+}
+
+func TestRolledBackSchemaIsRefused(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("APPDATA", t.TempDir())
+	repo, err := repository.NewRepository("")
+	if err != nil {
+		t.Fatalf("NewRepository() error = %v", err)
+	}
+	if err = repo.CheckSchema(); err != nil {
+		t.Fatalf("CheckSchema() of a new database error = %v", err)
+	}
+
+	if _, err = repo.RollbackDatabase("", 1); err != nil {
+		t.Fatalf("RollbackDatabase() error = %v", err)
+	}
+	if err = repo.CheckSchema(); !errors.Is(err, repository.ErrSchemaOutdated) {
+		t.Errorf("CheckSchema() after a rollback error = %v, want %v", err, repository.ErrSchemaOutdated)
+	}
+
+	// Opening the database again does not migrate it while migrations are held
+	repo.Close()
+	if repo, err = repository.NewRepository(""); err != nil {
+		t.Fatalf("NewRepository() error = %v", err)
+	}
+	defer repo.Close()
+	if err = repo.CheckSchema(); !errors.Is(err, repository.ErrSchemaOutdated) {
+		t.Errorf("CheckSchema() after reopening error = %v, want %v", err, repository.ErrSchemaOutdated)
+	}
+
+	if _, err = repo.MigrateDatabase(""); err != nil {
+		t.Fatalf("MigrateDatabase() error = %v", err)
+	}
+	if err = repo.CheckSchema(); err != nil {
+		t.Errorf("CheckSchema() after migrating error = %v", err)
+	}
+}
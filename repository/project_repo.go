@@ -1,12 +1,18 @@
 package repository
 
 import (
+	"strings"
+
 	"github.com/d4r1us-drk/clido/models"
+	"gorm.io/gorm"
 )
 
-// CreateProject inserts a new project into the database.
+// CreateProject inserts a new project into the database and records it in the change log.
 func (r *Repository) CreateProject(project *models.Project) error {
-	return r.db.Create(project).Error
+	if err := r.db.Create(project).Error; err != nil {
+		return err
+	}
+	return r.logProject(project)
 }
 
 // GetProjectByID retrieves a project from the database by its ID.
@@ -29,6 +35,13 @@ func (r *Repository) GetProjectByName(name string) (*models.Project, error) {
 	return &project, nil
 }
 
+// GetProjectsByUUIDPrefix retrieves the projects whose UUID starts with the given prefix.
+func (r *Repository) GetProjectsByUUIDPrefix(prefix string) ([]*models.Project, error) {
+	var projects []*models.Project
+	err := r.db.Where("uuid LIKE ?", strings.ToLower(prefix)+"%").Find(&projects).Error
+	return projects, err
+}
+
 // GetAllProjects retrieves all projects from the database.
 func (r *Repository) GetAllProjects() ([]*models.Project, error) {
 	var projects []*models.Project
@@ -43,16 +56,36 @@ func (r *Repository) GetSubprojects(parentProjectID int) ([]*models.Project, err
 	return projects, err
 }
 
-// UpdateProject updates an existing project in the database.
+// UpdateProject updates an existing project in the database and records its changes in the change log.
 func (r *Repository) UpdateProject(project *models.Project) error {
-	return r.db.Save(project).Error
+	if err := r.db.Save(project).Error; err != nil {
+		return err
+	}
+	return r.logProject(project)
 }
 
-// DeleteProject removes a project from the database by its ID.
+// DeleteProject removes a project from the database by its ID, recording the deletion in the change log.
 func (r *Repository) DeleteProject(id int) error {
+	if err := r.logDeletion(models.SyncItemProject, &models.Project{}, id); err != nil {
+		return err
+	}
 	return r.db.Delete(&models.Project{}, id).Error
 }
 
+// ReplaceProject saves a project as is, keeping its LastModifiedDate, e.g. when applying synced changes.
+// A project without a UUID keeps the one it has in the database.
+func (r *Repository) ReplaceProject(project *models.Project) error {
+	if project.UUID == "" {
+		if existing, err := r.GetProjectByID(project.ID); err == nil {
+			project.UUID = existing.UUID
+		}
+	}
+	if err := r.db.Session(&gorm.Session{SkipHooks: true}).Save(project).Error; err != nil {
+		return err
+	}
+	return r.logProject(project)
+}
+
 // GetNextProjectID retrieves the next available project ID in the database.
 // It selects the maximum project ID and adds 1 to determine the next available ID.
 func (r *Repository) GetNextProjectID() (int, error) {
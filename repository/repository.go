@@ -9,27 +9,83 @@ import (
 	"runtime"
 	"time"
 
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"github.com/d4r1us-drk/clido/models"
 	"github.com/glebarez/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
+// ErrStorage wraps the errors of the database, such as a locked, corrupted or read-only database file.
+var ErrStorage = errcode.New(errcode.Storage, "database error")
+
 // Repository manages the database connection and migrations for the application.
 // It encapsulates the GORM database instance and a migrator responsible for applying database migrations.
 type Repository struct {
-	db       *gorm.DB  // The GORM database instance
-	migrator *Migrator // The migrator responsible for handling database migrations
+	db        *gorm.DB             // The GORM database instance
+	dbPath    string               // The path of the SQLite database file
+	workspace string               // The name of the workspace the database belongs to
+	migrator  *Migrator            // The migrator responsible for handling database migrations
+	workflow  *models.Workflow     // The task workflow, loaded from the data directory
+	clock     *replicaClock        // The hybrid logical clock of the replica, shared with transactions
+	unlogged  bool                 // Whether changes are left out of the change log
+	committed *[]func(*Repository) // Functions run once the current transaction is committed, nil outside one
 }
 
-// NewRepository initializes a new Repository instance, setting up the SQLite database connection.
+// NewRepository initializes a new Repository instance, setting up the SQLite database connection
+// of the given workspace (the current workspace when empty).
 // It also configures a custom GORM logger and applies any pending migrations.
-func NewRepository() (*Repository, error) {
-	// Determine the database path
-	dbPath, err := getDBPath()
+func NewRepository(workspace string) (*Repository, error) {
+	// Determine the workspace and its database path
+	if workspace == "" {
+		currentWorkspace, err := CurrentWorkspace()
+		if err != nil {
+			return nil, err
+		}
+		workspace = currentWorkspace
+	}
+
+	dbPath, err := getDBPath(workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load the task workflow stored next to the database (if any)
+	workflow, err := loadWorkflow(filepath.Dir(dbPath))
 	if err != nil {
 		return nil, err
 	}
 
+	// Open the SQLite database
+	db, err := openDatabase(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize the migrator
+	migrator := NewMigrator()
+
+	// Create the repository instance
+	repo := &Repository{
+		db:        db,
+		dbPath:    dbPath,
+		workspace: workspace,
+		migrator:  migrator,
+		workflow:  workflow,
+		clock:     &replicaClock{},
+	}
+
+	// Run pending database migrations
+	err = repo.migratePending()
+	if err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// openDatabase opens the SQLite database at the given path using GORM.
+func openDatabase(dbPath string) (*gorm.DB, error) {
 	// Custom logger for GORM, disabling verbose logging
 	newLogger := logger.New(
 		log.New(os.Stdout, "\r\n", log.LstdFlags), // Output logger with timestamp
@@ -46,56 +102,117 @@ func NewRepository() (*Repository, error) {
 		Logger: newLogger, // Use the custom logger
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect database: %w", err)
+		return nil, fmt.Errorf("%w: failed to connect database: %w", ErrStorage, err)
 	}
 
-	// Initialize the migrator
-	migrator := NewMigrator()
-
-	// Create the repository instance
-	repo := &Repository{
-		db:       db,
-		migrator: migrator,
+	// Wrap the errors of every statement with ErrStorage, so they are told apart from invalid input.
+	// Missing records are left as is, since they are expected and translated by the callers.
+	wrapErrors := func(db *gorm.DB) {
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) && !errors.Is(db.Error, ErrStorage) {
+			db.Error = fmt.Errorf("%w: %w", ErrStorage, db.Error)
+		}
 	}
-
-	// Run database migrations
-	err = repo.migrator.Migrate(repo.db)
-	if err != nil {
-		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	callbacks := db.Callback()
+	for _, err = range []error{
+		callbacks.Create().Register("clido:storage_error", wrapErrors),
+		callbacks.Query().Register("clido:storage_error", wrapErrors),
+		callbacks.Update().Register("clido:storage_error", wrapErrors),
+		callbacks.Delete().Register("clido:storage_error", wrapErrors),
+		callbacks.Row().Register("clido:storage_error", wrapErrors),
+		callbacks.Raw().Register("clido:storage_error", wrapErrors),
+	} {
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return repo, nil
+	return db, nil
 }
 
-// getDBPath determines the path for the SQLite database based on the operating system.
+// getDataDir determines the directory holding the databases of all workspaces, based on the operating system.
 //
-// On Windows, the path is in the APPDATA directory.
-// On Unix-based systems, the path is in the ~/.local/share/clido directory.
-func getDBPath() (string, error) {
-	var dbPath string
-
+// On Windows, the directory is in the APPDATA directory.
+// On Unix-based systems, the directory is ~/.local/share/clido.
+func getDataDir() (string, error) {
 	// Determine the correct path based on the operating system
 	if runtime.GOOS == "windows" {
 		appDataPath := os.Getenv("APPDATA")
 		if appDataPath == "" {
 			return "", errors.New("the APPDATA environment variable is not set")
 		}
-		dbPath = filepath.Join(appDataPath, "clido", "data.db")
-	} else {
-		homePath := os.Getenv("HOME")
-		if homePath == "" {
-			return "", errors.New("the HOME environment variable is not set")
-		}
-		dbPath = filepath.Join(homePath, ".local", "share", "clido", "data.db")
+		return filepath.Join(appDataPath, "clido"), nil
+	}
+
+	homePath := os.Getenv("HOME")
+	if homePath == "" {
+		return "", errors.New("the HOME environment variable is not set")
+	}
+	return filepath.Join(homePath, ".local", "share", "clido"), nil
+}
+
+// getDBPath determines the path for the SQLite database of a workspace. The default workspace
+// uses the data directory itself, and other workspaces use a subdirectory of it.
+func getDBPath(workspace string) (string, error) {
+	workspaceDir, err := getWorkspaceDir(workspace)
+	if err != nil {
+		return "", err
+	}
+	if !WorkspaceExists(workspace) {
+		return "", fmt.Errorf("%w: '%s'", ErrWorkspaceNotFound, workspace)
 	}
 
 	// Ensure the database directory exists, creating it if necessary
-	dbDir := filepath.Dir(dbPath)
-	if err := os.MkdirAll(dbDir, 0o755); err != nil {
-		return "", fmt.Errorf("error creating database directory: %w", err)
+	if mkdirErr := os.MkdirAll(workspaceDir, 0o755); mkdirErr != nil {
+		return "", fmt.Errorf("error creating database directory: %w", mkdirErr)
+	}
+
+	return filepath.Join(workspaceDir, DatabaseFileName), nil
+}
+
+// Transaction runs fn inside a database transaction, passing it a repository bound to that transaction.
+// The transaction is committed when fn returns nil and rolled back otherwise.
+func (r *Repository) Transaction(fn func(txRepo *Repository) error) error {
+	// Nested transactions share the functions of the outermost one
+	committed := r.committed
+	if committed == nil {
+		committed = &[]func(*Repository){}
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		txRepo := *r
+		txRepo.db = tx
+		txRepo.committed = committed
+		return fn(&txRepo)
+	})
+	if err != nil || r.committed != nil {
+		return err
 	}
 
-	return dbPath, nil
+	for _, run := range *committed {
+		run(r)
+	}
+	return nil
+}
+
+// AfterCommit runs fn once the current transaction is committed, or right away outside a transaction,
+// passing it the repository the transaction was started from. Nothing is run when the transaction is
+// rolled back.
+func (r *Repository) AfterCommit(fn func(repo *Repository)) {
+	if r.committed == nil {
+		fn(r)
+		return
+	}
+	*r.committed = append(*r.committed, fn)
+}
+
+// DBPath returns the path of the SQLite database file.
+func (r *Repository) DBPath() string {
+	return r.dbPath
+}
+
+// Workspace returns the name of the workspace the database belongs to.
+func (r *Repository) Workspace() string {
+	return r.workspace
 }
 
 // Close closes the database connection gracefully.
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"path/filepath"
+
+	"github.com/d4r1us-drk/clido/models"
+)
+
+// SyncDirName is the name of the directory, next to the database, holding the git working tree used for syncing.
+const SyncDirName = "sync"
+
+// SyncDir returns the directory of the git working tree used to sync the database.
+func (r *Repository) SyncDir() string {
+	return filepath.Join(filepath.Dir(r.dbPath), SyncDirName)
+}
+
+// ReplaceProjectUUID gives a project the UUID of the same project added on another database, when both are
+// merged into one. The operations of the change log recorded under the previous UUID are moved to the new one.
+func (r *Repository) ReplaceProjectUUID(id int, uuid string) error {
+	project, err := r.GetProjectByID(id)
+	if err != nil || project.UUID == uuid {
+		return err
+	}
+	err = r.db.Model(&models.ChangeOperation{}).Where("item_key = ?", project.UUID).Update("item_key", uuid).Error
+	if err != nil {
+		return err
+	}
+	return r.db.Model(&models.Project{}).Where("id = ?", id).UpdateColumn("uuid", uuid).Error
+}
@@ -1,12 +1,18 @@
 package repository
 
 import (
+	"strings"
+
 	"github.com/d4r1us-drk/clido/models"
+	"gorm.io/gorm"
 )
 
-// CreateTask inserts a new task into the database.
+// CreateTask inserts a new task into the database and records it in the change log.
 func (r *Repository) CreateTask(task *models.Task) error {
-	return r.db.Create(task).Error
+	if err := r.db.Create(task).Error; err != nil {
+		return err
+	}
+	return r.logTask(task)
 }
 
 // GetTaskByID retrieves a task from the database by its ID.
@@ -19,6 +25,23 @@ func (r *Repository) GetTaskByID(id int) (*models.Task, error) {
 	return &task, nil
 }
 
+// GetTaskByUUID retrieves a task from the database by its UUID.
+func (r *Repository) GetTaskByUUID(uuid string) (*models.Task, error) {
+	var task models.Task
+	err := r.db.Where("uuid = ?", uuid).First(&task).Error
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// GetTasksByUUIDPrefix retrieves the tasks whose UUID starts with the given prefix.
+func (r *Repository) GetTasksByUUIDPrefix(prefix string) ([]*models.Task, error) {
+	var tasks []*models.Task
+	err := r.db.Where("uuid LIKE ?", strings.ToLower(prefix)+"%").Find(&tasks).Error
+	return tasks, err
+}
+
 // GetAllTasks retrieves all tasks from the database.
 func (r *Repository) GetAllTasks() ([]*models.Task, error) {
 	var tasks []*models.Task
@@ -40,16 +63,54 @@ func (r *Repository) GetSubtasks(parentTaskID int) ([]*models.Task, error) {
 	return tasks, err
 }
 
-// UpdateTask updates an existing task in the database.
+// UpdateTask updates an existing task in the database and records its changes in the change log.
 func (r *Repository) UpdateTask(task *models.Task) error {
-	return r.db.Save(task).Error
+	if err := r.db.Save(task).Error; err != nil {
+		return err
+	}
+	return r.logTask(task)
 }
 
-// DeleteTask removes a task from the database by its ID.
+// DeleteTask removes a task and its transition history from the database by its ID, recording the deletion
+// in the change log.
 func (r *Repository) DeleteTask(id int) error {
+	err := r.db.Where("task_id = ?", id).Delete(&models.TaskTransition{}).Error
+	if err != nil {
+		return err
+	}
+	err = r.logDeletion(models.SyncItemTask, &models.Task{}, id)
+	if err != nil {
+		return err
+	}
 	return r.db.Delete(&models.Task{}, id).Error
 }
 
+// ReplaceTask saves a task as is, keeping its LastUpdatedDate, e.g. when applying synced changes.
+// A task without a UUID keeps the one it has in the database.
+func (r *Repository) ReplaceTask(task *models.Task) error {
+	if task.UUID == "" {
+		if existing, err := r.GetTaskByID(task.ID); err == nil {
+			task.UUID = existing.UUID
+		}
+	}
+	if err := r.db.Session(&gorm.Session{SkipHooks: true}).Save(task).Error; err != nil {
+		return err
+	}
+	return r.logTask(task)
+}
+
+// CreateTaskTransition records a change of state of a task.
+func (r *Repository) CreateTaskTransition(transition *models.TaskTransition) error {
+	return r.db.Create(transition).Error
+}
+
+// GetTaskTransitions retrieves the state transitions of a task, oldest first.
+func (r *Repository) GetTaskTransitions(taskID int) ([]*models.TaskTransition, error) {
+	var transitions []*models.TaskTransition
+	err := r.db.Where("task_id = ?", taskID).Order("transition_date, id").Find(&transitions).Error
+	return transitions, err
+}
+
 // GetNextTaskID retrieves the next available task ID in the database.
 // It selects the maximum task ID and adds 1 to determine the next available ID.
 func (r *Repository) GetNextTaskID() (int, error) {
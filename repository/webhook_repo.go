@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/d4r1us-drk/clido/models"
+)
+
+// CreateWebhook adds a webhook.
+func (r *Repository) CreateWebhook(webhook *models.Webhook) error {
+	if webhook.CreationDate.IsZero() {
+		webhook.CreationDate = time.Now()
+	}
+	return r.db.Create(webhook).Error
+}
+
+// GetWebhooks retrieves all webhooks, in the order they were added.
+func (r *Repository) GetWebhooks() ([]*models.Webhook, error) {
+	var webhooks []*models.Webhook
+	err := r.db.Order("id").Find(&webhooks).Error
+	return webhooks, err
+}
+
+// GetWebhookByID retrieves a webhook by its ID.
+func (r *Repository) GetWebhookByID(id int) (*models.Webhook, error) {
+	var webhook models.Webhook
+	if err := r.db.First(&webhook, id).Error; err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// DeleteWebhook removes a webhook and its deliveries.
+func (r *Repository) DeleteWebhook(id int) error {
+	if err := r.db.Where("webhook_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+		return err
+	}
+	return r.db.Delete(&models.Webhook{}, id).Error
+}
+
+// CreateWebhookDelivery queues a delivery.
+func (r *Repository) CreateWebhookDelivery(delivery *models.WebhookDelivery) error {
+	if delivery.CreationDate.IsZero() {
+		delivery.CreationDate = time.Now()
+	}
+	return r.db.Create(delivery).Error
+}
+
+// UpdateWebhookDelivery saves the outcome of a delivery attempt.
+func (r *Repository) UpdateWebhookDelivery(delivery *models.WebhookDelivery) error {
+	return r.db.Save(delivery).Error
+}
+
+// GetPendingWebhookDeliveries retrieves the pending deliveries due at the given time, or all of them when the
+// time is zero, oldest first.
+func (r *Repository) GetPendingWebhookDeliveries(now time.Time) ([]*models.WebhookDelivery, error) {
+	query := r.db.Where("status = ?", models.DeliveryPending)
+	if !now.IsZero() {
+		query = query.Where("next_attempt <= ?", now)
+	}
+	var deliveries []*models.WebhookDelivery
+	err := query.Order("id").Find(&deliveries).Error
+	return deliveries, err
+}
+
+// GetWebhookDeliveries retrieves the latest deliveries, most recent first, of a webhook or of all webhooks
+// when webhookID is 0. At most limit deliveries are returned, or all of them when limit is 0.
+func (r *Repository) GetWebhookDeliveries(webhookID, limit int) ([]*models.WebhookDelivery, error) {
+	query := r.db.Order("id DESC")
+	if webhookID != 0 {
+		query = query.Where("webhook_id = ?", webhookID)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	var deliveries []*models.WebhookDelivery
+	err := query.Find(&deliveries).Error
+	return deliveries, err
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/d4r1us-drk/clido/models"
+)
+
+// WorkflowFileName is the name of the optional file, stored next to the database,
+// that defines a custom task workflow in JSON format.
+const WorkflowFileName = "workflow.json"
+
+// loadWorkflow reads the task workflow from the data directory.
+// The default workflow is returned when no workflow file exists.
+func loadWorkflow(dataDir string) (*models.Workflow, error) {
+	data, err := os.ReadFile(filepath.Join(dataDir, WorkflowFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return models.DefaultWorkflow(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading workflow file: %w", err)
+	}
+
+	var workflow models.Workflow
+	if unmarshalErr := json.Unmarshal(data, &workflow); unmarshalErr != nil {
+		return nil, fmt.Errorf("error parsing workflow file: %w", unmarshalErr)
+	}
+	if validateErr := workflow.Validate(); validateErr != nil {
+		return nil, validateErr
+	}
+
+	return &workflow, nil
+}
+
+// Workflow returns the task workflow in use.
+func (r *Repository) Workflow() *models.Workflow {
+	return r.workflow
+}
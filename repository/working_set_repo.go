@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"github.com/d4r1us-drk/clido/models"
+	"gorm.io/gorm"
+)
+
+// ReplaceWorkingSet replaces the working set with the given entries.
+func (r *Repository) ReplaceWorkingSet(entries []*models.WorkingSetEntry) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.WorkingSetEntry{}).Error; err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+		return tx.Create(entries).Error
+	})
+}
+
+// GetWorkingSetEntry retrieves the working set entry with the given display ID.
+func (r *Repository) GetWorkingSetEntry(displayID int) (*models.WorkingSetEntry, error) {
+	var entry models.WorkingSetEntry
+	err := r.db.First(&entry, displayID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetWorkingSet retrieves the entries of the working set, ordered by display ID.
+func (r *Repository) GetWorkingSet() ([]*models.WorkingSetEntry, error) {
+	var entries []*models.WorkingSetEntry
+	err := r.db.Order("display_id").Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
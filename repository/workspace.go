@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/d4r1us-drk/clido/internal/errcode"
+)
+
+// Constants for workspaces.
+const (
+	DefaultWorkspace         = "default"    // Workspace using the database at the root of the data directory
+	DatabaseFileName         = "data.db"    // Name of the database file of each workspace
+	WorkspacesDirName        = "workspaces" // Directory of the data directory holding the other workspaces
+	CurrentWorkspaceFileName = "workspace"  // File of the data directory holding the name of the current workspace
+)
+
+// Error constants for workspace operations.
+var (
+	ErrInvalidWorkspaceName = errcode.New(errcode.Validation,
+		"workspace names may only contain letters, digits, '-' and '_'",
+	)
+	ErrWorkspaceNotFound = errcode.New(errcode.NotFound, "workspace not found")
+	ErrWorkspaceExists   = errcode.New(errcode.Conflict, "workspace already exists")
+	ErrDefaultWorkspace  = errcode.New(errcode.Conflict, "the default workspace cannot be removed")
+	ErrCurrentWorkspace  = errcode.New(errcode.Conflict, "the current workspace cannot be removed")
+)
+
+// workspaceNamePattern matches valid workspace names.
+var workspaceNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ValidateWorkspaceName checks that a workspace name can be used as a directory name.
+func ValidateWorkspaceName(name string) error {
+	if !workspaceNamePattern.MatchString(name) {
+		return fmt.Errorf("%w: '%s'", ErrInvalidWorkspaceName, name)
+	}
+	return nil
+}
+
+// CurrentWorkspace returns the name of the workspace selected with SetCurrentWorkspace,
+// or the default workspace if none was selected.
+func CurrentWorkspace() (string, error) {
+	dataDir, err := getDataDir()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dataDir, CurrentWorkspaceFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return DefaultWorkspace, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading current workspace: %w", err)
+	}
+
+	name := strings.TrimSpace(string(data))
+	if name == "" {
+		return DefaultWorkspace, nil
+	}
+	return name, nil
+}
+
+// SetCurrentWorkspace selects the workspace used when none is given explicitly.
+func SetCurrentWorkspace(name string) error {
+	if !WorkspaceExists(name) {
+		return fmt.Errorf("%w: '%s'", ErrWorkspaceNotFound, name)
+	}
+
+	dataDir, err := getDataDir()
+	if err != nil {
+		return err
+	}
+	if mkdirErr := os.MkdirAll(dataDir, 0o755); mkdirErr != nil {
+		return fmt.Errorf("error creating data directory: %w", mkdirErr)
+	}
+
+	path := filepath.Join(dataDir, CurrentWorkspaceFileName)
+	if writeErr := os.WriteFile(path, []byte(name+"\n"), 0o644); writeErr != nil {
+		return fmt.Errorf("error saving current workspace: %w", writeErr)
+	}
+	return nil
+}
+
+// ListWorkspaces returns the names of all workspaces, starting with the default workspace.
+func ListWorkspaces() ([]string, error) {
+	dataDir, err := getDataDir()
+	if err != nil {
+		return nil, err
+	}
+
+	workspaces := []string{DefaultWorkspace}
+	entries, err := os.ReadDir(filepath.Join(dataDir, WorkspacesDirName))
+	if errors.Is(err, os.ErrNotExist) {
+		return workspaces, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading workspaces: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() && ValidateWorkspaceName(entry.Name()) == nil {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	return append(workspaces, names...), nil
+}
+
+// WorkspaceExists reports whether a workspace exists. The default workspace always exists.
+func WorkspaceExists(name string) bool {
+	if name == DefaultWorkspace {
+		return true
+	}
+
+	workspaceDir, err := getWorkspaceDir(name)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(workspaceDir)
+	return err == nil && info.IsDir()
+}
+
+// CreateWorkspace creates a workspace with an empty, fully migrated database.
+func CreateWorkspace(name string) error {
+	if WorkspaceExists(name) {
+		return fmt.Errorf("%w: '%s'", ErrWorkspaceExists, name)
+	}
+
+	workspaceDir, err := getWorkspaceDir(name)
+	if err != nil {
+		return err
+	}
+	if mkdirErr := os.MkdirAll(workspaceDir, 0o755); mkdirErr != nil {
+		return fmt.Errorf("error creating workspace directory: %w", mkdirErr)
+	}
+
+	repo, err := NewRepository(name)
+	if err != nil {
+		os.RemoveAll(workspaceDir)
+		return err
+	}
+	return repo.Close()
+}
+
+// RemoveWorkspace deletes a workspace with its database, configuration and backups.
+// The default workspace and the current workspace cannot be removed.
+func RemoveWorkspace(name string) error {
+	if name == DefaultWorkspace {
+		return ErrDefaultWorkspace
+	}
+	if !WorkspaceExists(name) {
+		return fmt.Errorf("%w: '%s'", ErrWorkspaceNotFound, name)
+	}
+
+	current, err := CurrentWorkspace()
+	if err != nil {
+		return err
+	}
+	if name == current {
+		return ErrCurrentWorkspace
+	}
+
+	workspaceDir, err := getWorkspaceDir(name)
+	if err != nil {
+		return err
+	}
+	if removeErr := os.RemoveAll(workspaceDir); removeErr != nil {
+		return fmt.Errorf("error removing workspace: %w", removeErr)
+	}
+	return nil
+}
+
+// getWorkspaceDir returns the directory holding the database and configuration of a workspace.
+func getWorkspaceDir(name string) (string, error) {
+	dataDir, err := getDataDir()
+	if err != nil {
+		return "", err
+	}
+	if name == DefaultWorkspace {
+		return dataDir, nil
+	}
+
+	if validateErr := ValidateWorkspaceName(name); validateErr != nil {
+		return "", validateErr
+	}
+	return filepath.Join(dataDir, WorkspacesDirName, name), nil
+}
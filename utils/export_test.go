@@ -0,0 +1,4 @@
+package utils
+
+// ParseDueDateAt parses a due date, relative days being counted from now.
+var ParseDueDateAt = parseDueDateAt
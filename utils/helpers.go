@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"errors"
+	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -16,11 +19,32 @@ const (
 	PriorityNone   = 4
 )
 
+// MinUUIDPrefixLength is the minimum number of characters of a UUID prefix identifying a project or task.
+const MinUUIDPrefixLength = 4
+
 // ParseIntOrError tries to parse a string as an integer and returns an error if the parsing fails.
 func ParseIntOrError(value string) (int, error) {
 	return strconv.Atoi(value)
 }
 
+// IsUUIDPrefix reports whether a value identifies an item by a prefix of its UUID: at least
+// MinUUIDPrefixLength hexadecimal digits and dashes, which do not read as a numeric ID or ID range.
+// A UUID whose first group is made of digits only can be given with a trailing dash, e.g. "12345678-".
+func IsUUIDPrefix(value string) bool {
+	if len(value) < MinUUIDPrefixLength || len(value) > len("00000000-0000-0000-0000-000000000000") {
+		return false
+	}
+	for _, r := range value {
+		if !strings.ContainsRune("0123456789abcdefABCDEF-", r) {
+			return false
+		}
+	}
+	if _, _, err := parseIDRange(value); err == nil {
+		return false
+	}
+	return true
+}
+
 // WrapText wraps a given text to a specified maximum line length.
 func WrapText(text string, maxLength int) string {
 	if len(text) <= maxLength {
@@ -74,9 +98,20 @@ func GetPriorityString(priority int) string {
 
 // ColoredPastDue returns a colored string depending on the due date.
 func ColoredPastDue(dueDate *time.Time, completed bool) string {
-	if dueDate == nil {
+	if !IsPastDue(dueDate) {
 		return color.GreenString("no")
 	}
+	if completed {
+		return color.GreenString("yes")
+	}
+	return color.RedString("yes")
+}
+
+// IsPastDue reports whether the due date, interpreted as local time, has passed.
+func IsPastDue(dueDate *time.Time) bool {
+	if dueDate == nil {
+		return false
+	}
 
 	// Ensure the current time is in the local time zone
 	now := time.Now()
@@ -94,14 +129,7 @@ func ColoredPastDue(dueDate *time.Time, completed bool) string {
 		localLocation, // Use local timezone for interpretation
 	)
 
-	if now.After(dueDateAsLocalTime) {
-		if completed {
-			return color.GreenString("yes")
-		}
-		return color.RedString("yes")
-	}
-
-	return color.GreenString("no")
+	return now.After(dueDateAsLocalTime)
 }
 
 // FormatDate formats a time.Time object into a human-readable string in the format "YYYY-MM-DD HH:MM".
@@ -112,11 +140,123 @@ func FormatDate(t *time.Time) string {
 	return t.Format("2006-01-02 15:04")
 }
 
-// ParseDueDate parses a date string in the format "2006-01-02 15:04" and returns a pointer to time.Time.
+// DueDateFormats describes the due dates accepted by ParseDueDate, for help texts.
+const DueDateFormats = "YYYY-MM-DD HH:MM, YYYY-MM-DD, today, tomorrow, a weekday such as fri, or +3d/+2w"
+
+// ErrInvalidDate is returned by ParseDueDate for dates in none of the accepted formats.
+var ErrInvalidDate = errors.New("invalid date")
+
+// ParseDueDate parses a due date and returns a pointer to time.Time. Besides the "2006-01-02 15:04" format,
+// it accepts a day, given as "2006-01-02", "today", "tomorrow", a weekday name or abbreviation (the next
+// one, today excluded) or a number of days or weeks from today such as "+3d" or "+2w". Days are due at the
+// end of the day, 23:59.
 func ParseDueDate(dueDateStr string) (*time.Time, error) {
-	date, err := time.Parse("2006-01-02 15:04", dueDateStr)
-	if err != nil {
-		return nil, err
+	return parseDueDateAt(dueDateStr, time.Now())
+}
+
+// parseDueDateAt parses a due date, relative days being counted from now.
+func parseDueDateAt(dueDateStr string, now time.Time) (*time.Time, error) {
+	if date, err := time.Parse("2006-01-02 15:04", dueDateStr); err == nil {
+		return &date, nil
+	}
+	if day, err := time.Parse("2006-01-02", dueDateStr); err == nil {
+		return endOfDay(day, 0), nil
+	}
+
+	value := strings.ToLower(strings.TrimSpace(dueDateStr))
+	switch value {
+	case "today":
+		return endOfDay(now, 0), nil
+	case "tomorrow":
+		return endOfDay(now, 1), nil
+	}
+
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		name := strings.ToLower(weekday.String())
+		if value == name || value == name[:3] {
+			days := (int(weekday)-int(now.Weekday())+6)%7 + 1
+			return endOfDay(now, days), nil
+		}
+	}
+
+	if len(value) > 2 && value[0] == '+' {
+		count, err := strconv.Atoi(value[1 : len(value)-1])
+		if err == nil && count >= 0 {
+			switch value[len(value)-1] {
+			case 'd':
+				return endOfDay(now, count), nil
+			case 'w':
+				return endOfDay(now, 7*count), nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%w: '%s'", ErrInvalidDate, dueDateStr)
+}
+
+// endOfDay returns 23:59 of the day a number of days after the day of t. Like the dates given with their
+// time, it is stored as is, in UTC.
+func endOfDay(t time.Time, days int) *time.Time {
+	year, month, day := t.Date()
+	date := time.Date(year, month, day+days, 23, 59, 0, 0, time.UTC)
+	return &date
+}
+
+// MaxIDRangeLength is the maximum number of IDs a single range such as "8-12" may expand to.
+const MaxIDRangeLength = 10000
+
+// ParseIDList parses lists of IDs and ID ranges such as "3,5,8-12" into a sorted list of unique IDs.
+// Each value may itself contain a comma-separated list.
+func ParseIDList(values ...string) ([]int, error) {
+	seen := make(map[int]bool)
+	var ids []int
+
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+
+			start, end, err := parseIDRange(part)
+			if err != nil {
+				return nil, err
+			}
+
+			for id := start; id <= end; id++ {
+				if !seen[id] {
+					seen[id] = true
+					ids = append(ids, id)
+				}
+			}
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil, errors.New("no IDs provided")
+	}
+
+	sort.Ints(ids)
+	return ids, nil
+}
+
+// parseIDRange parses a single ID ("5") or an inclusive ID range ("8-12").
+func parseIDRange(part string) (int, int, error) {
+	startStr, endStr, isRange := strings.Cut(part, "-")
+	start, err := strconv.Atoi(startStr)
+	if err != nil || start < 1 {
+		return 0, 0, fmt.Errorf("invalid ID '%s'", part)
+	}
+	if !isRange {
+		return start, start, nil
+	}
+
+	end, err := strconv.Atoi(endStr)
+	if err != nil || end < start {
+		return 0, 0, fmt.Errorf("invalid ID range '%s'", part)
+	}
+	if end-start >= MaxIDRangeLength {
+		return 0, 0, fmt.Errorf("ID range '%s' is too large", part)
 	}
-	return &date, nil
+	return start, end, nil
 }
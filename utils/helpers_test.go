@@ -0,0 +1,117 @@
+package utils_test
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/d4r1us-drk/clido/utils"
+)
+
+func TestParseIDList(t *testing.T) {
+	maxRange := "1-" + strconv.Itoa(utils.MaxIDRangeLength)
+	tooLarge := "1-" + strconv.Itoa(utils.MaxIDRangeLength+1)
+
+	tests := []struct {
+		name    string
+		values  []string
+		want    []int
+		wantErr bool
+	}{
+		{"single ID", []string{"7"}, []int{7}, false},
+		{"list with a range", []string{"1,3-5"}, []int{1, 3, 4, 5}, false},
+		{"several values", []string{"9", "2,4"}, []int{2, 4, 9}, false},
+		{"duplicates", []string{"3,1-3", "2"}, []int{1, 2, 3}, false},
+		{"one-item range", []string{"4-4"}, []int{4}, false},
+		{"empty items", []string{"1,, 2 ,", ""}, []int{1, 2}, false},
+		{"only empty items", []string{",", " "}, nil, true},
+		{"no values", nil, nil, true},
+		{"reversed range", []string{"5-3"}, nil, true},
+		{"open range", []string{"3-"}, nil, true},
+		{"zero", []string{"0"}, nil, true},
+		{"negative", []string{"-2"}, nil, true},
+		{"not a number", []string{"1,abc"}, nil, true},
+		{"largest range", []string{maxRange}, nil, false},
+		{"range too large", []string{tooLarge}, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := utils.ParseIDList(tt.values...)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ParseIDList(%q) error = %v, want error %t", tt.name, tt.values, err, tt.wantErr)
+			continue
+		}
+		if tt.want != nil && !slices.Equal(got, tt.want) {
+			t.Errorf("%s: ParseIDList(%q) = %v, want %v", tt.name, tt.values, got, tt.want)
+		}
+	}
+
+	ids, err := utils.ParseIDList(maxRange)
+	if err != nil || len(ids) != utils.MaxIDRangeLength || ids[len(ids)-1] != utils.MaxIDRangeLength {
+		t.Errorf("ParseIDList(%s) = %d IDs, %v, want %d", maxRange, len(ids), err, utils.MaxIDRangeLength)
+	}
+}
+
+func TestIsUUIDPrefix(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{"short prefix", "4f2a", true},
+		{"upper case", "4F2A9C", true},
+		{"full UUID", "6ba7b810-9dad-11d1-80b4-00c04fd430c8", true},
+		{"too short", "4f2", false},
+		{"too long", "6ba7b810-9dad-11d1-80b4-00c04fd430c8a", false},
+		{"not hexadecimal", "4f2g", false},
+		{"numeric ID", "1234", false},
+		{"ID range", "1234-5678", false},
+		{"digits with a trailing dash", "12345678-", true},
+		{"digit groups not forming a range", "12345678-9dad", true},
+		{"reversed range", "5678-1234", true},
+		{"display ID", "@123", false},
+		{"ID list", "1,2,3", false},
+	}
+	for _, tt := range tests {
+		if got := utils.IsUUIDPrefix(tt.value); got != tt.want {
+			t.Errorf("%s: IsUUIDPrefix(%q) = %t, want %t", tt.name, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseDueDate(t *testing.T) {
+	// A Wednesday
+	now := time.Date(2024, time.May, 1, 15, 30, 0, 0, time.UTC)
+	endOfDay := func(day int) time.Time {
+		return time.Date(2024, time.May, day, 23, 59, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"2024-06-02 08:15", time.Date(2024, time.June, 2, 8, 15, 0, 0, time.UTC)},
+		{"2024-06-02", time.Date(2024, time.June, 2, 23, 59, 0, 0, time.UTC)},
+		{"today", endOfDay(1)},
+		{"Tomorrow", endOfDay(2)},
+		{"fri", endOfDay(3)},
+		{"monday", endOfDay(6)},
+		{"wed", endOfDay(8)}, // The next one, today excluded
+		{"+0d", endOfDay(1)},
+		{"+3d", endOfDay(4)},
+		{"+2w", endOfDay(15)},
+	}
+	for _, tt := range tests {
+		got, err := utils.ParseDueDateAt(tt.value, now)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseDueDate(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", "soon", "+3", "+d", "+-1d", "+3m", "2024-13-01", "fr"} {
+		if _, err := utils.ParseDueDateAt(value, now); !errors.Is(err, utils.ErrInvalidDate) {
+			t.Errorf("ParseDueDate(%q) error = %v, want %v", value, err, utils.ErrInvalidDate)
+		}
+	}
+}
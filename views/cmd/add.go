@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"github.com/d4r1us-drk/clido/models"
+	"github.com/d4r1us-drk/clido/utils"
+	"github.com/spf13/cobra"
+)
+
+// quickAddPreviewJSON is the JSON output of 'add --dry-run': the task that would be created.
+type quickAddPreviewJSON struct {
+	Name          string     `json:"name"`
+	Project       string     `json:"project"`
+	ProjectID     *int       `json:"project_id,omitempty"`
+	CreateProject bool       `json:"create_project"`
+	Priority      int        `json:"priority"`
+	DueDate       *time.Time `json:"due_date,omitempty"`
+	Tags          []string   `json:"tags"`
+}
+
+// NewAddCmd creates and returns the 'add' command for creating tasks from a quick-add text.
+func NewAddCmd(
+	projectController *controllers.ProjectController,
+	taskController *controllers.TaskController,
+) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add <text>",
+		Short: "Create a task from a quick-add text",
+		Long: "Create a task from a single line such as \"Pay invoice +Finance !1 due:fri #billing @home\".\n\n" +
+			"Tokens are read as follows, the other words making up the name of the task:\n" +
+			"  +Project   the project of the task, by name, ID or UUID prefix\n" +
+			"  !1 to !4   the priority, from High to None\n" +
+			"  due:date   the due date (" + utils.DueDateFormats + ")\n" +
+			"  #tag       a tag\n" +
+			"  @context   a context, stored as a tag starting with '@'\n\n" +
+			"Double quotes group words, e.g. +\"Big Project\". A token starting with a backslash or a double " +
+			"quote is kept in the name, e.g. \\#1 or \"due:soon\". Several arguments are joined with spaces.\n\n" +
+			"A project that does not exist is an error, unless --create-project is given and the project is " +
+			"named rather than given by a numeric ID.",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			quickAdd, err := controllers.ParseQuickAdd(strings.Join(args, " "))
+			if err != nil {
+				return fmt.Errorf("error parsing task: %w", err)
+			}
+			return addTask(cmd, projectController, taskController, quickAdd)
+		},
+	}
+
+	cmd.Flags().StringP("project", "p", "", "Project name, ID or UUID prefix when the text has no +Project token")
+	cmd.Flags().StringP("description", "d", "", "Description of the task")
+	cmd.Flags().BoolP("create-project", "c", false, "Create the project when it does not exist")
+	cmd.Flags().Bool("dry-run", false, "Preview the task without creating it")
+	addJSONFlag(cmd, "Output the created task, or the preview with --dry-run, in JSON format")
+
+	return cmd
+}
+
+// addTask creates the task described by a quick-add text, after creating its project if asked to, or
+// previews it with --dry-run.
+func addTask(
+	cmd *cobra.Command,
+	projectController *controllers.ProjectController,
+	taskController *controllers.TaskController,
+	quickAdd *controllers.QuickAdd,
+) error {
+	description, _ := cmd.Flags().GetString("description")
+	createProject, _ := cmd.Flags().GetBool("create-project")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	outputJSON, _ := cmd.Flags().GetBool("json")
+
+	projectIdentifier := quickAdd.Project
+	if projectIdentifier == "" {
+		projectIdentifier, _ = cmd.Flags().GetString("project")
+	}
+	if projectIdentifier == "" {
+		return errcode.New(errcode.Validation, "no project given. Add a +Project token or use --project")
+	}
+
+	// Projects are only created for names, as a numeric identifier matching no project is a wrong ID
+	project, err := projectController.GetProject(projectIdentifier)
+	_, parseErr := utils.ParseIntOrError(projectIdentifier)
+	switch {
+	case errors.Is(err, controllers.ErrNoProjectFound) && createProject && parseErr != nil:
+		project = nil
+	case errors.Is(err, controllers.ErrNoProjectFound) && parseErr != nil:
+		return fmt.Errorf("error adding task: %w (use --create-project to create it)", err)
+	case err != nil:
+		return fmt.Errorf("error adding task: %w", err)
+	}
+
+	var dueDate *time.Time
+	if quickAdd.Due != "" {
+		if dueDate, err = utils.ParseDueDate(quickAdd.Due); err != nil {
+			return fmt.Errorf("error adding task: %w: '%s'", controllers.ErrInvalidDueDate, quickAdd.Due)
+		}
+	}
+
+	if dryRun {
+		return printQuickAddPreview(cmd, quickAdd, projectIdentifier, project, dueDate, outputJSON)
+	}
+
+	if project == nil {
+		if project, err = projectController.CreateProject(projectIdentifier, "", ""); err != nil {
+			return fmt.Errorf("error creating project: %w", err)
+		}
+		if !outputJSON {
+			cmd.Println("Project '" + project.Name + "' (ID: " + strconv.Itoa(project.ID) + ") created successfully.")
+		}
+	}
+
+	task, err := taskController.CreateTask(
+		quickAdd.Name,
+		description,
+		strconv.Itoa(project.ID),
+		"",
+		quickAdd.Due,
+		quickAdd.Priority,
+		quickAdd.Tags,
+	)
+	if err != nil {
+		return fmt.Errorf("error creating task: %w", err)
+	}
+
+	if outputJSON {
+		return printJSON(cmd, task)
+	}
+	cmd.Println("Task '" + task.Name + "' (ID: " + strconv.Itoa(task.ID) + ") created successfully in project '" +
+		project.Name + "'.")
+	return nil
+}
+
+// printQuickAddPreview prints the task a quick-add text would create. The project is nil when it would be
+// created.
+func printQuickAddPreview(
+	cmd *cobra.Command,
+	quickAdd *controllers.QuickAdd,
+	projectIdentifier string,
+	project *models.Project,
+	dueDate *time.Time,
+	outputJSON bool,
+) error {
+	tags := quickAdd.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	priority := quickAdd.Priority
+	if priority == PriorityEmpty {
+		priority = utils.PriorityNone
+	}
+
+	if outputJSON {
+		preview := quickAddPreviewJSON{
+			Name:          quickAdd.Name,
+			Project:       projectIdentifier,
+			CreateProject: project == nil,
+			Priority:      priority,
+			DueDate:       dueDate,
+			Tags:          tags,
+		}
+		if project != nil {
+			preview.Project = project.Name
+			preview.ProjectID = &project.ID
+		}
+		return printJSON(cmd, preview)
+	}
+
+	projectLabel := projectIdentifier + " (will be created)"
+	if project != nil {
+		projectLabel = formatProjectLabel(project)
+	}
+
+	cmd.Println("Dry run: the following task would be created.")
+	printFieldTable(cmd, [][]string{
+		{"Name", utils.WrapText(quickAdd.Name, MaxProjectDescLength)},
+		{"Project", projectLabel},
+		{"Priority", utils.GetPriorityString(priority)},
+		{"Due Date", utils.FormatDate(dueDate)},
+		{"Tags", formatTags(strings.Join(tags, ","))},
+	})
+	return nil
+}
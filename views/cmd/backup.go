@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/utils"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// NewBackupCmd creates and returns the 'backup' command, which writes a consistent copy of the
+// database, and its 'list' subcommand.
+func NewBackupCmd(backupController *controllers.BackupController) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Back up the database",
+		Long: "Write a consistent copy of the database, safe to take while clido is in use, to the given " +
+			"path or to the backup directory. Automatic backups are also written before migrations, " +
+			"removals and restores, and only the most recent ones are kept.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			path, _ := cmd.Flags().GetString("to")
+
+			backupPath, err := backupController.CreateBackup(path)
+			if err != nil {
+				return fmt.Errorf("error creating backup: %w", err)
+			}
+
+			cmd.Println("Database backed up to '" + backupPath + "'.")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("to", "t", "", "Path of the backup file (defaults to the backup directory)")
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the backups in the backup directory",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			backups, err := backupController.ListBackups()
+			if err != nil {
+				return fmt.Errorf("error listing backups: %w", err)
+			}
+			if len(backups) == 0 {
+				cmd.Println("No backups found in '" + backupController.BackupDir() + "'.")
+				return nil
+			}
+
+			table := tablewriter.NewWriter(cmd.OutOrStdout())
+			table.SetHeader([]string{"Name", "Date", "Size", "Type", "Schema"})
+			for _, backup := range backups {
+				backupType := "manual"
+				if backup.Automatic {
+					backupType = "automatic"
+				}
+				schema := backup.SchemaVersion
+				if schema == "" {
+					schema = "unreadable"
+				}
+
+				table.Append([]string{
+					backup.Name,
+					utils.FormatDate(&backup.CreationDate),
+					formatSize(backup.Size),
+					backupType,
+					schema,
+				})
+			}
+			table.Render()
+			return nil
+		},
+	})
+
+	return cmd
+}
+
+// NewRestoreCmd creates and returns the 'restore' command, which replaces the database with a backup.
+func NewRestoreCmd(backupController *controllers.BackupController) *cobra.Command {
+	return &cobra.Command{
+		Use:   "restore <file>",
+		Short: "Restore the database from a backup",
+		Long: "Replace the database with a backup, given by path or by name in the backup directory " +
+			"(see 'backup list'). Backups created by a newer version of clido are rejected, and older ones " +
+			"are migrated. The current database is backed up automatically first.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			safetyBackup, err := backupController.RestoreBackup(args[0])
+			if safetyBackup != "" {
+				cmd.Println("The previous database was backed up to '" + safetyBackup + "'.")
+			}
+			if err != nil {
+				return fmt.Errorf("error restoring backup: %w", err)
+			}
+
+			cmd.Println("Database restored from '" + args[0] + "'.")
+			return nil
+		},
+	}
+}
+
+// autoBackup writes an automatic backup before a destructive command, unless --no-backup is set.
+func autoBackup(cmd *cobra.Command, backupController *controllers.BackupController, reason string) error {
+	noBackup, _ := cmd.Flags().GetBool("no-backup")
+	if noBackup {
+		return nil
+	}
+
+	if _, err := backupController.AutoBackup(reason); err != nil {
+		return fmt.Errorf("error creating automatic backup (use --no-backup to skip it): %w", err)
+	}
+	return nil
+}
+
+// formatSize formats a size in bytes for display.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return strconv.FormatInt(size, 10) + " B"
+	}
+
+	value, suffix := float64(size)/unit, "KiB"
+	for _, next := range []string{"MiB", "GiB"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, next
+	}
+	return strconv.FormatFloat(value, 'f', 1, 64) + " " + suffix
+}
@@ -0,0 +1,284 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"github.com/d4r1us-drk/clido/models"
+	"github.com/d4r1us-drk/clido/utils"
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// Constants for board rendering.
+const (
+	DefaultCardWidth = 24 // Default width of a task card
+	MinCardWidth     = 10 // Minimum width of a task card
+	MaxCardNameLines = 3  // Maximum number of lines of the task name shown on a card
+)
+
+// boardColumn is a column of the board with the tasks it holds.
+type boardColumn struct {
+	key   string // The key used to match WIP limits (state, priority or project name)
+	title string
+	tasks []*models.Task
+}
+
+// NewBoardCmd creates and returns the 'board' command, which displays tasks as cards in columns
+// grouped by workflow state, priority or project.
+func NewBoardCmd(
+	projectController *controllers.ProjectController,
+	taskController *controllers.TaskController,
+) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "board",
+		Short: "Display tasks as a kanban board",
+		Long: "Display tasks as cards in columns grouped by workflow state, priority or project, with the " +
+			"number of tasks per column. Columns holding more tasks than their WIP limit are highlighted.\n\n" +
+			"Cards show the display IDs given to open tasks by 'list tasks' (e.g. @3), or the IDs of the " +
+			"other tasks (e.g. #12).",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			projectFilter, _ := cmd.Flags().GetString("project")
+			groupBy, _ := cmd.Flags().GetString("by")
+			openOnly, _ := cmd.Flags().GetBool("open")
+			cardWidth, _ := cmd.Flags().GetInt("width")
+			wipFlags, _ := cmd.Flags().GetStringToInt("wip")
+
+			if cardWidth < MinCardWidth {
+				return errcode.New(errcode.Validation, "invalid card width. Use at least "+strconv.Itoa(MinCardWidth))
+			}
+
+			tasks, _, err := taskController.ListTasksByProjectFilter(projectFilter)
+			if err != nil {
+				return fmt.Errorf("error listing tasks: %w", err)
+			}
+
+			workflow := taskController.Workflow()
+			if openOnly {
+				var open []*models.Task
+				for _, task := range tasks {
+					if !workflow.IsTerminal(task.State) {
+						open = append(open, task)
+					}
+				}
+				tasks = open
+			}
+
+			var columns []*boardColumn
+			wipLimits := make(map[string]int)
+			switch groupBy {
+			case "state":
+				columns = groupTasksByState(tasks, workflow)
+				for state, limit := range workflow.WIPLimits {
+					wipLimits[state] = limit
+				}
+			case "priority":
+				columns = groupTasksByPriority(tasks)
+			case "project":
+				columns, err = groupTasksByProject(tasks, projectController)
+				if err != nil {
+					return fmt.Errorf("error listing projects: %w", err)
+				}
+			default:
+				return errcode.New(errcode.Validation, "invalid grouping. Use '--by state', '--by priority' or '--by project'")
+			}
+
+			for key, limit := range wipFlags {
+				wipLimits[key] = limit
+			}
+
+			displayIDs, err := taskController.DisplayIDs(tasks)
+			if err != nil {
+				return fmt.Errorf("error reading display IDs: %w", err)
+			}
+
+			printBoard(cmd, columns, displayIDs, wipLimits, cardWidth)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("project", "p", "", "Only show tasks of the given project name, ID or UUID prefix")
+	cmd.Flags().StringP("by", "b", "state", "Group tasks by 'state', 'priority' or 'project'")
+	cmd.Flags().BoolP("open", "o", false, "Only show tasks that are not closed")
+	cmd.Flags().IntP("width", "W", DefaultCardWidth, "Width of the task cards")
+	cmd.Flags().StringToInt("wip", nil, "WIP limits per column (e.g. --wip in-progress=3,review=2)")
+
+	return cmd
+}
+
+// groupTasksByState creates one column per workflow state, in workflow order.
+func groupTasksByState(tasks []*models.Task, workflow *models.Workflow) []*boardColumn {
+	columns := make([]*boardColumn, 0, len(workflow.States))
+	byState := make(map[string]*boardColumn, len(workflow.States))
+
+	for _, state := range workflow.States {
+		column := &boardColumn{key: state, title: state}
+		byState[state] = column
+		columns = append(columns, column)
+	}
+
+	for _, task := range tasks {
+		column, exists := byState[task.State]
+		if !exists {
+			// Tasks left in a state removed from the workflow get their own column
+			column = &boardColumn{key: task.State, title: task.State}
+			byState[task.State] = column
+			columns = append(columns, column)
+		}
+		column.tasks = append(column.tasks, task)
+	}
+
+	return columns
+}
+
+// groupTasksByPriority creates one column per priority level, from high to none.
+func groupTasksByPriority(tasks []*models.Task) []*boardColumn {
+	var columns []*boardColumn
+	byPriority := make(map[int]*boardColumn)
+
+	for priority := utils.PriorityHigh; priority <= utils.PriorityNone; priority++ {
+		name := utils.GetPriorityString(priority)
+		column := &boardColumn{key: strings.ToLower(name), title: name}
+		byPriority[priority] = column
+		columns = append(columns, column)
+	}
+
+	for _, task := range tasks {
+		column, exists := byPriority[task.Priority]
+		if !exists {
+			column = byPriority[utils.PriorityNone]
+		}
+		column.tasks = append(column.tasks, task)
+	}
+
+	return columns
+}
+
+// groupTasksByProject creates one column per project holding tasks, sorted by project name.
+func groupTasksByProject(
+	tasks []*models.Task,
+	projectController *controllers.ProjectController,
+) ([]*boardColumn, error) {
+	projects, err := projectController.ListProjects()
+	if err != nil {
+		return nil, err
+	}
+
+	projectNames := make(map[int]string, len(projects))
+	for _, project := range projects {
+		projectNames[project.ID] = project.Name
+	}
+
+	var columns []*boardColumn
+	byProject := make(map[int]*boardColumn)
+	for _, task := range tasks {
+		column, exists := byProject[task.ProjectID]
+		if !exists {
+			name := projectNames[task.ProjectID]
+			column = &boardColumn{key: name, title: name}
+			byProject[task.ProjectID] = column
+			columns = append(columns, column)
+		}
+		column.tasks = append(column.tasks, task)
+	}
+
+	sort.SliceStable(columns, func(i, j int) bool {
+		return strings.ToLower(columns[i].title) < strings.ToLower(columns[j].title)
+	})
+	return columns, nil
+}
+
+// printBoard renders the columns side by side, one card per cell, with the task count
+// (and WIP limit, if any) of each column in the footer.
+func printBoard(
+	cmd *cobra.Command,
+	columns []*boardColumn,
+	displayIDs map[int]int,
+	wipLimits map[string]int,
+	cardWidth int,
+) {
+	if len(columns) == 0 {
+		cmd.Println("No tasks to display.")
+		return
+	}
+
+	table := tablewriter.NewWriter(cmd.OutOrStdout())
+	table.SetAutoFormatHeaders(false)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+
+	headers := make([]string, len(columns))
+	footers := make([]string, len(columns))
+	rowCount := 0
+	for i, column := range columns {
+		headers[i] = utils.WrapText(column.title, cardWidth)
+		footers[i] = formatColumnCount(len(column.tasks), wipLimits[column.key])
+		rowCount = max(rowCount, len(column.tasks))
+	}
+	table.SetHeader(headers)
+	table.SetFooter(footers)
+
+	for row := range rowCount {
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			if row < len(column.tasks) {
+				cells[i] = formatTaskCard(column.tasks[row], displayIDs, cardWidth)
+			}
+		}
+		table.Append(cells)
+	}
+
+	table.Render()
+}
+
+// formatColumnCount formats the number of tasks in a column, highlighting columns over their WIP limit.
+func formatColumnCount(count, limit int) string {
+	if limit <= 0 {
+		return strconv.Itoa(count)
+	}
+
+	text := strconv.Itoa(count) + "/" + strconv.Itoa(limit)
+	if count > limit {
+		return color.RedString(text + " over WIP")
+	}
+	return color.GreenString(text)
+}
+
+// formatTaskCard formats a task as a card: its display ID, or its ID when it has none, and its name,
+// truncated to a few lines, followed by its due date when it has one, highlighted when past due.
+func formatTaskCard(task *models.Task, displayIDs map[int]int, cardWidth int) string {
+	label := "#" + strconv.Itoa(task.ID)
+	if displayID, found := displayIDs[task.ID]; found {
+		label = controllers.DisplayIDPrefix + strconv.Itoa(displayID)
+	}
+
+	lines := strings.Split(utils.WrapText(label+" "+task.Name, cardWidth), "\n")
+	if len(lines) > MaxCardNameLines {
+		lines = lines[:MaxCardNameLines]
+		lines[MaxCardNameLines-1] = truncateLine(lines[MaxCardNameLines-1], cardWidth)
+	}
+
+	if task.DueDate != nil {
+		due := utils.WrapText("due "+utils.FormatDate(task.DueDate), cardWidth)
+		if task.CompletionDate == nil && utils.IsPastDue(task.DueDate) {
+			due = color.RedString(due)
+		}
+		lines = append(lines, due)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// truncateLine shortens a line so that it fits the card width with a trailing ellipsis.
+func truncateLine(line string, cardWidth int) string {
+	runes := []rune(line)
+	if len(runes) >= cardWidth {
+		runes = runes[:cardWidth-1]
+	}
+	return string(runes) + "…"
+}
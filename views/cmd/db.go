@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/utils"
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// NewDBCmd creates and returns the 'db' command, which manages the schema migrations of the database.
+func NewDBCmd(databaseController *controllers.DatabaseController) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Manage the database schema",
+		Long: "Apply, roll back or inspect the schema migrations of the database. Pending migrations are " +
+			"normally applied automatically; after a rollback, they are only applied again with 'db migrate', " +
+			"and other commands using the database are refused until then.",
+	}
+
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply pending migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			target, _ := cmd.Flags().GetString("to")
+
+			applied, err := databaseController.Migrate(target)
+			if err != nil {
+				return fmt.Errorf("error migrating database: %w", err)
+			}
+
+			if len(applied) == 0 {
+				cmd.Println("The database is up to date.")
+				return nil
+			}
+			cmd.Println("Applied migration(s): " + strings.Join(applied, ", ") + ".")
+			return nil
+		},
+	}
+	migrateCmd.Flags().StringP("to", "t", "", "Only migrate up to the given version (pauses automatic migrations)")
+
+	rollbackCmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Revert applied migrations",
+		Long: "Revert the latest applied migrations (one by default), or every migration newer than a version. " +
+			"The database is backed up first, and automatic migrations are paused until 'db migrate' is run.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			target, _ := cmd.Flags().GetString("to")
+			steps, _ := cmd.Flags().GetInt("steps")
+
+			reverted, err := databaseController.Rollback(target, steps)
+			if err != nil {
+				return fmt.Errorf("error rolling back database: %w", err)
+			}
+
+			if len(reverted) == 0 {
+				cmd.Println("No migrations to roll back.")
+				return nil
+			}
+			cmd.Println("Rolled back migration(s): " + strings.Join(reverted, ", ") + ".")
+			return nil
+		},
+	}
+	rollbackCmd.Flags().StringP("to", "t", "", "Roll back every migration newer than the given version")
+	rollbackCmd.Flags().IntP("steps", "n", 1, "Number of migrations to roll back")
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the state of every migration",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			statuses, hold, err := databaseController.Status()
+			if err != nil {
+				return fmt.Errorf("error retrieving migration status: %w", err)
+			}
+
+			table := tablewriter.NewWriter(cmd.OutOrStdout())
+			table.SetHeader([]string{"Version", "Description", "Status", "Applied"})
+			for _, status := range statuses {
+				state, appliedAt := color.YellowString("pending"), ""
+				switch {
+				case status.Unknown:
+					state = color.RedString("unknown")
+				case status.Modified:
+					state = color.RedString("modified")
+				case status.Applied:
+					state = color.GreenString("applied")
+				}
+				if status.AppliedAt != nil {
+					appliedAt = utils.FormatDate(status.AppliedAt)
+				}
+
+				table.Append([]string{status.Version, status.Description, state, appliedAt})
+			}
+			table.Render()
+
+			if hold != nil {
+				version := hold.Version
+				if version == "" {
+					version = "none"
+				}
+				cmd.Println("Automatic migrations are paused at schema version " + version + " since " +
+					utils.FormatDate(&hold.HeldAt) + ". Run 'clido db migrate' to resume them.")
+			}
+			return nil
+		},
+	}
+
+	cmd.AddCommand(migrateCmd, rollbackCmd, statusCmd)
+	return cmd
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/models"
+	"github.com/spf13/cobra"
+)
+
+// NewDoneCmd creates and returns the 'done' command for marking tasks as completed.
+// Unlike 'toggle', running it several times always leaves the tasks completed.
+func NewDoneCmd(taskController *controllers.TaskController) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "done <task_ids>",
+		Short: "Mark tasks as completed",
+		Long: "Mark tasks identified by IDs or ID ranges (e.g. 3,5,8-12), or selected with a query " +
+			"(--where), as completed. Tasks that are already completed are left untouched. " +
+			"A task with open subtasks can only be completed with --recursive or --force, and only the " +
+			"transitions allowed by the workflow are accepted unless --force is set.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tasks, selectErr := selectTasks(cmd, taskController, args)
+			if selectErr != nil {
+				return selectErr
+			}
+			if previewSelection(cmd, "completed", taskLabels(tasks)) {
+				return nil
+			}
+
+			recursive, _ := cmd.Flags().GetBool("recursive")
+			force, _ := cmd.Flags().GetBool("force")
+			completeParent, _ := cmd.Flags().GetBool("complete-parent")
+
+			var changed, parents []*models.Task
+			err := taskController.InTransaction(func(txController *controllers.TaskController) error {
+				for _, task := range tasks {
+					completed, completeErr := txController.CompleteTask(task.ID, recursive, force)
+					if completeErr != nil {
+						return fmt.Errorf("task with ID '%d': %w", task.ID, completeErr)
+					}
+					changed = append(changed, completed...)
+
+					if completeParent {
+						completedParents, parentErr := txController.CompleteParents(task.ID)
+						if parentErr != nil {
+							return fmt.Errorf("task with ID '%d': %w", task.ID, parentErr)
+						}
+						parents = append(parents, completedParents...)
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("error completing task: %w", err)
+			}
+
+			if outputJSON, _ := cmd.Flags().GetBool("json"); outputJSON {
+				return printChangedTasksJSON(cmd, append(changed, parents...))
+			}
+
+			printCompletionChanges(cmd, tasks, changed, "completed")
+			for _, parent := range parents {
+				cmd.Println("Parent task (ID: " + strconv.Itoa(parent.ID) + ") has been set as completed.")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolP("recursive", "r", false, "Also mark all subtasks as completed")
+	cmd.Flags().BoolP("force", "f", false,
+		"Complete the task even if it has open subtasks or the workflow does not allow it")
+	cmd.Flags().BoolP("complete-parent", "c", false,
+		"Complete the parent task when all of its subtasks are completed")
+	addSelectionFlags(cmd)
+	addJSONFlag(cmd, "Output the tasks whose state changed in JSON format")
+
+	return cmd
+}
+
+// NewReopenCmd creates and returns the 'reopen' command for marking tasks as not completed.
+// Unlike 'toggle', running it several times always leaves the tasks open.
+func NewReopenCmd(taskController *controllers.TaskController) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reopen <task_ids>",
+		Short: "Mark tasks as not completed",
+		Long: "Mark tasks identified by IDs or ID ranges (e.g. 3,5,8-12), or selected with a query " +
+			"(--where), as not completed. Tasks that are already open are left untouched. Only the " +
+			"transitions allowed by the workflow are accepted unless --force is set.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tasks, selectErr := selectTasks(cmd, taskController, args)
+			if selectErr != nil {
+				return selectErr
+			}
+			if previewSelection(cmd, "reopened", taskLabels(tasks)) {
+				return nil
+			}
+
+			recursive, _ := cmd.Flags().GetBool("recursive")
+			force, _ := cmd.Flags().GetBool("force")
+
+			var changed []*models.Task
+			err := taskController.InTransaction(func(txController *controllers.TaskController) error {
+				for _, task := range tasks {
+					reopened, reopenErr := txController.ReopenTask(task.ID, recursive, force)
+					if reopenErr != nil {
+						return fmt.Errorf("task with ID '%d': %w", task.ID, reopenErr)
+					}
+					changed = append(changed, reopened...)
+				}
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("error reopening task: %w", err)
+			}
+
+			if outputJSON, _ := cmd.Flags().GetBool("json"); outputJSON {
+				return printChangedTasksJSON(cmd, changed)
+			}
+
+			printCompletionChanges(cmd, tasks, changed, "not completed")
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolP("recursive", "r", false, "Also reopen all subtasks")
+	cmd.Flags().BoolP("force", "f", false, "Ignore the workflow transition rules")
+	addSelectionFlags(cmd)
+	addJSONFlag(cmd, "Output the tasks whose state changed in JSON format")
+
+	return cmd
+}
+
+// printChangedTasksJSON prints the tasks whose state changed as a JSON array, empty rather than null when
+// no task changed.
+func printChangedTasksJSON(cmd *cobra.Command, changed []*models.Task) error {
+	if changed == nil {
+		changed = []*models.Task{}
+	}
+	return printJSON(cmd, changed)
+}
+
+// printCompletionChanges reports, for each selected task, whether its status changed, followed by
+// the number of subtasks that changed along with them.
+func printCompletionChanges(cmd *cobra.Command, selected, changed []*models.Task, status string) {
+	changedIDs := make(map[int]bool, len(changed))
+	for _, task := range changed {
+		changedIDs[task.ID] = true
+	}
+
+	selectedIDs := make(map[int]bool, len(selected))
+	for _, task := range selected {
+		selectedIDs[task.ID] = true
+		if changedIDs[task.ID] {
+			cmd.Println("Task (ID: " + strconv.Itoa(task.ID) + ") has been set as " + status + ".")
+		} else {
+			cmd.Println("Task (ID: " + strconv.Itoa(task.ID) + ") is already " + status + ".")
+		}
+	}
+
+	subtaskCount := 0
+	for id := range changedIDs {
+		if !selectedIDs[id] {
+			subtaskCount++
+		}
+	}
+	if subtaskCount > 0 {
+		cmd.Println(strconv.Itoa(subtaskCount) + " subtask(s) have been set as " + status + ".")
+	}
+}
@@ -1,10 +1,12 @@
 package cmd
 
 import (
-	"errors"
+	"fmt"
 	"strconv"
 
 	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"github.com/d4r1us-drk/clido/models"
 	"github.com/d4r1us-drk/clido/utils"
 	"github.com/spf13/cobra"
 )
@@ -15,75 +17,132 @@ func NewEditCmd(
 	taskController *controllers.TaskController,
 ) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "edit [project|task] <id>",
-		Short: "Edit an existing project or task",
-		Long:  "Edit the details of an existing project or task identified by its ID.",
+		Use:   "edit [project|task] <ids>",
+		Short: "Edit existing projects or tasks",
+		Long: "Edit the details of existing projects or tasks identified by IDs or ID ranges " +
+			"(e.g. 3,5,8-12), or selected with a query (--where). All changes are applied together.\n\n" +
+			"When run in a terminal on a single project or task without any field to update, the fields are " +
+			"prompted for with their current values as defaults, unless --no-input is given.\n\n" +
+			"With --editor, a single task is edited as a document in $VISUAL or $EDITOR, subtasks included.",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// Ensure the command receives sufficient arguments (either "project" or "task" followed by an ID)
-			if len(args) < MinArgsLength {
-				return errors.New(
-					"insufficient arguments. Use 'edit project <id>' or 'edit task <id>'",
-				)
+			// Determine whether the user wants to edit projects or tasks
+			kind, idArgs, ok := splitKindArgs(cmd, args)
+			if !ok {
+				if len(args) == 0 {
+					return errcode.New(errcode.Validation,
+						"insufficient arguments. Use 'edit project <ids>' or 'edit task <ids>'",
+					)
+				}
+				return errcode.New(errcode.Validation, "invalid option. Use 'edit project <ids>' or 'edit task <ids>'")
 			}
 
-			// Parse the ID argument into an integer
-			id, err := strconv.Atoi(args[1])
-			if err != nil {
-				return errors.New("invalid ID. Please provide a numeric ID")
-			}
-
-			// Determine whether the user wants to edit a project or a task
-			switch args[0] {
-			case "project":
-				return editProject(cmd, projectController, id)
-			case "task":
-				return editTask(cmd, taskController, id)
-			default:
-				return errors.New("invalid option. Use 'edit project <id>' or 'edit task <id>'")
+			if kind == "project" {
+				if useEditor, _ := cmd.Flags().GetBool("editor"); useEditor {
+					return errcode.New(errcode.Validation, "--editor only edits tasks")
+				}
+				return editProjects(cmd, projectController, idArgs)
 			}
+			return editTasks(cmd, taskController, idArgs)
 		},
 	}
 
 	// Define flags for the edit command, allowing users to specify what fields they want to update
 	cmd.Flags().StringP("name", "n", "", "New name")
 	cmd.Flags().StringP("description", "d", "", "New description")
-	cmd.Flags().StringP("project", "p", "", "New parent project name or ID")
-	cmd.Flags().StringP("task", "t", "", "New parent task ID for subtasks")
-	cmd.Flags().StringP("due", "D", "", "New due date for task (format: YYYY-MM-DD HH:MM)")
+	cmd.Flags().StringP("project", "p", "", "New parent project name, ID or UUID prefix ('none' to detach)")
+	cmd.Flags().StringP("task", "t", "", "New parent task ID or UUID prefix for subtasks ('none' to detach)")
+	cmd.Flags().StringP("due", "D", "", "New due date for task ("+utils.DueDateFormats+")")
 	cmd.Flags().
 		IntP("priority", "P", 0, "New priority for task (1: High, 2: Medium, 3: Low, 4: None)")
+	addSelectionFlags(cmd)
+	addJSONFlag(cmd, "Output the updated projects or tasks in JSON format")
+	addNoInputFlag(cmd)
+	cmd.Flags().BoolP("editor", "e", false, "Edit a single task as a document in $VISUAL or $EDITOR")
 
 	return cmd
 }
 
-// editProject handles updating an existing project by its ID.
-func editProject(
+// editProjects handles updating the selected projects.
+func editProjects(
 	cmd *cobra.Command,
 	projectController *controllers.ProjectController,
-	id int,
+	idArgs []string,
 ) error {
+	if !anyFlagChanged(cmd, "name", "description", "project") && canPrompt(cmd) {
+		prompted, err := promptEditProject(cmd, projectController, idArgs)
+		if err != nil {
+			return err
+		}
+		if prompted && !anyFlagChanged(cmd, "name", "description", "project") {
+			cmd.Println("No changes to apply.")
+			return nil
+		}
+	}
+
 	name, _ := cmd.Flags().GetString("name")
 	description, _ := cmd.Flags().GetString("description")
 	parentProjectIdentifier, _ := cmd.Flags().GetString("project")
 
 	// Check if any fields are provided for update
 	if name == "" && description == "" && parentProjectIdentifier == "" {
-		return errors.New("no fields provided for update. " +
+		return errcode.New(errcode.Validation, "no fields provided for update. "+
 			"Use flags to update the name, description, or parent project")
 	}
 
-	// Call the controller to edit the project
-	err := projectController.EditProject(id, name, description, parentProjectIdentifier)
+	projects, selectErr := selectProjects(cmd, projectController, idArgs)
+	if selectErr != nil {
+		return selectErr
+	}
+	if previewSelection(cmd, "updated", projectLabels(projects)) {
+		return nil
+	}
+
+	// Call the controller to edit the projects in a single transaction
+	edited := make([]*models.Project, 0, len(projects))
+	err := projectController.InTransaction(func(txController *controllers.ProjectController) error {
+		for _, project := range projects {
+			editedProject, editErr := txController.EditProject(project.ID, name, description, parentProjectIdentifier)
+			if editErr != nil {
+				return fmt.Errorf("project with ID '%d': %w", project.ID, editErr)
+			}
+			edited = append(edited, editedProject)
+		}
+		return nil
+	})
 	if err != nil {
-		return errors.New("error updating project: " + err.Error())
+		return fmt.Errorf("error updating project: %w", err)
 	}
 
-	cmd.Println("Project with ID '" + strconv.Itoa(id) + "' updated successfully.")
+	if outputJSON, _ := cmd.Flags().GetBool("json"); outputJSON {
+		return printJSON(cmd, edited)
+	}
+
+	for _, project := range projects {
+		cmd.Println("Project with ID '" + strconv.Itoa(project.ID) + "' updated successfully.")
+	}
 	return nil
 }
 
-// editTask handles updating an existing task by its ID.
-func editTask(cmd *cobra.Command, taskController *controllers.TaskController, id int) error {
+// editTasks handles updating the selected tasks.
+func editTasks(cmd *cobra.Command, taskController *controllers.TaskController, idArgs []string) error {
+	taskFields := []string{"name", "description", "due", "priority", "task"}
+	if useEditor, _ := cmd.Flags().GetBool("editor"); useEditor {
+		if anyFlagChanged(cmd, taskFields...) {
+			return errcode.New(errcode.Validation, "--editor cannot be combined with flags updating fields")
+		}
+		return editTaskInEditor(cmd, taskController, idArgs)
+	}
+	if !anyFlagChanged(cmd, taskFields...) && canPrompt(cmd) {
+		prompted, err := promptEditTask(cmd, taskController, idArgs)
+		if err != nil {
+			return err
+		}
+		if prompted && !anyFlagChanged(cmd, taskFields...) {
+			cmd.Println("No changes to apply.")
+			return nil
+		}
+	}
+
 	name, _ := cmd.Flags().GetString("name")
 	description, _ := cmd.Flags().GetString("description")
 	dueDateStr, _ := cmd.Flags().GetString("due")
@@ -92,7 +151,7 @@ func editTask(cmd *cobra.Command, taskController *controllers.TaskController, id
 
 	// Validate priority if provided
 	if priority != 0 && (priority < PriorityHigh || priority > PriorityNone) {
-		return errors.New(
+		return errcode.New(errcode.Validation,
 			"invalid priority. Use 1 for High, 2 for Medium, 3 for Low, or 4 for None",
 		)
 	}
@@ -100,21 +159,43 @@ func editTask(cmd *cobra.Command, taskController *controllers.TaskController, id
 	// Check if any fields are provided for update
 	if name == "" && description == "" && dueDateStr == "" && priority == 0 &&
 		parentTaskIdentifier == "" {
-		return errors.New("no fields provided for update. " +
+		return errcode.New(errcode.Validation, "no fields provided for update. "+
 			"Use flags to update the name, description, due date, priority, or parent task")
 	}
 
-	// Call the controller to edit the task
-	err := taskController.EditTask(
-		id,
-		name,
-		description,
-		dueDateStr,
-		priority,
-		parentTaskIdentifier,
-	)
+	tasks, selectErr := selectTasks(cmd, taskController, idArgs)
+	if selectErr != nil {
+		return selectErr
+	}
+	if previewSelection(cmd, "updated", taskLabels(tasks)) {
+		return nil
+	}
+
+	// Call the controller to edit the tasks in a single transaction
+	edited := make([]*models.Task, 0, len(tasks))
+	err := taskController.InTransaction(func(txController *controllers.TaskController) error {
+		for _, task := range tasks {
+			editedTask, editErr := txController.EditTask(
+				task.ID,
+				name,
+				description,
+				dueDateStr,
+				priority,
+				parentTaskIdentifier,
+			)
+			if editErr != nil {
+				return fmt.Errorf("task with ID '%d': %w", task.ID, editErr)
+			}
+			edited = append(edited, editedTask)
+		}
+		return nil
+	})
 	if err != nil {
-		return errors.New("error updating task: " + err.Error())
+		return fmt.Errorf("error updating task: %w", err)
+	}
+
+	if outputJSON, _ := cmd.Flags().GetBool("json"); outputJSON {
+		return printJSON(cmd, edited)
 	}
 
 	// Format and display the new details
@@ -125,7 +206,136 @@ func editTask(cmd *cobra.Command, taskController *controllers.TaskController, id
 		formattedDueDate = utils.FormatDate(parsedDueDate)
 	}
 
-	cmd.Println("Task with ID '" + strconv.Itoa(id) + "' updated successfully.")
+	for _, task := range tasks {
+		cmd.Println("Task with ID '" + strconv.Itoa(task.ID) + "' updated successfully.")
+	}
 	cmd.Println("New details: Priority: " + priorityStr + ", Due Date: " + formattedDueDate)
 	return nil
 }
+
+// anyFlagChanged reports whether any of the given flags is set.
+func anyFlagChanged(cmd *cobra.Command, names ...string) bool {
+	for _, name := range names {
+		if cmd.Flags().Changed(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// promptEditProject prompts for the fields of the project to edit, with their current values as defaults,
+// and sets the flags of the changed fields. It reports whether it prompted, which it only does when a single
+// project is selected.
+func promptEditProject(
+	cmd *cobra.Command,
+	projectController *controllers.ProjectController,
+	idArgs []string,
+) (bool, error) {
+	projects, err := selectProjects(cmd, projectController, idArgs)
+	if err != nil || len(projects) != 1 {
+		return false, err
+	}
+	project := projects[0]
+
+	p := newPrompter(cmd)
+	cmd.Println("Editing project " + formatProjectLabel(project) + ":")
+	if err = promptChangedField(cmd, "name", project.Name, func(defaultValue string) (string, error) {
+		return p.askRequired("Name", defaultValue, controllers.ErrNoProjectName)
+	}); err != nil {
+		return true, err
+	}
+	if err = promptChangedField(cmd, "description", project.Description, func(defaultValue string) (string, error) {
+		return p.ask("Description", defaultValue, nil)
+	}); err != nil {
+		return true, err
+	}
+
+	allProjects, err := projectController.ListProjects()
+	if err != nil {
+		return true, fmt.Errorf("error listing projects: %w", err)
+	}
+	choices := append(projectChoices(allProjects, project.ID), noneChoice())
+	currentParent := ""
+	if project.ParentProjectID != nil {
+		currentParent = strconv.Itoa(*project.ParentProjectID)
+	}
+	err = promptChangedField(cmd, "project", currentParent, func(defaultValue string) (string, error) {
+		return p.choose("Parent project ('none' to detach)", choices, defaultValue, true)
+	})
+	return true, err
+}
+
+// promptEditTask prompts for the fields of the task to edit, with their current values as defaults, and
+// sets the flags of the changed fields. It reports whether it prompted, which it only does when a single
+// task is selected.
+func promptEditTask(cmd *cobra.Command, taskController *controllers.TaskController, idArgs []string) (bool, error) {
+	tasks, err := selectTasks(cmd, taskController, idArgs)
+	if err != nil || len(tasks) != 1 {
+		return false, err
+	}
+	task := tasks[0]
+
+	p := newPrompter(cmd)
+	cmd.Println("Editing task " + formatTaskLabel(task) + ":")
+	if err = promptChangedField(cmd, "name", task.Name, func(defaultValue string) (string, error) {
+		return p.askRequired("Name", defaultValue, controllers.ErrNoTaskName)
+	}); err != nil {
+		return true, err
+	}
+	if err = promptChangedField(cmd, "description", task.Description, func(defaultValue string) (string, error) {
+		return p.ask("Description", defaultValue, nil)
+	}); err != nil {
+		return true, err
+	}
+
+	currentDue := ""
+	if task.DueDate != nil {
+		currentDue = utils.FormatDate(task.DueDate)
+	}
+	if err = promptChangedField(cmd, "due", currentDue, p.askDueDate); err != nil {
+		return true, err
+	}
+
+	currentPriority := task.Priority
+	if currentPriority < PriorityHigh || currentPriority > PriorityNone {
+		currentPriority = PriorityNone
+	}
+	priority, err := p.askPriority(currentPriority)
+	if err != nil {
+		return true, err
+	}
+	if priority != currentPriority {
+		_ = cmd.Flags().Set("priority", strconv.Itoa(priority))
+	}
+
+	projectTasks, _, err := taskController.ListTasksByProjectFilter(strconv.Itoa(task.ProjectID))
+	if err != nil {
+		return true, fmt.Errorf("error listing tasks: %w", err)
+	}
+	choices := append(taskChoices(projectTasks, task.ID), noneChoice())
+	currentParent := ""
+	if task.ParentTaskID != nil {
+		currentParent = strconv.Itoa(*task.ParentTaskID)
+	}
+	err = promptChangedField(cmd, "task", currentParent, func(defaultValue string) (string, error) {
+		return p.choose("Parent task ('none' to detach)", choices, defaultValue, true)
+	})
+	return true, err
+}
+
+// promptChangedField prompts for a field with its current value as default, and sets its flag when the
+// answer is another, non-empty value.
+func promptChangedField(
+	cmd *cobra.Command,
+	flag, current string,
+	prompt func(defaultValue string) (string, error),
+) error {
+	answer, err := prompt(current)
+	if err != nil {
+		return err
+	}
+	if answer != "" && answer != current {
+		_ = cmd.Flags().Set(flag, answer)
+	}
+	return nil
+}
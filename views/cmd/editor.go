@@ -0,0 +1,489 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"github.com/d4r1us-drk/clido/models"
+	"github.com/d4r1us-drk/clido/utils"
+	"github.com/spf13/cobra"
+)
+
+// Markers of the task documents edited with 'edit task --editor'.
+const (
+	taskDocumentDelimiter = "---"
+	taskDocumentSubtasks  = "## Subtasks"
+	taskDocumentError     = "# ERROR: "
+)
+
+// DefaultEditor is the editor used when neither VISUAL nor EDITOR is set.
+const DefaultEditor = "vi"
+
+var errInvalidTaskDocument = errcode.New(errcode.Validation, "invalid task document")
+
+// checklistItemPattern matches a subtask of a task document, e.g. "- [x] Call the bank (#13)". New subtasks
+// have no ID.
+var checklistItemPattern = regexp.MustCompile(`^[-*] \[([ xX])\] (.*?)(?: \(#(\d+)\))?$`)
+
+// taskDocument is a task as edited in the editor: front matter fields, the description as body and the
+// subtasks as a checklist.
+type taskDocument struct {
+	fields      map[string]string // The fields found in the front matter, by key
+	description string
+	subtasks    []checklistItem
+	hasSubtasks bool // Whether the document has a subtasks section
+}
+
+// documentError is an error found in a task document, at a line of it unless zero.
+type documentError struct {
+	line    int
+	message string
+}
+
+// format formats the error, its line being shifted by a number of lines inserted before it.
+func (e documentError) format(shift int) string {
+	if e.line == 0 {
+		return e.message
+	}
+	return fmt.Sprintf("line %d: %s", e.line+shift, e.message)
+}
+
+// checklistItem is a subtask in a task document. New subtasks have a zero ID.
+type checklistItem struct {
+	id   int
+	name string
+	done bool
+}
+
+// taskDocumentFields are the keys of the front matter of a task document, in order.
+var taskDocumentFields = []string{"name", "state", "priority", "due", "tags", "parent"}
+
+// editTaskInEditor edits a single task as a document in the editor and applies the changes. When the
+// document is invalid or the changes fail, the editor is opened again with the errors at the top of the
+// document, until the changes are applied or the document is emptied or saved unchanged.
+func editTaskInEditor(cmd *cobra.Command, taskController *controllers.TaskController, idArgs []string) error {
+	tasks, err := selectTasks(cmd, taskController, idArgs)
+	if err != nil {
+		return err
+	}
+	if len(tasks) != 1 {
+		return errcode.New(errcode.Validation, "--editor edits a single task")
+	}
+	task := tasks[0]
+	subtasks, err := taskController.ListSubtasks(task.ID)
+	if err != nil {
+		return fmt.Errorf("error listing subtasks: %w", err)
+	}
+
+	original := renderTaskDocument(task, subtasks, taskController.Workflow())
+	text := original
+	var lastErr error
+	for {
+		edited, editErr := runEditor(cmd, text)
+		if editErr != nil {
+			return editErr
+		}
+		switch {
+		case strings.TrimSpace(edited) == "":
+			cmd.Println("Edit cancelled: the document is empty.")
+			return lastErr
+		case edited == original:
+			cmd.Println("No changes to apply.")
+			return nil
+		case edited == text:
+			cmd.Println("Edit cancelled: the document was saved unchanged.")
+			return fmt.Errorf("error updating task: %w", lastErr)
+		}
+
+		// The errors of a previous attempt are replaced
+		edited = stripTaskDocumentErrors(edited)
+		doc, docErrs := parseTaskDocument(edited)
+		if len(docErrs) == 0 {
+			updated, applyErr := applyTaskDocument(taskController, task, subtasks, doc)
+			if applyErr == nil {
+				if outputJSON, _ := cmd.Flags().GetBool("json"); outputJSON {
+					return printJSON(cmd, []*models.Task{updated})
+				}
+				cmd.Println("Task with ID '" + strconv.Itoa(task.ID) + "' updated successfully.")
+				return nil
+			}
+			docErrs = []documentError{{message: applyErr.Error()}}
+			lastErr = applyErr
+		} else {
+			messages := make([]string, 0, len(docErrs))
+			for _, docErr := range docErrs {
+				messages = append(messages, docErr.format(0))
+			}
+			lastErr = fmt.Errorf("%w: %s", errInvalidTaskDocument, strings.Join(messages, "; "))
+		}
+
+		cmd.Println("The task document has errors, opening it again.")
+		text = annotateTaskDocument(edited, docErrs)
+	}
+}
+
+// runEditor opens a text in the editor given by VISUAL or EDITOR, which may include arguments, and returns
+// the text as saved.
+func runEditor(cmd *cobra.Command, text string) (string, error) {
+	file, err := os.CreateTemp("", "clido-task-*.md")
+	if err != nil {
+		return "", fmt.Errorf("error creating the task document: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.WriteString(text)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("error writing the task document: %w", err)
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if strings.TrimSpace(editor) == "" {
+		editor = DefaultEditor
+	}
+	editorArgs := strings.Fields(editor)
+
+	editorCmd := exec.Command(editorArgs[0], append(editorArgs[1:], file.Name())...)
+	editorCmd.Stdin = cmd.InOrStdin()
+	editorCmd.Stdout = cmd.OutOrStdout()
+	editorCmd.Stderr = cmd.ErrOrStderr()
+	if err = editorCmd.Run(); err != nil {
+		return "", fmt.Errorf("error running editor '%s': %w", editor, err)
+	}
+
+	edited, err := os.ReadFile(file.Name())
+	if err != nil {
+		return "", fmt.Errorf("error reading the task document: %w", err)
+	}
+	return string(edited), nil
+}
+
+// renderTaskDocument renders a task and its subtasks as the document edited in the editor.
+func renderTaskDocument(task *models.Task, subtasks []*models.Task, workflow *models.Workflow) string {
+	var b strings.Builder
+	b.WriteString(taskDocumentDelimiter + "\n")
+	b.WriteString("# Editing task " + strconv.Itoa(task.ID) + ". Save and quit to apply the changes, or empty " +
+		"the file to cancel.\n")
+	b.WriteString("# Lines starting with '#' are ignored here. An empty value clears the field.\n")
+	b.WriteString("#   state: " + strings.Join(workflow.States, ", ") + "\n")
+	b.WriteString("#   priority: 1 (High), 2 (Medium), 3 (Low) or 4 (None)\n")
+	b.WriteString("#   due: " + utils.DueDateFormats + "\n")
+	b.WriteString("#   tags: #tags and @contexts, separated by spaces\n")
+	b.WriteString("#   parent: the ID of the parent task\n")
+	b.WriteString("# The description follows the front matter. Check subtasks with [x] and add '- [ ] name' lines\n")
+	b.WriteString("# to create subtasks; removing a line keeps the subtask.\n")
+
+	values := taskDocumentValues(task)
+	for _, key := range taskDocumentFields {
+		b.WriteString(strings.TrimSpace(key+": "+values[key]) + "\n")
+	}
+	b.WriteString(taskDocumentDelimiter + "\n")
+
+	if task.Description != "" {
+		b.WriteString(strings.TrimSpace(task.Description) + "\n")
+	}
+	b.WriteString("\n" + taskDocumentSubtasks + "\n")
+	for _, subtask := range subtasks {
+		check := " "
+		if subtask.TaskCompleted {
+			check = "x"
+		}
+		b.WriteString("- [" + check + "] " + subtask.Name + " (#" + strconv.Itoa(subtask.ID) + ")\n")
+	}
+	return b.String()
+}
+
+// taskDocumentValues returns the front matter values of a task, by key.
+func taskDocumentValues(task *models.Task) map[string]string {
+	priority := task.Priority
+	if priority < PriorityHigh || priority > PriorityNone {
+		priority = PriorityNone
+	}
+	values := map[string]string{
+		"name":     task.Name,
+		"state":    task.State,
+		"priority": strconv.Itoa(priority),
+		"due":      "",
+		"tags":     "",
+		"parent":   "",
+	}
+	if task.DueDate != nil {
+		values["due"] = utils.FormatDate(task.DueDate)
+	}
+	if task.Tags != "" {
+		values["tags"] = formatTags(task.Tags)
+	}
+	if task.ParentTaskID != nil {
+		values["parent"] = strconv.Itoa(*task.ParentTaskID)
+	}
+	return values
+}
+
+// parseTaskDocument parses a task document edited in the editor, and returns the errors found with their
+// line numbers.
+func parseTaskDocument(text string) (*taskDocument, []documentError) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	doc := &taskDocument{fields: make(map[string]string)}
+	var errs []documentError
+
+	// Skip the comments, e.g. errors, before the front matter
+	start := 0
+	for start < len(lines) && (strings.HasPrefix(lines[start], "#") || strings.TrimSpace(lines[start]) == "") {
+		start++
+	}
+	if start == len(lines) || strings.TrimSpace(lines[start]) != taskDocumentDelimiter {
+		return nil, []documentError{{message: "the document must start with a '" + taskDocumentDelimiter + "' line"}}
+	}
+
+	end := start + 1
+	for ; end < len(lines) && strings.TrimSpace(lines[end]) != taskDocumentDelimiter; end++ {
+		line := strings.TrimSpace(lines[end])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(line, ":")
+		key = strings.ToLower(strings.TrimSpace(key))
+		switch {
+		case !found:
+			errs = append(errs, documentError{line: end + 1, message: "expected 'field: value'"})
+		case !isTaskDocumentField(key):
+			errs = append(errs, documentError{line: end + 1, message: "unknown field '" + key + "'"})
+		default:
+			value = strings.TrimSpace(value)
+			if _, err := parsePriorityAnswer(value); key == "priority" && value != "" && err != nil {
+				errs = append(errs, documentError{line: end + 1, message: err.Error()})
+			}
+			doc.fields[key] = value
+		}
+	}
+	if end == len(lines) {
+		return nil, append(errs, documentError{
+			message: "the front matter must end with a '" + taskDocumentDelimiter + "' line",
+		})
+	}
+
+	body := lines[end+1:]
+	subtasksStart := len(body)
+	for i := len(body) - 1; i >= 0; i-- {
+		if strings.TrimSpace(body[i]) == taskDocumentSubtasks {
+			subtasksStart = i
+			doc.hasSubtasks = true
+			break
+		}
+	}
+	doc.description = strings.TrimSpace(strings.Join(body[:subtasksStart], "\n"))
+
+	for i := subtasksStart + 1; i < len(body); i++ {
+		line := strings.TrimSpace(body[i])
+		if line == "" {
+			continue
+		}
+		lineNumber := end + 2 + i
+		match := checklistItemPattern.FindStringSubmatch(line)
+		if match == nil {
+			errs = append(errs, documentError{line: lineNumber, message: "expected a subtask such as '- [ ] name'"})
+			continue
+		}
+		item := checklistItem{name: strings.TrimSpace(match[2]), done: match[1] != " "}
+		if item.name == "" {
+			errs = append(errs, documentError{line: lineNumber, message: controllers.ErrNoTaskName.Error()})
+			continue
+		}
+		if match[3] != "" {
+			item.id, _ = strconv.Atoi(match[3])
+		}
+		doc.subtasks = append(doc.subtasks, item)
+	}
+
+	return doc, errs
+}
+
+// isTaskDocumentField reports whether a key is a field of the front matter of task documents.
+func isTaskDocumentField(key string) bool {
+	for _, field := range taskDocumentFields {
+		if key == field {
+			return true
+		}
+	}
+	return false
+}
+
+// stripTaskDocumentErrors removes the errors added to a task document by annotateTaskDocument.
+func stripTaskDocumentErrors(text string) string {
+	lines := strings.Split(text, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !strings.HasPrefix(line, taskDocumentError) {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// annotateTaskDocument adds errors at the top of the front matter of a task document, shifting their line
+// numbers to match the annotated document.
+func annotateTaskDocument(text string, errs []documentError) string {
+	lines := strings.Split(text, "\n")
+
+	// Errors go right after the opening delimiter, or at the top when it is missing
+	at := 0
+	for i, line := range lines {
+		if strings.TrimSpace(line) == taskDocumentDelimiter {
+			at = i + 1
+			break
+		}
+		if !strings.HasPrefix(line, "#") && strings.TrimSpace(line) != "" {
+			break
+		}
+	}
+
+	annotated := make([]string, 0, len(lines)+len(errs))
+	annotated = append(annotated, lines[:at]...)
+	for _, err := range errs {
+		shift := 0
+		if err.line > at {
+			shift = len(errs)
+		}
+		annotated = append(annotated, taskDocumentError+err.format(shift))
+	}
+	annotated = append(annotated, lines[at:]...)
+	return strings.Join(annotated, "\n")
+}
+
+// applyTaskDocument applies the changes of a task document to the task and its subtasks in a single
+// transaction, and returns the task as stored. Subtasks are changed first, so that the task can be
+// completed along with its subtasks.
+func applyTaskDocument(
+	taskController *controllers.TaskController,
+	task *models.Task,
+	subtasks []*models.Task,
+	doc *taskDocument,
+) (*models.Task, error) {
+	changes, changed := taskDocumentChanges(task, doc)
+	state, stateFound := doc.fields["state"]
+
+	var updated *models.Task
+	err := taskController.InTransaction(func(txController *controllers.TaskController) error {
+		if doc.hasSubtasks {
+			if err := applyChecklist(txController, task, subtasks, doc.subtasks); err != nil {
+				return err
+			}
+		}
+
+		if changed {
+			if _, err := txController.UpdateTask(task.ID, changes); err != nil {
+				return err
+			}
+		}
+		if stateFound && state != task.State {
+			if _, _, err := txController.SetTaskState(task.ID, state, false); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		updated, err = txController.GetTaskByID(task.ID)
+		return err
+	})
+	return updated, err
+}
+
+// taskDocumentChanges returns the changes of the fields of a task made in a task document, and whether
+// there are any. Fields missing from the document are left unchanged.
+func taskDocumentChanges(task *models.Task, doc *taskDocument) (controllers.TaskChanges, bool) {
+	var changes controllers.TaskChanges
+	values := taskDocumentValues(task)
+	changedField := func(key string) (string, bool) {
+		value, found := doc.fields[key]
+		return value, found && value != values[key]
+	}
+
+	changed := false
+	if name, found := changedField("name"); found {
+		changes.Name, changed = &name, true
+	}
+	if doc.description != strings.TrimSpace(task.Description) {
+		changes.Description, changed = &doc.description, true
+	}
+	if due, found := changedField("due"); found {
+		changes.DueDate, changed = &due, true
+	}
+	if priorityValue, found := changedField("priority"); found {
+		priority := PriorityNone
+		if priorityValue != "" {
+			priority, _ = parsePriorityAnswer(priorityValue)
+		}
+		changes.Priority, changed = &priority, true
+	}
+	if tagsValue, found := changedField("tags"); found {
+		tags := strings.FieldsFunc(tagsValue, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		changes.Tags, changed = &tags, true
+	}
+	if parent, found := changedField("parent"); found {
+		if parent == "" {
+			parent = controllers.NoParentIdentifier
+		}
+		changes.Parent, changed = &parent, true
+	}
+	return changes, changed
+}
+
+// applyChecklist applies the subtasks of a task document: renamed subtasks are renamed, checked subtasks
+// completed, unchecked ones reopened, and new ones created.
+func applyChecklist(
+	txController *controllers.TaskController,
+	task *models.Task,
+	subtasks []*models.Task,
+	items []checklistItem,
+) error {
+	subtasksByID := make(map[int]*models.Task, len(subtasks))
+	for _, subtask := range subtasks {
+		subtasksByID[subtask.ID] = subtask
+	}
+
+	for _, item := range items {
+		subtask, found := subtasksByID[item.id]
+		if item.id == 0 {
+			created, err := txController.CreateTask(
+				item.name, "", strconv.Itoa(task.ProjectID), strconv.Itoa(task.ID), "", 0, nil,
+			)
+			if err != nil {
+				return fmt.Errorf("new subtask '%s': %w", item.name, err)
+			}
+			subtask = created
+		} else if !found {
+			return fmt.Errorf("%w: task %d is not a subtask of task %d", errInvalidTaskDocument, item.id, task.ID)
+		}
+
+		if item.name != subtask.Name {
+			if _, err := txController.EditTask(subtask.ID, item.name, "", "", 0, ""); err != nil {
+				return fmt.Errorf("subtask with ID '%d': %w", subtask.ID, err)
+			}
+		}
+
+		var err error
+		switch {
+		case item.done && !subtask.TaskCompleted:
+			_, err = txController.CompleteTask(subtask.ID, false, false)
+		case !item.done && subtask.TaskCompleted:
+			_, err = txController.ReopenTask(subtask.ID, false, false)
+		}
+		if err != nil {
+			return fmt.Errorf("subtask with ID '%d': %w", subtask.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Exit statuses of clido, telling scripts the kind of failure.
+const (
+	ExitOK         = 0
+	ExitFailure    = 1 // Any failure of no specific kind
+	ExitValidation = 2 // Invalid arguments, flags or input
+	ExitNotFound   = 3 // A project, task or other item does not exist
+	ExitConflict   = 4 // The change conflicts with the current data
+	ExitRejected   = 5 // A pre-hook rejected the change
+	ExitStorage    = 6 // The database could not be read or written
+)
+
+// exitCodes maps the kinds of errors to the exit statuses. Other errors exit with ExitFailure.
+var exitCodes = map[errcode.Code]int{
+	errcode.Validation: ExitValidation,
+	errcode.NotFound:   ExitNotFound,
+	errcode.Conflict:   ExitConflict,
+	errcode.Rejected:   ExitRejected,
+	errcode.Storage:    ExitStorage,
+}
+
+// JSONError is the document written to the standard error when a command run with --json fails.
+type JSONError struct {
+	Error JSONErrorDetail `json:"error"`
+}
+
+// JSONErrorDetail describes the failure of a command run with --json.
+type JSONErrorDetail struct {
+	Code     errcode.Code `json:"code"`      // The kind of failure, e.g. "not_found"
+	Message  string       `json:"message"`   // The error message, as printed without --json
+	ExitCode int          `json:"exit_code"` // The exit status of clido
+}
+
+// ExitCode returns the exit status of clido when a command fails with the given error.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var exitErr *ExitCodeError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	if code, found := exitCodes[errcode.Of(err)]; found {
+		return code
+	}
+	return ExitFailure
+}
+
+// JSONFromArgs reports whether the --json flag is set in the command-line arguments. It is used to report
+// failures as JSON, including those of parsing the arguments.
+func JSONFromArgs(args []string) bool {
+	flags := pflag.NewFlagSet("clido", pflag.ContinueOnError)
+	flags.ParseErrorsWhitelist.UnknownFlags = true
+	flags.Usage = func() {}
+	outputJSON := flags.BoolP("json", "j", false, "")
+
+	// Errors are reported by the command parser later on
+	_ = flags.Parse(args)
+	return *outputJSON
+}
+
+// WriteJSONError writes the JSON document describing an error to w.
+func WriteJSONError(w io.Writer, err error) {
+	jsonData, _ := json.Marshal(JSONError{Error: JSONErrorDetail{
+		Code:     errcode.Of(err),
+		Message:  err.Error(),
+		ExitCode: ExitCode(err),
+	}})
+	fmt.Fprintln(w, string(jsonData))
+}
+
+// Run executes the root command with the given command-line arguments and returns the exit status of clido.
+// When --json is set, failures are written to the standard error as a JSON document instead of a message
+// followed by the usage.
+func Run(rootCmd *cobra.Command, args []string) int {
+	outputJSON := JSONFromArgs(args)
+	if outputJSON {
+		rootCmd.SilenceErrors = true
+		rootCmd.SilenceUsage = true
+	}
+
+	rootCmd.SetArgs(args)
+	err := rootCmd.Execute()
+	if err == nil {
+		return ExitOK
+	}
+
+	// Plugins report their failures themselves
+	var exitErr *ExitCodeError
+	if outputJSON && !errors.As(err, &exitErr) {
+		WriteJSONError(rootCmd.ErrOrStderr(), err)
+	}
+	return ExitCode(err)
+}
+
+// markUsageErrors makes the errors of parsing the flags and arguments of the root command and its
+// subcommands validation errors.
+func markUsageErrors(rootCmd *cobra.Command) {
+	// Subcommands inherit the flag error function
+	rootCmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return errcode.Wrap(errcode.Validation, err)
+	})
+	markArgsErrors(rootCmd)
+}
+
+// markArgsErrors makes the errors of validating the arguments of a command and its subcommands validation
+// errors.
+func markArgsErrors(cmd *cobra.Command) {
+	if validateArgs := cmd.Args; validateArgs != nil {
+		cmd.Args = func(cmd *cobra.Command, args []string) error {
+			if err := validateArgs(cmd, args); err != nil {
+				return errcode.Wrap(errcode.Validation, err)
+			}
+			return nil
+		}
+	}
+	for _, subCmd := range cmd.Commands() {
+		markArgsErrors(subCmd)
+	}
+}
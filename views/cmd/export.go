@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/models"
+	"github.com/spf13/cobra"
+)
+
+// ExportFileMode is the permission mode of export files.
+const ExportFileMode = 0o600
+
+// NewExportCmd creates and returns the 'export' command, which writes the whole database
+// to a file or to the standard output.
+func NewExportCmd(exportController *controllers.ExportController) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export projects and tasks",
+		Long:  "Export every project and task of the database, e.g. 'export json --nested -o backup.json'.",
+	}
+
+	jsonCmd := &cobra.Command{
+		Use:   "json",
+		Short: "Export projects and tasks as JSON",
+		Long: "Export every project and task as a versioned JSON document. With --nested, projects " +
+			"contain their subprojects and tasks, and tasks contain their subtasks; otherwise projects " +
+			"and tasks are listed flat with the IDs of their parents.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			nested, _ := cmd.Flags().GetBool("nested")
+			output, _ := cmd.Flags().GetString("output")
+
+			export, err := exportController.Export(nested)
+			if err != nil {
+				return fmt.Errorf("error exporting data: %w", err)
+			}
+
+			jsonData, err := json.MarshalIndent(export, "", "  ")
+			if err != nil {
+				return fmt.Errorf("error marshalling export to JSON: %w", err)
+			}
+
+			jsonData = append(jsonData, '\n')
+			if output == "" || output == "-" {
+				// Write to the standard output rather than cmd.Println (stderr) so exports can be piped
+				if _, writeErr := cmd.OutOrStdout().Write(jsonData); writeErr != nil {
+					return fmt.Errorf("error writing export: %w", writeErr)
+				}
+				return nil
+			}
+
+			if writeErr := os.WriteFile(output, jsonData, ExportFileMode); writeErr != nil {
+				return fmt.Errorf("error writing export file: %w", writeErr)
+			}
+			cmd.Println("Exported " + strconv.Itoa(countExportProjects(export)) + " project(s) and " +
+				strconv.Itoa(countExportTasks(export)) + " task(s) to '" + output + "'.")
+			return nil
+		},
+	}
+
+	jsonCmd.Flags().BoolP("nested", "n", false, "Nest subprojects, tasks and subtasks inside their parents")
+	jsonCmd.Flags().StringP("output", "o", "", "Write the export to a file instead of the standard output")
+
+	cmd.AddCommand(jsonCmd)
+	return cmd
+}
+
+// NewImportCmd creates and returns the 'import' command, which recreates projects and tasks
+// from an export, assigning them new IDs.
+func NewImportCmd(exportController *controllers.ExportController) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import projects and tasks",
+		Long:  "Import projects and tasks from an export, e.g. 'import json backup.json'.",
+	}
+
+	jsonCmd := &cobra.Command{
+		Use:   "json [file]",
+		Short: "Import projects and tasks from a JSON export",
+		Long: "Recreate the projects and tasks of a nested or flat JSON export (read from the standard " +
+			"input when no file or '-' is given). Imported items get new IDs and parent relationships " +
+			"are remapped. When a project with the same name already exists, --on-conflict decides " +
+			"whether to fail, merge into the existing project, or rename the imported one.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conflict, _ := cmd.Flags().GetString("on-conflict")
+
+			var jsonData []byte
+			var readErr error
+			if len(args) == 0 || args[0] == "-" {
+				jsonData, readErr = io.ReadAll(cmd.InOrStdin())
+			} else {
+				jsonData, readErr = os.ReadFile(args[0])
+			}
+			if readErr != nil {
+				return fmt.Errorf("error reading import data: %w", readErr)
+			}
+
+			var export models.Export
+			if err := json.Unmarshal(jsonData, &export); err != nil {
+				return fmt.Errorf("error parsing import data: %w", err)
+			}
+
+			result, err := exportController.Import(&export, conflict)
+			if err != nil {
+				return fmt.Errorf("error importing data: %w", err)
+			}
+
+			cmd.Println("Imported " + strconv.Itoa(len(result.ProjectIDs)-result.MergedProjects) +
+				" project(s) and " + strconv.Itoa(len(result.TaskIDs)) + " task(s).")
+			if result.MergedProjects > 0 {
+				cmd.Println("Merged " + strconv.Itoa(result.MergedProjects) + " project(s) into existing ones.")
+			}
+
+			verbose, _ := cmd.Flags().GetBool("verbose")
+			if verbose {
+				printIDMapping(cmd, "Project", result.ProjectIDs)
+				printIDMapping(cmd, "Task", result.TaskIDs)
+			}
+			return nil
+		},
+	}
+
+	jsonCmd.Flags().StringP("on-conflict", "c", controllers.ImportConflictFail,
+		"What to do when a project name already exists: 'fail', 'merge' or 'rename'")
+	jsonCmd.Flags().BoolP("verbose", "v", false, "Print the new ID of every imported project and task")
+
+	cmd.AddCommand(jsonCmd)
+	return cmd
+}
+
+// printIDMapping prints the exported IDs and the IDs they were imported as, sorted by exported ID.
+func printIDMapping(cmd *cobra.Command, kind string, mapping map[int]int) {
+	ids := make([]int, 0, len(mapping))
+	for id := range mapping {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		cmd.Println(kind + " " + strconv.Itoa(id) + " -> " + strconv.Itoa(mapping[id]))
+	}
+}
+
+// countExportProjects counts the projects of an export, including nested subprojects.
+func countExportProjects(export *models.Export) int {
+	var count func(projects []*models.ExportProject) int
+	count = func(projects []*models.ExportProject) int {
+		total := len(projects)
+		for _, project := range projects {
+			total += count(project.Subprojects)
+		}
+		return total
+	}
+	return count(export.Projects)
+}
+
+// countExportTasks counts the tasks of an export, including nested subtasks.
+func countExportTasks(export *models.Export) int {
+	var countTasks func(tasks []*models.ExportTask) int
+	countTasks = func(tasks []*models.ExportTask) int {
+		total := len(tasks)
+		for _, task := range tasks {
+			total += countTasks(task.Subtasks)
+		}
+		return total
+	}
+
+	var countProjects func(projects []*models.ExportProject) int
+	countProjects = func(projects []*models.ExportProject) int {
+		total := 0
+		for _, project := range projects {
+			total += countTasks(project.Tasks) + countProjects(project.Subprojects)
+		}
+		return total
+	}
+
+	return len(export.Tasks) + countProjects(export.Projects)
+}
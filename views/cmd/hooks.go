@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/internal/hooks"
+	"github.com/d4r1us-drk/clido/models"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// NewHooksCmd creates and returns the 'hooks' command, which lists and tests the hooks run when projects
+// and tasks change.
+func NewHooksCmd(hookController *controllers.HookController) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hooks",
+		Short: "List and test the hooks run when projects and tasks change",
+		Long: "Hooks are executable files of the 'hooks' directory, next to the database, named after the " +
+			"event they run for (" + strings.Join(hooks.Events, ", ") + "), optionally followed by a '.' or " +
+			"'-' and any suffix, e.g. 'on-complete.notify.sh'. They receive the change as JSON on their " +
+			"standard input: the event, the item type ('project' or 'task') and the item before and after " +
+			"the change. Hooks whose name starts with 'pre-' run before the change is saved: a non-zero exit " +
+			"status rejects the change with the first line of their output as reason, and printing the item " +
+			"as JSON modifies it (name, description, due date and priority). Other hooks run once the change " +
+			"is saved. Hooks are killed after " + hooks.DefaultTimeout.String() + ", or the duration set by " +
+			"the " + hooks.TimeoutEnv + " environment variable. Completing a task runs the on-complete hooks " +
+			"instead of the on-modify ones.",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the hooks of the hooks directory",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			hookList, err := hookController.ListHooks()
+			if err != nil {
+				return fmt.Errorf("error listing hooks: %w", err)
+			}
+			if len(hookList) == 0 {
+				cmd.Println("No hooks found in '" + hookController.HooksDir() + "'.")
+				return nil
+			}
+
+			table := tablewriter.NewWriter(cmd.OutOrStdout())
+			table.SetHeader([]string{"Name", "Event", "Runs"})
+			for _, hook := range hookList {
+				runs := "after the change"
+				if hook.Pre {
+					runs = "before the change"
+				}
+				table.Append([]string{hook.Name, hook.Event, runs})
+			}
+			table.Render()
+			return nil
+		},
+	})
+
+	testCmd := &cobra.Command{
+		Use:       "test <event>",
+		Short:     "Run the hooks of an event with a sample task or project",
+		Long:      "Run the pre-hooks and then the other hooks of an event with a sample change, without saving anything.",
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: hooks.Events,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			project, _ := cmd.Flags().GetBool("project")
+			itemType := models.SyncItemTask
+			if project {
+				itemType = models.SyncItemProject
+			}
+
+			results, err := hookController.TestHooks(args[0], itemType)
+			if err != nil {
+				return fmt.Errorf("error testing hooks: %w", err)
+			}
+			if len(results) == 0 {
+				cmd.Println("No hooks found for " + args[0] + " in '" + hookController.HooksDir() + "'.")
+				return nil
+			}
+
+			failed := 0
+			for _, result := range results {
+				status := "ok"
+				if result.Error != "" {
+					status = "failed: " + result.Error
+					failed++
+				}
+				cmd.Println(result.Hook.Name + ": " + status)
+				if result.Output != "" {
+					cmd.Println(result.Output)
+				}
+			}
+			if failed > 0 {
+				return errors.New(strconv.Itoa(failed) + " hook(s) failed")
+			}
+			return nil
+		},
+	}
+	testCmd.Flags().Bool("project", false, "Use a sample project instead of a sample task")
+	cmd.AddCommand(testCmd)
+
+	return cmd
+}
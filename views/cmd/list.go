@@ -1,12 +1,12 @@
 package cmd
 
 import (
-	"encoding/json"
-	"errors"
+	"fmt"
 	"os"
 	"strconv"
 
 	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/internal/errcode"
 	"github.com/d4r1us-drk/clido/models"
 	"github.com/d4r1us-drk/clido/utils"
 	"github.com/olekukonko/tablewriter"
@@ -22,10 +22,14 @@ func NewListCmd(
 	cmd := &cobra.Command{
 		Use:   "list [projects|tasks]",
 		Short: "List projects or tasks",
-		Long:  "List all projects or tasks, optionally filtered by project for tasks.",
+		Long: "List all projects or tasks, optionally filtered by project for tasks.\n\n" +
+			"Listing tasks gives display IDs (e.g. @3) to the open tasks that have none yet, which every " +
+			"command accepts instead of their IDs. New tasks get the smallest display IDs freed by closed or " +
+			"removed tasks, and the display IDs of open tasks do not change until --renumber numbers them " +
+			"from 1 again.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) < 1 {
-				return errors.New("insufficient arguments. Use 'list projects' or 'list tasks'")
+				return errcode.New(errcode.Validation, "insufficient arguments. Use 'list projects' or 'list tasks'")
 			}
 
 			// Retrieve flags for output format
@@ -37,21 +41,27 @@ func NewListCmd(
 				return listProjects(cmd, projectController, outputJSON, treeView)
 			case "tasks":
 				projectFilter, _ := cmd.Flags().GetString("project")
+				stateFilter, _ := cmd.Flags().GetString("state")
+				renumber, _ := cmd.Flags().GetBool("renumber")
 				return listTasks(
 					cmd,
 					taskController,
 					projectController,
 					projectFilter,
+					stateFilter,
+					renumber,
 					outputJSON,
 					treeView,
 				)
 			default:
-				return errors.New("invalid option. Use 'list projects' or 'list tasks'")
+				return errcode.New(errcode.Validation, "invalid option. Use 'list projects' or 'list tasks'")
 			}
 		},
 	}
 
-	cmd.Flags().StringP("project", "p", "", "Filter tasks by project name or ID")
+	cmd.Flags().StringP("project", "p", "", "Filter tasks by project name, ID or UUID prefix")
+	cmd.Flags().StringP("state", "s", "", "Filter tasks by workflow state")
+	cmd.Flags().Bool("renumber", false, "Number the display IDs of open tasks from 1 again")
 	cmd.Flags().BoolP("json", "j", false, "Output list in JSON format")
 	cmd.Flags().BoolP("tree", "t", false, "Display projects or tasks in a tree-like structure")
 
@@ -67,12 +77,12 @@ func listProjects(
 ) error {
 	projects, err := projectController.ListProjects()
 	if err != nil {
-		return errors.New("error listing projects: " + err.Error())
+		return fmt.Errorf("error listing projects: %w", err)
 	}
 
 	switch {
 	case outputJSON:
-		printProjectsJSON(cmd, projects)
+		return printJSON(cmd, projects)
 	case treeView:
 		printProjectTree(cmd, projects)
 	default:
@@ -81,31 +91,53 @@ func listProjects(
 	return nil
 }
 
-// listTasks lists tasks, optionally filtered by a project, in table, tree view, or JSON format.
+// listTasks lists tasks, optionally filtered by a project and a workflow state,
+// in table, tree view, or JSON format.
 func listTasks(
 	cmd *cobra.Command,
 	taskController *controllers.TaskController,
 	projectController *controllers.ProjectController,
 	projectFilter string,
+	stateFilter string,
+	renumber bool,
 	outputJSON bool,
 	treeView bool,
 ) error {
+	if stateFilter != "" && !taskController.Workflow().HasState(stateFilter) {
+		return errcode.New(errcode.Validation, "unknown state '"+stateFilter+"'. Available states: "+
+			formatStates(taskController.Workflow().States))
+	}
+
 	tasks, project, err := taskController.ListTasksByProjectFilter(projectFilter)
 	if err != nil {
-		return errors.New("error listing tasks: " + err.Error())
+		return fmt.Errorf("error listing tasks: %w", err)
+	}
+	if stateFilter != "" {
+		tasks = filterTasksByState(tasks, stateFilter)
 	}
 
 	if !outputJSON {
 		printTaskHeader(cmd, project)
 	}
 
+	// Listing numbers the open tasks without display ID, which other commands accept as @<display ID>, and
+	// frees the display IDs of closed and removed tasks
+	updateWorkingSet := taskController.UpdateWorkingSet
+	if renumber {
+		updateWorkingSet = taskController.RenumberWorkingSet
+	}
+	displayIDs, err := updateWorkingSet()
+	if err != nil {
+		return fmt.Errorf("error numbering open tasks: %w", err)
+	}
+
 	switch {
 	case outputJSON:
-		printTasksJSON(cmd, tasks)
+		return printJSON(cmd, tasks)
 	case treeView:
 		printTaskTree(cmd, tasks)
 	default:
-		printTaskTable(taskController, projectController, tasks)
+		printTaskTable(taskController, projectController, tasks, displayIDs)
 	}
 	return nil
 }
@@ -119,26 +151,6 @@ func printTaskHeader(cmd *cobra.Command, project *models.Project) {
 	}
 }
 
-// printProjectsJSON outputs the projects in JSON format.
-func printProjectsJSON(cmd *cobra.Command, projects []*models.Project) {
-	jsonData, jsonErr := json.MarshalIndent(projects, "", "  ")
-	if jsonErr != nil {
-		cmd.Printf("Error marshalling projects to JSON: %v\n", jsonErr)
-		return
-	}
-	cmd.Println(string(jsonData))
-}
-
-// printTasksJSON outputs the tasks in JSON format.
-func printTasksJSON(cmd *cobra.Command, tasks []*models.Task) {
-	jsonData, err := json.MarshalIndent(tasks, "", "  ")
-	if err != nil {
-		cmd.Printf("Error marshalling tasks to JSON: %v\n", err)
-		return
-	}
-	cmd.Println(string(jsonData))
-}
-
 // printProjectTable displays the list of projects in a table format.
 func printProjectTable(cmd *cobra.Command, projects []*models.Project) {
 	table := tablewriter.NewWriter(cmd.OutOrStdout())
@@ -164,15 +176,16 @@ func printProjectTable(cmd *cobra.Command, projects []*models.Project) {
 	table.Render()
 }
 
-// printTaskTable displays the list of tasks in a table format.
+// printTaskTable displays the list of tasks in a table format, with the display IDs of open tasks.
 func printTaskTable(
 	taskController *controllers.TaskController,
 	projectController *controllers.ProjectController,
 	tasks []*models.Task,
+	displayIDs map[int]int,
 ) {
 	table := tablewriter.NewWriter(os.Stdout)
 	table.SetHeader([]string{
-		"ID", "Name", "Description", "Due Date", "Completed", "Past Due", "Priority", "Project", "Type", "Parent/Child Of",
+		"#", "ID", "Name", "Description", "Due Date", "State", "Past Due", "Priority", "Project", "Type", "Parent/Child Of",
 	})
 	table.SetRowLine(true)
 
@@ -199,13 +212,19 @@ func printTaskTable(
 			projectName = project.Name
 		}
 
+		displayID := ""
+		if id, open := displayIDs[task.ID]; open {
+			displayID = controllers.DisplayIDPrefix + strconv.Itoa(id)
+		}
+
 		table.Append([]string{
+			displayID,
 			strconv.Itoa(task.ID),
 			utils.WrapText(task.Name, MaxTaskNameLength),
 			utils.WrapText(task.Description, MaxTaskDescLength),
 			utils.FormatDate(task.DueDate),
-			strconv.FormatBool(task.TaskCompleted),
-			utils.ColoredPastDue(task.DueDate, task.TaskCompleted),
+			task.State,
+			utils.ColoredPastDue(task.DueDate, task.CompletionDate != nil),
 			utils.GetPriorityString(task.Priority),
 			utils.WrapText(projectName, MaxProjectNameWrapLength),
 			typeField,
@@ -217,43 +236,99 @@ func printTaskTable(
 }
 
 // printProjectTree displays projects in a tree view using treeprint.
+// Projects whose parent is not part of the list are displayed at the root level.
 func printProjectTree(cmd *cobra.Command, projects []*models.Project) {
+	printTree(cmd, projects, func(project *models.Project) (int, *int) {
+		return project.ID, project.ParentProjectID
+	}, formatProjectLabel)
+}
+
+// printTaskTree displays tasks in a tree view using treeprint.
+// Tasks whose parent is not part of the list are displayed at the root level.
+func printTaskTree(cmd *cobra.Command, tasks []*models.Task) {
+	printTree(cmd, tasks, func(task *models.Task) (int, *int) {
+		return task.ID, task.ParentTaskID
+	}, formatTaskLabel)
+}
+
+// printTree displays items in a tree view using treeprint, given the ID and parent ID of each item. Items
+// whose parent is not part of the list are displayed at the root level. Items that cannot be reached from
+// the root level, because of a cycle of parents, are displayed at the root level too, starting with an
+// item of the cycle, whose label is marked, and followed by a warning.
+func printTree[T any](cmd *cobra.Command, items []T, idOf func(item T) (int, *int), label func(item T) string) {
 	tree := treeprint.New()
-	projectMap := make(map[int]treeprint.Tree)
+	byID := make(map[int]T, len(items))
+	children := make(map[int][]T)
 
-	for _, project := range projects {
-		projectLabel := formatProjectLabel(project)
-		if project.ParentProjectID != nil {
-			parentNode, exists := projectMap[*project.ParentProjectID]
-			if exists {
-				projectMap[project.ID] = parentNode.AddBranch(projectLabel)
+	for _, item := range items {
+		id, _ := idOf(item)
+		byID[id] = item
+	}
+
+	var roots []T
+	for _, item := range items {
+		if _, parentID := idOf(item); parentID != nil {
+			if _, known := byID[*parentID]; known {
+				children[*parentID] = append(children[*parentID], item)
+				continue
 			}
-		} else {
-			projectMap[project.ID] = tree.AddBranch(projectLabel)
 		}
+		roots = append(roots, item)
 	}
 
-	cmd.Println(tree.String())
-}
+	visited := make(map[int]bool, len(items))
+	var addBranches func(node treeprint.Tree, items []T)
+	addBranches = func(node treeprint.Tree, items []T) {
+		for _, item := range items {
+			id, _ := idOf(item)
+			if visited[id] {
+				continue
+			}
+			visited[id] = true
+			addBranches(node.AddBranch(label(item)), children[id])
+		}
+	}
+	addBranches(tree, roots)
 
-// printTaskTree displays tasks in a tree view using treeprint.
-func printTaskTree(cmd *cobra.Command, tasks []*models.Task) {
-	tree := treeprint.New()
-	taskMap := make(map[int]treeprint.Tree)
+	cyclic := 0
+	for _, item := range items {
+		if id, _ := idOf(item); visited[id] {
+			continue
+		}
 
-	for _, task := range tasks {
-		taskLabel := formatTaskLabel(task)
-		if task.ParentTaskID != nil {
-			parentNode, exists := taskMap[*task.ParentTaskID]
-			if exists {
-				taskMap[task.ID] = parentNode.AddBranch(taskLabel)
+		// Walk up the parents until one repeats, which is part of the cycle
+		seen := make(map[int]bool)
+		for {
+			id, parentID := idOf(item)
+			if seen[id] {
+				break
 			}
-		} else {
-			taskMap[task.ID] = tree.AddBranch(taskLabel)
+			seen[id] = true
+			item = byID[*parentID]
 		}
+
+		cyclic++
+		id, _ := idOf(item)
+		visited[id] = true
+		addBranches(tree.AddBranch(label(item)+" [parent cycle]"), children[id])
 	}
 
 	cmd.Println(tree.String())
+	if cyclic > 0 {
+		cmd.Printf("Warning: %d cycle(s) of parents found, marked '[parent cycle]'. "+
+			"Detach an item with '--project none' or '--task none' to fix them.\n", cyclic)
+	}
+}
+
+// filterTasksByState returns the tasks in the given workflow state.
+func filterTasksByState(tasks []*models.Task, state string) []*models.Task {
+	var filtered []*models.Task
+	for _, task := range tasks {
+		if task.State == state {
+			filtered = append(filtered, task)
+		}
+	}
+	return filtered
 }
 
 // formatProjectLabel creates a label for each project node.
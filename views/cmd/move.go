@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"github.com/spf13/cobra"
+)
+
+// workspaceMoveJSON is the JSON output of moves to another workspace: the IDs the moved items were given
+// there, by their IDs in the current workspace.
+type workspaceMoveJSON struct {
+	Workspace  string      `json:"workspace"`
+	ProjectIDs map[int]int `json:"project_ids"`
+	TaskIDs    map[int]int `json:"task_ids"`
+}
+
+// NewMoveCmd creates and returns the 'move' command for moving tasks between projects
+// and re-parenting projects.
+func NewMoveCmd(
+	projectController *controllers.ProjectController,
+	taskController *controllers.TaskController,
+	workspaceController *controllers.WorkspaceController,
+	backupController *controllers.BackupController,
+) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "move [project|task] <id>",
+		Short: "Move a task to another project or a project under another project",
+		Long: "Move a task, together with all its subtasks, to another project, " +
+			"or move a project, together with all its subprojects and tasks, under another project. " +
+			"With --to-workspace, the items are moved to another workspace, where they keep their UUIDs but get " +
+			"new IDs, and are removed from the current one like with 'remove', after an automatic backup.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Ensure sufficient arguments (either 'project' or 'task' followed by an ID)
+			if len(args) < MinArgsLength {
+				return errcode.New(errcode.Validation,
+					"insufficient arguments. Use 'move project <id>' or 'move task <id>'",
+				)
+			}
+
+			// Resolve the ID argument, a numeric ID or a UUID prefix
+			id, err := resolveItemID(projectController, taskController, args[0], args[1])
+			if err != nil {
+				return err
+			}
+
+			// Moves to another workspace are handled separately
+			workspace, _ := cmd.Flags().GetString("to-workspace")
+			if workspace != "" {
+				return moveToWorkspace(cmd, workspaceController, backupController, args[0], id, workspace)
+			}
+
+			// Determine whether the user wants to move a project or a task
+			switch args[0] {
+			case "project":
+				return moveProject(cmd, projectController, id)
+			case "task":
+				return moveTask(cmd, taskController, id)
+			default:
+				return errcode.New(errcode.Validation, "invalid option. Use 'move project <id>' or 'move task <id>'")
+			}
+		},
+	}
+
+	cmd.Flags().String("to", "", "Target project name, ID or UUID prefix for the task")
+	cmd.Flags().String("under", "", "New parent project name, ID or UUID prefix ('none' to move to the top level)")
+	cmd.Flags().Bool("detach", false, "Detach the task from its parent task")
+	cmd.Flags().String("to-workspace", "", "Target workspace (with --to, the project in that workspace)")
+	cmd.Flags().Bool("no-backup", false, "Do not back up the database before moving to another workspace")
+	addJSONFlag(cmd, "Output the moved projects or tasks in JSON format")
+
+	return cmd
+}
+
+// moveProject re-parents a project and prints a summary of the moved projects.
+func moveProject(
+	cmd *cobra.Command,
+	projectController *controllers.ProjectController,
+	id int,
+) error {
+	parentProjectIdentifier, _ := cmd.Flags().GetString("under")
+	if parentProjectIdentifier == "" {
+		return errcode.New(errcode.Validation, "no target provided. Use '--under <project>' or '--under none'")
+	}
+
+	moved, err := projectController.MoveProject(id, parentProjectIdentifier)
+	if err != nil {
+		return fmt.Errorf("error moving project: %w", err)
+	}
+
+	if outputJSON, _ := cmd.Flags().GetBool("json"); outputJSON {
+		return printJSON(cmd, moved)
+	}
+
+	target := "the top level"
+	if parentProjectIdentifier != controllers.NoParentIdentifier {
+		target = "project '" + parentProjectIdentifier + "'"
+	}
+
+	cmd.Println("Project '" + moved[0].Name + "' (ID: " + strconv.Itoa(id) + ") moved to " + target +
+		" together with " + strconv.Itoa(len(moved)-1) + " subproject(s):")
+	for _, project := range moved {
+		cmd.Println("  - " + formatProjectLabel(project))
+	}
+	return nil
+}
+
+// moveTask moves a task and its subtasks to another project and prints a summary of the moved tasks.
+func moveTask(cmd *cobra.Command, taskController *controllers.TaskController, id int) error {
+	projectIdentifier, _ := cmd.Flags().GetString("to")
+	detach, _ := cmd.Flags().GetBool("detach")
+	if projectIdentifier == "" {
+		return errcode.New(errcode.Validation, "no target provided. Use '--to <project>'")
+	}
+
+	moved, project, err := taskController.MoveTask(id, projectIdentifier, detach)
+	if err != nil {
+		return fmt.Errorf("error moving task: %w", err)
+	}
+
+	if outputJSON, _ := cmd.Flags().GetBool("json"); outputJSON {
+		return printJSON(cmd, moved)
+	}
+
+	cmd.Println("Task '" + moved[0].Name + "' (ID: " + strconv.Itoa(id) + ") moved to project '" +
+		project.Name + "' together with " + strconv.Itoa(len(moved)-1) + " subtask(s):")
+	for _, task := range moved {
+		cmd.Println("  - " + formatTaskLabel(task))
+	}
+	return nil
+}
+
+// moveToWorkspace moves a task or a project, with all its sub-items, to another workspace and prints
+// the IDs the moved items were given there.
+func moveToWorkspace(
+	cmd *cobra.Command,
+	workspaceController *controllers.WorkspaceController,
+	backupController *controllers.BackupController,
+	kind string,
+	id int,
+	workspace string,
+) error {
+	if kind != "project" && kind != "task" {
+		return errcode.New(errcode.Validation, "invalid option. Use 'move project <id>' or 'move task <id>'")
+	}
+	if backupErr := autoBackup(cmd, backupController, "move"); backupErr != nil {
+		return backupErr
+	}
+
+	var result *controllers.ImportResult
+	var err error
+	switch kind {
+	case "project":
+		result, err = workspaceController.MoveProjectToWorkspace(id, workspace)
+	case "task":
+		projectIdentifier, _ := cmd.Flags().GetString("to")
+		result, err = workspaceController.MoveTaskToWorkspace(id, workspace, projectIdentifier)
+	}
+	if err != nil {
+		return fmt.Errorf("error moving %s to workspace: %w", kind, err)
+	}
+
+	if outputJSON, _ := cmd.Flags().GetBool("json"); outputJSON {
+		return printJSON(cmd, workspaceMoveJSON{
+			Workspace:  workspace,
+			ProjectIDs: result.ProjectIDs,
+			TaskIDs:    result.TaskIDs,
+		})
+	}
+
+	cmd.Println(strings.ToUpper(kind[:1]) + kind[1:] + " (ID: " + strconv.Itoa(id) + ") moved to workspace '" +
+		workspace + "' together with its sub-items:")
+	printIDMapping(cmd, "  - Project", result.ProjectIDs)
+	printIDMapping(cmd, "  - Task", result.TaskIDs)
+	return nil
+}
@@ -1,9 +1,12 @@
 package cmd
 
 import (
-	"errors"
+	"fmt"
+	"strconv"
 
 	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"github.com/d4r1us-drk/clido/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -15,10 +18,11 @@ func NewNewCmd(
 	cmd := &cobra.Command{
 		Use:   "new [project|task]",
 		Short: "Create a new project or task",
-		Long:  "Create a new project or task with the specified details.",
+		Long: "Create a new project or task with the specified details. When run in a terminal without a " +
+			"name or, for tasks, a project, the missing fields are prompted for, unless --no-input is given.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) < 1 {
-				return errors.New("insufficient arguments. Use 'new project' or 'new task'")
+				return errcode.New(errcode.Validation, "insufficient arguments. Use 'new project' or 'new task'")
 			}
 
 			// Create project or task based on the argument
@@ -26,9 +30,9 @@ func NewNewCmd(
 			case "project":
 				return createProject(cmd, projectController)
 			case "task":
-				return createTask(cmd, taskController)
+				return createTask(cmd, projectController, taskController)
 			default:
-				return errors.New("invalid option. Use 'new project' or 'new task'")
+				return errcode.New(errcode.Validation, "invalid option. Use 'new project' or 'new task'")
 			}
 		},
 	}
@@ -36,68 +40,204 @@ func NewNewCmd(
 	// Define flags for project and task creation
 	cmd.Flags().StringP("name", "n", "", "Name of the project or task")
 	cmd.Flags().StringP("description", "d", "", "Description of the project or task")
-	cmd.Flags().StringP("project", "p", "", "Parent project name or ID for subprojects or tasks")
-	cmd.Flags().StringP("task", "t", "", "Parent task ID for subtasks")
-	cmd.Flags().StringP("due", "D", "", "Due date for the task (format: YYYY-MM-DD HH:MM)")
+	cmd.Flags().StringP("project", "p", "", "Parent project name, ID or UUID prefix for subprojects or tasks")
+	cmd.Flags().StringP("task", "t", "", "Parent task ID or UUID prefix for subtasks")
+	cmd.Flags().StringP("due", "D", "", "Due date for the task ("+utils.DueDateFormats+")")
 	cmd.Flags().
 		IntP("priority", "P", PriorityEmpty, "Priority of the task (1: High, 2: Medium, 3: Low, 4: None)")
+	cmd.Flags().StringSlice("tags", nil, "Tags of the task, contexts starting with '@' (e.g. billing,@home)")
+	addJSONFlag(cmd, "Output the created project or task in JSON format")
+	addNoInputFlag(cmd)
 
 	return cmd
 }
 
 func createProject(cmd *cobra.Command, projectController *controllers.ProjectController) error {
+	if name, _ := cmd.Flags().GetString("name"); name == "" && canPrompt(cmd) {
+		if err := promptNewProject(cmd, projectController); err != nil {
+			return err
+		}
+	}
+
 	name, _ := cmd.Flags().GetString("name")
 	description, _ := cmd.Flags().GetString("description")
 	parentProjectIdentifier, _ := cmd.Flags().GetString("project")
 
 	// Ensure project name is provided
 	if name == "" {
-		return errors.New("project name is required")
+		return controllers.ErrNoProjectName
 	}
 
 	// Call the controller to create the project
-	err := projectController.CreateProject(name, description, parentProjectIdentifier)
+	project, err := projectController.CreateProject(name, description, parentProjectIdentifier)
 	if err != nil {
-		return errors.New("error creating project: " + err.Error())
+		return fmt.Errorf("error creating project: %w", err)
 	}
 
-	cmd.Println("Project '" + name + "' created successfully.")
+	if outputJSON, _ := cmd.Flags().GetBool("json"); outputJSON {
+		return printJSON(cmd, project)
+	}
+	cmd.Println("Project '" + project.Name + "' (ID: " + strconv.Itoa(project.ID) + ") created successfully.")
 	return nil
 }
 
-func createTask(cmd *cobra.Command, taskController *controllers.TaskController) error {
+func createTask(
+	cmd *cobra.Command,
+	projectController *controllers.ProjectController,
+	taskController *controllers.TaskController,
+) error {
 	name, _ := cmd.Flags().GetString("name")
-	description, _ := cmd.Flags().GetString("description")
 	projectIdentifier, _ := cmd.Flags().GetString("project")
+	if (name == "" || projectIdentifier == "") && canPrompt(cmd) {
+		if err := promptNewTask(cmd, projectController, taskController); err != nil {
+			return err
+		}
+	}
+
+	name, _ = cmd.Flags().GetString("name")
+	description, _ := cmd.Flags().GetString("description")
+	projectIdentifier, _ = cmd.Flags().GetString("project")
 	parentTaskIdentifier, _ := cmd.Flags().GetString("task")
 	dueDateStr, _ := cmd.Flags().GetString("due")
 	priority, _ := cmd.Flags().GetInt("priority")
+	tags, _ := cmd.Flags().GetStringSlice("tags")
 
 	// Ensure task name is provided
 	if name == "" {
-		return errors.New("task name is required")
+		return controllers.ErrNoTaskName
 	}
 
 	// Validate priority
 	if priority != 0 && (priority < 1 || priority > 4) {
-		return errors.New(
+		return errcode.New(errcode.Validation,
 			"invalid priority. Use 1 for High, 2 for Medium, 3 for Low, or 4 for None",
 		)
 	}
 
 	// Call the controller to create the task
-	err := taskController.CreateTask(
+	task, err := taskController.CreateTask(
 		name,
 		description,
 		projectIdentifier,
 		parentTaskIdentifier,
 		dueDateStr,
 		priority,
+		tags,
 	)
 	if err != nil {
-		return errors.New("error creating task: " + err.Error())
+		return fmt.Errorf("error creating task: %w", err)
+	}
+
+	if outputJSON, _ := cmd.Flags().GetBool("json"); outputJSON {
+		return printJSON(cmd, task)
+	}
+	cmd.Println("Task '" + task.Name + "' (ID: " + strconv.Itoa(task.ID) + ") created successfully.")
+	return nil
+}
+
+// promptNewProject prompts for the fields of a new project not given with flags, and sets their flags.
+func promptNewProject(cmd *cobra.Command, projectController *controllers.ProjectController) error {
+	p := newPrompter(cmd)
+	if err := promptNameAndDescription(cmd, p, controllers.ErrNoProjectName); err != nil {
+		return err
+	}
+
+	if !cmd.Flags().Changed("project") {
+		projects, err := projectController.ListProjects()
+		if err != nil {
+			return fmt.Errorf("error listing projects: %w", err)
+		}
+		if len(projects) > 0 {
+			parent, chooseErr := p.choose("Parent project (optional)", projectChoices(projects, 0), "", true)
+			if chooseErr != nil {
+				return chooseErr
+			}
+			_ = cmd.Flags().Set("project", parent)
+		}
+	}
+	return nil
+}
+
+// promptNewTask prompts for the fields of a new task not given with flags, and sets their flags.
+func promptNewTask(
+	cmd *cobra.Command,
+	projectController *controllers.ProjectController,
+	taskController *controllers.TaskController,
+) error {
+	p := newPrompter(cmd)
+	if err := promptNameAndDescription(cmd, p, controllers.ErrNoTaskName); err != nil {
+		return err
+	}
+
+	if !cmd.Flags().Changed("project") {
+		projects, err := projectController.ListProjects()
+		if err != nil {
+			return fmt.Errorf("error listing projects: %w", err)
+		}
+		if len(projects) == 0 {
+			return errcode.New(errcode.Validation, "no project to add the task to. Use 'new project' first")
+		}
+		projectID, chooseErr := p.choose("Project", projectChoices(projects, 0), "", false)
+		if chooseErr != nil {
+			return chooseErr
+		}
+		_ = cmd.Flags().Set("project", projectID)
+	}
+
+	if !cmd.Flags().Changed("task") {
+		projectIdentifier, _ := cmd.Flags().GetString("project")
+		project, err := projectController.GetProject(projectIdentifier)
+		if err != nil {
+			return fmt.Errorf("error creating task: %w", err)
+		}
+		tasks, _, err := taskController.ListTasksByProjectFilter(strconv.Itoa(project.ID))
+		if err != nil {
+			return fmt.Errorf("error listing tasks: %w", err)
+		}
+		if len(tasks) > 0 {
+			parent, chooseErr := p.choose("Parent task (optional)", taskChoices(tasks, 0), "", true)
+			if chooseErr != nil {
+				return chooseErr
+			}
+			_ = cmd.Flags().Set("task", parent)
+		}
 	}
 
-	cmd.Println("Task '" + name + "' created successfully.")
+	if !cmd.Flags().Changed("due") {
+		due, err := p.askDueDate("")
+		if err != nil {
+			return err
+		}
+		_ = cmd.Flags().Set("due", due)
+	}
+
+	if !cmd.Flags().Changed("priority") {
+		priority, err := p.askPriority(PriorityNone)
+		if err != nil {
+			return err
+		}
+		_ = cmd.Flags().Set("priority", strconv.Itoa(priority))
+	}
+	return nil
+}
+
+// promptNameAndDescription prompts for the name and the description of a new project or task when not given
+// with flags, and sets their flags.
+func promptNameAndDescription(cmd *cobra.Command, p *prompter, noName error) error {
+	if name, _ := cmd.Flags().GetString("name"); name == "" {
+		name, err := p.askRequired("Name", "", noName)
+		if err != nil {
+			return err
+		}
+		_ = cmd.Flags().Set("name", name)
+	}
+
+	if !cmd.Flags().Changed("description") {
+		description, err := p.ask("Description (optional)", "", nil)
+		if err != nil {
+			return err
+		}
+		_ = cmd.Flags().Set("description", description)
+	}
 	return nil
 }
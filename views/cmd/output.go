@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// removedJSON is the JSON output of removals: the IDs of the removed items, sub-items included.
+type removedJSON struct {
+	Removed []int `json:"removed"`
+}
+
+// addJSONFlag adds the --json flag to a command changing projects or tasks.
+func addJSONFlag(cmd *cobra.Command, usage string) {
+	cmd.Flags().BoolP("json", "j", false, usage)
+}
+
+// printJSON writes a value as indented JSON to the standard output of a command, for scripts.
+func printJSON(cmd *cobra.Command, value any) error {
+	jsonData, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling to JSON: %w", err)
+	}
+	if _, err = fmt.Fprintln(cmd.OutOrStdout(), string(jsonData)); err != nil {
+		return fmt.Errorf("error writing JSON: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// ExitCodeError is returned by commands that exit with a specific status, such as plugins, whose own output
+// already describes the failure.
+type ExitCodeError struct {
+	Code int // The exit status of the process
+}
+
+func (e *ExitCodeError) Error() string {
+	return "exit status " + strconv.Itoa(e.Code)
+}
+
+// NewPluginsCmd creates and returns the 'plugins' command, which lists the plugins found on the PATH.
+func NewPluginsCmd(pluginController *controllers.PluginController) *cobra.Command {
+	return &cobra.Command{
+		Use:   "plugins",
+		Short: "List the plugins adding commands to clido",
+		Long: "List the plugins found on the PATH. A plugin is an executable named '" + controllers.PluginPrefix +
+			"<command>', run by 'clido <command>' with the remaining arguments when clido has no such command. " +
+			"It inherits the standard streams and environment of clido, extended with " +
+			controllers.PluginEnvBinary + " (the clido executable), " + controllers.PluginEnvDBPath + ", " +
+			controllers.PluginEnvDataDir + ", " + controllers.PluginEnvWorkspace + " and " +
+			controllers.PluginEnvVersion + ", and its exit status is the one of clido.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			plugins, err := pluginController.ListPlugins()
+			if err != nil {
+				return fmt.Errorf("error listing plugins: %w", err)
+			}
+			if len(plugins) == 0 {
+				cmd.Println("No plugins found on the PATH.")
+				return nil
+			}
+
+			table := tablewriter.NewWriter(cmd.OutOrStdout())
+			table.SetHeader([]string{"Command", "Path"})
+			for _, plugin := range plugins {
+				table.Append([]string{plugin.Name, plugin.Path})
+			}
+			table.Render()
+			return nil
+		},
+	}
+}
+
+// runPlugin runs the plugin of the command given first in args, passing it the remaining arguments as is.
+func runPlugin(cmd *cobra.Command, pluginController *controllers.PluginController, args []string) error {
+	cmd.SilenceUsage = true
+	plugin, err := pluginController.FindPlugin(args[0])
+	if err != nil {
+		suggestions := ""
+		if cmd.SuggestionsMinimumDistance <= 0 {
+			cmd.SuggestionsMinimumDistance = 2 // The default distance of cobra
+		}
+		if names := cmd.SuggestionsFor(args[0]); len(names) > 0 {
+			suggestions = "\n\nDid you mean this?\n\t" + strings.Join(names, "\n\t") + "\n"
+		}
+		return errcode.New(errcode.Validation, fmt.Sprintf("unknown command %q for %q%s\nRun '%s --help' for usage",
+			args[0], cmd.CommandPath(), suggestions, cmd.CommandPath()))
+	}
+
+	pluginCmd := exec.Command(plugin.Path, args[1:]...)
+	pluginCmd.Stdin = cmd.InOrStdin()
+	pluginCmd.Stdout = cmd.OutOrStdout()
+	pluginCmd.Stderr = cmd.ErrOrStderr()
+	pluginCmd.Env = pluginController.PluginEnv()
+
+	var exitErr *exec.ExitError
+	if runErr := pluginCmd.Run(); errors.As(runErr, &exitErr) {
+		// The plugin reported its failure itself
+		cmd.SilenceErrors = true
+		return &ExitCodeError{Code: exitErr.ExitCode()}
+	} else if runErr != nil {
+		return fmt.Errorf("error running plugin '%s': %w", plugin.Name, runErr)
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"github.com/d4r1us-drk/clido/models"
+	"github.com/d4r1us-drk/clido/utils"
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+)
+
+// MaxPromptMatches is the maximum number of matching items listed to pick from when prompting for a
+// project or task.
+const MaxPromptMatches = 10
+
+var errPromptAborted = errcode.New(errcode.Validation, "input ended before all fields were given")
+
+// prompter asks for the fields missing from the flags of a command, reading the answers from its standard
+// input and writing the questions to its standard error, leaving the standard output to --json.
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// promptChoice is an item that can be picked when prompting for a project or task.
+type promptChoice struct {
+	name  string // The name matched against the answer
+	label string // The label listed when several items match
+	value string // The value of the flag set when picked, e.g. the ID of the item
+}
+
+// addNoInputFlag adds the --no-input flag to a command prompting for the fields missing from its flags.
+func addNoInputFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("no-input", false, "Never prompt for missing fields, even when run in a terminal")
+}
+
+// canPrompt reports whether a command may prompt for the fields missing from its flags: its standard input
+// is a terminal and --no-input is not set. Otherwise, missing fields are errors as usual.
+func canPrompt(cmd *cobra.Command) bool {
+	if noInput, _ := cmd.Flags().GetBool("no-input"); noInput {
+		return false
+	}
+	file, ok := cmd.InOrStdin().(*os.File)
+	return ok && (isatty.IsTerminal(file.Fd()) || isatty.IsCygwinTerminal(file.Fd()))
+}
+
+func newPrompter(cmd *cobra.Command) *prompter {
+	return &prompter{in: bufio.NewReader(cmd.InOrStdin()), out: cmd.ErrOrStderr()}
+}
+
+// ask prompts for a value until validate, if any, accepts it. An empty answer stands for the default value,
+// shown between brackets.
+func (p *prompter) ask(label, defaultValue string, validate func(string) error) (string, error) {
+	for {
+		if defaultValue != "" {
+			fmt.Fprintf(p.out, "%s [%s]: ", label, defaultValue)
+		} else {
+			fmt.Fprintf(p.out, "%s: ", label)
+		}
+
+		line, err := p.in.ReadString('\n')
+		answer := strings.TrimSpace(line)
+		if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+			fmt.Fprintln(p.out)
+			return "", errPromptAborted
+		}
+		if answer == "" {
+			answer = defaultValue
+		}
+
+		if validate != nil {
+			if validateErr := validate(answer); validateErr != nil {
+				fmt.Fprintln(p.out, "  "+validateErr.Error())
+				continue
+			}
+		}
+		return answer, nil
+	}
+}
+
+// askRequired prompts for a value that cannot be empty.
+func (p *prompter) askRequired(label, defaultValue string, required error) (string, error) {
+	return p.ask(label, defaultValue, func(answer string) error {
+		if answer == "" {
+			return required
+		}
+		return nil
+	})
+}
+
+// askDueDate prompts for a due date in one of the formats accepted by utils.ParseDueDate, or none.
+func (p *prompter) askDueDate(defaultValue string) (string, error) {
+	return p.ask("Due date ("+utils.DueDateFormats+")", defaultValue, func(answer string) error {
+		if answer == "" {
+			return nil
+		}
+		if _, err := utils.ParseDueDate(answer); err != nil {
+			return fmt.Errorf("%w: '%s'", controllers.ErrInvalidDueDate, answer)
+		}
+		return nil
+	})
+}
+
+// askPriority prompts for a priority, given as a number from 1 to 4 or as its name, and returns its number.
+func (p *prompter) askPriority(defaultPriority int) (int, error) {
+	answer, err := p.ask("Priority (1: High, 2: Medium, 3: Low, 4: None)", strconv.Itoa(defaultPriority),
+		func(answer string) error {
+			_, parseErr := parsePriorityAnswer(answer)
+			return parseErr
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return parsePriorityAnswer(answer)
+}
+
+// parsePriorityAnswer parses a priority given as a number from 1 to 4 or as its name, e.g. "high".
+func parsePriorityAnswer(answer string) (int, error) {
+	for priority := PriorityHigh; priority <= PriorityNone; priority++ {
+		if answer == strconv.Itoa(priority) || strings.EqualFold(answer, utils.GetPriorityString(priority)) {
+			return priority, nil
+		}
+	}
+	return 0, errcode.New(errcode.Validation, "invalid priority. Use 1 for High, 2 for Medium, 3 for Low, or 4 for None")
+}
+
+// choose prompts for one of the choices, searched by a fuzzy match of their names or by their value, and
+// returns its value. When several choices match, they are listed to pick one by number. An empty answer
+// without default value returns an empty value when the field is optional.
+func (p *prompter) choose(label string, choices []promptChoice, defaultValue string, optional bool) (string, error) {
+	for {
+		answer, err := p.ask(label, defaultValue, nil)
+		if err != nil {
+			return "", err
+		}
+		if answer == "" {
+			if optional {
+				return "", nil
+			}
+			fmt.Fprintln(p.out, "  a value is required")
+			continue
+		}
+
+		matches := matchChoices(choices, answer)
+		switch len(matches) {
+		case 0:
+			fmt.Fprintln(p.out, "  nothing matches '"+answer+"'")
+			continue
+		case 1:
+			fmt.Fprintln(p.out, "  "+matches[0].label)
+			return matches[0].value, nil
+		}
+
+		if len(matches) > MaxPromptMatches {
+			matches = matches[:MaxPromptMatches]
+		}
+		for i, match := range matches {
+			fmt.Fprintf(p.out, "  %d) %s\n", i+1, match.label)
+		}
+		number, pickErr := p.ask("Number", "", func(answer string) error {
+			if number, parseErr := strconv.Atoi(answer); parseErr != nil || number < 1 || number > len(matches) {
+				return fmt.Errorf("enter a number from 1 to %d", len(matches))
+			}
+			return nil
+		})
+		if pickErr != nil {
+			return "", pickErr
+		}
+		index, _ := strconv.Atoi(number)
+		return matches[index-1].value, nil
+	}
+}
+
+// matchChoices returns the choices whose value is the answer or, failing that, whose name matches the answer,
+// best matches first: names equal to the answer, starting with it, containing it, then containing its
+// characters in order. Only the equal names are returned when there are some.
+func matchChoices(choices []promptChoice, answer string) []promptChoice {
+	for _, choice := range choices {
+		if choice.value == answer {
+			return []promptChoice{choice}
+		}
+	}
+
+	type scoredChoice struct {
+		choice promptChoice
+		score  int
+	}
+	var scored []scoredChoice
+	for _, choice := range choices {
+		if score := fuzzyScore(choice.name, answer); score >= 0 {
+			scored = append(scored, scoredChoice{choice: choice, score: score})
+		}
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].score < scored[j].score
+	})
+
+	var matches []promptChoice
+	for _, match := range scored {
+		if match.score > 0 && len(matches) > 0 && scored[0].score == 0 {
+			break
+		}
+		matches = append(matches, match.choice)
+	}
+	return matches
+}
+
+// fuzzyScore returns how well a name matches a pattern, regardless of case: 0 when equal, 1 when it starts
+// with the pattern, 2 when it contains it, 3 when it contains its characters in order, or -1.
+func fuzzyScore(name, pattern string) int {
+	name, pattern = strings.ToLower(name), strings.ToLower(pattern)
+	switch {
+	case name == pattern:
+		return 0
+	case strings.HasPrefix(name, pattern):
+		return 1
+	case strings.Contains(name, pattern):
+		return 2
+	}
+
+	remaining := []rune(pattern)
+	for _, r := range name {
+		if len(remaining) > 0 && r == remaining[0] {
+			remaining = remaining[1:]
+		}
+	}
+	if len(remaining) == 0 {
+		return 3
+	}
+	return -1
+}
+
+// projectChoices returns the projects to pick from, except the excluded one, if any.
+func projectChoices(projects []*models.Project, excludedID int) []promptChoice {
+	choices := make([]promptChoice, 0, len(projects))
+	for _, project := range projects {
+		if project.ID == excludedID {
+			continue
+		}
+		choices = append(choices, promptChoice{
+			name:  project.Name,
+			label: formatProjectLabel(project),
+			value: strconv.Itoa(project.ID),
+		})
+	}
+	return choices
+}
+
+// taskChoices returns the tasks to pick from, except the excluded one, if any.
+func taskChoices(tasks []*models.Task, excludedID int) []promptChoice {
+	choices := make([]promptChoice, 0, len(tasks))
+	for _, task := range tasks {
+		if task.ID == excludedID {
+			continue
+		}
+		choices = append(choices, promptChoice{
+			name:  task.Name,
+			label: formatTaskLabel(task),
+			value: strconv.Itoa(task.ID),
+		})
+	}
+	return choices
+}
+
+// noneChoice is the choice detaching a project or task from its parent.
+func noneChoice() promptChoice {
+	return promptChoice{
+		name:  controllers.NoParentIdentifier,
+		label: "None (no parent)",
+		value: controllers.NoParentIdentifier,
+	}
+}
@@ -1,10 +1,11 @@
 package cmd
 
 import (
-	"errors"
+	"fmt"
 	"strconv"
 
 	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/internal/errcode"
 	"github.com/spf13/cobra"
 )
 
@@ -12,66 +13,125 @@ import (
 func NewRemoveCmd(
 	projectController *controllers.ProjectController,
 	taskController *controllers.TaskController,
+	backupController *controllers.BackupController,
 ) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "remove [project|task] <id>",
-		Short: "Remove a project or task along with all its subprojects or subtasks",
-		Long:  "Remove a project or task by ID, along with all its sub-items.",
+		Use:   "remove [project|task] <ids>",
+		Short: "Remove projects or tasks along with all their subprojects or subtasks",
+		Long: "Remove projects or tasks identified by IDs or ID ranges (e.g. 3,5,8-12), or selected " +
+			"with a query (--where), along with all their sub-items. All removals are applied together.",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// Ensure sufficient arguments (either 'project' or 'task' followed by an ID)
-			if len(args) < MinArgsLength {
-				return errors.New(
-					"insufficient arguments. Use 'remove project <id>' or 'remove task <id>'",
-				)
+			// Determine whether the user wants to remove projects or tasks
+			kind, idArgs, ok := splitKindArgs(cmd, args)
+			if !ok {
+				if len(args) == 0 {
+					return errcode.New(errcode.Validation,
+						"insufficient arguments. Use 'remove project <ids>' or 'remove task <ids>'",
+					)
+				}
+				return errcode.New(errcode.Validation, "invalid option. Use 'remove project <ids>' or 'remove task <ids>'")
 			}
 
-			// Parse the ID argument into an integer
-			id, err := strconv.Atoi(args[1])
-			if err != nil {
-				return errors.New("invalid ID. Please provide a numeric ID")
-			}
-
-			// Determine whether the user wants to remove a project or a task
-			switch args[0] {
-			case "project":
-				return removeProject(cmd, projectController, id)
-			case "task":
-				return removeTask(cmd, taskController, id)
-			default:
-				return errors.New("invalid option. Use 'remove project <id>' or 'remove task <id>'")
+			if kind == "project" {
+				return removeProjects(cmd, projectController, backupController, idArgs)
 			}
+			return removeTasks(cmd, taskController, backupController, idArgs)
 		},
 	}
 
+	addSelectionFlags(cmd)
+	cmd.Flags().Bool("no-backup", false, "Do not back up the database before removing")
+	addJSONFlag(cmd, "Output the IDs of the removed projects or tasks, sub-items included, in JSON format")
+
 	return cmd
 }
 
-// removeProject handles the recursive removal of a project and all its subprojects.
+// removeProjects handles the recursive removal of the selected projects and all their subprojects.
 // It uses the ProjectController to handle the deletion.
-func removeProject(
+func removeProjects(
 	cmd *cobra.Command,
 	projectController *controllers.ProjectController,
-	id int,
+	backupController *controllers.BackupController,
+	idArgs []string,
 ) error {
-	err := projectController.RemoveProject(id)
+	projects, selectErr := selectProjects(cmd, projectController, idArgs)
+	if selectErr != nil {
+		return selectErr
+	}
+	if previewSelection(cmd, "removed", projectLabels(projects)) {
+		return nil
+	}
+	if backupErr := autoBackup(cmd, backupController, "remove"); backupErr != nil {
+		return backupErr
+	}
+
+	removed := removedJSON{Removed: []int{}}
+	err := projectController.InTransaction(func(txController *controllers.ProjectController) error {
+		for _, project := range projects {
+			removedIDs, removeErr := txController.RemoveProject(project.ID)
+			if removeErr != nil {
+				return fmt.Errorf("project with ID '%d': %w", project.ID, removeErr)
+			}
+			removed.Removed = append(removed.Removed, removedIDs...)
+		}
+		return nil
+	})
 	if err != nil {
-		return errors.New("error removing project: " + err.Error())
+		return fmt.Errorf("error removing project: %w", err)
 	}
 
-	cmd.Println(
-		"Project (ID: " + strconv.Itoa(id) + ") and all its subprojects removed successfully.",
-	)
+	if outputJSON, _ := cmd.Flags().GetBool("json"); outputJSON {
+		return printJSON(cmd, removed)
+	}
+
+	for _, project := range projects {
+		cmd.Println(
+			"Project (ID: " + strconv.Itoa(project.ID) + ") and all its subprojects removed successfully.",
+		)
+	}
 	return nil
 }
 
-// removeTask handles the recursive removal of a task and all its subtasks.
+// removeTasks handles the recursive removal of the selected tasks and all their subtasks.
 // It uses the TaskController to handle the deletion.
-func removeTask(cmd *cobra.Command, taskController *controllers.TaskController, id int) error {
-	err := taskController.RemoveTask(id)
+func removeTasks(
+	cmd *cobra.Command,
+	taskController *controllers.TaskController,
+	backupController *controllers.BackupController,
+	idArgs []string,
+) error {
+	tasks, selectErr := selectTasks(cmd, taskController, idArgs)
+	if selectErr != nil {
+		return selectErr
+	}
+	if previewSelection(cmd, "removed", taskLabels(tasks)) {
+		return nil
+	}
+	if backupErr := autoBackup(cmd, backupController, "remove"); backupErr != nil {
+		return backupErr
+	}
+
+	removed := removedJSON{Removed: []int{}}
+	err := taskController.InTransaction(func(txController *controllers.TaskController) error {
+		for _, task := range tasks {
+			removedIDs, removeErr := txController.RemoveTask(task.ID)
+			if removeErr != nil {
+				return fmt.Errorf("task with ID '%d': %w", task.ID, removeErr)
+			}
+			removed.Removed = append(removed.Removed, removedIDs...)
+		}
+		return nil
+	})
 	if err != nil {
-		return errors.New("error removing task: " + err.Error())
+		return fmt.Errorf("error removing task: %w", err)
 	}
 
-	cmd.Println("Task (ID: " + strconv.Itoa(id) + ") and all its subtasks removed successfully.")
+	if outputJSON, _ := cmd.Flags().GetBool("json"); outputJSON {
+		return printJSON(cmd, removed)
+	}
+
+	for _, task := range tasks {
+		cmd.Println("Task (ID: " + strconv.Itoa(task.ID) + ") and all its subtasks removed successfully.")
+	}
 	return nil
 }
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/models"
+	"github.com/spf13/cobra"
+)
+
+// NewReplicateCmd creates and returns the 'replicate' command, which exchanges the change log of the
+// database with other databases through replication bundles.
+func NewReplicateCmd(replicaController *controllers.ReplicaController) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "replicate",
+		Short: "Replicate the database through change log bundles",
+		Long: "Replicate projects and tasks between databases by exchanging their change logs, which record " +
+			"every change of a field with a hybrid logical clock timestamp. Each field keeps the value of its " +
+			"latest change, so databases that imported each other's bundles converge to the same state, " +
+			"whatever the order of the imports, e.g. 'replicate export -o laptop.json' on one machine and " +
+			"'replicate import laptop.json' on the other.",
+	}
+
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the change log as a replication bundle",
+		Long:  "Write the change log of the database as a JSON replication bundle, to a file or to the standard output.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			output, _ := cmd.Flags().GetString("output")
+
+			bundle, err := replicaController.Export()
+			if err != nil {
+				return fmt.Errorf("error exporting the change log: %w", err)
+			}
+
+			jsonData, err := json.MarshalIndent(bundle, "", "  ")
+			if err != nil {
+				return fmt.Errorf("error marshalling the bundle to JSON: %w", err)
+			}
+
+			jsonData = append(jsonData, '\n')
+			if output == "" || output == "-" {
+				if _, writeErr := cmd.OutOrStdout().Write(jsonData); writeErr != nil {
+					return fmt.Errorf("error writing the bundle: %w", writeErr)
+				}
+				return nil
+			}
+
+			if writeErr := os.WriteFile(output, jsonData, ExportFileMode); writeErr != nil {
+				return fmt.Errorf("error writing the bundle file: %w", writeErr)
+			}
+			cmd.Println("Exported " + strconv.Itoa(len(bundle.Operations)) + " operation(s) of replica " +
+				bundle.Node + " to '" + output + "'.")
+			return nil
+		},
+	}
+	exportCmd.Flags().StringP("output", "o", "", "Write the bundle to a file instead of the standard output")
+
+	importCmd := &cobra.Command{
+		Use:   "import [file]",
+		Short: "Import a replication bundle",
+		Long: "Add the operations of a replication bundle (read from the standard input when no file or '-' " +
+			"is given) to the change log and update projects and tasks accordingly. Importing the same " +
+			"bundle again changes nothing.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var jsonData []byte
+			var readErr error
+			if len(args) == 0 || args[0] == "-" {
+				jsonData, readErr = io.ReadAll(cmd.InOrStdin())
+			} else {
+				jsonData, readErr = os.ReadFile(args[0])
+			}
+			if readErr != nil {
+				return fmt.Errorf("error reading the bundle: %w", readErr)
+			}
+
+			var bundle models.ReplicaBundle
+			if err := json.Unmarshal(jsonData, &bundle); err != nil {
+				return fmt.Errorf("error parsing the bundle: %w", err)
+			}
+
+			result, err := replicaController.Import(&bundle)
+			if err != nil {
+				return fmt.Errorf("error importing the bundle: %w", err)
+			}
+
+			if result.Backup != "" {
+				cmd.Println("Backup of the database written to " + result.Backup + ".")
+			}
+			cmd.Println("Imported " + strconv.Itoa(result.Imported) + " new operation(s) from replica " +
+				result.Node + ": " + strconv.Itoa(result.Created) + " created, " + strconv.Itoa(result.Updated) +
+				" updated, " + strconv.Itoa(result.Deleted) + " deleted.")
+			return nil
+		},
+	}
+
+	cmd.AddCommand(exportCmd)
+	cmd.AddCommand(importCmd)
+	return cmd
+}
@@ -1,7 +1,10 @@
 package cmd
 
 import (
+	"strings"
+
 	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/internal/errcode"
 	"github.com/d4r1us-drk/clido/internal/version"
 	"github.com/spf13/cobra"
 )
@@ -21,26 +24,87 @@ const (
 	PriorityEmpty            = 0
 )
 
+// schemaIndependentCommands are the top-level commands that can run while the database schema is older than
+// expected after a rollback: they manage the schema, the database file or workspaces, or do not use the
+// database at all.
+var schemaIndependentCommands = map[string]bool{
+	"db":                            true,
+	"backup":                        true,
+	"restore":                       true,
+	"workspace":                     true,
+	"version":                       true,
+	"completion":                    true,
+	"help":                          true,
+	cobra.ShellCompRequestCmd:       true,
+	cobra.ShellCompNoDescRequestCmd: true,
+}
+
 // NewRootCmd creates and returns the root command for the CLI application.
 func NewRootCmd(
 	projectController *controllers.ProjectController,
 	taskController *controllers.TaskController,
+	exportController *controllers.ExportController,
+	backupController *controllers.BackupController,
+	databaseController *controllers.DatabaseController,
+	workspaceController *controllers.WorkspaceController,
+	syncController *controllers.SyncController,
+	caldavController *controllers.CalDAVController,
+	replicaController *controllers.ReplicaController,
+	hookController *controllers.HookController,
+	pluginController *controllers.PluginController,
+	webhookController *controllers.WebhookController,
 ) *cobra.Command {
 	rootCmd := &cobra.Command{
 		Use:   "clido",
 		Short: "clido is an awesome CLI to-do list management application",
 		Long: "clido is a simple yet powerful CLI tool designed to help you manage " +
 			"your projects and tasks effectively from the terminal.",
+		// Commands that are not built in are left to the root command, which runs the plugin of that name
+		Args:               cobra.ArbitraryArgs,
+		DisableFlagParsing: true,
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			return checkSchema(cmd, databaseController)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRoot(cmd, pluginController, args)
+		},
 	}
 
+	// The workspace is selected before parsing (see WorkspaceFromArgs), the flag is declared for help and validation
+	rootCmd.PersistentFlags().String(WorkspaceFlag, "", "Workspace to use instead of the current one")
+	// The root flags are parsed by runRoot, they are declared for help
+	rootCmd.Flags().BoolP("version", "v", false, "Print the version number of Clido")
+
 	// Add subcommands and pass the controllers
 	rootCmd.AddCommand(NewVersionCmd()) // Version command to display the app version
 	rootCmd.AddCommand(NewCompletionCmd())
 	rootCmd.AddCommand(NewNewCmd(projectController, taskController))
+	rootCmd.AddCommand(NewAddCmd(projectController, taskController))
 	rootCmd.AddCommand(NewEditCmd(projectController, taskController))
 	rootCmd.AddCommand(NewListCmd(projectController, taskController))
-	rootCmd.AddCommand(NewRemoveCmd(projectController, taskController))
+	rootCmd.AddCommand(NewShowCmd(projectController, taskController))
+	rootCmd.AddCommand(NewBoardCmd(projectController, taskController))
+	rootCmd.AddCommand(NewRemoveCmd(projectController, taskController, backupController))
+	rootCmd.AddCommand(NewMoveCmd(projectController, taskController, workspaceController, backupController))
 	rootCmd.AddCommand(NewToggleCmd(taskController))
+	rootCmd.AddCommand(NewDoneCmd(taskController))
+	rootCmd.AddCommand(NewReopenCmd(taskController))
+	rootCmd.AddCommand(NewStatusCmd(taskController))
+	rootCmd.AddCommand(NewExportCmd(exportController))
+	rootCmd.AddCommand(NewImportCmd(exportController))
+	rootCmd.AddCommand(NewBackupCmd(backupController))
+	rootCmd.AddCommand(NewRestoreCmd(backupController))
+	rootCmd.AddCommand(NewDBCmd(databaseController))
+	rootCmd.AddCommand(NewWorkspaceCmd(workspaceController))
+	rootCmd.AddCommand(NewSyncCmd(syncController, caldavController))
+	rootCmd.AddCommand(NewReplicateCmd(replicaController))
+	rootCmd.AddCommand(NewRPCCmd(projectController, taskController, backupController))
+	rootCmd.AddCommand(NewHooksCmd(hookController))
+	rootCmd.AddCommand(NewWebhooksCmd(webhookController))
+	rootCmd.AddCommand(NewPluginsCmd(pluginController))
+
+	// Report invalid flags and arguments as validation errors, with their own exit status
+	markUsageErrors(rootCmd)
 
 	return rootCmd
 }
@@ -56,11 +120,41 @@ func NewVersionCmd() *cobra.Command {
 	}
 }
 
-// Execute runs the root command.
-func Execute() error {
-	rootCmd := NewRootCmd(nil, nil)
-	if err := rootCmd.Execute(); err != nil {
-		return err
+// checkSchema refuses to run commands using the projects and tasks of the database while its schema is older
+// than expected, after a rollback, until it is migrated again. The root command itself only prints help or
+// runs plugins, whose own calls to clido are checked.
+func checkSchema(cmd *cobra.Command, databaseController *controllers.DatabaseController) error {
+	top := cmd
+	for top.HasParent() && top.Parent().HasParent() {
+		top = top.Parent()
+	}
+	if !top.HasParent() || schemaIndependentCommands[top.Name()] {
+		return nil
+	}
+	return databaseController.CheckSchema()
+}
+
+// runRoot runs the root command, which receives its arguments unparsed so that those of plugins are passed
+// as is. Only the global flags, --help and --version may precede the command, which runs the plugin of that
+// name.
+func runRoot(cmd *cobra.Command, pluginController *controllers.PluginController, args []string) error {
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		switch {
+		case args[0] == "-h" || args[0] == "--help":
+			return cmd.Help()
+		case args[0] == "-v" || args[0] == "--version":
+			cmd.Println(version.FullVersion())
+			return nil
+		case args[0] == "--"+WorkspaceFlag && len(args) > 1:
+			args = args[2:]
+		case strings.HasPrefix(args[0], "--"+WorkspaceFlag+"="):
+			args = args[1:]
+		default:
+			return errcode.New(errcode.Validation, "unknown flag: "+args[0])
+		}
+	}
+	if len(args) == 0 {
+		return cmd.Help()
 	}
-	return nil
+	return runPlugin(cmd, pluginController, args)
 }
@@ -0,0 +1,482 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"github.com/d4r1us-drk/clido/internal/jsonrpc"
+	"github.com/d4r1us-drk/clido/models"
+	"github.com/spf13/cobra"
+)
+
+// Error codes of the JSON-RPC mode, in the range the JSON-RPC specification leaves to applications.
+const (
+	RPCCodeNotFound   = -32001 // The project or task does not exist
+	RPCCodeValidation = -32002 // The parameters are not valid for the change
+	RPCCodeConflict   = -32003 // The change conflicts with the state of the item
+	RPCCodeRejected   = -32004 // A pre-hook rejected the change
+	RPCCodeStorage    = -32005 // The database could not be read or written
+)
+
+// RPCChangedMethod is the method of the notifications sent when projects or tasks change.
+const RPCChangedMethod = "changed"
+
+// rpcErrorCodes maps the kinds of errors to the JSON-RPC error codes. Other errors are internal errors.
+var rpcErrorCodes = map[errcode.Code]int{
+	errcode.NotFound:   RPCCodeNotFound,
+	errcode.Validation: RPCCodeValidation,
+	errcode.Conflict:   RPCCodeConflict,
+	errcode.Rejected:   RPCCodeRejected,
+	errcode.Storage:    RPCCodeStorage,
+}
+
+// rpcIdentifier identifies a project or task in the parameters of a call: a numeric ID, given as a number
+// or a string, or any identifier the commands accept, such as a UUID prefix or a display ID.
+type rpcIdentifier string
+
+// UnmarshalJSON accepts a JSON number or string.
+func (id *rpcIdentifier) UnmarshalJSON(data []byte) error {
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err == nil {
+		*id = rpcIdentifier(number.String())
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return errors.New("an ID must be a number or a string")
+	}
+	*id = rpcIdentifier(text)
+	return nil
+}
+
+// rpcChange is the parameter of the notifications sent when projects or tasks change.
+type rpcChange struct {
+	ItemType string `json:"item_type"`
+	Action   string `json:"action"` // "created", "updated" or "removed"
+	IDs      []int  `json:"ids,omitempty"`
+}
+
+// NewRPCCmd creates and returns the 'rpc' command, which serves JSON-RPC 2.0 requests on the standard input
+// and output, for editor integrations that would otherwise start clido for every operation.
+func NewRPCCmd(
+	projectController *controllers.ProjectController,
+	taskController *controllers.TaskController,
+	backupController *controllers.BackupController,
+) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rpc",
+		Short: "Serve JSON-RPC 2.0 requests on the standard input and output",
+		Long: "Serve JSON-RPC 2.0 requests read from the standard input, one message per line, until it is " +
+			"closed, and write one response per line to the standard output. The methods mirror the " +
+			"commands: project.list, project.get, project.create, project.edit, project.remove, " +
+			"project.subtree, task.list, task.get, task.create, task.edit, task.toggle, task.complete, " +
+			"task.reopen, task.remove and task.subtree, with named parameters, plus rpc.methods. Every change " +
+			"is followed by a '" + RPCChangedMethod + "' notification. Errors of the controllers have the " +
+			"codes -32001 (not found), -32002 (validation), -32003 (conflict), -32004 (rejected by a hook) and " +
+			"-32005 (storage), with their kind in the data of the error.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			server := jsonrpc.NewServer(cmd.OutOrStdout(), rpcError)
+			registerProjectMethods(cmd, server, projectController, backupController)
+			registerTaskMethods(cmd, server, taskController, backupController)
+			server.Handle("rpc.methods", func(_ json.RawMessage) (any, error) {
+				return server.Methods(), nil
+			})
+
+			if err := server.Serve(cmd.InOrStdin()); err != nil {
+				return fmt.Errorf("error serving JSON-RPC requests: %w", err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("no-backup", false, "Do not back up the database before removals")
+	return cmd
+}
+
+// registerProjectMethods registers the project.* methods.
+func registerProjectMethods(
+	cmd *cobra.Command,
+	server *jsonrpc.Server,
+	projectController *controllers.ProjectController,
+	backupController *controllers.BackupController,
+) {
+	getProject := func(id rpcIdentifier) (*models.Project, error) {
+		projectID, err := projectController.ResolveProjectID(string(id))
+		if err != nil {
+			return nil, err
+		}
+		project, err := projectController.GetProjectByID(projectID)
+		if err != nil {
+			return nil, controllers.ErrNoProjectFound
+		}
+		return project, nil
+	}
+
+	server.Handle("project.list", func(params json.RawMessage) (any, error) {
+		var args struct {
+			Query string `json:"query"`
+		}
+		if err := decodeRPCParams(params, &args); err != nil {
+			return nil, err
+		}
+		if args.Query != "" {
+			return rpcList(projectController.FindProjects(args.Query))
+		}
+		return rpcList(projectController.ListProjects())
+	})
+
+	server.Handle("project.get", func(params json.RawMessage) (any, error) {
+		var args struct {
+			ID rpcIdentifier `json:"id"`
+		}
+		if err := decodeRPCParams(params, &args); err != nil {
+			return nil, err
+		}
+		return getProject(args.ID)
+	})
+
+	server.Handle("project.create", func(params json.RawMessage) (any, error) {
+		var args struct {
+			Name        string        `json:"name"`
+			Description string        `json:"description"`
+			Parent      rpcIdentifier `json:"parent"`
+		}
+		if err := decodeRPCParams(params, &args); err != nil {
+			return nil, err
+		}
+		project, err := projectController.CreateProject(args.Name, args.Description, string(args.Parent))
+		if err != nil {
+			return nil, err
+		}
+		notifyRPCChange(server, models.SyncItemProject, "created", []int{project.ID})
+		return project, nil
+	})
+
+	server.Handle("project.edit", func(params json.RawMessage) (any, error) {
+		var args struct {
+			ID          rpcIdentifier `json:"id"`
+			Name        string        `json:"name"`
+			Description string        `json:"description"`
+			Parent      rpcIdentifier `json:"parent"`
+		}
+		if err := decodeRPCParams(params, &args); err != nil {
+			return nil, err
+		}
+		project, err := getProject(args.ID)
+		if err != nil {
+			return nil, err
+		}
+		project, err = projectController.EditProject(project.ID, args.Name, args.Description, string(args.Parent))
+		if err != nil {
+			return nil, err
+		}
+		notifyRPCChange(server, models.SyncItemProject, "updated", []int{project.ID})
+		return project, nil
+	})
+
+	server.Handle("project.remove", func(params json.RawMessage) (any, error) {
+		var args struct {
+			ID rpcIdentifier `json:"id"`
+		}
+		if err := decodeRPCParams(params, &args); err != nil {
+			return nil, err
+		}
+		project, err := getProject(args.ID)
+		if err != nil {
+			return nil, err
+		}
+		if err = autoBackup(cmd, backupController, "remove"); err != nil {
+			return nil, err
+		}
+
+		var removed removedJSON
+		err = projectController.InTransaction(func(txController *controllers.ProjectController) error {
+			var removeErr error
+			removed.Removed, removeErr = txController.RemoveProject(project.ID)
+			return removeErr
+		})
+		if err != nil {
+			return nil, err
+		}
+		notifyRPCChange(server, models.SyncItemProject, "removed", removed.Removed)
+		return removed, nil
+	})
+
+	server.Handle("project.subtree", func(params json.RawMessage) (any, error) {
+		var args struct {
+			ID rpcIdentifier `json:"id"`
+		}
+		if err := decodeRPCParams(params, &args); err != nil {
+			return nil, err
+		}
+		project, err := getProject(args.ID)
+		if err != nil {
+			return nil, err
+		}
+		return rpcList(projectController.ListProjectSubtree(project.ID))
+	})
+}
+
+// registerTaskMethods registers the task.* methods.
+func registerTaskMethods(
+	cmd *cobra.Command,
+	server *jsonrpc.Server,
+	taskController *controllers.TaskController,
+	backupController *controllers.BackupController,
+) {
+	getTask := func(id rpcIdentifier) (*models.Task, error) {
+		taskID, err := taskController.ResolveTaskID(string(id))
+		if err != nil {
+			return nil, err
+		}
+		return taskController.GetTaskByID(taskID)
+	}
+	// Changes of state return the task and notify the tasks whose state changed
+	changeState := func(task *models.Task, changed []*models.Task) (any, error) {
+		ids := make([]int, 0, len(changed))
+		for _, changedTask := range changed {
+			ids = append(ids, changedTask.ID)
+		}
+		if len(ids) > 0 {
+			notifyRPCChange(server, models.SyncItemTask, "updated", ids)
+		}
+		return taskController.GetTaskByID(task.ID)
+	}
+
+	server.Handle("task.list", func(params json.RawMessage) (any, error) {
+		var args struct {
+			Project rpcIdentifier `json:"project"`
+			Query   string        `json:"query"`
+		}
+		if err := decodeRPCParams(params, &args); err != nil {
+			return nil, err
+		}
+		if args.Query != "" {
+			return rpcList(taskController.FindTasks(args.Query))
+		}
+		tasks, _, err := taskController.ListTasksByProjectFilter(string(args.Project))
+		return rpcList(tasks, err)
+	})
+
+	server.Handle("task.get", func(params json.RawMessage) (any, error) {
+		var args struct {
+			ID rpcIdentifier `json:"id"`
+		}
+		if err := decodeRPCParams(params, &args); err != nil {
+			return nil, err
+		}
+		return getTask(args.ID)
+	})
+
+	server.Handle("task.create", func(params json.RawMessage) (any, error) {
+		var args struct {
+			Name        string        `json:"name"`
+			Description string        `json:"description"`
+			Project     rpcIdentifier `json:"project"`
+			Parent      rpcIdentifier `json:"parent"`
+			Due         string        `json:"due"`
+			Priority    int           `json:"priority"`
+			Tags        []string      `json:"tags"`
+		}
+		if err := decodeRPCParams(params, &args); err != nil {
+			return nil, err
+		}
+		task, err := taskController.CreateTask(
+			args.Name, args.Description, string(args.Project), string(args.Parent), args.Due, args.Priority,
+			args.Tags,
+		)
+		if err != nil {
+			return nil, err
+		}
+		notifyRPCChange(server, models.SyncItemTask, "created", []int{task.ID})
+		return task, nil
+	})
+
+	server.Handle("task.edit", func(params json.RawMessage) (any, error) {
+		var args struct {
+			ID          rpcIdentifier `json:"id"`
+			Name        string        `json:"name"`
+			Description string        `json:"description"`
+			Due         string        `json:"due"`
+			Priority    int           `json:"priority"`
+			Parent      rpcIdentifier `json:"parent"`
+		}
+		if err := decodeRPCParams(params, &args); err != nil {
+			return nil, err
+		}
+		task, err := getTask(args.ID)
+		if err != nil {
+			return nil, err
+		}
+		task, err = taskController.EditTask(
+			task.ID, args.Name, args.Description, args.Due, args.Priority, string(args.Parent),
+		)
+		if err != nil {
+			return nil, err
+		}
+		notifyRPCChange(server, models.SyncItemTask, "updated", []int{task.ID})
+		return task, nil
+	})
+
+	server.Handle("task.toggle", func(params json.RawMessage) (any, error) {
+		var args struct {
+			ID        rpcIdentifier `json:"id"`
+			Recursive bool          `json:"recursive"`
+			Force     bool          `json:"force"`
+		}
+		if err := decodeRPCParams(params, &args); err != nil {
+			return nil, err
+		}
+		task, err := getTask(args.ID)
+		if err != nil {
+			return nil, err
+		}
+		var changed []*models.Task
+		err = taskController.InTransaction(func(txController *controllers.TaskController) error {
+			var toggleErr error
+			changed, _, toggleErr = txController.ToggleTaskCompletion(task.ID, args.Recursive, args.Force)
+			return toggleErr
+		})
+		if err != nil {
+			return nil, err
+		}
+		return changeState(task, changed)
+	})
+
+	server.Handle("task.complete", func(params json.RawMessage) (any, error) {
+		var args struct {
+			ID        rpcIdentifier `json:"id"`
+			Recursive bool          `json:"recursive"`
+			Force     bool          `json:"force"`
+		}
+		if err := decodeRPCParams(params, &args); err != nil {
+			return nil, err
+		}
+		task, err := getTask(args.ID)
+		if err != nil {
+			return nil, err
+		}
+		var changed []*models.Task
+		err = taskController.InTransaction(func(txController *controllers.TaskController) error {
+			var completeErr error
+			changed, completeErr = txController.CompleteTask(task.ID, args.Recursive, args.Force)
+			return completeErr
+		})
+		if err != nil {
+			return nil, err
+		}
+		return changeState(task, changed)
+	})
+
+	server.Handle("task.reopen", func(params json.RawMessage) (any, error) {
+		var args struct {
+			ID        rpcIdentifier `json:"id"`
+			Recursive bool          `json:"recursive"`
+			Force     bool          `json:"force"`
+		}
+		if err := decodeRPCParams(params, &args); err != nil {
+			return nil, err
+		}
+		task, err := getTask(args.ID)
+		if err != nil {
+			return nil, err
+		}
+		var changed []*models.Task
+		err = taskController.InTransaction(func(txController *controllers.TaskController) error {
+			var reopenErr error
+			changed, reopenErr = txController.ReopenTask(task.ID, args.Recursive, args.Force)
+			return reopenErr
+		})
+		if err != nil {
+			return nil, err
+		}
+		return changeState(task, changed)
+	})
+
+	server.Handle("task.remove", func(params json.RawMessage) (any, error) {
+		var args struct {
+			ID rpcIdentifier `json:"id"`
+		}
+		if err := decodeRPCParams(params, &args); err != nil {
+			return nil, err
+		}
+		task, err := getTask(args.ID)
+		if err != nil {
+			return nil, err
+		}
+		if err = autoBackup(cmd, backupController, "remove"); err != nil {
+			return nil, err
+		}
+
+		var removed removedJSON
+		err = taskController.InTransaction(func(txController *controllers.TaskController) error {
+			var removeErr error
+			removed.Removed, removeErr = txController.RemoveTask(task.ID)
+			return removeErr
+		})
+		if err != nil {
+			return nil, err
+		}
+		notifyRPCChange(server, models.SyncItemTask, "removed", removed.Removed)
+		return removed, nil
+	})
+
+	server.Handle("task.subtree", func(params json.RawMessage) (any, error) {
+		var args struct {
+			ID rpcIdentifier `json:"id"`
+		}
+		if err := decodeRPCParams(params, &args); err != nil {
+			return nil, err
+		}
+		task, err := getTask(args.ID)
+		if err != nil {
+			return nil, err
+		}
+		return rpcList(taskController.ListTaskSubtree(task.ID))
+	})
+}
+
+// decodeRPCParams decodes the named parameters of a call, which may be left out when none is required.
+func decodeRPCParams(params json.RawMessage, args any) error {
+	if len(params) == 0 || bytes.Equal(params, []byte("null")) {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(params))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(args); err != nil {
+		return &jsonrpc.Error{Code: jsonrpc.CodeInvalidParams, Message: "invalid params: " + err.Error()}
+	}
+	return nil
+}
+
+// notifyRPCChange sends the notification of a change. Failures to write it will also fail the response,
+// which reports them.
+func notifyRPCChange(server *jsonrpc.Server, itemType, action string, ids []int) {
+	_ = server.Notify(RPCChangedMethod, rpcChange{ItemType: itemType, Action: action, IDs: ids})
+}
+
+// rpcError converts an error of the controllers to a JSON-RPC error with the code of its kind.
+func rpcError(err error) *jsonrpc.Error {
+	kind := errcode.Of(err)
+	code, found := rpcErrorCodes[kind]
+	if !found {
+		code = jsonrpc.CodeInternalError
+	}
+	return &jsonrpc.Error{Code: code, Message: err.Error(), Data: map[string]string{"kind": string(kind)}}
+}
+
+// rpcList returns the items of a list result, empty rather than nil so they are encoded as an array.
+func rpcList[T any](items []T, err error) ([]T, error) {
+	if err != nil {
+		return nil, err
+	}
+	if items == nil {
+		items = []T{}
+	}
+	return items, nil
+}
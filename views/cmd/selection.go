@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"github.com/d4r1us-drk/clido/models"
+	"github.com/d4r1us-drk/clido/utils"
+	"github.com/spf13/cobra"
+)
+
+// addSelectionFlags adds the flags shared by commands that can operate on several items at once.
+func addSelectionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("where", "w", "", "Select items with a query (e.g. 'project:Inbox priority:1')")
+	cmd.Flags().Bool("dry-run", false, "Preview the affected items without applying any change")
+}
+
+// splitKindArgs extracts the item kind ("project" or "task") from the arguments and returns it
+// together with the remaining ID arguments. The kind may only be omitted when --where is used,
+// in which case tasks are selected.
+func splitKindArgs(cmd *cobra.Command, args []string) (string, []string, bool) {
+	if len(args) > 0 && (args[0] == "project" || args[0] == "task") {
+		return args[0], args[1:], true
+	}
+
+	where, _ := cmd.Flags().GetString("where")
+	if len(args) == 0 && where != "" {
+		return "task", nil, true
+	}
+
+	return "", nil, false
+}
+
+// selectTasks resolves the tasks targeted by a command, either from lists of IDs and ranges
+// such as "3,5,8-12" or from the --where query.
+func selectTasks(
+	cmd *cobra.Command,
+	taskController *controllers.TaskController,
+	idArgs []string,
+) ([]*models.Task, error) {
+	where, _ := cmd.Flags().GetString("where")
+	if err := checkSelection(where, idArgs); err != nil {
+		return nil, err
+	}
+
+	if where != "" {
+		tasks, err := taskController.FindTasks(where)
+		if err != nil {
+			return nil, fmt.Errorf("error selecting tasks: %w", err)
+		}
+		if len(tasks) == 0 {
+			return nil, errcode.New(errcode.NotFound, "no tasks match the query '"+where+"'")
+		}
+		return tasks, nil
+	}
+
+	ids, err := resolveIDList(idArgs, taskController.ResolveTaskID)
+	if err != nil {
+		return nil, fmt.Errorf("error selecting tasks: %w", err)
+	}
+
+	tasks := make([]*models.Task, 0, len(ids))
+	for _, id := range ids {
+		task, getTaskErr := taskController.GetTaskByID(id)
+		if getTaskErr != nil {
+			return nil, fmt.Errorf("error selecting task with ID '%d': %w", id, getTaskErr)
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// selectProjects resolves the projects targeted by a command, either from lists of IDs and ranges
+// such as "3,5,8-12" or from the --where query.
+func selectProjects(
+	cmd *cobra.Command,
+	projectController *controllers.ProjectController,
+	idArgs []string,
+) ([]*models.Project, error) {
+	where, _ := cmd.Flags().GetString("where")
+	if err := checkSelection(where, idArgs); err != nil {
+		return nil, err
+	}
+
+	if where != "" {
+		projects, err := projectController.FindProjects(where)
+		if err != nil {
+			return nil, fmt.Errorf("error selecting projects: %w", err)
+		}
+		if len(projects) == 0 {
+			return nil, errcode.New(errcode.NotFound, "no projects match the query '"+where+"'")
+		}
+		return projects, nil
+	}
+
+	ids, err := resolveIDList(idArgs, projectController.ResolveProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("error selecting projects: %w", err)
+	}
+
+	projects := make([]*models.Project, 0, len(ids))
+	for _, id := range ids {
+		project, getProjectErr := projectController.GetProjectByID(id)
+		if getProjectErr != nil {
+			return nil, fmt.Errorf("error selecting project with ID '%d': %w", id, controllers.ErrNoProjectFound)
+		}
+		projects = append(projects, project)
+	}
+	return projects, nil
+}
+
+// resolveIDList parses lists of IDs, ID ranges, display IDs and UUID prefixes such as "3,5,8-12,@2-4,4f2a"
+// into a sorted list of unique IDs, resolving display IDs and UUID prefixes with the given function.
+func resolveIDList(idArgs []string, resolve func(identifier string) (int, error)) ([]int, error) {
+	var numeric []string
+	var ids []int
+	for _, arg := range idArgs {
+		for _, part := range strings.Split(arg, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			if !utils.IsUUIDPrefix(part) && !strings.HasPrefix(part, controllers.DisplayIDPrefix) {
+				numeric = append(numeric, part)
+				continue
+			}
+			for _, identifier := range expandDisplayIDRange(part) {
+				id, err := resolve(identifier)
+				if err != nil {
+					return nil, err
+				}
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	if len(numeric) > 0 || len(ids) == 0 {
+		parsed, err := utils.ParseIDList(numeric...)
+		if err != nil {
+			return nil, errcode.New(errcode.Validation, err.Error()+
+				". Please provide numeric IDs, ranges (e.g. 3,5,8-12), display IDs (e.g. @3) or UUID prefixes")
+		}
+		ids = append(ids, parsed...)
+	}
+
+	// UUID prefixes may resolve to IDs that are also listed
+	slices.Sort(ids)
+	return slices.Compact(ids), nil
+}
+
+// expandDisplayIDRange expands a range of display IDs such as "@2-4" into "@2", "@3" and "@4". Other
+// identifiers are returned as is.
+func expandDisplayIDRange(identifier string) []string {
+	if !strings.HasPrefix(identifier, controllers.DisplayIDPrefix) || !strings.Contains(identifier, "-") {
+		return []string{identifier}
+	}
+	displayIDs, err := utils.ParseIDList(strings.TrimPrefix(identifier, controllers.DisplayIDPrefix))
+	if err != nil {
+		// Left to the resolver, which reports the invalid identifier
+		return []string{identifier}
+	}
+
+	identifiers := make([]string, 0, len(displayIDs))
+	for _, displayID := range displayIDs {
+		identifiers = append(identifiers, controllers.DisplayIDPrefix+strconv.Itoa(displayID))
+	}
+	return identifiers
+}
+
+// resolveItemID resolves the numeric ID or UUID prefix of a project or task, depending on kind. Unknown
+// kinds are left to the caller, which reports them.
+func resolveItemID(
+	projectController *controllers.ProjectController,
+	taskController *controllers.TaskController,
+	kind, identifier string,
+) (int, error) {
+	var id int
+	var err error
+	switch kind {
+	case "project":
+		id, err = projectController.ResolveProjectID(identifier)
+	case "task":
+		id, err = taskController.ResolveTaskID(identifier)
+	default:
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid ID: %w", err)
+	}
+	return id, nil
+}
+
+// checkSelection ensures that items are selected either by IDs or by a query, but not both.
+func checkSelection(where string, idArgs []string) error {
+	switch {
+	case where != "" && len(idArgs) > 0:
+		return errcode.New(errcode.Validation, "use either IDs or --where, not both")
+	case where == "" && len(idArgs) == 0:
+		return errcode.New(errcode.Validation, "no IDs provided. Use IDs or ranges (e.g. 3,5,8-12) or --where")
+	default:
+		return nil
+	}
+}
+
+// previewSelection prints the items about to be changed when several items are selected, a query
+// is used or --dry-run is set. It reports whether the command must stop without applying changes.
+func previewSelection(cmd *cobra.Command, action string, labels []string) bool {
+	where, _ := cmd.Flags().GetString("where")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	if dryRun || where != "" || len(labels) > 1 {
+		cmd.Println("The following " + strconv.Itoa(len(labels)) + " item(s) will be " + action + ":")
+		for _, label := range labels {
+			cmd.Println("  - " + label)
+		}
+	}
+
+	if dryRun {
+		cmd.Println("Dry run: no changes were made.")
+	}
+	return dryRun
+}
+
+// taskLabels returns the display labels of the given tasks.
+func taskLabels(tasks []*models.Task) []string {
+	labels := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		labels = append(labels, formatTaskLabel(task))
+	}
+	return labels
+}
+
+// projectLabels returns the display labels of the given projects.
+func projectLabels(projects []*models.Project) []string {
+	labels := make([]string, 0, len(projects))
+	for _, project := range projects {
+		labels = append(labels, formatProjectLabel(project))
+	}
+	return labels
+}
@@ -0,0 +1,409 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"github.com/d4r1us-drk/clido/models"
+	"github.com/d4r1us-drk/clido/utils"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/xlab/treeprint"
+)
+
+// breadcrumbJSON is an element of the parent chain of a task or project in JSON output.
+type breadcrumbJSON struct {
+	Type string `json:"type"`
+	ID   int    `json:"id"`
+	UUID string `json:"uuid"`
+	Name string `json:"name"`
+}
+
+// taskDetailJSON is the JSON representation of a task together with its nested subtasks.
+type taskDetailJSON struct {
+	*models.Task
+	ProjectName  string                `json:"project_name"`
+	Breadcrumb   []breadcrumbJSON      `json:"breadcrumb,omitempty"`
+	SubtaskStats controllers.TaskStats `json:"subtask_stats"`
+	Subtasks     []*taskDetailJSON     `json:"subtasks"`
+}
+
+// projectDetailJSON is the JSON representation of a project together with its nested
+// subprojects and tasks.
+type projectDetailJSON struct {
+	*models.Project
+	Breadcrumb  []breadcrumbJSON      `json:"breadcrumb,omitempty"`
+	TaskStats   controllers.TaskStats `json:"task_stats"`
+	Subprojects []*projectDetailJSON  `json:"subprojects"`
+	Tasks       []*taskDetailJSON     `json:"tasks"`
+}
+
+// NewShowCmd creates and returns the 'show' command for displaying every detail of a project or task.
+func NewShowCmd(
+	projectController *controllers.ProjectController,
+	taskController *controllers.TaskController,
+) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show [project|task] <id>",
+		Short: "Show the details of a project or task",
+		Long: "Show every field of a project or task, its parent chain, its subprojects or subtasks " +
+			"with completion counts and, for projects, task statistics.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Ensure sufficient arguments (either 'project' or 'task' followed by an ID)
+			if len(args) < MinArgsLength {
+				return errcode.New(errcode.Validation,
+					"insufficient arguments. Use 'show project <id>' or 'show task <id>'",
+				)
+			}
+
+			// Resolve the ID argument, a numeric ID or a UUID prefix
+			id, err := resolveItemID(projectController, taskController, args[0], args[1])
+			if err != nil {
+				return err
+			}
+
+			outputJSON, _ := cmd.Flags().GetBool("json")
+
+			// Determine whether the user wants to show a project or a task
+			switch args[0] {
+			case "project":
+				return showProject(cmd, projectController, taskController, id, outputJSON)
+			case "task":
+				return showTask(cmd, projectController, taskController, id, outputJSON)
+			default:
+				return errcode.New(errcode.Validation, "invalid option. Use 'show project <id>' or 'show task <id>'")
+			}
+		},
+	}
+
+	cmd.Flags().BoolP("json", "j", false, "Output details in JSON format, including nested children")
+
+	return cmd
+}
+
+// showTask displays a task with its breadcrumb and subtasks.
+func showTask(
+	cmd *cobra.Command,
+	projectController *controllers.ProjectController,
+	taskController *controllers.TaskController,
+	id int,
+	outputJSON bool,
+) error {
+	task, err := taskController.GetTaskByID(id)
+	if err != nil {
+		return fmt.Errorf("error retrieving task: %w", err)
+	}
+
+	project, err := projectController.GetProjectByID(task.ProjectID)
+	if err != nil {
+		return fmt.Errorf("error retrieving project: %w", controllers.ErrNoProjectFound)
+	}
+
+	breadcrumb, err := taskBreadcrumb(projectController, taskController, task)
+	if err != nil {
+		return fmt.Errorf("error retrieving parent chain: %w", err)
+	}
+
+	subtasks, err := taskController.ListTaskSubtree(task.ID)
+	if err != nil {
+		return fmt.Errorf("error retrieving subtasks: %w", err)
+	}
+
+	roots := buildTaskDetails(taskController, append([]*models.Task{task}, subtasks...), project.Name)
+	detail := roots[0]
+	detail.Breadcrumb = breadcrumb
+
+	if outputJSON {
+		return printJSON(cmd, detail)
+	}
+
+	cmd.Println(formatBreadcrumb(breadcrumb))
+
+	parentTask := "None"
+	if len(breadcrumb) > 1 && breadcrumb[len(breadcrumb)-2].Type == "task" {
+		parent := breadcrumb[len(breadcrumb)-2]
+		parentTask = parent.Name + " (ID: " + strconv.Itoa(parent.ID) + ")"
+	}
+
+	printFieldTable(cmd, [][]string{
+		{"ID", strconv.Itoa(task.ID)},
+		{"UUID", task.UUID},
+		{"Name", utils.WrapText(task.Name, MaxProjectDescLength)},
+		{"Description", utils.WrapText(task.Description, MaxProjectDescLength)},
+		{"Project", formatProjectLabel(project)},
+		{"Parent Task", parentTask},
+		{"State", task.State},
+		{"Completed", strconv.FormatBool(task.TaskCompleted)},
+		{"Priority", utils.GetPriorityString(task.Priority)},
+		{"Tags", formatTags(task.Tags)},
+		{"Due Date", utils.FormatDate(task.DueDate)},
+		{"Past Due", utils.ColoredPastDue(task.DueDate, task.CompletionDate != nil)},
+		{"Completion Date", utils.FormatDate(task.CompletionDate)},
+		{"Creation Date", utils.FormatDate(&task.CreationDate)},
+		{"Last Updated", utils.FormatDate(&task.LastUpdatedDate)},
+	})
+
+	cmd.Println("Subtasks (" + formatCompletionCount(detail.SubtaskStats) + "):")
+	if len(detail.Subtasks) > 0 {
+		tree := treeprint.New()
+		addTaskDetailBranches(tree, detail.Subtasks)
+		cmd.Println(tree.String())
+	}
+	return nil
+}
+
+// showProject displays a project with its breadcrumb, task statistics, subprojects and tasks.
+func showProject(
+	cmd *cobra.Command,
+	projectController *controllers.ProjectController,
+	taskController *controllers.TaskController,
+	id int,
+	outputJSON bool,
+) error {
+	project, err := projectController.GetProjectByID(id)
+	if err != nil {
+		return fmt.Errorf("error retrieving project: %w", controllers.ErrNoProjectFound)
+	}
+
+	ancestors, err := projectController.ListProjectAncestors(id)
+	if err != nil {
+		return fmt.Errorf("error retrieving parent chain: %w", err)
+	}
+	breadcrumb := make([]breadcrumbJSON, 0, len(ancestors)+1)
+	for _, ancestor := range append(ancestors, project) {
+		breadcrumb = append(breadcrumb, breadcrumbJSON{
+			Type: "project", ID: ancestor.ID, UUID: ancestor.UUID, Name: ancestor.Name,
+		})
+	}
+
+	detail, err := buildProjectDetail(projectController, taskController, project, map[int]bool{})
+	if err != nil {
+		return err
+	}
+	detail.Breadcrumb = breadcrumb
+
+	if outputJSON {
+		return printJSON(cmd, detail)
+	}
+
+	cmd.Println(formatBreadcrumb(breadcrumb))
+
+	parentProject := "None"
+	if len(ancestors) > 0 {
+		parentProject = formatProjectLabel(ancestors[len(ancestors)-1])
+	}
+
+	printFieldTable(cmd, [][]string{
+		{"ID", strconv.Itoa(project.ID)},
+		{"UUID", project.UUID},
+		{"Name", utils.WrapText(project.Name, MaxProjectDescLength)},
+		{"Description", utils.WrapText(project.Description, MaxProjectDescLength)},
+		{"Parent Project", parentProject},
+		{"Creation Date", utils.FormatDate(&project.CreationDate)},
+		{"Last Modified", utils.FormatDate(&project.LastModifiedDate)},
+	})
+
+	stats := detail.TaskStats
+	statRows := [][]string{
+		{"Total Tasks", strconv.Itoa(stats.Total)},
+		{"Completed", strconv.Itoa(stats.Completed)},
+		{"Open", strconv.Itoa(stats.Open)},
+		{"Overdue", strconv.Itoa(stats.Overdue)},
+	}
+	for _, state := range taskController.Workflow().States {
+		statRows = append(statRows, []string{"State: " + state, strconv.Itoa(stats.ByState[state])})
+	}
+	cmd.Println("Task statistics:")
+	printFieldTable(cmd, statRows)
+
+	cmd.Println("Subprojects (" + strconv.Itoa(len(detail.Subprojects)) + "):")
+	if len(detail.Subprojects) > 0 {
+		tree := treeprint.New()
+		addProjectDetailBranches(tree, detail.Subprojects)
+		cmd.Println(tree.String())
+	}
+
+	cmd.Println("Tasks (" + formatCompletionCount(stats) + "):")
+	if len(detail.Tasks) > 0 {
+		tree := treeprint.New()
+		addTaskDetailBranches(tree, detail.Tasks)
+		cmd.Println(tree.String())
+	}
+	return nil
+}
+
+// taskBreadcrumb returns the parent chain of a task: its project, its ancestor tasks and the task itself.
+func taskBreadcrumb(
+	projectController *controllers.ProjectController,
+	taskController *controllers.TaskController,
+	task *models.Task,
+) ([]breadcrumbJSON, error) {
+	projectAncestors, err := projectController.ListProjectAncestors(task.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	project, err := projectController.GetProjectByID(task.ProjectID)
+	if err != nil {
+		return nil, controllers.ErrNoProjectFound
+	}
+	taskAncestors, err := taskController.ListTaskAncestors(task.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var breadcrumb []breadcrumbJSON
+	for _, ancestor := range append(projectAncestors, project) {
+		breadcrumb = append(breadcrumb, breadcrumbJSON{
+			Type: "project", ID: ancestor.ID, UUID: ancestor.UUID, Name: ancestor.Name,
+		})
+	}
+	for _, ancestor := range append(taskAncestors, task) {
+		breadcrumb = append(breadcrumb, breadcrumbJSON{
+			Type: "task", ID: ancestor.ID, UUID: ancestor.UUID, Name: ancestor.Name,
+		})
+	}
+	return breadcrumb, nil
+}
+
+// buildProjectDetail builds the detail of a project with its tasks and, recursively, its subprojects.
+func buildProjectDetail(
+	projectController *controllers.ProjectController,
+	taskController *controllers.TaskController,
+	project *models.Project,
+	visited map[int]bool,
+) (*projectDetailJSON, error) {
+	visited[project.ID] = true
+
+	tasks, _, err := taskController.ListTasksByProjectFilter(strconv.Itoa(project.ID))
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving tasks: %w", err)
+	}
+
+	detail := &projectDetailJSON{
+		Project:     project,
+		TaskStats:   taskController.GetTaskStats(tasks),
+		Subprojects: []*projectDetailJSON{},
+		Tasks:       buildTaskDetails(taskController, tasks, project.Name),
+	}
+
+	subprojects, err := projectController.ListSubprojects(project.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving subprojects: %w", err)
+	}
+	for _, subproject := range subprojects {
+		if visited[subproject.ID] {
+			continue
+		}
+		subDetail, subErr := buildProjectDetail(projectController, taskController, subproject, visited)
+		if subErr != nil {
+			return nil, subErr
+		}
+		detail.Subprojects = append(detail.Subprojects, subDetail)
+	}
+
+	return detail, nil
+}
+
+// buildTaskDetails arranges a flat list of tasks into trees, returning the tasks whose parent is
+// not part of the list. Each node holds statistics over all of its descendants.
+func buildTaskDetails(
+	taskController *controllers.TaskController,
+	tasks []*models.Task,
+	projectName string,
+) []*taskDetailJSON {
+	known := make(map[int]bool, len(tasks))
+	children := make(map[int][]*models.Task)
+	for _, task := range tasks {
+		known[task.ID] = true
+	}
+
+	var roots []*models.Task
+	for _, task := range tasks {
+		if task.ParentTaskID != nil && known[*task.ParentTaskID] {
+			children[*task.ParentTaskID] = append(children[*task.ParentTaskID], task)
+		} else {
+			roots = append(roots, task)
+		}
+	}
+
+	// build returns the node of a task and the flat list of its descendants
+	var build func(task *models.Task) (*taskDetailJSON, []*models.Task)
+	build = func(task *models.Task) (*taskDetailJSON, []*models.Task) {
+		node := &taskDetailJSON{Task: task, ProjectName: projectName, Subtasks: []*taskDetailJSON{}}
+		var descendants []*models.Task
+		for _, child := range children[task.ID] {
+			childNode, childDescendants := build(child)
+			node.Subtasks = append(node.Subtasks, childNode)
+			descendants = append(descendants, child)
+			descendants = append(descendants, childDescendants...)
+		}
+		node.SubtaskStats = taskController.GetTaskStats(descendants)
+		return node, descendants
+	}
+
+	nodes := make([]*taskDetailJSON, 0, len(roots))
+	for _, root := range roots {
+		node, _ := build(root)
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// addTaskDetailBranches adds the task nodes, recursively, to the tree.
+func addTaskDetailBranches(tree treeprint.Tree, nodes []*taskDetailJSON) {
+	for _, node := range nodes {
+		label := formatTaskLabel(node.Task) + " [" + node.State + "]"
+		if node.SubtaskStats.Total > 0 {
+			label += " - " + formatCompletionCount(node.SubtaskStats)
+		}
+		addTaskDetailBranches(tree.AddBranch(label), node.Subtasks)
+	}
+}
+
+// addProjectDetailBranches adds the project nodes, recursively, to the tree.
+func addProjectDetailBranches(tree treeprint.Tree, nodes []*projectDetailJSON) {
+	for _, node := range nodes {
+		label := formatProjectLabel(node.Project) + " - tasks: " + formatCompletionCount(node.TaskStats)
+		addProjectDetailBranches(tree.AddBranch(label), node.Subprojects)
+	}
+}
+
+// formatTags formats the comma-separated tags of a task as "#billing @home", or "None".
+func formatTags(tags string) string {
+	if tags == "" {
+		return "None"
+	}
+	labels := strings.Split(tags, ",")
+	for i, tag := range labels {
+		if !strings.HasPrefix(tag, "@") {
+			labels[i] = "#" + tag
+		}
+	}
+	return strings.Join(labels, " ")
+}
+
+// formatCompletionCount formats statistics as "completed/total completed".
+func formatCompletionCount(stats controllers.TaskStats) string {
+	return strconv.Itoa(stats.Completed) + "/" + strconv.Itoa(stats.Total) + " completed"
+}
+
+// formatBreadcrumb formats a parent chain as "Project > Subproject > Task".
+func formatBreadcrumb(breadcrumb []breadcrumbJSON) string {
+	names := make([]string, 0, len(breadcrumb))
+	for _, element := range breadcrumb {
+		names = append(names, element.Name)
+	}
+	return strings.Join(names, " > ")
+}
+
+// printFieldTable displays name/value pairs in a two-column table.
+func printFieldTable(cmd *cobra.Command, rows [][]string) {
+	table := tablewriter.NewWriter(cmd.OutOrStdout())
+	table.SetAutoWrapText(false)
+	table.SetColumnAlignment([]int{tablewriter.ALIGN_LEFT, tablewriter.ALIGN_LEFT})
+	table.AppendBulk(rows)
+	table.Render()
+}
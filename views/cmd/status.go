@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/internal/errcode"
+	"github.com/d4r1us-drk/clido/models"
+	"github.com/d4r1us-drk/clido/utils"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// NewStatusCmd creates and returns the 'status' command for moving tasks through the workflow
+// states, or displaying the state history of a task.
+func NewStatusCmd(taskController *controllers.TaskController) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status <task_ids> [state]",
+		Short: "Set or show the workflow state of tasks",
+		Long: "Move tasks identified by IDs or ID ranges (e.g. 3,5,8-12), or selected with a query " +
+			"(--where), to a workflow state. Only the transitions allowed by the workflow are accepted " +
+			"unless --force is set. Without a state, the state history of a single task is displayed.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			where, _ := cmd.Flags().GetString("where")
+
+			// Show the history of a single task when no state is given
+			if len(args) == 1 && where == "" {
+				id, err := taskController.ResolveTaskID(args[0])
+				if err != nil {
+					return fmt.Errorf("invalid task ID: %w", err)
+				}
+				return showTaskStatus(cmd, taskController, id)
+			}
+
+			if len(args) < 1 {
+				return errcode.New(errcode.Validation, "insufficient arguments. Use 'status <task_ids> <state>'")
+			}
+			state := args[len(args)-1]
+
+			return setTaskStatus(cmd, taskController, args[:len(args)-1], state)
+		},
+	}
+
+	cmd.Flags().BoolP("force", "f", false, "Ignore the workflow transition rules")
+	addSelectionFlags(cmd)
+	addJSONFlag(cmd, "Output the tasks whose state changed in JSON format")
+
+	return cmd
+}
+
+// setTaskStatus moves the selected tasks to the given state in a single transaction.
+func setTaskStatus(
+	cmd *cobra.Command,
+	taskController *controllers.TaskController,
+	idArgs []string,
+	state string,
+) error {
+	if !taskController.Workflow().HasState(state) {
+		return errcode.New(errcode.Validation, "unknown state '"+state+"'. Available states: "+
+			strings.Join(taskController.Workflow().States, ", "))
+	}
+
+	tasks, selectErr := selectTasks(cmd, taskController, idArgs)
+	if selectErr != nil {
+		return selectErr
+	}
+	if previewSelection(cmd, "set as "+state, taskLabels(tasks)) {
+		return nil
+	}
+
+	force, _ := cmd.Flags().GetBool("force")
+
+	changed := make([]bool, len(tasks))
+	var changedTasks []*models.Task
+	err := taskController.InTransaction(func(txController *controllers.TaskController) error {
+		for i, task := range tasks {
+			updated, stateChanged, setErr := txController.SetTaskState(task.ID, state, force)
+			if setErr != nil {
+				return fmt.Errorf("task with ID '%d': %w", task.ID, setErr)
+			}
+			changed[i] = stateChanged
+			if stateChanged {
+				changedTasks = append(changedTasks, updated)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error setting task state: %w", err)
+	}
+
+	if outputJSON, _ := cmd.Flags().GetBool("json"); outputJSON {
+		return printChangedTasksJSON(cmd, changedTasks)
+	}
+
+	for i, task := range tasks {
+		if changed[i] {
+			cmd.Println("Task (ID: " + strconv.Itoa(task.ID) + ") has been set as " + state + ".")
+		} else {
+			cmd.Println("Task (ID: " + strconv.Itoa(task.ID) + ") is already " + state + ".")
+		}
+	}
+	return nil
+}
+
+// showTaskStatus prints the current state of a task, the states it can move to, and its history.
+func showTaskStatus(cmd *cobra.Command, taskController *controllers.TaskController, id int) error {
+	task, err := taskController.GetTaskByID(id)
+	if err != nil {
+		return fmt.Errorf("error retrieving task: %w", err)
+	}
+
+	transitions, err := taskController.ListTaskTransitions(id)
+	if err != nil {
+		return fmt.Errorf("error retrieving task history: %w", err)
+	}
+
+	workflow := taskController.Workflow()
+	cmd.Println("Task '" + task.Name + "' (ID: " + strconv.Itoa(task.ID) + ") is " + task.State + ".")
+	cmd.Println("Allowed transitions: " + formatStates(workflow.Transitions[task.State]))
+
+	table := tablewriter.NewWriter(cmd.OutOrStdout())
+	table.SetHeader([]string{"Date", "From", "To"})
+	table.Append([]string{utils.FormatDate(&task.CreationDate), "", "created"})
+	for _, transition := range transitions {
+		table.Append([]string{
+			utils.FormatDate(&transition.TransitionDate),
+			transition.FromState,
+			transition.ToState,
+		})
+	}
+	table.Render()
+
+	return nil
+}
+
+// formatStates joins state names for display, or returns "None" if there are none.
+func formatStates(states []string) string {
+	if len(states) == 0 {
+		return "None"
+	}
+	return strings.Join(states, ", ")
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// CalDAVPasswordEnv is the environment variable holding the password of the CalDAV server.
+const CalDAVPasswordEnv = "CLIDO_CALDAV_PASSWORD"
+
+// NewSyncCmd creates and returns the 'sync' command for syncing the database through a git repository,
+// and its 'caldav' subcommand for syncing with a CalDAV server.
+func NewSyncCmd(
+	syncController *controllers.SyncController,
+	caldavController *controllers.CalDAVController,
+) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Sync projects and tasks through a git repository",
+		Long: "Sync projects and tasks through a git repository holding one file per project and task. " +
+			"Local changes are committed, remote changes are pulled and merged field by field, and the " +
+			"result is pushed back. Fields changed on both sides keep the most recently updated value and " +
+			"are reported as conflicts. The remote is saved, so --remote is only needed once.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			remote, _ := cmd.Flags().GetString("remote")
+			noPush, _ := cmd.Flags().GetBool("no-push")
+
+			result, err := syncController.Sync(remote, !noPush)
+			if err != nil {
+				return fmt.Errorf("error syncing: %w", err)
+			}
+
+			if result.Remote == "" {
+				cmd.Println("No remote configured, changes are only committed in " + result.Dir + ".")
+			}
+			if result.Backup != "" {
+				cmd.Println("Backup of the database written to " + result.Backup + ".")
+			}
+			cmd.Println("Applied remote changes: " + strconv.Itoa(result.Created) + " created, " +
+				strconv.Itoa(result.Updated) + " updated, " + strconv.Itoa(result.Deleted) + " deleted.")
+			if result.Committed {
+				cmd.Println("Committed local changes.")
+			}
+			if result.Pushed {
+				cmd.Println("Pushed to " + result.Remote + ".")
+			}
+
+			if len(result.Conflicts) > 0 {
+				cmd.Println(strconv.Itoa(len(result.Conflicts)) + " conflict(s) resolved automatically:")
+				printSyncConflicts(cmd, result.Conflicts)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("remote", "r", "", "URL or path of the git remote to sync with (saved for later syncs)")
+	cmd.Flags().Bool("no-push", false, "Commit and merge remote changes without pushing")
+
+	cmd.AddCommand(newSyncCalDAVCmd(caldavController))
+
+	return cmd
+}
+
+// newSyncCalDAVCmd creates the 'sync caldav' command for syncing tasks with a CalDAV server.
+func newSyncCalDAVCmd(caldavController *controllers.CalDAVController) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "caldav",
+		Short: "Sync projects and tasks with a CalDAV server",
+		Long: "Sync projects and tasks with a CalDAV server, e.g. to see them in Thunderbird or reminder apps. " +
+			"Projects are mapped to calendars and tasks to VTODO items. Changes made on either side since the " +
+			"last sync are applied to the other side; items changed on both sides keep the most recently " +
+			"modified version. The URL is the calendar home collection holding the user's calendars, e.g. " +
+			"https://example.com/remote.php/dav/calendars/me/, and is saved with the username for later syncs. " +
+			"The password is read from the " + CalDAVPasswordEnv + " environment variable.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			url, _ := cmd.Flags().GetString("url")
+			username, _ := cmd.Flags().GetString("user")
+
+			result, err := caldavController.Sync(url, username, os.Getenv(CalDAVPasswordEnv))
+			if err != nil {
+				return fmt.Errorf("error syncing with CalDAV server: %w", err)
+			}
+
+			if result.Backup != "" {
+				cmd.Println("Backup of the database written to " + result.Backup + ".")
+			}
+			cmd.Println("Applied server changes: " + strconv.Itoa(result.Created) + " created, " +
+				strconv.Itoa(result.Updated) + " updated, " + strconv.Itoa(result.Deleted) + " deleted.")
+			cmd.Println("Sent local changes to " + result.URL + ": " + strconv.Itoa(result.Uploaded) +
+				" created or updated, " + strconv.Itoa(result.Removed) + " deleted.")
+
+			if len(result.Conflicts) > 0 {
+				cmd.Println(strconv.Itoa(len(result.Conflicts)) + " conflict(s) resolved automatically:")
+				printSyncConflicts(cmd, result.Conflicts)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("url", "u", "", "URL of the calendar home collection (saved for later syncs)")
+	cmd.Flags().String("user", "", "Username of the CalDAV server (saved for later syncs)")
+
+	return cmd
+}
+
+// printSyncConflicts prints the conflicts of a sync as a table.
+func printSyncConflicts(cmd *cobra.Command, conflicts []*controllers.SyncConflict) {
+	table := tablewriter.NewWriter(cmd.OutOrStdout())
+	table.SetHeader([]string{"Type", "Name", "Field", "Local", "Remote", "Kept"})
+	for _, conflict := range conflicts {
+		field := conflict.Field
+		if field == "" {
+			field = "(item)"
+		}
+		table.Append([]string{conflict.ItemType, conflict.Name, field, conflict.Local, conflict.Remote, conflict.Kept})
+	}
+	table.Render()
+}
@@ -1,49 +1,71 @@
 package cmd
 
 import (
-	"errors"
+	"fmt"
 	"strconv"
 
 	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/models"
 	"github.com/spf13/cobra"
 )
 
 // NewToggleCmd creates and returns the 'toggle' command for marking tasks as completed or uncompleted.
 func NewToggleCmd(taskController *controllers.TaskController) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "toggle <task_id>",
+		Use:   "toggle <task_ids>",
 		Short: "Toggle task completion status",
-		Long:  "Toggle the completion status of a task identified by its ID.",
+		Long: "Toggle the completion status of tasks identified by IDs or ID ranges (e.g. 3,5,8-12), " +
+			"or selected with a query (--where): open tasks are completed and done tasks are reopened, while " +
+			"tasks closed in another state, such as cancelled, are rejected. All changes are applied together. " +
+			"Only the transitions allowed by the workflow are accepted unless --force is set.",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// Ensure at least one argument (the task ID) is provided
-			if len(args) < 1 {
-				return errors.New("insufficient arguments. Use 'toggle <task_id>'")
+			// Resolve the selected tasks from the ID arguments or the query
+			tasks, selectErr := selectTasks(cmd, taskController, args)
+			if selectErr != nil {
+				return selectErr
 			}
-
-			// Parse the task ID argument into an integer
-			id, err := strconv.Atoi(args[0])
-			if err != nil {
-				return errors.New("invalid task ID. Please provide a numeric ID")
+			if previewSelection(cmd, "toggled", taskLabels(tasks)) {
+				return nil
 			}
 
-			// Check if the recursive flag was provided
+			// Check if the recursive and force flags were provided
 			recursive, _ := cmd.Flags().GetBool("recursive")
+			force, _ := cmd.Flags().GetBool("force")
 
-			// Toggle task completion status using the controller
-			completionStatus, toggleErr := taskController.ToggleTaskCompletion(id, recursive)
+			// Toggle task completion status using the controller, in a single transaction
+			completionStatuses := make([]string, len(tasks))
+			var changed []*models.Task
+			toggleErr := taskController.InTransaction(func(txController *controllers.TaskController) error {
+				for i, task := range tasks {
+					toggled, completionStatus, err := txController.ToggleTaskCompletion(task.ID, recursive, force)
+					if err != nil {
+						return fmt.Errorf("task with ID '%d': %w", task.ID, err)
+					}
+					changed = append(changed, toggled...)
+					completionStatuses[i] = completionStatus
+				}
+				return nil
+			})
 			if toggleErr != nil {
-				return errors.New("error toggling task: " + toggleErr.Error())
+				return fmt.Errorf("error toggling task: %w", toggleErr)
+			}
+
+			if outputJSON, _ := cmd.Flags().GetBool("json"); outputJSON {
+				return printChangedTasksJSON(cmd, changed)
 			}
 
 			// Print the result based on the recursive flag
-			if recursive {
-				cmd.Println(
-					"Task (ID: " + strconv.Itoa(
-						id,
-					) + ") and its subtasks (if any) have been set as " + completionStatus + ".",
-				)
-			} else {
-				cmd.Println("Task (ID: " + strconv.Itoa(id) + ") has been set as " + completionStatus + ".")
+			for i, task := range tasks {
+				id := task.ID
+				if recursive {
+					cmd.Println(
+						"Task (ID: " + strconv.Itoa(
+							id,
+						) + ") and its subtasks (if any) have been set as " + completionStatuses[i] + ".",
+					)
+				} else {
+					cmd.Println("Task (ID: " + strconv.Itoa(id) + ") has been set as " + completionStatuses[i] + ".")
+				}
 			}
 
 			return nil
@@ -52,6 +74,9 @@ func NewToggleCmd(taskController *controllers.TaskController) *cobra.Command {
 
 	// Add flag for recursive toggle, allowing users to recursively toggle all subtasks
 	cmd.Flags().BoolP("recursive", "r", false, "Recursively toggle subtasks")
+	cmd.Flags().BoolP("force", "f", false, "Ignore the workflow transition rules")
+	addSelectionFlags(cmd)
+	addJSONFlag(cmd, "Output the tasks whose state changed in JSON format")
 
 	return cmd
 }
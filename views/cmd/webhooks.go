@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/d4r1us-drk/clido/internal/hooks"
+	"github.com/d4r1us-drk/clido/internal/webhook"
+	"github.com/d4r1us-drk/clido/utils"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// MaxDeliveryErrorLength is the maximum length of the delivery errors shown in the webhook log.
+const MaxDeliveryErrorLength = 40
+
+// NewWebhooksCmd creates and returns the 'webhooks' command, which manages the webhooks notified of the
+// changes of projects and tasks and inspects their deliveries.
+func NewWebhooksCmd(webhookController *controllers.WebhookController) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "webhooks",
+		Short: "Manage the webhooks notified of project and task changes",
+		Long: "Post the changes of projects and tasks (the " + strings.Join(hooks.Events, ", ") + " events) to " +
+			"HTTP endpoints as JSON, with the event, item type, workspace and the item before and after the " +
+			"change. Each payload is signed with the secret of the webhook in the " + webhook.HeaderSignature +
+			" header ('" + webhook.SignaturePrefix + "' followed by the hex HMAC-SHA256 of the body), and " +
+			webhook.HeaderDelivery + " identifies the delivery across attempts. Deliveries are queued with the " +
+			"change and posted once it is saved; failed ones are attempted again by later changes or by " +
+			"'webhooks deliver', waiting longer after each attempt, and given up after " +
+			strconv.Itoa(controllers.MaxDeliveryAttempts) + " attempts.",
+	}
+
+	addCmd := &cobra.Command{
+		Use:   "add <url>",
+		Short: "Add a webhook",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			secret, _ := cmd.Flags().GetString("secret")
+			project, _ := cmd.Flags().GetString("project")
+			events, _ := cmd.Flags().GetStringSlice("events")
+
+			hook, err := webhookController.AddWebhook(args[0], secret, project, events)
+			if err != nil {
+				return fmt.Errorf("error adding webhook: %w", err)
+			}
+			cmd.Println("Webhook (ID: " + strconv.Itoa(hook.ID) + ") added for '" + hook.URL + "'.")
+			if secret == "" {
+				cmd.Println("Signing secret: " + hook.Secret)
+			}
+			return nil
+		},
+	}
+	addCmd.Flags().String("secret", "", "Secret used to sign the payloads (generated when not given)")
+	addCmd.Flags().StringP("project", "p", "", "Only post the changes of this project (name, ID or UUID prefix) "+
+		"and its tasks")
+	addCmd.Flags().StringSlice("events", nil, "Events to post, e.g. on-create,on-complete (all by default)")
+	cmd.AddCommand(addCmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the webhooks",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			webhooks, err := webhookController.ListWebhooks()
+			if err != nil {
+				return fmt.Errorf("error listing webhooks: %w", err)
+			}
+			if len(webhooks) == 0 {
+				cmd.Println("No webhooks found.")
+				return nil
+			}
+
+			table := tablewriter.NewWriter(cmd.OutOrStdout())
+			table.SetHeader([]string{"ID", "URL", "Project", "Events"})
+			for _, hook := range webhooks {
+				project, events := "all", "all"
+				if hook.ProjectID != nil {
+					project = strconv.Itoa(*hook.ProjectID)
+				}
+				if hook.Events != "" {
+					events = hook.Events
+				}
+				table.Append([]string{strconv.Itoa(hook.ID), hook.URL, project, events})
+			}
+			table.Render()
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "remove <id>",
+		Short: "Remove a webhook and its delivery log",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, parseErr := utils.ParseIntOrError(args[0])
+			if parseErr != nil {
+				return errors.New("invalid webhook ID: " + args[0])
+			}
+			if err := webhookController.RemoveWebhook(id); err != nil {
+				return fmt.Errorf("error removing webhook: %w", err)
+			}
+			cmd.Println("Webhook (ID: " + args[0] + ") removed successfully.")
+			return nil
+		},
+	})
+
+	logCmd := &cobra.Command{
+		Use:   "log",
+		Short: "Show the latest webhook deliveries",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			webhookID, _ := cmd.Flags().GetInt("webhook")
+			limit, _ := cmd.Flags().GetInt("limit")
+			outputJSON, _ := cmd.Flags().GetBool("json")
+
+			deliveries, err := webhookController.ListDeliveries(webhookID, limit)
+			if err != nil {
+				return fmt.Errorf("error listing webhook deliveries: %w", err)
+			}
+
+			if outputJSON {
+				jsonData, jsonErr := json.MarshalIndent(deliveries, "", "  ")
+				if jsonErr != nil {
+					return fmt.Errorf("error marshalling deliveries to JSON: %w", jsonErr)
+				}
+				cmd.Println(string(jsonData))
+				return nil
+			}
+			if len(deliveries) == 0 {
+				cmd.Println("No webhook deliveries found.")
+				return nil
+			}
+
+			table := tablewriter.NewWriter(cmd.OutOrStdout())
+			table.SetHeader([]string{"ID", "Webhook", "Event", "Item", "Status", "Attempts", "Response", "Date",
+				"Error"})
+			for _, delivery := range deliveries {
+				response := ""
+				if delivery.ResponseCode != 0 {
+					response = strconv.Itoa(delivery.ResponseCode)
+				}
+				table.Append([]string{
+					strconv.Itoa(delivery.ID),
+					strconv.Itoa(delivery.WebhookID),
+					delivery.Event,
+					delivery.ItemType + " " + strconv.Itoa(delivery.ItemID),
+					delivery.Status,
+					strconv.Itoa(delivery.Attempts),
+					response,
+					utils.FormatDate(&delivery.CreationDate),
+					utils.WrapText(delivery.LastError, MaxDeliveryErrorLength),
+				})
+			}
+			table.Render()
+			return nil
+		},
+	}
+	logCmd.Flags().Int("webhook", 0, "Only show the deliveries of this webhook")
+	logCmd.Flags().IntP("limit", "l", 20, "Maximum number of deliveries shown (0 for all)")
+	logCmd.Flags().BoolP("json", "j", false, "Output deliveries in JSON format")
+	cmd.AddCommand(logCmd)
+
+	deliverCmd := &cobra.Command{
+		Use:   "deliver",
+		Short: "Attempt the pending webhook deliveries",
+		Long: "Attempt the pending webhook deliveries whose next attempt is due, or all of them with --all, " +
+			"e.g. from a scheduled job.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			all, _ := cmd.Flags().GetBool("all")
+
+			result, err := webhookController.DeliverPending(all)
+			if err != nil {
+				return fmt.Errorf("error delivering webhooks: %w", err)
+			}
+			cmd.Println("Attempted " + strconv.Itoa(result.Attempted) + " delivery(ies): " +
+				strconv.Itoa(result.Delivered) + " delivered, " + strconv.Itoa(result.Retrying) + " to retry, " +
+				strconv.Itoa(result.Failed) + " given up.")
+			return nil
+		},
+	}
+	deliverCmd.Flags().Bool("all", false, "Also attempt the deliveries whose next attempt is not due yet")
+	cmd.AddCommand(deliverCmd)
+
+	return cmd
+}
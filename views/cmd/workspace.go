@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/d4r1us-drk/clido/controllers"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// WorkspaceFlag is the name of the global flag selecting the workspace to use.
+const WorkspaceFlag = "workspace"
+
+// WorkspaceFromArgs returns the value of the --workspace flag in the command-line arguments, or an empty
+// string if it is not set. It is used to open the right database before the commands are parsed.
+func WorkspaceFromArgs(args []string) string {
+	flags := pflag.NewFlagSet("clido", pflag.ContinueOnError)
+	flags.ParseErrorsWhitelist.UnknownFlags = true
+	flags.Usage = func() {}
+	workspace := flags.String(WorkspaceFlag, "", "")
+
+	// Errors are reported by the command parser later on
+	_ = flags.Parse(args)
+	return *workspace
+}
+
+// NewWorkspaceCmd creates and returns the 'workspace' command for managing workspaces, each with
+// its own database and configuration.
+func NewWorkspaceCmd(workspaceController *controllers.WorkspaceController) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "workspace",
+		Short: "Manage workspaces",
+		Long: "Manage workspaces, e.g. to keep work and personal projects apart. Each workspace has its own " +
+			"database, workflow configuration and backups. Commands use the current workspace unless " +
+			"--workspace is given.",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "create <name>",
+		Short: "Create a workspace",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := workspaceController.CreateWorkspace(args[0]); err != nil {
+				return fmt.Errorf("error creating workspace: %w", err)
+			}
+			cmd.Println("Workspace '" + args[0] + "' created successfully.")
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List workspaces",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			workspaces, err := workspaceController.ListWorkspaces()
+			if err != nil {
+				return fmt.Errorf("error listing workspaces: %w", err)
+			}
+
+			for _, workspace := range workspaces {
+				if workspace == workspaceController.ActiveWorkspace() {
+					cmd.Println("* " + workspace)
+				} else {
+					cmd.Println("  " + workspace)
+				}
+			}
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "use <name>",
+		Short: "Make a workspace the current one",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := workspaceController.UseWorkspace(args[0]); err != nil {
+				return fmt.Errorf("error switching workspace: %w", err)
+			}
+			cmd.Println("Now using workspace '" + args[0] + "'.")
+			return nil
+		},
+	})
+
+	removeCmd := &cobra.Command{
+		Use:     "rm <name>",
+		Aliases: []string{"remove"},
+		Short:   "Remove a workspace with its database, configuration and backups",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			force, _ := cmd.Flags().GetBool("force")
+			if err := workspaceController.RemoveWorkspace(args[0], force); err != nil {
+				return fmt.Errorf("error removing workspace: %w", err)
+			}
+			cmd.Println("Workspace '" + args[0] + "' removed successfully.")
+			return nil
+		},
+	}
+	removeCmd.Flags().BoolP("force", "f", false, "Remove the workspace even if it holds projects or tasks")
+	cmd.AddCommand(removeCmd)
+
+	return cmd
+}